@@ -1,13 +1,28 @@
 package config
 
 import (
+	"fmt"
+	"orderPickupPoint/internal/models"
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
 	ServerAddress string
 	DbURL         string
 	SecretWord    string
+
+	StaleReceptions    models.StaleReceptionPolicy
+	StaleCheckInterval time.Duration
+
+	AttachmentsDir     string
+	AttachmentMaxBytes int64
+
+	PickupCodes             models.PickupCodePolicy
+	PickupCodeCheckInterval time.Duration
+
+	UnclaimedCheckInterval time.Duration
 }
 
 func LoadConfig() (*Config, error) {
@@ -15,7 +30,87 @@ func LoadConfig() (*Config, error) {
 		ServerAddress: os.Getenv("SERVER_ADDRESS"),
 		DbURL:         os.Getenv("DB_URL"),
 		SecretWord:    os.Getenv("SECRET_WORD"),
+		StaleReceptions: models.StaleReceptionPolicy{
+			Action: envOr("RECEPTION_STALE_ACTION", models.StaleActionClose),
+		},
+		AttachmentsDir: envOr("ATTACHMENTS_DIR", "./data/attachments"),
+	}
+
+	var err error
+	config.StaleReceptions.MaxOpen, err = durationEnv("RECEPTION_MAX_OPEN", 24*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	config.StaleReceptions.IdleTimeout, err = durationEnv("RECEPTION_IDLE_TIMEOUT", 0)
+	if err != nil {
+		return nil, err
+	}
+	config.StaleCheckInterval, err = durationEnv("RECEPTION_STALE_CHECK_INTERVAL", 10*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	config.AttachmentMaxBytes, err = int64Env("ATTACHMENT_MAX_BYTES", 10<<20)
+	if err != nil {
+		return nil, err
+	}
+	config.PickupCodeCheckInterval, err = durationEnv("PICKUP_CODE_CHECK_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	config.UnclaimedCheckInterval, err = durationEnv("UNCLAIMED_CHECK_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	digits, err := int64Env("PICKUP_CODE_DIGITS", 6)
+	if err != nil {
+		return nil, err
+	}
+	maxAttempts, err := int64Env("PICKUP_CODE_MAX_ATTEMPTS", 5)
+	if err != nil {
+		return nil, err
+	}
+	config.PickupCodes = models.PickupCodePolicy{
+		Digits:      int(digits),
+		MaxAttempts: int(maxAttempts),
+	}
+	if digits > models.MaxPickupCodeDigits {
+		return nil, fmt.Errorf("PICKUP_CODE_DIGITS: at most %d digits, got %d", models.MaxPickupCodeDigits, digits)
+	}
+	if action := config.StaleReceptions.Action; action != models.StaleActionClose && action != models.StaleActionFlag {
+		return nil, fmt.Errorf("RECEPTION_STALE_ACTION: unknown action %q", action)
 	}
 
 	return config, nil
 }
+
+func envOr(key string, def string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return def
+}
+
+func int64Env(key string, def int64) (int64, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("%s: invalid positive number %q", key, value)
+	}
+	return n, nil
+}
+
+// "0" disables the corresponding job or check
+func durationEnv(key string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s: invalid duration %q", key, value)
+	}
+	return d, nil
+}
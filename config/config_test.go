@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigPickupCodes(t *testing.T) {
+	tests := []struct {
+		name        string
+		digits      string
+		maxAttempts string
+		wantError   bool
+	}{
+		{name: "defaults"},
+		{name: "valid", digits: "8", maxAttempts: "3"},
+		{name: "zero digits", digits: "0", wantError: true},
+		{name: "too many digits", digits: "73", wantError: true},
+		{name: "negative attempts", maxAttempts: "-1", wantError: true},
+		{name: "zero attempts", maxAttempts: "0", wantError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("PICKUP_CODE_DIGITS", tt.digits)
+			t.Setenv("PICKUP_CODE_MAX_ATTEMPTS", tt.maxAttempts)
+
+			config, err := LoadConfig()
+
+			if tt.wantError {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Positive(t, config.PickupCodes.Digits)
+			require.Positive(t, config.PickupCodes.MaxAttempts)
+		})
+	}
+}
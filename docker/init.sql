@@ -24,31 +24,378 @@ create table reception_statuses (
     id   serial primary key,
     name text not null unique);
 
-create table product_types (
+create table product_conditions (
     id   serial primary key,
     name text not null unique);
 
+create table product_types (
+    id     serial primary key,
+    code   text not null unique,
+    -- default (ru) display name
+    name   text not null unique,
+    -- inactive types are kept for existing products but not accepted anymore
+    active boolean not null default true,
+    -- attributes of accepted products, see models.ProductTypeRules
+    weight_required boolean not null default false,
+    dimensions_required boolean not null default false,
+    declared_value_required boolean not null default false,
+    max_weight_grams int check (max_weight_grams > 0),
+    max_side_mm int check (max_side_mm > 0),
+    max_declared_value bigint check (max_declared_value > 0),
+    -- days accepted products wait for the customer, null is models.DefaultStorageDays
+    storage_days int check (storage_days > 0));
+
+create table product_type_names (
+    type_id int not null references product_types(id) ON DELETE CASCADE,
+    locale  text not null,
+    name    text not null,
+    primary key (type_id, locale));
+
 create table pvzs (
     id       UUID primary key default gen_random_uuid(),
     reg_date date not null default CURRENT_DATE,
-    city_id  int not null references cities(id) ON DELETE RESTRICT);
+    city_id  int not null references cities(id) ON DELETE RESTRICT,
+    max_items int check (max_items >= 0),
+    strict_capacity boolean not null default true,
+    -- overrides the storage period of the product types
+    storage_days int check (storage_days > 0));
+
+create table pvz_type_capacities (
+    pvz_id    UUID not null references pvzs(id) ON DELETE CASCADE,
+    type_id   int not null references product_types(id) ON DELETE RESTRICT,
+    max_items int not null check (max_items >= 0),
+    primary key (pvz_id, type_id));
+
+create table pvz_type_storage_days (
+    pvz_id  UUID not null references pvzs(id) ON DELETE CASCADE,
+    type_id int not null references product_types(id) ON DELETE RESTRICT,
+    days    int not null check (days > 0),
+    primary key (pvz_id, type_id));
+
+-- storage deadline of a product of the type accepted in the pvz now. The period set for the type in the pvz
+-- comes first, then the period of the pvz and of the type, then default_days
+create function storage_deadline(pvz UUID, type_id int, default_days int) returns TIMESTAMPTZ
+language sql stable as $$
+    select now() + make_interval(days => coalesce(
+        (select sd.days from pvz_type_storage_days sd where sd.pvz_id = pvz and sd.type_id = storage_deadline.type_id),
+        (select p.storage_days from pvzs p where p.id = pvz),
+        (select t.storage_days from product_types t where t.id = storage_deadline.type_id),
+        default_days))
+$$;
+
+create table pvz_stock (
+    pvz_id  UUID not null references pvzs(id) ON DELETE CASCADE,
+    type_id int not null references product_types(id) ON DELETE RESTRICT,
+    items   int not null default 0,
+    primary key (pvz_id, type_id));
 
 create table receptions (
 	id UUID primary key default gen_random_uuid(),
 	reception_start_datetime TIMESTAMPTZ not null default now(),
 	pvz_id UUID not null references pvzs(id)ON DELETE RESTRICT,
-	status_id int not null default 1 references reception_statuses(id) ON DELETE RESTRICT);
+	status_id int not null default 1 references reception_statuses(id) ON DELETE RESTRICT,
+	closed_at TIMESTAMPTZ,
+	opened_by int,
+	closed_by int,
+	-- set by the stale receptions job, see models.StaleActionFlag
+	stale_since TIMESTAMPTZ);
+
+create index receptions_pvz_start_idx on receptions(pvz_id, reception_start_datetime desc, id desc);
+
+-- at most one in_progress reception per pickup point
+create unique index receptions_pvz_open_idx on receptions(pvz_id) where status_id = 1;
+
+create table reception_status_history (
+	id bigserial primary key,
+	reception_id UUID not null references receptions(id) ON DELETE CASCADE,
+	from_status_id int references reception_statuses(id) ON DELETE RESTRICT,
+	to_status_id int not null references reception_statuses(id) ON DELETE RESTRICT,
+	changed_at TIMESTAMPTZ not null default now(),
+	changed_by int, -- models.SystemActorId for background jobs
+	comment text not null default '');
+
+create index reception_status_history_reception_idx on reception_status_history(reception_id, changed_at);
+
+-- actions on a reception that keep its status, see models.ReceptionEvent*
+create table reception_events (
+	id bigserial primary key,
+	reception_id UUID not null references receptions(id) ON DELETE CASCADE,
+	event text not null,
+	created_at TIMESTAMPTZ not null default now(),
+	created_by int, -- models.SystemActorId for background jobs
+	comment text not null default '');
+
+create index reception_events_reception_idx on reception_events(reception_id, created_at);
+
+create table reopen_request_statuses (
+    id   serial primary key,
+    name text not null unique);
+
+create table reception_reopen_requests (
+	id UUID primary key default gen_random_uuid(),
+	reception_id UUID not null references receptions(id) ON DELETE CASCADE,
+	status_id int not null default 1 references reopen_request_statuses(id) ON DELETE RESTRICT,
+	reason text not null,
+	requested_at TIMESTAMPTZ not null default now(),
+	requested_by int,
+	resolved_at TIMESTAMPTZ,
+	resolved_by int,
+	comment text not null default '');
+
+-- at most one pending request per reception
+create unique index reception_reopen_requests_pending_idx on reception_reopen_requests(reception_id) where status_id = 1;
+
+-- storage layout of a pvz, products are placed into cells on acceptance
+create table storage_cells (
+	id UUID primary key default gen_random_uuid(),
+	pvz_id UUID not null references pvzs(id) ON DELETE CASCADE,
+	rack text not null,
+	shelf text not null,
+	cell text not null,
+	code text generated always as (rack || '-' || shelf || '-' || cell) stored,
+	capacity int not null check (capacity > 0),
+	-- longest side of an item the cell takes, null is any size
+	max_side_mm int check (max_side_mm > 0),
+	-- cell reserved for one product type, null is any type
+	type_id int references product_types(id) ON DELETE RESTRICT,
+	active boolean not null default true,
+	unique (pvz_id, rack, shelf, cell));
 
 create table products (
 	id UUID primary key default gen_random_uuid(),
 	added_at TIMESTAMPTZ not null default now(),
-	type_id int  not null references product_types(id) ON DELETE RESTRICT);
+	type_id int  not null references product_types(id) ON DELETE RESTRICT,
+	-- cleared when the product leaves the pvz, see left_at
+	pvz_id UUID references pvzs(id) ON DELETE RESTRICT,
+	added_by int,
+	deleted_at TIMESTAMPTZ,
+	deleted_by int,
+	barcode text,
+	external_order_id text,
+	duplicate boolean not null default false,
+	weight_grams int check (weight_grams > 0),
+	length_mm int check (length_mm > 0),
+	width_mm int check (width_mm > 0),
+	height_mm int check (height_mm > 0),
+	-- kopecks
+	declared_value bigint check (declared_value > 0),
+	check ((length_mm is null) = (width_mm is null) and (width_mm is null) = (height_mm is null)),
+	condition_id int not null default 1 references product_conditions(id) ON DELETE RESTRICT,
+	condition_notes text not null default '',
+	-- handed to the customer, issued products are out of the pvz stock
+	issued_at TIMESTAMPTZ,
+	issued_by int,
+	-- shipped to another pvz, see shipments, or dispatched to the sender as unclaimed, see returns.
+	-- Present products are not deleted, issued or left
+	left_at TIMESTAMPTZ,
+	-- kept after the product leaves the pvz, only present products occupy the cell
+	cell_id UUID references storage_cells(id) ON DELETE SET NULL,
+	-- the customer picks the product up till then, set on acceptance, see storage_deadline
+	storage_until TIMESTAMPTZ);
+
+create index products_barcode_idx on products(barcode) where barcode is not null;
+
+create index products_cell_idx on products(cell_id) where cell_id is not null;
+
+create index products_storage_until_idx on products(pvz_id, storage_until) where issued_at is null and deleted_at is null;
+
+-- placements and moves of products between cells, from_cell_id is null for the placement on acceptance,
+-- to_cell_id is null when the product is shipped out of the pvz
+create table cell_moves (
+	id bigserial primary key,
+	product_id UUID not null references products(id) ON DELETE CASCADE,
+	from_cell_id UUID references storage_cells(id) ON DELETE SET NULL,
+	to_cell_id UUID references storage_cells(id) ON DELETE SET NULL,
+	moved_at TIMESTAMPTZ not null default now(),
+	moved_by int);
+
+create index cell_moves_product_idx on cell_moves(product_id, moved_at);
+
+-- problem products are few, the index keeps the hasProblems filter cheap
+create index products_condition_idx on products(condition_id) where condition_id <> 1;
+
+-- expected delivery (ASN) sent by the supplier
+create table reception_manifests (
+	reception_id UUID primary key references receptions(id) ON DELETE CASCADE,
+	supplier_ref text not null default '',
+	max_discrepancy int check (max_discrepancy >= 0),
+	created_at TIMESTAMPTZ not null default now(),
+	created_by int);
+
+create table manifest_items (
+	id bigserial primary key,
+	reception_id UUID not null references reception_manifests(reception_id) ON DELETE CASCADE,
+	barcode text,
+	type_id int references product_types(id) ON DELETE RESTRICT,
+	quantity int not null check (quantity > 0),
+	check (barcode is not null or type_id is not null));
+
+create index manifest_items_reception_idx on manifest_items(reception_id);
+
+-- files are kept in the blob store under storage_key
+create table attachments (
+	id UUID primary key,
+	reception_id UUID not null references receptions(id) ON DELETE CASCADE,
+	product_id UUID references products(id) ON DELETE CASCADE,
+	file_name text not null,
+	content_type text not null,
+	size_bytes bigint not null check (size_bytes > 0),
+	storage_key text not null,
+	width int,
+	height int,
+	thumbnail_key text,
+	thumbnail_content_type text,
+	thumbnail_width int,
+	thumbnail_height int,
+	created_at TIMESTAMPTZ not null default now(),
+	created_by int);
+
+create index attachments_reception_idx on attachments(reception_id, created_at);
 
 create table reception_products (
     reception_id UUID not null references receptions(id) ON DELETE CASCADE,
     product_id   UUID not null references products(id) ON DELETE CASCADE,
     primary key (reception_id, product_id));
 
+create table order_statuses (
+    id   serial primary key,
+    name text not null unique);
+
+-- customer order waiting in the pvz, its items are the products accepted
+-- in the pvz with external_order_id = external_id
+create table orders (
+	id UUID primary key default gen_random_uuid(),
+	external_id text not null,
+	pvz_id UUID not null references pvzs(id) ON DELETE RESTRICT,
+	customer_name text not null default '',
+	customer_phone text not null default '',
+	expected_items int not null check (expected_items > 0),
+	status_id int not null default 1 references order_statuses(id) ON DELETE RESTRICT,
+	created_at TIMESTAMPTZ not null default now(),
+	created_by int,
+	issued_at TIMESTAMPTZ,
+	-- one-time pickup code, only its bcrypt hash is stored. Cleared when the order is issued or cancelled
+	pickup_code_hash text,
+	pickup_code_created_at TIMESTAMPTZ,
+	pickup_code_attempts int not null default 0);
+
+-- ready orders still waiting for a pickup code
+create index orders_pickup_code_pending_idx on orders(created_at) where status_id = 2 and pickup_code_hash is null;
+
+-- one active order per external id in a pvz, cancelled orders can be recreated
+create unique index orders_pvz_external_idx on orders(pvz_id, external_id) where status_id <> 5;
+
+create index products_external_order_idx on products(pvz_id, external_order_id) where external_order_id is not null;
+
+create table order_status_history (
+	id bigserial primary key,
+	order_id UUID not null references orders(id) ON DELETE CASCADE,
+	from_status_id int references order_statuses(id) ON DELETE RESTRICT,
+	to_status_id int not null references order_statuses(id) ON DELETE RESTRICT,
+	changed_at TIMESTAMPTZ not null default now(),
+	changed_by int,
+	comment text not null default '');
+
+create index order_status_history_order_idx on order_status_history(order_id, changed_at);
+
+-- actions on an order that keep its status, see models.OrderEvent*
+create table order_events (
+	id bigserial primary key,
+	order_id UUID not null references orders(id) ON DELETE CASCADE,
+	event text not null,
+	created_at TIMESTAMPTZ not null default now(),
+	created_by int,
+	comment text not null default '');
+
+create index order_events_order_idx on order_events(order_id, created_at);
+
+create table return_statuses (
+    id   serial primary key,
+    name text not null unique);
+
+create table return_reasons (
+    id   serial primary key,
+    name text not null unique);
+
+-- issued product brought back by the customer, waits in the outbound queue
+-- of the pvz until it is dispatched to the sender. Products of orders not picked up
+-- in the storage period are queued by the unclaimed items job with the unclaimed reason
+create table returns (
+	id UUID primary key default gen_random_uuid(),
+	pvz_id UUID not null references pvzs(id) ON DELETE RESTRICT,
+	order_id UUID not null references orders(id) ON DELETE RESTRICT,
+	product_id UUID not null references products(id) ON DELETE RESTRICT,
+	reason_id int not null references return_reasons(id) ON DELETE RESTRICT,
+	comment text not null default '',
+	condition_id int not null references product_conditions(id) ON DELETE RESTRICT,
+	condition_notes text not null default '',
+	status_id int not null default 1 references return_statuses(id) ON DELETE RESTRICT,
+	created_at TIMESTAMPTZ not null default now(),
+	created_by int,
+	dispatched_at TIMESTAMPTZ);
+
+-- a product can be in one not cancelled return only
+create unique index returns_product_idx on returns(product_id) where status_id <> 3;
+
+create index returns_pvz_idx on returns(pvz_id, created_at);
+
+create table return_status_history (
+	id bigserial primary key,
+	return_id UUID not null references returns(id) ON DELETE CASCADE,
+	from_status_id int references return_statuses(id) ON DELETE RESTRICT,
+	to_status_id int not null references return_statuses(id) ON DELETE RESTRICT,
+	changed_at TIMESTAMPTZ not null default now(),
+	changed_by int,
+	comment text not null default '');
+
+create index return_status_history_return_idx on return_status_history(return_id, changed_at);
+
+create table shipment_statuses (
+    id   serial primary key,
+    name text not null unique);
+
+-- products sent from one pvz to another. Dispatched products are in transit and belong to no pvz,
+-- on arrival their copies are accepted at the destination in a new reception
+create table shipments (
+	id UUID primary key default gen_random_uuid(),
+	from_pvz_id UUID not null references pvzs(id) ON DELETE RESTRICT,
+	to_pvz_id UUID not null references pvzs(id) ON DELETE RESTRICT,
+	status_id int not null default 1 references shipment_statuses(id) ON DELETE RESTRICT,
+	created_at TIMESTAMPTZ not null default now(),
+	created_by int,
+	dispatched_at TIMESTAMPTZ,
+	arrived_at TIMESTAMPTZ,
+	-- reception created at the destination on arrival
+	reception_id UUID references receptions(id) ON DELETE SET NULL,
+	check (from_pvz_id <> to_pvz_id));
+
+create index shipments_from_pvz_idx on shipments(from_pvz_id, created_at);
+
+create index shipments_to_pvz_idx on shipments(to_pvz_id, created_at);
+
+create table shipment_products (
+	shipment_id UUID not null references shipments(id) ON DELETE CASCADE,
+	product_id UUID not null references products(id) ON DELETE RESTRICT,
+	-- copy of the product accepted at the destination
+	received_product_id UUID references products(id) ON DELETE SET NULL,
+	-- false once the shipment is cancelled
+	active boolean not null default true,
+	primary key (shipment_id, product_id));
+
+-- a product can be in one not cancelled shipment only
+create unique index shipment_products_product_idx on shipment_products(product_id) where active;
+
+create table shipment_status_history (
+	id bigserial primary key,
+	shipment_id UUID not null references shipments(id) ON DELETE CASCADE,
+	from_status_id int references shipment_statuses(id) ON DELETE RESTRICT,
+	to_status_id int not null references shipment_statuses(id) ON DELETE RESTRICT,
+	changed_at TIMESTAMPTZ not null default now(),
+	changed_by int,
+	comment text not null default '');
+
+create index shipment_status_history_shipment_idx on shipment_status_history(shipment_id, changed_at);
+
 
 
 insert into cities(name)
@@ -56,14 +403,65 @@ values ('Москва'),
 	('Санкт-Петербург'),
 	('Казань');
 
+-- ids are used as models.ReceptionStatus* constants
 insert into reception_statuses(name)
 values ('in_progress'),
-	('close');
+	('close'),
+	('verified'),
+	('cancelled');
+
+-- ids are used as models.ProductCondition* constants
+insert into product_conditions(name)
+values ('ok'),
+	('damaged'),
+	('wrong_item'),
+	('missing_packaging');
+
+-- ids are used as models.OrderStatus* constants
+insert into order_statuses(name)
+values ('awaiting'),
+	('ready'),
+	('partially_issued'),
+	('issued'),
+	('cancelled');
+
+-- ids are used as models.ReturnStatus* constants
+insert into return_statuses(name)
+values ('queued'),
+	('dispatched'),
+	('cancelled');
+
+-- ids are used as models.ReturnReason* constants
+insert into return_reasons(name)
+values ('changed_mind'),
+	('defective'),
+	('wrong_item'),
+	('damaged_in_delivery'),
+	('other'),
+	('unclaimed');
+
+-- ids are used as models.ShipmentStatus* constants
+insert into shipment_statuses(name)
+values ('created'),
+	('dispatched'),
+	('arrived'),
+	('cancelled');
+
+-- ids are used as models.ReopenRequest* constants
+insert into reopen_request_statuses(name)
+values ('pending'),
+	('approved'),
+	('rejected');
+
+-- attributes stay optional for existing clients, required flags are set through PATCH /product-types
+insert into product_types(code, name, max_weight_grams, max_side_mm, max_declared_value)
+values ('shoes', 'обувь', 5000, 600, null),
+	('clothing', 'одежда', 10000, 1000, null),
+	('electronics', 'электроника', 30000, 1500, 100000000);
 
-insert into product_types(name)
-values ('обувь'),
-	('одежда'),
-	('электроника');
+insert into product_type_names(type_id, locale, name)
+select id, 'en', initcap(code)
+from product_types;
 
 insert into role(name)
 values ('moderator'),
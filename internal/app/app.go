@@ -5,8 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"orderPickupPoint/config"
+	"orderPickupPoint/internal/notifier"
+	"orderPickupPoint/internal/scheduler"
 	"orderPickupPoint/internal/service"
 	"orderPickupPoint/internal/storage"
+	"orderPickupPoint/internal/storage/blobStore"
 	"orderPickupPoint/internal/storage/postgres"
 	"orderPickupPoint/internal/transport"
 	"os"
@@ -18,7 +21,8 @@ import (
 func Run() {
 	cfg, err := config.LoadConfig()
 	if err != nil {
-		fmt.Println("something wrong with config")
+		fmt.Println("something wrong with config:", err)
+		return
 	}
 
 	dbConnPool, err := postgres.InitDb()
@@ -27,10 +31,18 @@ func Run() {
 	}
 	defer dbConnPool.Close()
 
+	blobs, err := blobStore.NewLocalBlobStore(cfg.AttachmentsDir)
+	if err != nil {
+		fmt.Println("something wrong with attachments storage:", err)
+		return
+	}
+
 	repos := storage.NewRepositories(dbConnPool)
 	services := service.NewServices(&service.Deps{
-		Repos: repos,
-		Cfg:   cfg,
+		Repos:    repos,
+		Blobs:    blobs,
+		Notifier: notifier.NewLogNotifier(),
+		Cfg:      cfg,
 	})
 	handler := transport.NewHandler(services)
 	router := handler.InitRouter()
@@ -40,6 +52,30 @@ func Run() {
 		Handler: router,
 	}
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	go scheduler.Every(jobsCtx, "stale receptions", cfg.StaleCheckInterval, func(ctx context.Context) error {
+		result, err := services.Reception.HandleStaleReceptions(ctx, &cfg.StaleReceptions)
+		if result != nil && (result.Closed > 0 || result.Flagged > 0) {
+			fmt.Printf("stale receptions: %d closed, %d flagged\n", result.Closed, result.Flagged)
+		}
+		return err
+	})
+	go scheduler.Every(jobsCtx, "pickup codes", cfg.PickupCodeCheckInterval, func(ctx context.Context) error {
+		sent, err := services.Order.GeneratePickupCodes(ctx)
+		if sent > 0 {
+			fmt.Printf("pickup codes: %d sent\n", sent)
+		}
+		return err
+	})
+	go scheduler.Every(jobsCtx, "unclaimed orders", cfg.UnclaimedCheckInterval, func(ctx context.Context) error {
+		queued, err := services.Order.ReturnUnclaimedOrders(ctx)
+		if queued > 0 {
+			fmt.Printf("unclaimed orders: %d items queued for return\n", queued)
+		}
+		return err
+	})
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
@@ -54,6 +90,7 @@ func Run() {
 
 	<-quit
 	fmt.Println("shutting down server")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
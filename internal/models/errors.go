@@ -0,0 +1,52 @@
+package models
+
+import "errors"
+
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrCapacityExceeded    = errors.New("pickup point capacity exceeded")
+	ErrUnknownProductType  = errors.New("unknown product type")
+	ErrInactiveProductType = errors.New("product type is not active")
+	ErrInvalidProductType  = errors.New("invalid product type")
+	ErrProductTypeExists   = errors.New("product type already exists")
+	ErrInvalidAttributes   = errors.New("invalid product attributes")
+	ErrInvalidCondition    = errors.New("invalid product condition")
+	ErrUnsupportedMedia    = errors.New("unsupported attachment content type")
+	ErrAttachmentTooLarge  = errors.New("attachment is too large")
+	ErrInvalidFilter       = errors.New("invalid filter")
+	ErrInvalidBatch        = errors.New("invalid batch size")
+	ErrInvalidBarcode      = errors.New("invalid barcode")
+	ErrDuplicateBarcode    = errors.New("barcode already scanned in this reception")
+	ErrInvalidManifest     = errors.New("invalid manifest")
+	ErrDiscrepancyExceeded = errors.New("reception does not match the manifest")
+	ErrIllegalTransition   = errors.New("illegal reception status transition")
+	ErrReceptionOpen       = errors.New("pickup point already has an open reception")
+	ErrReceptionNotOpen    = errors.New("reception is not in progress")
+	ErrReopenPending       = errors.New("reopen request is already pending")
+	ErrReopenResolved      = errors.New("reopen request is already resolved")
+	ErrInvalidOrder        = errors.New("invalid order")
+	ErrOrderExists         = errors.New("order already exists in the pickup point")
+	ErrOrderNotReady       = errors.New("order is not ready for issuance")
+	ErrOrderClosed         = errors.New("order is already issued or cancelled")
+	ErrOrderTransition     = errors.New("illegal order status transition")
+	ErrItemNotIssuable     = errors.New("item cannot be issued for the order")
+	ErrPickupCodeRequired  = errors.New("pickup code is required")
+	ErrNoPickupCode        = errors.New("order has no pickup code yet")
+	ErrNoCustomerPhone     = errors.New("order has no customer phone to send the pickup code to")
+	ErrWrongPickupCode     = errors.New("wrong pickup code")
+	ErrPickupCodeLocked    = errors.New("pickup code is locked after too many attempts")
+	ErrInvalidReturn       = errors.New("invalid return")
+	ErrNotReturnable       = errors.New("product cannot be returned for the order")
+	ErrReturnExists        = errors.New("product is already returned")
+	ErrReturnTransition    = errors.New("illegal return status transition")
+	ErrInvalidCell         = errors.New("invalid storage cell")
+	ErrUnknownCell         = errors.New("unknown storage cell")
+	ErrCellExists          = errors.New("storage cell already exists")
+	ErrCellFull            = errors.New("storage cell is full")
+	ErrCellMismatch        = errors.New("product does not fit the storage cell")
+	ErrInvalidShipment     = errors.New("invalid shipment")
+	ErrNotShippable        = errors.New("product cannot be shipped from the pickup point")
+	ErrShipmentTransition  = errors.New("illegal shipment status transition")
+	ErrInvalidStorageDays  = errors.New("invalid storage period")
+	ErrInvalidCapacity     = errors.New("invalid capacity")
+)
@@ -1,16 +1,108 @@
 package models
 
 import (
+	"orderPickupPoint/internal/utils/cursor"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// ids from reception_statuses
+const (
+	ReceptionStatusInProgress = 1
+	ReceptionStatusClosed     = 2
+	ReceptionStatusVerified   = 3
+	ReceptionStatusCancelled  = 4
+)
+
+// ids from reopen_request_statuses
+const (
+	ReopenRequestPending  = 1
+	ReopenRequestApproved = 2
+	ReopenRequestRejected = 3
+)
+
+// ids from product_conditions
+const (
+	ProductConditionOk               = 1
+	ProductConditionDamaged          = 2
+	ProductConditionWrongItem        = 3
+	ProductConditionMissingPackaging = 4
+)
+
+// ids from order_statuses
+const (
+	OrderStatusAwaiting        = 1
+	OrderStatusReady           = 2
+	OrderStatusPartiallyIssued = 3
+	OrderStatusIssued          = 4
+	OrderStatusCancelled       = 5
+)
+
+// ids from return_statuses
+const (
+	ReturnStatusQueued     = 1
+	ReturnStatusDispatched = 2
+	ReturnStatusCancelled  = 3
+)
+
+// ids from return_reasons
+const (
+	ReturnReasonChangedMind       = 1
+	ReturnReasonDefective         = 2
+	ReturnReasonWrongItem         = 3
+	ReturnReasonDamagedInDelivery = 4
+	ReturnReasonOther             = 5
+	// set by the unclaimed items job only
+	ReturnReasonUnclaimed = 6
+)
+
+// ids from shipment_statuses
+const (
+	ShipmentStatusCreated    = 1
+	ShipmentStatusDispatched = 2
+	ShipmentStatusArrived    = 3
+	ShipmentStatusCancelled  = 4
+)
+
+// actor of the changes made by background jobs, user ids start from 1
+const SystemActorId = 0
+
+// storage period of the product types without their own
+const DefaultStorageDays = 7
+
+// what the scheduler does with a stale reception
+const (
+	StaleActionClose = "close"
+	StaleActionFlag  = "flag"
+)
+
 type Reception struct {
 	Id            uuid.UUID
 	DateTime      time.Time
 	PickupPointId uuid.UUID
 	StatusId      int
+	OpenedBy      *int
+}
+
+// when an in_progress reception is considered forgotten, zero duration disables the check
+type StaleReceptionPolicy struct {
+	MaxOpen     time.Duration
+	IdleTimeout time.Duration
+	Action      string
+}
+
+type StaleReception struct {
+	Reception
+	// last transition to in_progress, differs from DateTime for reopened receptions
+	OpenedAt     time.Time
+	LastActivity time.Time
+	StaleSince   *time.Time
+}
+
+type StaleReceptionsResult struct {
+	Closed  int
+	Flagged int
 }
 
 type ReceptionAPI struct {
@@ -20,43 +112,304 @@ type ReceptionAPI struct {
 	Status        string    `json:"status"`
 }
 
-type ProductAPI struct {
+type ReceptionDetailsAPI struct {
+	Id            uuid.UUID      `json:"id"`
+	PickupPointId uuid.UUID      `json:"pvzId"`
+	Status        string         `json:"status"`
+	DateTime      time.Time      `json:"dateTime"`
+	ClosedAt      *time.Time     `json:"closedAt,omitempty"`
+	OpenedBy      *int           `json:"openedBy,omitempty"`
+	ClosedBy      *int           `json:"closedBy,omitempty"`
+	StaleSince    *time.Time     `json:"staleSince,omitempty"`
+	ProductsCount int            `json:"productsCount"`
+	CountsByType  map[string]int `json:"countsByType"`
+	Totals        ProductTotals  `json:"totals"`
+	Products      []ProductInfo  `json:"products,omitempty"`
+
+	// products removed from the reception, kept for the audit
+	DeletedProducts []ProductInfo `json:"deletedProducts,omitempty"`
+}
+
+const ReceptionSortByDate = "dateTime"
+
+// receptions of one pickup point, newest first
+type ReceptionFilter struct {
+	PvzId     uuid.UUID
+	Statuses  []string
+	StartDate *time.Time
+	EndDate   *time.Time
+	Cursor    *cursor.Cursor
+	Page      int
+	PageLimit int
+}
+
+type ReceptionTransition struct {
+	ReceptionId  uuid.UUID
+	FromStatusId int
+	ToStatusId   int
+	ActorId      *int
+	Comment      string
+}
+
+// reception events that do not change the reception status, kept apart from the status history
+const ReceptionEventFlaggedStale = "flagged_stale"
+
+type ReceptionEvent struct {
+	ReceptionId uuid.UUID
+	Event       string
+	ActorId     *int
+	Comment     string
+}
+
+type ReceptionHistoryItem struct {
+	From      *string   `json:"from"`
+	To        string    `json:"to"`
+	ChangedAt time.Time `json:"changedAt"`
+	ChangedBy *int      `json:"changedBy,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+}
+
+type ProductBatchAPI struct {
+	Products []ProductAPI `json:"products"`
+}
+
+// result of one batch item: the accepted product or the reason it was rejected
+type ProductBatchItemAPI struct {
+	Index   int         `json:"index"`
+	Product *ProductAPI `json:"product,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// outcome of one batch item in storage: the added product or the reason it was rejected
+type ProductBatchItem struct {
+	Product *Product
+	Err     error
+}
+
+type ProductBatchResultAPI struct {
+	ReceptionId uuid.UUID             `json:"receptionId"`
+	Accepted    int                   `json:"accepted"`
+	Items       []ProductBatchItemAPI `json:"items"`
+}
+
+type ManifestItem struct {
+	Barcode  *string
+	TypeId   *int
+	Quantity int
+}
+
+type Manifest struct {
+	ReceptionId    uuid.UUID
+	SupplierRef    string
+	MaxDiscrepancy *int
+	CreatedBy      *int
+	Items          []ManifestItem
+}
+
+// expected item either by barcode or by product type
+type ManifestItemAPI struct {
+	Barcode  string `json:"barcode,omitempty"`
+	Type     string `json:"type,omitempty"`
+	Quantity int    `json:"quantity"`
+}
+
+type ManifestAPI struct {
+	ReceptionId uuid.UUID `json:"receptionId"`
+	SupplierRef string    `json:"supplierRef,omitempty"`
+	// closing is blocked when the reception differs from the manifest by more items, nil disables the check
+	MaxDiscrepancy *int              `json:"maxDiscrepancy,omitempty"`
+	CreatedAt      time.Time         `json:"createdAt"`
+	CreatedBy      *int              `json:"createdBy,omitempty"`
+	Items          []ManifestItemAPI `json:"items"`
+}
+
+type ReconciliationLineAPI struct {
+	Barcode string `json:"barcode,omitempty"`
+	Type    string `json:"type,omitempty"`
+	// type of the scanned products for mismatches
+	ActualType string `json:"actualType,omitempty"`
+	Expected   int    `json:"expected"`
+	Actual     int    `json:"actual"`
+}
+
+type ReconciliationAPI struct {
+	ReceptionId    uuid.UUID               `json:"receptionId"`
+	Expected       int                     `json:"expected"`
+	Actual         int                     `json:"actual"`
+	Shortages      []ReconciliationLineAPI `json:"shortages"`
+	Surpluses      []ReconciliationLineAPI `json:"surpluses"`
+	Mismatches     []ReconciliationLineAPI `json:"mismatches"`
+	Discrepancies  int                     `json:"discrepancies"`
+	MaxDiscrepancy *int                    `json:"maxDiscrepancy,omitempty"`
+	Blocking       bool                    `json:"blocking"`
+}
+
+// products of a reception which are not in ok condition and duplicate scans,
+// with the manifest reconciliation if the reception has a manifest
+type DiscrepancyReportAPI struct {
+	ReceptionId    uuid.UUID          `json:"receptionId"`
+	PickupPointId  uuid.UUID          `json:"pvzId"`
+	Status         string             `json:"status"`
+	ProductsCount  int                `json:"productsCount"`
+	Problems       int                `json:"problems"`
+	Duplicates     int                `json:"duplicates"`
+	ByCondition    map[string]int     `json:"byCondition"`
+	Products       []ProductInfo      `json:"products"`
+	Reconciliation *ReconciliationAPI `json:"reconciliation,omitempty"`
+}
+
+type ReopenRequest struct {
+	Id          uuid.UUID
+	ReceptionId uuid.UUID
+	Reason      string
+	RequestedAt time.Time
+	RequestedBy *int
+}
+
+type ReopenResolution struct {
+	RequestId uuid.UUID
+	StatusId  int
+	ActorId   *int
+	Comment   string
+}
+
+type ReopenRequestAPI struct {
 	Id          uuid.UUID  `json:"id"`
-	AddedAt     time.Time  `json:"dateTime"`
-	Type        string     `json:"type"`
-	PvzId       *uuid.UUID `json:"pvzId,omitempty"`
 	ReceptionId uuid.UUID  `json:"receptionId"`
+	Status      string     `json:"status"`
+	Reason      string     `json:"reason"`
+	RequestedAt time.Time  `json:"requestedAt"`
+	RequestedBy *int       `json:"requestedBy,omitempty"`
+	ResolvedAt  *time.Time `json:"resolvedAt,omitempty"`
+	ResolvedBy  *int       `json:"resolvedBy,omitempty"`
+	Comment     string     `json:"comment,omitempty"`
+}
+
+type ProductAPI struct {
+	Id              uuid.UUID  `json:"id"`
+	AddedAt         time.Time  `json:"dateTime"`
+	Type            string     `json:"type"`
+	PvzId           *uuid.UUID `json:"pvzId,omitempty"`
+	ReceptionId     uuid.UUID  `json:"receptionId"`
+	Barcode         string     `json:"barcode,omitempty"`
+	ExternalOrderId string     `json:"externalOrderId,omitempty"`
+	ProductAttributes
+	// ok when empty, notes are required for the other conditions
+	Condition string `json:"condition,omitempty"`
+	Notes     string `json:"notes,omitempty"`
+	// storage cell code, chosen automatically when empty
+	Cell string `json:"cell,omitempty"`
+	// accept a barcode already scanned in the reception instead of rejecting it
+	AllowDuplicate bool   `json:"allowDuplicate,omitempty"`
+	Duplicate      bool   `json:"duplicate,omitempty"`
+	Warning        string `json:"warning,omitempty"`
 }
 
 type Product struct {
-	Id          uuid.UUID
-	AddedAt     time.Time
-	TypeId      int
-	ReceptionId uuid.UUID
+	Id              uuid.UUID
+	AddedAt         time.Time
+	TypeId          int
+	ReceptionId     uuid.UUID
+	AddedBy         *int
+	Barcode         *string
+	ExternalOrderId *string
+	ProductAttributes
+	ConditionId int
+	Notes       string
+	// requested cell on input, the cell the product is placed into on output. Nil is automatic placement
+	// on input and no fitting cell on output
+	CellCode *string
+
+	// a product with a barcode already present in the reception is rejected unless AllowDuplicate is set,
+	// then it is accepted with Duplicate flag
+	AllowDuplicate bool
+	Duplicate      bool
+
+	// set when the product was accepted above a non-strict capacity limit
+	OverCapacity bool
 }
 
+const (
+	PvzSortByReceptionDate = "receptionDate"
+	PvzSortByProductDate   = "productDate"
+	PvzSortByRegDate       = "regDate"
+	PvzSortByCity          = "city"
+)
+
+// empty fields are not applied, dates can be set separately.
+// With Cursor set the page is taken after (or before) it and Page is ignored
 type PvzFilter struct {
-	StartDate *time.Time
-	EndDate   *time.Time
-	Page      int
-	PageLimit int
+	StartDate    *time.Time
+	EndDate      *time.Time
+	Cities       []string
+	Statuses     []string
+	ProductTypes []string
+	PvzIds       []uuid.UUID
+	// products in these conditions
+	Conditions []string
+	// receptions with at least one product not in ok condition
+	WithProblems bool
+	// cancelled receptions are shown only when set or when asked for in Statuses
+	IncludeCancelled bool
+	SortBy           string
+	SortDesc         bool
+	Cursor           *cursor.Cursor
+	Page             int
+	PageLimit        int
 }
 
+// reception and product fields are nil for pickup points without receptions
+// and for receptions without products
 type PvzFilteredInfo struct {
 	PvzID         uuid.UUID
 	CityName      string
 	RegDate       time.Time
-	ReceptionID   uuid.UUID
-	ReceptionTime time.Time
-	ProductID     uuid.UUID
-	AddedAt       time.Time
-	ProductType   string
+	ReceptionID   *uuid.UUID
+	ReceptionTime *time.Time
+	ProductID     *uuid.UUID
+	AddedAt       *time.Time
+	ProductType   *string
+	Condition     *string
+}
+
+// page of a list read from storage
+type ListResult[T any] struct {
+	Items []T
+	Total int
+	Next  *cursor.Cursor
+	Prev  *cursor.Cursor
+}
+
+type PvzFilteredResult struct {
+	Total int
+	Next  *cursor.Cursor
+	Prev  *cursor.Cursor
 }
 
 type ProductInfo struct {
-	ID      uuid.UUID `json:"id"`
-	AddedAt time.Time `json:"addedAt"`
-	Type    string    `json:"type"`
+	ID        uuid.UUID  `json:"id"`
+	AddedAt   time.Time  `json:"addedAt"`
+	Type      string     `json:"type"`
+	AddedBy   *int       `json:"addedBy,omitempty"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	DeletedBy *int       `json:"deletedBy,omitempty"`
+	// shipped to another pvz or returned to the sender
+	LeftAt *time.Time `json:"leftAt,omitempty"`
+
+	Barcode         *string `json:"barcode,omitempty"`
+	ExternalOrderId *string `json:"externalOrderId,omitempty"`
+	Duplicate       bool    `json:"duplicate,omitempty"`
+	ProductAttributes
+	Condition string  `json:"condition,omitempty"`
+	Notes     string  `json:"notes,omitempty"`
+	Cell      *string `json:"cell,omitempty"`
+}
+
+// where a product found by barcode was accepted
+type ProductLocationAPI struct {
+	ProductInfo
+	ReceptionId uuid.UUID  `json:"receptionId"`
+	PvzId       *uuid.UUID `json:"pvzId,omitempty"`
 }
 
 type ReceptionInfo struct {
@@ -72,6 +425,35 @@ type PvzInfo struct {
 	Receptions []ReceptionInfo `json:"receptions"`
 }
 
+// response envelope for list endpoints. Page is set only for offset pagination,
+// Next and Prev are opaque cursors of the neighbouring pages
+type Page[T any] struct {
+	Items []T     `json:"items"`
+	Total int     `json:"total"`
+	Page  int     `json:"page,omitempty"`
+	Limit int     `json:"limit"`
+	Next  *string `json:"next,omitempty"`
+	Prev  *string `json:"prev,omitempty"`
+}
+
+// page envelope of a list, the page number is only known when the list is paged by offset
+func NewPage[T any](list *ListResult[T], current *cursor.Cursor, pageNumber int, limit int) *Page[T] {
+	page := &Page[T]{
+		Items: list.Items,
+		Total: list.Total,
+		Limit: limit,
+		Next:  cursor.EncodeOrNil(list.Next),
+		Prev:  cursor.EncodeOrNil(list.Prev),
+	}
+	if page.Items == nil {
+		page.Items = []T{}
+	}
+	if current == nil {
+		page.Page = pageNumber
+	}
+	return page
+}
+
 type PickupPoint struct {
 	Id      uuid.UUID
 	RegDate time.Time
@@ -84,6 +466,44 @@ type PickupPointAPI struct {
 	City    string    `json:"city"`
 }
 
+// nil MaxItems means the limit is not set.
+// with Strict=false products above the limit are accepted with a warning
+type PvzCapacity struct {
+	MaxItems       *int           `json:"maxItems"`
+	MaxItemsByType map[string]int `json:"maxItemsByType,omitempty"`
+	Strict         bool           `json:"strict"`
+}
+
+// storage periods of the pvz in days, they override the periods of the product types.
+// nil Days keeps the periods of the types
+type PvzStoragePeriods struct {
+	Days       *int           `json:"days"`
+	DaysByType map[string]int `json:"daysByType,omitempty"`
+}
+
+type TypeUtilization struct {
+	Type        string   `json:"type"`
+	StoredItems int      `json:"storedItems"`
+	MaxItems    *int     `json:"maxItems"`
+	Utilization *float64 `json:"utilization,omitempty"`
+}
+
+type PvzUtilization struct {
+	StoredItems int               `json:"storedItems"`
+	MaxItems    *int              `json:"maxItems"`
+	Utilization *float64          `json:"utilization,omitempty"`
+	ByType      []TypeUtilization `json:"byType"`
+}
+
+type PickupPointDetailsAPI struct {
+	PickupPointAPI
+	Capacity       PvzCapacity       `json:"capacity"`
+	StoragePeriods PvzStoragePeriods `json:"storagePeriods"`
+	Utilization    PvzUtilization    `json:"utilization"`
+	// over the products stored in the pvz
+	Totals ProductTotals `json:"totals"`
+}
+
 type User struct {
 	Id           int     `json:"id"`
 	Email        string  `json:"email"`
@@ -105,3 +525,427 @@ type AuthTokens struct {
 	NewAccessToken  bool   `json:"-"`
 	NewRefreshToken bool   `json:"-"`
 }
+
+// name is the default (ru) display name, names holds the other locales
+type ProductType struct {
+	Id     int
+	Code   string
+	Name   string
+	Names  map[string]string
+	Active bool
+	Rules  ProductTypeRules
+}
+
+// which physical attributes a product of the type must have and their upper limits, nil limit is unbounded
+type ProductTypeRules struct {
+	WeightRequired        bool   `json:"weightRequired"`
+	DimensionsRequired    bool   `json:"dimensionsRequired"`
+	DeclaredValueRequired bool   `json:"declaredValueRequired"`
+	MaxWeightGrams        *int   `json:"maxWeightGrams,omitempty"`
+	MaxSideMm             *int   `json:"maxSideMm,omitempty"`
+	MaxDeclaredValue      *int64 `json:"maxDeclaredValue,omitempty"`
+	// days accepted products wait for the customer, nil is DefaultStorageDays
+	StorageDays *int `json:"storageDays,omitempty"`
+}
+
+type ProductTypeAPI struct {
+	Code   string            `json:"code"`
+	Name   string            `json:"name"`
+	Names  map[string]string `json:"names"`
+	Active bool              `json:"active"`
+	Rules  ProductTypeRules  `json:"rules"`
+}
+
+// body of create and update requests, on update nil fields are kept
+// and an empty localized name removes the locale
+type ProductTypeInputAPI struct {
+	Code   string            `json:"code"`
+	Name   *string           `json:"name"`
+	Names  map[string]string `json:"names"`
+	Active *bool             `json:"active"`
+	// replaces all the rules of the type
+	Rules *ProductTypeRules `json:"rules"`
+}
+
+// physical attributes of a product: weight in grams, dimensions in millimeters
+// and declared value in kopecks. Dimensions are set all together or not at all
+type ProductAttributes struct {
+	WeightGrams   *int   `json:"weightGrams,omitempty"`
+	LengthMm      *int   `json:"lengthMm,omitempty"`
+	WidthMm       *int   `json:"widthMm,omitempty"`
+	HeightMm      *int   `json:"heightMm,omitempty"`
+	DeclaredValue *int64 `json:"declaredValue,omitempty"`
+}
+
+// sums over the products which have the attribute set
+type ProductTotals struct {
+	WeightGrams   int64 `json:"weightGrams"`
+	VolumeCm3     int64 `json:"volumeCm3"`
+	DeclaredValue int64 `json:"declaredValue"`
+}
+
+// evidence file attached to a reception or to one of its products
+type Attachment struct {
+	Id          uuid.UUID  `json:"id"`
+	ReceptionId uuid.UUID  `json:"receptionId"`
+	ProductId   *uuid.UUID `json:"productId,omitempty"`
+	FileName    string     `json:"fileName"`
+	ContentType string     `json:"contentType"`
+	SizeBytes   int64      `json:"sizeBytes"`
+	// pixel size of images
+	Width       *int                 `json:"width,omitempty"`
+	Height      *int                 `json:"height,omitempty"`
+	Thumbnail   *AttachmentThumbnail `json:"thumbnail,omitempty"`
+	CreatedAt   time.Time            `json:"createdAt"`
+	CreatedBy   *int                 `json:"createdBy,omitempty"`
+	DownloadUrl string               `json:"downloadUrl"`
+	StorageKey  string               `json:"-"`
+}
+
+type AttachmentThumbnail struct {
+	Width       int    `json:"width"`
+	Height      int    `json:"height"`
+	ContentType string `json:"contentType"`
+	Url         string `json:"url"`
+	StorageKey  string `json:"-"`
+}
+
+// customer order, its items are the products accepted in the pvz with ExternalId as externalOrderId
+type Order struct {
+	Id            uuid.UUID
+	ExternalId    string
+	PvzId         uuid.UUID
+	CustomerName  string
+	CustomerPhone string
+	ExpectedItems int
+	StatusId      int
+	CreatedBy     *int
+}
+
+type OrderAPI struct {
+	Id            uuid.UUID      `json:"id"`
+	ExternalId    string         `json:"externalId"`
+	PvzId         uuid.UUID      `json:"pvzId"`
+	CustomerName  string         `json:"customerName,omitempty"`
+	CustomerPhone string         `json:"customerPhone,omitempty"`
+	Status        string         `json:"status"`
+	StatusId      int            `json:"-"`
+	ExpectedItems int            `json:"expectedItems"`
+	ArrivedItems  int            `json:"arrivedItems"`
+	IssuedItems   int            `json:"issuedItems"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	CreatedBy     *int           `json:"createdBy,omitempty"`
+	IssuedAt      *time.Time     `json:"issuedAt,omitempty"`
+	Items         []OrderItemAPI `json:"items,omitempty"`
+
+	PickupCodeCreatedAt *time.Time `json:"pickupCodeCreatedAt,omitempty"`
+	PickupCodeAttempts  int        `json:"pickupCodeAttempts,omitempty"`
+}
+
+// accepted product of an order. Only products of closed receptions can be issued
+type OrderItemAPI struct {
+	ProductInfo
+	ReceptionId uuid.UUID  `json:"receptionId"`
+	IssuedAt    *time.Time `json:"issuedAt,omitempty"`
+	IssuedBy    *int       `json:"issuedBy,omitempty"`
+	Issuable    bool       `json:"issuable"`
+}
+
+type OrderInputAPI struct {
+	ExternalId    string    `json:"externalId"`
+	PvzId         uuid.UUID `json:"pvzId"`
+	ExpectedItems int       `json:"expectedItems"`
+	CustomerName  string    `json:"customerName"`
+	CustomerPhone string    `json:"customerPhone"`
+}
+
+// items handed to the customer, scanned barcodes and/or product ids.
+// Without both all the remaining items of the order are issued
+type OrderIssueAPI struct {
+	PickupCode string      `json:"pickupCode"`
+	ProductIds []uuid.UUID `json:"productIds"`
+	Barcodes   []string    `json:"barcodes"`
+}
+
+type OrderIssue struct {
+	OrderId    uuid.UUID
+	ProductIds []uuid.UUID
+	ActorId    *int
+}
+
+type OrderIssueResultAPI struct {
+	Order  *OrderAPI   `json:"order"`
+	Issued []uuid.UUID `json:"issued"`
+}
+
+const OrderSortByDate = "createdAt"
+
+// orders of one pickup point, newest first
+type OrderFilter struct {
+	PvzId     uuid.UUID
+	Statuses  []string
+	Cursor    *cursor.Cursor
+	Page      int
+	PageLimit int
+}
+
+type OrderHistoryItem struct {
+	From      *string   `json:"from"`
+	To        string    `json:"to"`
+	ChangedAt time.Time `json:"changedAt"`
+	ChangedBy *int      `json:"changedBy,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+}
+
+// how pickup codes are generated and how many wrong codes lock the order until the code is regenerated
+type PickupCodePolicy struct {
+	Digits      int
+	MaxAttempts int
+}
+
+// bcrypt ignores everything past 72 bytes, a longer code would be checked by its prefix only
+const MaxPickupCodeDigits = 72
+
+type PickupCode struct {
+	Hash     string
+	Attempts int
+}
+
+// new pickup code of an order. Without Replace an existing code is kept
+type PickupCodeChange struct {
+	OrderId uuid.UUID
+	Hash    string
+	Replace bool
+	ActorId *int
+	Comment string
+}
+
+// order events that do not change the order status, kept apart from the status history
+const (
+	OrderEventPickupCodeSet      = "pickup_code_set"
+	OrderEventPickupCodeReplaced = "pickup_code_replaced"
+)
+
+const (
+	NotificationPickupCode = "pickup_code"
+	NotificationUnclaimed  = "unclaimed"
+)
+
+// message to the customer of an order.
+// Secret is the part of the message only the customer may see (a pickup code), it is masked in logs
+type Notification struct {
+	Kind      string
+	OrderId   uuid.UUID
+	PvzId     uuid.UUID
+	Recipient string
+	Message   string
+	Secret    string
+}
+
+type OrderTransition struct {
+	OrderId      uuid.UUID
+	FromStatusId int
+	ToStatusId   int
+	ActorId      *int
+	Comment      string
+}
+
+// product brought back by the customer. Either the product id or its barcode identifies the item
+// of the order, the pvz defaults to the pvz of the order
+type ReturnInputAPI struct {
+	OrderId   uuid.UUID  `json:"orderId"`
+	ProductId *uuid.UUID `json:"productId,omitempty"`
+	Barcode   *string    `json:"barcode,omitempty"`
+	PvzId     *uuid.UUID `json:"pvzId,omitempty"`
+	Reason    string     `json:"reason"`
+	Comment   string     `json:"comment,omitempty"`
+	Condition string     `json:"condition,omitempty"`
+	Notes     string     `json:"notes,omitempty"`
+}
+
+type Return struct {
+	OrderId     uuid.UUID
+	ProductId   *uuid.UUID
+	Barcode     *string
+	PvzId       *uuid.UUID
+	ReasonId    int
+	Comment     string
+	ConditionId int
+	Notes       string
+	CreatedBy   *int
+}
+
+type ReturnAPI struct {
+	Id              uuid.UUID  `json:"id"`
+	PvzId           uuid.UUID  `json:"pvzId"`
+	OrderId         uuid.UUID  `json:"orderId"`
+	ExternalOrderId string     `json:"externalOrderId"`
+	ProductId       uuid.UUID  `json:"productId"`
+	Type            string     `json:"type"`
+	Barcode         *string    `json:"barcode,omitempty"`
+	Reason          string     `json:"reason"`
+	Comment         string     `json:"comment,omitempty"`
+	Condition       string     `json:"condition"`
+	Notes           string     `json:"notes,omitempty"`
+	Status          string     `json:"status"`
+	StatusId        int        `json:"-"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	CreatedBy       *int       `json:"createdBy,omitempty"`
+	DispatchedAt    *time.Time `json:"dispatchedAt,omitempty"`
+}
+
+const ReturnSortByDate = "createdAt"
+
+// returns of one pickup point, oldest first
+type ReturnFilter struct {
+	PvzId     uuid.UUID
+	Statuses  []string
+	Cursor    *cursor.Cursor
+	Page      int
+	PageLimit int
+}
+
+type ReturnTransition struct {
+	ReturnId     uuid.UUID
+	FromStatusId int
+	ToStatusId   int
+	ActorId      *int
+	Comment      string
+}
+
+type ReturnHistoryItem struct {
+	From      *string   `json:"from"`
+	To        string    `json:"to"`
+	ChangedAt time.Time `json:"changedAt"`
+	ChangedBy *int      `json:"changedBy,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+}
+
+// storage cell of a pvz, addressed by the rack-shelf-cell code
+type StorageCellInputAPI struct {
+	Rack      string  `json:"rack"`
+	Shelf     string  `json:"shelf"`
+	Cell      string  `json:"cell"`
+	Capacity  int     `json:"capacity"`
+	MaxSideMm *int    `json:"maxSideMm,omitempty"`
+	Type      *string `json:"type,omitempty"`
+}
+
+type StorageCellsInputAPI struct {
+	Cells []StorageCellInputAPI `json:"cells"`
+}
+
+type StorageCellAPI struct {
+	Id        uuid.UUID `json:"id"`
+	PvzId     uuid.UUID `json:"pvzId"`
+	Code      string    `json:"code"`
+	Rack      string    `json:"rack"`
+	Shelf     string    `json:"shelf"`
+	Cell      string    `json:"cell"`
+	Capacity  int       `json:"capacity"`
+	MaxSideMm *int      `json:"maxSideMm,omitempty"`
+	Type      *string   `json:"type,omitempty"`
+	Active    bool      `json:"active"`
+	Items     int       `json:"items"`
+}
+
+type StorageCellUpdateAPI struct {
+	Capacity *int  `json:"capacity"`
+	Active   *bool `json:"active"`
+}
+
+// where a product present in the pvz is stored, Cell is nil for unplaced products
+type PlacementAPI struct {
+	ProductId       uuid.UUID `json:"productId"`
+	Type            string    `json:"type"`
+	Barcode         *string   `json:"barcode,omitempty"`
+	ExternalOrderId *string   `json:"externalOrderId,omitempty"`
+	AddedAt         time.Time `json:"addedAt"`
+	Cell            *string   `json:"cell"`
+	// nil for products accepted before the storage periods
+	StorageUntil *time.Time `json:"storageUntil,omitempty"`
+}
+
+// exactly one of the fields selects the products
+type PlacementFilter struct {
+	PvzId           uuid.UUID
+	Barcode         *string
+	OrderId         *uuid.UUID
+	ExternalOrderId *string
+}
+
+const PlacementSortByDeadline = "storageUntil"
+
+// products present in the pvz with the storage deadline before Until and, if set, not before From,
+// nearest deadline first. The service sets From and Until
+type StorageDeadlineFilter struct {
+	PvzId     uuid.UUID
+	From      *time.Time
+	Until     time.Time
+	Cursor    *cursor.Cursor
+	Page      int
+	PageLimit int
+}
+
+type ProductMove struct {
+	ProductId uuid.UUID
+	CellCode  string
+	ActorId   *int
+}
+
+// products of a pvz sent to another pvz
+type ShipmentInputAPI struct {
+	FromPvzId  uuid.UUID   `json:"fromPvzId"`
+	ToPvzId    uuid.UUID   `json:"toPvzId"`
+	ProductIds []uuid.UUID `json:"productIds"`
+}
+
+type Shipment struct {
+	FromPvzId  uuid.UUID
+	ToPvzId    uuid.UUID
+	ProductIds []uuid.UUID
+	CreatedBy  *int
+}
+
+// ReceptionId is the reception created at the destination on arrival
+type ShipmentAPI struct {
+	Id           uuid.UUID   `json:"id"`
+	FromPvzId    uuid.UUID   `json:"fromPvzId"`
+	ToPvzId      uuid.UUID   `json:"toPvzId"`
+	Status       string      `json:"status"`
+	StatusId     int         `json:"-"`
+	ProductIds   []uuid.UUID `json:"productIds"`
+	CreatedAt    time.Time   `json:"createdAt"`
+	CreatedBy    *int        `json:"createdBy,omitempty"`
+	DispatchedAt *time.Time  `json:"dispatchedAt,omitempty"`
+	ArrivedAt    *time.Time  `json:"arrivedAt,omitempty"`
+	ReceptionId  *uuid.UUID  `json:"receptionId,omitempty"`
+}
+
+const ShipmentSortByDate = "createdAt"
+
+// Inbound lists the shipments coming to the pvz instead of the ones sent from it
+type ShipmentFilter struct {
+	PvzId     uuid.UUID
+	Inbound   bool
+	Statuses  []string
+	Cursor    *cursor.Cursor
+	Page      int
+	PageLimit int
+}
+
+type ShipmentTransition struct {
+	ShipmentId   uuid.UUID
+	FromStatusId int
+	ToStatusId   int
+	ActorId      *int
+	Comment      string
+}
+
+type ShipmentHistoryItem struct {
+	From      *string   `json:"from"`
+	To        string    `json:"to"`
+	ChangedAt time.Time `json:"changedAt"`
+	ChangedBy *int      `json:"changedBy,omitempty"`
+	Comment   string    `json:"comment,omitempty"`
+}
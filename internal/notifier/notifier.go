@@ -0,0 +1,34 @@
+package notifier
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"orderPickupPoint/internal/models"
+	"os"
+	"strings"
+)
+
+type Notifier interface {
+	Notify(ctx context.Context, notification *models.Notification) error
+}
+
+// prints notifications to stdout instead of sending them, used until an sms gateway is connected.
+// The secret part of a message is masked, anyone reading the server log must not be able to use a pickup code
+type LogNotifier struct {
+	out io.Writer
+}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{out: os.Stdout}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, notification *models.Notification) error {
+	message := notification.Message
+	if notification.Secret != "" {
+		message = strings.ReplaceAll(message, notification.Secret, strings.Repeat("*", len(notification.Secret)))
+	}
+	_, err := fmt.Fprintf(n.out, "notification %s for order %s to %q: %s\n",
+		notification.Kind, notification.OrderId, notification.Recipient, message)
+	return err
+}
@@ -0,0 +1,28 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"orderPickupPoint/internal/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogNotifierMasksSecret(t *testing.T) {
+	out := &bytes.Buffer{}
+	n := &LogNotifier{out: out}
+
+	err := n.Notify(context.Background(), &models.Notification{
+		Kind:      models.NotificationPickupCode,
+		OrderId:   uuid.New(),
+		Recipient: "+79991234567",
+		Message:   "Код получения заказа WB-1: 482913",
+		Secret:    "482913",
+	})
+
+	require.NoError(t, err)
+	require.NotContains(t, out.String(), "482913")
+	require.Contains(t, out.String(), "Код получения заказа WB-1: ******")
+}
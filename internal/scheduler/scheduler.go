@@ -0,0 +1,35 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type Job func(ctx context.Context) error
+
+// runs the job every interval until ctx is done, a failed run is logged and retried on the next tick.
+// Non-positive interval disables the job
+func Every(ctx context.Context, name string, interval time.Duration, job Job) {
+	if interval <= 0 {
+		fmt.Println(name, "job is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if ctx.Err() != nil {
+				return
+			}
+			if err := job(ctx); err != nil && ctx.Err() == nil {
+				fmt.Printf("%s job failed: %v\n", name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package attachmentService
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage"
+	"orderPickupPoint/internal/utils/thumbnail"
+	"orderPickupPoint/internal/utils/userCtx"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// content types accepted as evidence, detected from the content itself
+var allowedContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"application/pdf": true,
+}
+
+const maxFileNameLength = 255
+
+// images are decoded for the thumbnail, a small file may declare a huge picture
+// and take gigabytes of memory, so the dimensions are checked from the header first
+const maxImagePixels = 50_000_000
+
+type AttachmentService struct {
+	AttachmentRepo storage.Attachment
+	Blobs          storage.BlobStore
+	MaxBytes       int64
+}
+
+func NewAttachmentService(attachmentRepo storage.Attachment, blobs storage.BlobStore, maxBytes int64) *AttachmentService {
+	return &AttachmentService{
+		AttachmentRepo: attachmentRepo,
+		Blobs:          blobs,
+		MaxBytes:       maxBytes,
+	}
+}
+
+// stores the file and, for images, its thumbnail, then the metadata.
+// The blobs are removed again if the reception or product is not found
+func (s *AttachmentService) Upload(ctx context.Context, receptionId uuid.UUID, productId *uuid.UUID, fileName string, content io.Reader) (*models.Attachment, error) {
+	data, err := io.ReadAll(io.LimitReader(content, s.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.MaxBytes {
+		return nil, fmt.Errorf("%w: limit is %d bytes", models.ErrAttachmentTooLarge, s.MaxBytes)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: empty file", models.ErrUnsupportedMedia)
+	}
+
+	contentType, _, _ := strings.Cut(http.DetectContentType(data), ";")
+	if !allowedContentTypes[contentType] {
+		return nil, fmt.Errorf("%w: %s", models.ErrUnsupportedMedia, contentType)
+	}
+
+	attachment := &models.Attachment{
+		Id:          uuid.New(),
+		ReceptionId: receptionId,
+		ProductId:   productId,
+		FileName:    cleanFileName(fileName),
+		ContentType: contentType,
+		SizeBytes:   int64(len(data)),
+		CreatedBy:   userCtx.UserId(ctx),
+	}
+	attachment.StorageKey = "attachments/" + attachment.Id.String()
+
+	var thumbData []byte
+	if strings.HasPrefix(contentType, "image/") {
+		config, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: broken image", models.ErrUnsupportedMedia)
+		}
+		if int64(config.Width)*int64(config.Height) > maxImagePixels {
+			return nil, fmt.Errorf("%w: image is %dx%d, limit is %d pixels", models.ErrAttachmentTooLarge, config.Width, config.Height, maxImagePixels)
+		}
+
+		img, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: broken image", models.ErrUnsupportedMedia)
+		}
+		width, height := img.Bounds().Dx(), img.Bounds().Dy()
+		attachment.Width, attachment.Height = &width, &height
+
+		var size image.Point
+		thumbData, size, err = thumbnail.Make(img)
+		if err != nil {
+			return nil, err
+		}
+		attachment.Thumbnail = &models.AttachmentThumbnail{
+			Width:       size.X,
+			Height:      size.Y,
+			ContentType: thumbnail.ContentType,
+			StorageKey:  attachment.StorageKey + ".thumb",
+		}
+	}
+
+	if err := s.Blobs.Put(ctx, attachment.StorageKey, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	if attachment.Thumbnail != nil {
+		err = s.Blobs.Put(ctx, attachment.Thumbnail.StorageKey, bytes.NewReader(thumbData))
+	}
+	if err == nil {
+		err = s.AttachmentRepo.CreateAttachment(ctx, attachment)
+	}
+	if err != nil {
+		return nil, errors.Join(err, s.deleteBlobs(attachment))
+	}
+
+	setUrls(attachment)
+	return attachment, nil
+}
+
+func (s *AttachmentService) List(ctx context.Context, receptionId uuid.UUID, productId *uuid.UUID) ([]models.Attachment, error) {
+	attachments, err := s.AttachmentRepo.ListAttachments(ctx, receptionId, productId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range attachments {
+		setUrls(&attachments[i])
+	}
+	return attachments, nil
+}
+
+// metadata and content of the file or of its thumbnail, the caller closes the content
+func (s *AttachmentService) Open(ctx context.Context, attachmentId uuid.UUID, thumb bool) (*models.Attachment, io.ReadCloser, error) {
+	attachment, err := s.AttachmentRepo.GetAttachment(ctx, attachmentId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := attachment.StorageKey
+	if thumb {
+		if attachment.Thumbnail == nil {
+			return nil, nil, models.ErrNotFound
+		}
+		key = attachment.Thumbnail.StorageKey
+	}
+
+	content, err := s.Blobs.Open(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	setUrls(attachment)
+	return attachment, content, nil
+}
+
+// a leftover blob without metadata is harmless, the error is only reported along with the upload error
+func (s *AttachmentService) deleteBlobs(attachment *models.Attachment) error {
+	ctx := context.Background()
+	errs := []error{s.Blobs.Delete(ctx, attachment.StorageKey)}
+	if attachment.Thumbnail != nil {
+		errs = append(errs, s.Blobs.Delete(ctx, attachment.Thumbnail.StorageKey))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("attachment %s: blobs not deleted: %w", attachment.Id, err)
+	}
+	return nil
+}
+
+// download urls are served behind the same authentication as the api
+func setUrls(attachment *models.Attachment) {
+	attachment.DownloadUrl = "/attachments/" + attachment.Id.String() + "/content"
+	if attachment.Thumbnail != nil {
+		attachment.Thumbnail.Url = "/attachments/" + attachment.Id.String() + "/thumbnail"
+	}
+}
+
+// base name without control characters, the name is only used for Content-Disposition
+func cleanFileName(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	for len(name) > maxFileNameLength {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
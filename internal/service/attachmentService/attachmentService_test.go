@@ -0,0 +1,186 @@
+package attachmentService
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage"
+	"orderPickupPoint/internal/storage/blobStore"
+	"orderPickupPoint/internal/utils/userCtx"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockAttachmentRepo struct {
+	mock.Mock
+	storage.Attachment
+}
+
+func (m *MockAttachmentRepo) CreateAttachment(ctx context.Context, attachment *models.Attachment) error {
+	args := m.Called(ctx, attachment)
+	return args.Error(0)
+}
+
+func (m *MockAttachmentRepo) GetAttachment(ctx context.Context, attachmentId uuid.UUID) (*models.Attachment, error) {
+	args := m.Called(ctx, attachmentId)
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+// blob store which keeps everything it is asked to delete
+type undeletableBlobs struct {
+	*blobStore.LocalBlobStore
+}
+
+func (b undeletableBlobs) Delete(ctx context.Context, key string) error {
+	return errors.New("disk is read only")
+}
+
+func pngImage(t *testing.T, width, height int) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+	return buf.Bytes()
+}
+
+// a small png which declares the given dimensions in its header
+func pngHeader(t *testing.T, width, height uint32) []byte {
+	data := pngImage(t, 1, 1)
+	// signature, then the IHDR chunk: length, type, width, height, ... and its crc
+	binary.BigEndian.PutUint32(data[16:], width)
+	binary.BigEndian.PutUint32(data[20:], height)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	return data
+}
+
+func newService(t *testing.T, maxBytes int64) (*AttachmentService, *MockAttachmentRepo, *blobStore.LocalBlobStore) {
+	blobs, err := blobStore.NewLocalBlobStore(t.TempDir())
+	require.NoError(t, err)
+	mockRepo := new(MockAttachmentRepo)
+	return NewAttachmentService(mockRepo, blobs, maxBytes), mockRepo, blobs
+}
+
+func TestUpload(t *testing.T) {
+	ctx := userCtx.WithUser(context.Background(), &models.User{Id: 5})
+
+	t.Run("image with thumbnail", func(t *testing.T) {
+		service, mockRepo, blobs := newService(t, 1<<20)
+		receptionId := uuid.New()
+
+		mockRepo.On("CreateAttachment", ctx, mock.AnythingOfType("*models.Attachment")).Return(nil)
+
+		attachment, err := service.Upload(ctx, receptionId, nil, `C:\photos\box "1".png`, bytes.NewReader(pngImage(t, 1024, 512)))
+
+		require.NoError(t, err)
+		require.Equal(t, "image/png", attachment.ContentType)
+		require.Equal(t, "box 1.png", attachment.FileName)
+		require.Equal(t, 1024, *attachment.Width)
+		require.Equal(t, 256, attachment.Thumbnail.Width)
+		require.Equal(t, 128, attachment.Thumbnail.Height)
+		require.Equal(t, 5, *attachment.CreatedBy)
+		require.Equal(t, "/attachments/"+attachment.Id.String()+"/content", attachment.DownloadUrl)
+
+		thumb, err := blobs.Open(ctx, attachment.Thumbnail.StorageKey)
+		require.NoError(t, err)
+		thumb.Close()
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("pdf without thumbnail", func(t *testing.T) {
+		service, mockRepo, _ := newService(t, 1<<20)
+		productId := uuid.New()
+
+		mockRepo.On("CreateAttachment", ctx, mock.MatchedBy(func(attachment *models.Attachment) bool {
+			return attachment.ProductId == &productId
+		})).Return(nil)
+
+		attachment, err := service.Upload(ctx, uuid.New(), &productId, "act.pdf", bytes.NewBufferString("%PDF-1.4\n%âãÏÓ\n"))
+
+		require.NoError(t, err)
+		require.Equal(t, "application/pdf", attachment.ContentType)
+		require.Nil(t, attachment.Thumbnail)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		service, mockRepo, _ := newService(t, 10)
+
+		_, err := service.Upload(ctx, uuid.New(), nil, "act.pdf", bytes.NewBufferString("%PDF-1.4 and more"))
+
+		require.ErrorIs(t, err, models.ErrAttachmentTooLarge)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("too many pixels", func(t *testing.T) {
+		service, mockRepo, _ := newService(t, 1<<20)
+
+		_, err := service.Upload(ctx, uuid.New(), nil, "bomb.png", bytes.NewReader(pngHeader(t, 100_000, 100_000)))
+
+		require.ErrorIs(t, err, models.ErrAttachmentTooLarge)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		service, mockRepo, _ := newService(t, 1<<20)
+
+		// the declared extension does not matter, the content is sniffed
+		_, err := service.Upload(ctx, uuid.New(), nil, "photo.png", bytes.NewBufferString("<html><script>alert(1)</script>"))
+
+		require.ErrorIs(t, err, models.ErrUnsupportedMedia)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("unknown reception removes blobs", func(t *testing.T) {
+		service, mockRepo, blobs := newService(t, 1<<20)
+
+		var stored *models.Attachment
+		mockRepo.On("CreateAttachment", ctx, mock.AnythingOfType("*models.Attachment")).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.Attachment)
+		}).Return(models.ErrNotFound)
+
+		_, err := service.Upload(ctx, uuid.New(), nil, "box.png", bytes.NewReader(pngImage(t, 10, 10)))
+
+		require.ErrorIs(t, err, models.ErrNotFound)
+		_, err = blobs.Open(ctx, stored.StorageKey)
+		require.ErrorIs(t, err, models.ErrNotFound)
+		_, err = blobs.Open(ctx, stored.Thumbnail.StorageKey)
+		require.ErrorIs(t, err, models.ErrNotFound)
+	})
+
+	t.Run("failed cleanup is returned with the upload error", func(t *testing.T) {
+		service, mockRepo, blobs := newService(t, 1<<20)
+		service.Blobs = undeletableBlobs{blobs}
+
+		mockRepo.On("CreateAttachment", ctx, mock.AnythingOfType("*models.Attachment")).Return(models.ErrNotFound)
+
+		_, err := service.Upload(ctx, uuid.New(), nil, "act.pdf", bytes.NewBufferString("%PDF-1.4\n"))
+
+		require.ErrorIs(t, err, models.ErrNotFound)
+		require.ErrorContains(t, err, "blobs not deleted: disk is read only")
+	})
+}
+
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	service, mockRepo, blobs := newService(t, 1<<20)
+
+	attachment := &models.Attachment{Id: uuid.New(), StorageKey: "attachments/a", ContentType: "application/pdf"}
+	require.NoError(t, blobs.Put(ctx, attachment.StorageKey, bytes.NewBufferString("%PDF")))
+	mockRepo.On("GetAttachment", ctx, attachment.Id).Return(attachment, nil)
+
+	_, content, err := service.Open(ctx, attachment.Id, false)
+	require.NoError(t, err)
+	data, _ := io.ReadAll(content)
+	content.Close()
+	require.Equal(t, "%PDF", string(data))
+
+	_, _, err = service.Open(ctx, attachment.Id, true)
+	require.ErrorIs(t, err, models.ErrNotFound)
+}
@@ -209,6 +209,28 @@ func (s *AuthService) AvaliableForUser(tokens *models.AuthTokens, avaliableRoles
 
 }
 
+// user from the access token claims
+func (s *AuthService) GetUserFromTokens(tokens *models.AuthTokens) (*models.User, error) {
+	accessTokenClaims, err := s.tokensHandler.ParseJwt(tokens.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	userId, ok := (*accessTokenClaims)["userId"].(float64)
+	if !ok {
+		return nil, errors.New("wrong token")
+	}
+	userRole, ok := (*accessTokenClaims)["userRole"].(string)
+	if !ok {
+		return nil, errors.New("wrong token")
+	}
+
+	return &models.User{
+		Id:   int(userId),
+		Role: userRole,
+	}, nil
+}
+
 func (p *TokenHandlerImpl) ParseJwt(token string) (*jwt.MapClaims, error) {
 	jwtToken, err := jwt.Parse(token, func(t *jwt.Token) (interface{}, error) {
 		_, ok := t.Method.(*jwt.SigningMethodHMAC)
@@ -0,0 +1,129 @@
+package cellService
+
+import (
+	"context"
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage"
+	"orderPickupPoint/internal/utils/userCtx"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const (
+	maxCellsPerRequest = 500
+)
+
+// parts of the cell code, the dash is the separator in the code
+var partPattern = regexp.MustCompile(`^[0-9A-Za-zА-Яа-яЁё]{1,16}$`)
+
+type CellService struct {
+	CellRepo storage.Cell
+}
+
+func NewCellService(cellRepo storage.Cell) *CellService {
+	return &CellService{
+		CellRepo: cellRepo,
+	}
+}
+
+func (s *CellService) CreateCells(ctx context.Context, pvzId uuid.UUID, input *models.StorageCellsInputAPI) ([]models.StorageCellAPI, error) {
+	if len(input.Cells) == 0 {
+		return nil, fmt.Errorf("%w: no cells", models.ErrInvalidCell)
+	}
+	if len(input.Cells) > maxCellsPerRequest {
+		return nil, fmt.Errorf("%w: at most %d cells per request", models.ErrInvalidCell, maxCellsPerRequest)
+	}
+
+	cells := make([]models.StorageCellInputAPI, len(input.Cells))
+	seen := make(map[string]bool, len(input.Cells))
+	for i, item := range input.Cells {
+		cell, err := normalizeCell(&item)
+		if err != nil {
+			return nil, fmt.Errorf("cell %d: %w", i, err)
+		}
+		code := cell.Rack + "-" + cell.Shelf + "-" + cell.Cell
+		if seen[code] {
+			return nil, fmt.Errorf("cell %d: %w", i, models.ErrCellExists)
+		}
+		seen[code] = true
+		cells[i] = *cell
+	}
+
+	return s.CellRepo.CreateCells(ctx, pvzId, cells)
+}
+
+func (s *CellService) ListCells(ctx context.Context, pvzId uuid.UUID) ([]models.StorageCellAPI, error) {
+	return s.CellRepo.ListCells(ctx, pvzId)
+}
+
+func (s *CellService) UpdateCell(ctx context.Context, cellId uuid.UUID, update *models.StorageCellUpdateAPI) error {
+	if update.Capacity == nil && update.Active == nil {
+		return fmt.Errorf("%w: nothing to update", models.ErrInvalidCell)
+	}
+	if update.Capacity != nil && *update.Capacity <= 0 {
+		return fmt.Errorf("%w: capacity must be positive", models.ErrInvalidCell)
+	}
+	return s.CellRepo.UpdateCell(ctx, cellId, update)
+}
+
+func (s *CellService) MoveProduct(ctx context.Context, productId uuid.UUID, cellCode string) error {
+	cellCode = strings.TrimSpace(cellCode)
+	if cellCode == "" {
+		return fmt.Errorf("%w: cell is required", models.ErrInvalidCell)
+	}
+	return s.CellRepo.MoveProduct(ctx, &models.ProductMove{
+		ProductId: productId,
+		CellCode:  cellCode,
+		ActorId:   userCtx.UserId(ctx),
+	})
+}
+
+// exactly one of barcode, order and external order id has to be set
+func (s *CellService) FindPlacements(ctx context.Context, filter *models.PlacementFilter) ([]models.PlacementAPI, error) {
+	set := 0
+	if filter.Barcode != nil {
+		set++
+	}
+	if filter.OrderId != nil {
+		set++
+	}
+	if filter.ExternalOrderId != nil {
+		set++
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("%w: exactly one of barcode, orderId and externalOrderId is required", models.ErrInvalidFilter)
+	}
+	return s.CellRepo.FindPlacements(ctx, filter)
+}
+
+func normalizeCell(item *models.StorageCellInputAPI) (*models.StorageCellInputAPI, error) {
+	cell := &models.StorageCellInputAPI{
+		Rack:      strings.TrimSpace(item.Rack),
+		Shelf:     strings.TrimSpace(item.Shelf),
+		Cell:      strings.TrimSpace(item.Cell),
+		Capacity:  item.Capacity,
+		MaxSideMm: item.MaxSideMm,
+	}
+	for _, part := range []string{cell.Rack, cell.Shelf, cell.Cell} {
+		if !partPattern.MatchString(part) {
+			return nil, fmt.Errorf("%w: rack, shelf and cell must match %s", models.ErrInvalidCell, partPattern)
+		}
+	}
+	if cell.Capacity <= 0 {
+		return nil, fmt.Errorf("%w: capacity must be positive", models.ErrInvalidCell)
+	}
+	if cell.MaxSideMm != nil && *cell.MaxSideMm <= 0 {
+		return nil, fmt.Errorf("%w: maxSideMm must be positive", models.ErrInvalidCell)
+	}
+	if item.Type != nil {
+		productType := strings.TrimSpace(*item.Type)
+		if productType == "" {
+			return nil, fmt.Errorf("%w: type cannot be empty", models.ErrInvalidCell)
+		}
+		cell.Type = &productType
+	}
+	return cell, nil
+}
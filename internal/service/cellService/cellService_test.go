@@ -0,0 +1,170 @@
+package cellService
+
+import (
+	"context"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage"
+	"orderPickupPoint/internal/utils/userCtx"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockCellRepo struct {
+	mock.Mock
+	storage.Cell
+}
+
+func (m *MockCellRepo) CreateCells(ctx context.Context, pvzId uuid.UUID, cells []models.StorageCellInputAPI) ([]models.StorageCellAPI, error) {
+	args := m.Called(ctx, pvzId, cells)
+	return args.Get(0).([]models.StorageCellAPI), args.Error(1)
+}
+
+func (m *MockCellRepo) MoveProduct(ctx context.Context, move *models.ProductMove) error {
+	args := m.Called(ctx, move)
+	return args.Error(0)
+}
+
+func (m *MockCellRepo) FindPlacements(ctx context.Context, filter *models.PlacementFilter) ([]models.PlacementAPI, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.PlacementAPI), args.Error(1)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestCreateCells(t *testing.T) {
+	tests := []struct {
+		name      string
+		cells     []models.StorageCellInputAPI
+		wantError error
+	}{
+		{
+			name: "valid layout",
+			cells: []models.StorageCellInputAPI{
+				{Rack: " A ", Shelf: "1", Cell: "1", Capacity: 4},
+				{Rack: "A", Shelf: "1", Cell: "2", Capacity: 1, MaxSideMm: intPtr(1200), Type: strPtr(" электроника ")},
+			},
+		},
+		{
+			name:      "no cells",
+			wantError: models.ErrInvalidCell,
+		},
+		{
+			name:      "dash in the code part",
+			cells:     []models.StorageCellInputAPI{{Rack: "A-1", Shelf: "1", Cell: "1", Capacity: 4}},
+			wantError: models.ErrInvalidCell,
+		},
+		{
+			name:      "zero capacity",
+			cells:     []models.StorageCellInputAPI{{Rack: "A", Shelf: "1", Cell: "1"}},
+			wantError: models.ErrInvalidCell,
+		},
+		{
+			name:      "negative max side",
+			cells:     []models.StorageCellInputAPI{{Rack: "A", Shelf: "1", Cell: "1", Capacity: 1, MaxSideMm: intPtr(-1)}},
+			wantError: models.ErrInvalidCell,
+		},
+		{
+			name: "same cell twice",
+			cells: []models.StorageCellInputAPI{
+				{Rack: "A", Shelf: "1", Cell: "1", Capacity: 4},
+				{Rack: "A ", Shelf: "1", Cell: "1", Capacity: 2},
+			},
+			wantError: models.ErrCellExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockRepo := new(MockCellRepo)
+			service := NewCellService(mockRepo)
+			pvzId := uuid.New()
+
+			if tt.wantError == nil {
+				normalized := mock.MatchedBy(func(cells []models.StorageCellInputAPI) bool {
+					return len(cells) == 2 && cells[0].Rack == "A" && cells[1].Type != nil && *cells[1].Type == "электроника"
+				})
+				mockRepo.On("CreateCells", ctx, pvzId, normalized).Return([]models.StorageCellAPI{{Code: "A-1-1"}, {Code: "A-1-2"}}, nil)
+			}
+
+			cells, err := service.CreateCells(ctx, pvzId, &models.StorageCellsInputAPI{Cells: tt.cells})
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantError == nil {
+				require.Len(t, cells, 2)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMoveProduct(t *testing.T) {
+	ctx := userCtx.WithUser(context.Background(), &models.User{Id: 5})
+	mockRepo := new(MockCellRepo)
+	service := NewCellService(mockRepo)
+	productId := uuid.New()
+
+	err := service.MoveProduct(ctx, productId, "  ")
+	require.ErrorIs(t, err, models.ErrInvalidCell)
+
+	move := mock.MatchedBy(func(move *models.ProductMove) bool {
+		return move.ProductId == productId && move.CellCode == "B-2-1" && move.ActorId != nil && *move.ActorId == 5
+	})
+	mockRepo.On("MoveProduct", ctx, move).Return(models.ErrCellFull)
+
+	err = service.MoveProduct(ctx, productId, " B-2-1 ")
+	require.ErrorIs(t, err, models.ErrCellFull)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestFindPlacements(t *testing.T) {
+	orderId := uuid.New()
+	tests := []struct {
+		name      string
+		filter    *models.PlacementFilter
+		wantError error
+	}{
+		{
+			name:   "by barcode",
+			filter: &models.PlacementFilter{Barcode: strPtr("4600000000001")},
+		},
+		{
+			name:   "by order",
+			filter: &models.PlacementFilter{OrderId: &orderId},
+		},
+		{
+			name:      "no filter",
+			filter:    &models.PlacementFilter{},
+			wantError: models.ErrInvalidFilter,
+		},
+		{
+			name:      "two filters",
+			filter:    &models.PlacementFilter{OrderId: &orderId, ExternalOrderId: strPtr("WB-1")},
+			wantError: models.ErrInvalidFilter,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockRepo := new(MockCellRepo)
+			service := NewCellService(mockRepo)
+
+			if tt.wantError == nil {
+				mockRepo.On("FindPlacements", ctx, tt.filter).Return([]models.PlacementAPI{}, nil)
+			}
+
+			_, err := service.FindPlacements(ctx, tt.filter)
+			require.ErrorIs(t, err, tt.wantError)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
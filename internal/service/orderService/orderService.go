@@ -0,0 +1,158 @@
+package orderService
+
+import (
+	"context"
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/notifier"
+	"orderPickupPoint/internal/storage"
+	"orderPickupPoint/internal/utils/userCtx"
+	"regexp"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+const maxExternalIdLength = 64
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{10,15}$`)
+
+type OrderService struct {
+	OrderRepo  storage.Order
+	Notifier   notifier.Notifier
+	CodePolicy models.PickupCodePolicy
+}
+
+func NewOrderService(orderRepo storage.Order, notify notifier.Notifier, codePolicy models.PickupCodePolicy) *OrderService {
+	return &OrderService{
+		OrderRepo:  orderRepo,
+		Notifier:   notify,
+		CodePolicy: codePolicy,
+	}
+}
+
+// items already accepted in the pvz with the external id are linked to the order at once
+func (s *OrderService) CreateOrder(ctx context.Context, input *models.OrderInputAPI) (*models.OrderAPI, error) {
+	order := &models.Order{
+		ExternalId:    strings.TrimSpace(input.ExternalId),
+		PvzId:         input.PvzId,
+		CustomerName:  strings.TrimSpace(input.CustomerName),
+		CustomerPhone: strings.TrimSpace(input.CustomerPhone),
+		ExpectedItems: input.ExpectedItems,
+		CreatedBy:     userCtx.UserId(ctx),
+	}
+
+	switch {
+	case order.ExternalId == "" || len(order.ExternalId) > maxExternalIdLength:
+		return nil, fmt.Errorf("%w: externalId is required, up to %d characters", models.ErrInvalidOrder, maxExternalIdLength)
+	case order.PvzId == uuid.Nil:
+		return nil, fmt.Errorf("%w: pvzId is required", models.ErrInvalidOrder)
+	case order.ExpectedItems <= 0:
+		return nil, fmt.Errorf("%w: expectedItems must be positive", models.ErrInvalidOrder)
+	case !phonePattern.MatchString(order.CustomerPhone):
+		// the pickup code is sent to the phone
+		return nil, fmt.Errorf("%w: customerPhone is required, 10 to 15 digits", models.ErrInvalidOrder)
+	}
+
+	orderId, err := s.OrderRepo.CreateOrder(ctx, order)
+	if err != nil {
+		return nil, err
+	}
+	return s.OrderRepo.GetOrder(ctx, orderId)
+}
+
+func (s *OrderService) GetOrder(ctx context.Context, orderId uuid.UUID) (*models.OrderAPI, error) {
+	order, err := s.OrderRepo.GetOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	order.Items, err = s.OrderRepo.GetOrderItems(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+func (s *OrderService) ListOrders(ctx context.Context, filter *models.OrderFilter) (*models.Page[models.OrderAPI], error) {
+	orders, err := s.OrderRepo.ListOrders(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return models.NewPage(orders, filter.Cursor, filter.Page, filter.PageLimit), nil
+}
+
+// checks the pickup code and the presented items against the order and hands them to the customer
+func (s *OrderService) IssueOrder(ctx context.Context, orderId uuid.UUID, request *models.OrderIssueAPI) (*models.OrderIssueResultAPI, error) {
+	order, err := s.OrderRepo.GetOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+	switch order.StatusId {
+	case models.OrderStatusReady, models.OrderStatusPartiallyIssued:
+	case models.OrderStatusAwaiting:
+		return nil, fmt.Errorf("%w: %d of %d items arrived", models.ErrOrderNotReady, order.ArrivedItems, order.ExpectedItems)
+	default:
+		return nil, models.ErrOrderClosed
+	}
+
+	items, err := s.OrderRepo.GetOrderItems(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	productIds, err := selectItems(items, request)
+	if err != nil {
+		return nil, err
+	}
+
+	// checked after the items, so a mismatch in them does not use up an attempt
+	if err := s.verifyPickupCode(ctx, order, request.PickupCode); err != nil {
+		return nil, err
+	}
+
+	err = s.OrderRepo.IssueProducts(ctx, &models.OrderIssue{
+		OrderId:    orderId,
+		ProductIds: productIds,
+		ActorId:    userCtx.UserId(ctx),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	order, err = s.GetOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+	return &models.OrderIssueResultAPI{
+		Order:  order,
+		Issued: productIds,
+	}, nil
+}
+
+// only orders which have not been handed to the customer yet can be cancelled
+func (s *OrderService) CancelOrder(ctx context.Context, orderId uuid.UUID, reason string) error {
+	order, err := s.OrderRepo.GetOrder(ctx, orderId)
+	if err != nil {
+		return err
+	}
+	if order.StatusId != models.OrderStatusAwaiting && order.StatusId != models.OrderStatusReady {
+		return fmt.Errorf("%w: %s -> cancelled", models.ErrOrderTransition, order.Status)
+	}
+
+	return s.OrderRepo.ChangeOrderStatus(ctx, &models.OrderTransition{
+		OrderId:      orderId,
+		FromStatusId: order.StatusId,
+		ToStatusId:   models.OrderStatusCancelled,
+		ActorId:      userCtx.UserId(ctx),
+		Comment:      reason,
+	})
+}
+
+func (s *OrderService) GetOrderHistory(ctx context.Context, orderId uuid.UUID) ([]models.OrderHistoryItem, error) {
+	if _, err := s.OrderRepo.GetOrder(ctx, orderId); err != nil {
+		return nil, err
+	}
+	return s.OrderRepo.GetOrderHistory(ctx, orderId)
+}
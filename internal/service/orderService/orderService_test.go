@@ -0,0 +1,250 @@
+package orderService
+
+import (
+	"context"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage"
+	"orderPickupPoint/internal/utils/userCtx"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockOrderRepo struct {
+	mock.Mock
+	storage.Order
+}
+
+func (m *MockOrderRepo) CreateOrder(ctx context.Context, order *models.Order) (uuid.UUID, error) {
+	args := m.Called(ctx, order)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockOrderRepo) GetOrder(ctx context.Context, orderId uuid.UUID) (*models.OrderAPI, error) {
+	args := m.Called(ctx, orderId)
+	return args.Get(0).(*models.OrderAPI), args.Error(1)
+}
+
+func (m *MockOrderRepo) GetOrderItems(ctx context.Context, orderId uuid.UUID) ([]models.OrderItemAPI, error) {
+	args := m.Called(ctx, orderId)
+	return args.Get(0).([]models.OrderItemAPI), args.Error(1)
+}
+
+func (m *MockOrderRepo) IssueProducts(ctx context.Context, issue *models.OrderIssue) error {
+	args := m.Called(ctx, issue)
+	return args.Error(0)
+}
+
+func (m *MockOrderRepo) ListOrdersWithoutPickupCode(ctx context.Context, limit int) ([]models.OrderAPI, error) {
+	args := m.Called(ctx, limit)
+	return args.Get(0).([]models.OrderAPI), args.Error(1)
+}
+
+func (m *MockOrderRepo) SetPickupCode(ctx context.Context, change *models.PickupCodeChange) (bool, error) {
+	args := m.Called(ctx, change)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockOrderRepo) ClaimPickupCodeAttempt(ctx context.Context, orderId uuid.UUID, maxAttempts int) (*models.PickupCode, error) {
+	args := m.Called(ctx, orderId, maxAttempts)
+	return args.Get(0).(*models.PickupCode), args.Error(1)
+}
+
+func (m *MockOrderRepo) ChangeOrderStatus(ctx context.Context, transition *models.OrderTransition) error {
+	args := m.Called(ctx, transition)
+	return args.Error(0)
+}
+
+func (m *MockOrderRepo) ListUnclaimedOrders(ctx context.Context, statusIds []int, limit int) ([]models.OrderAPI, error) {
+	args := m.Called(ctx, statusIds, limit)
+	return args.Get(0).([]models.OrderAPI), args.Error(1)
+}
+
+func (m *MockOrderRepo) ReturnUnclaimedOrder(ctx context.Context, transition *models.OrderTransition) (int, error) {
+	args := m.Called(ctx, transition)
+	return args.Int(0), args.Error(1)
+}
+
+func TestCreateOrder(t *testing.T) {
+	pvzId := uuid.New()
+
+	tests := []struct {
+		name      string
+		input     models.OrderInputAPI
+		wantError error
+	}{
+		{
+			name:  "valid",
+			input: models.OrderInputAPI{ExternalId: " WB-1 ", PvzId: pvzId, ExpectedItems: 2, CustomerPhone: "+79991234567"},
+		},
+		{
+			name:      "no external id",
+			input:     models.OrderInputAPI{ExternalId: "  ", PvzId: pvzId, ExpectedItems: 2},
+			wantError: models.ErrInvalidOrder,
+		},
+		{
+			name:      "no pvz",
+			input:     models.OrderInputAPI{ExternalId: "WB-1", ExpectedItems: 2},
+			wantError: models.ErrInvalidOrder,
+		},
+		{
+			name:      "no items expected",
+			input:     models.OrderInputAPI{ExternalId: "WB-1", PvzId: pvzId},
+			wantError: models.ErrInvalidOrder,
+		},
+		{
+			name:      "no phone",
+			input:     models.OrderInputAPI{ExternalId: "WB-1", PvzId: pvzId, ExpectedItems: 1},
+			wantError: models.ErrInvalidOrder,
+		},
+		{
+			name:      "invalid phone",
+			input:     models.OrderInputAPI{ExternalId: "WB-1", PvzId: pvzId, ExpectedItems: 1, CustomerPhone: "8-999-123"},
+			wantError: models.ErrInvalidOrder,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := userCtx.WithUser(context.Background(), &models.User{Id: 5})
+			repo := new(MockOrderRepo)
+			service := NewOrderService(repo, nil, testCodePolicy)
+			orderId := uuid.New()
+
+			if tt.wantError == nil {
+				repo.On("CreateOrder", ctx, mock.MatchedBy(func(order *models.Order) bool {
+					return order.ExternalId == "WB-1" && order.CreatedBy != nil && *order.CreatedBy == 5
+				})).Return(orderId, nil)
+				repo.On("GetOrder", ctx, orderId).Return(&models.OrderAPI{Id: orderId}, nil)
+			}
+
+			order, err := service.CreateOrder(ctx, &tt.input)
+
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantError == nil {
+				require.Equal(t, orderId, order.Id)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestIssueOrder(t *testing.T) {
+	orderId := uuid.New()
+	item := orderItem("4600000000011", true, false)
+
+	t.Run("not all items arrived", func(t *testing.T) {
+		ctx := context.Background()
+		repo := new(MockOrderRepo)
+		service := NewOrderService(repo, nil, testCodePolicy)
+
+		repo.On("GetOrder", ctx, orderId).Return(&models.OrderAPI{Id: orderId, StatusId: models.OrderStatusAwaiting, ExpectedItems: 2, ArrivedItems: 1}, nil)
+
+		_, err := service.IssueOrder(ctx, orderId, &models.OrderIssueAPI{})
+
+		require.ErrorIs(t, err, models.ErrOrderNotReady)
+		repo.AssertNotCalled(t, "IssueProducts", mock.Anything, mock.Anything)
+	})
+
+	t.Run("already issued", func(t *testing.T) {
+		ctx := context.Background()
+		repo := new(MockOrderRepo)
+		service := NewOrderService(repo, nil, testCodePolicy)
+
+		repo.On("GetOrder", ctx, orderId).Return(&models.OrderAPI{Id: orderId, StatusId: models.OrderStatusIssued}, nil)
+
+		_, err := service.IssueOrder(ctx, orderId, &models.OrderIssueAPI{})
+
+		require.ErrorIs(t, err, models.ErrOrderClosed)
+	})
+
+	t.Run("items do not match", func(t *testing.T) {
+		ctx := context.Background()
+		repo := new(MockOrderRepo)
+		service := NewOrderService(repo, nil, testCodePolicy)
+
+		repo.On("GetOrder", ctx, orderId).Return(readyOrder(orderId), nil)
+		repo.On("GetOrderItems", ctx, orderId).Return([]models.OrderItemAPI{item}, nil)
+
+		_, err := service.IssueOrder(ctx, orderId, &models.OrderIssueAPI{PickupCode: "123456", Barcodes: []string{"0000"}})
+
+		require.ErrorIs(t, err, models.ErrItemNotIssuable)
+		// the code is not checked, so no attempt is used up
+		repo.AssertNotCalled(t, "ClaimPickupCodeAttempt", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("wrong pickup code", func(t *testing.T) {
+		ctx := context.Background()
+		repo := new(MockOrderRepo)
+		service := NewOrderService(repo, nil, testCodePolicy)
+
+		repo.On("GetOrder", ctx, orderId).Return(readyOrder(orderId), nil)
+		repo.On("GetOrderItems", ctx, orderId).Return([]models.OrderItemAPI{item}, nil)
+		repo.On("ClaimPickupCodeAttempt", ctx, orderId, testCodePolicy.MaxAttempts).Return(&models.PickupCode{Hash: hashCode(t, "123456"), Attempts: 1}, nil)
+
+		_, err := service.IssueOrder(ctx, orderId, &models.OrderIssueAPI{PickupCode: "654321"})
+
+		require.ErrorIs(t, err, models.ErrWrongPickupCode)
+		repo.AssertNotCalled(t, "IssueProducts", mock.Anything, mock.Anything)
+	})
+
+	t.Run("issued by barcode", func(t *testing.T) {
+		ctx := userCtx.WithUser(context.Background(), &models.User{Id: 5})
+		repo := new(MockOrderRepo)
+		service := NewOrderService(repo, nil, testCodePolicy)
+
+		repo.On("GetOrder", ctx, orderId).Return(readyOrder(orderId), nil).Once()
+		repo.On("GetOrderItems", ctx, orderId).Return([]models.OrderItemAPI{item}, nil)
+		repo.On("ClaimPickupCodeAttempt", ctx, orderId, testCodePolicy.MaxAttempts).Return(&models.PickupCode{Hash: hashCode(t, "123456"), Attempts: 1}, nil)
+		repo.On("IssueProducts", ctx, mock.MatchedBy(func(issue *models.OrderIssue) bool {
+			return issue.OrderId == orderId && len(issue.ProductIds) == 1 && issue.ProductIds[0] == item.ID &&
+				issue.ActorId != nil && *issue.ActorId == 5
+		})).Return(nil)
+		repo.On("GetOrder", ctx, orderId).Return(&models.OrderAPI{Id: orderId, StatusId: models.OrderStatusIssued, Status: "issued"}, nil).Once()
+
+		result, err := service.IssueOrder(ctx, orderId, &models.OrderIssueAPI{PickupCode: " 123456 ", Barcodes: []string{"4600000000011"}})
+
+		require.NoError(t, err)
+		require.Equal(t, []uuid.UUID{item.ID}, result.Issued)
+		require.Equal(t, "issued", result.Order.Status)
+		repo.AssertExpectations(t)
+	})
+}
+
+func TestCancelOrder(t *testing.T) {
+	orderId := uuid.New()
+
+	tests := []struct {
+		name      string
+		statusId  int
+		wantError error
+	}{
+		{name: "awaiting", statusId: models.OrderStatusAwaiting},
+		{name: "ready", statusId: models.OrderStatusReady},
+		{name: "partially issued", statusId: models.OrderStatusPartiallyIssued, wantError: models.ErrOrderTransition},
+		{name: "issued", statusId: models.OrderStatusIssued, wantError: models.ErrOrderTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := new(MockOrderRepo)
+			service := NewOrderService(repo, nil, testCodePolicy)
+
+			repo.On("GetOrder", ctx, orderId).Return(&models.OrderAPI{Id: orderId, StatusId: tt.statusId}, nil)
+			if tt.wantError == nil {
+				repo.On("ChangeOrderStatus", ctx, mock.MatchedBy(func(transition *models.OrderTransition) bool {
+					return transition.FromStatusId == tt.statusId && transition.ToStatusId == models.OrderStatusCancelled &&
+						transition.Comment == "отказ клиента"
+				})).Return(nil)
+			}
+
+			err := service.CancelOrder(ctx, orderId, "отказ клиента")
+
+			require.ErrorIs(t, err, tt.wantError)
+			repo.AssertExpectations(t)
+		})
+	}
+}
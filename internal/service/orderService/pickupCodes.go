@@ -0,0 +1,139 @@
+package orderService
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/utils/userCtx"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ready orders handled by one run of the job
+const pickupCodeBatch = 100
+
+// gives pickup codes to the ready orders which have none and sends them to the customers.
+// Returns the number of codes sent
+func (s *OrderService) GeneratePickupCodes(ctx context.Context) (int, error) {
+	orders, err := s.OrderRepo.ListOrdersWithoutPickupCode(ctx, pickupCodeBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	systemActor := models.SystemActorId
+	sent := 0
+	var errs []error
+	for i := range orders {
+		ok, err := s.sendPickupCode(ctx, &orders[i], false, &systemActor, "pickup code generated")
+		if errors.Is(err, models.ErrOrderNotReady) || errors.Is(err, models.ErrOrderClosed) {
+			// changed meanwhile
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("order %s: %w", orders[i].Id, err))
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, errors.Join(errs...)
+}
+
+// replaces the pickup code of the order, e.g. when the customer lost it or it is locked by wrong attempts
+func (s *OrderService) RegeneratePickupCode(ctx context.Context, orderId uuid.UUID) (*models.OrderAPI, error) {
+	order, err := s.OrderRepo.GetOrder(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.sendPickupCode(ctx, order, true, userCtx.UserId(ctx), "pickup code regenerated")
+	if err != nil {
+		return nil, err
+	}
+	return s.OrderRepo.GetOrder(ctx, orderId)
+}
+
+// only the hash is stored, the code itself is known to the customer alone.
+// ErrNoCustomerPhone for an order created without a phone, no code is set then
+func (s *OrderService) sendPickupCode(ctx context.Context, order *models.OrderAPI, replace bool, actorId *int, comment string) (bool, error) {
+	if order.CustomerPhone == "" {
+		return false, models.ErrNoCustomerPhone
+	}
+
+	code, err := generatePickupCode(s.CodePolicy.Digits)
+	if err != nil {
+		return false, err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return false, err
+	}
+
+	set, err := s.OrderRepo.SetPickupCode(ctx, &models.PickupCodeChange{
+		OrderId: order.Id,
+		Hash:    string(hash),
+		Replace: replace,
+		ActorId: actorId,
+		Comment: comment,
+	})
+	if err != nil || !set {
+		return false, err
+	}
+
+	err = s.Notifier.Notify(ctx, &models.Notification{
+		Kind:      models.NotificationPickupCode,
+		OrderId:   order.Id,
+		PvzId:     order.PvzId,
+		Recipient: order.CustomerPhone,
+		Message:   fmt.Sprintf("Код получения заказа %s: %s", order.ExternalId, code),
+		Secret:    code,
+	})
+	if err != nil {
+		return false, fmt.Errorf("pickup code is set but not sent: %w", err)
+	}
+	return true, nil
+}
+
+// every check of a presented code uses up an attempt, the right code resets them on issuance
+func (s *OrderService) verifyPickupCode(ctx context.Context, order *models.OrderAPI, code string) error {
+	code = strings.TrimSpace(code)
+	switch {
+	case code == "":
+		return models.ErrPickupCodeRequired
+	case order.PickupCodeCreatedAt == nil:
+		return models.ErrNoPickupCode
+	case order.PickupCodeAttempts >= s.CodePolicy.MaxAttempts:
+		return models.ErrPickupCodeLocked
+	}
+
+	stored, err := s.OrderRepo.ClaimPickupCodeAttempt(ctx, order.Id, s.CodePolicy.MaxAttempts)
+	if err != nil {
+		return err
+	}
+	if bcrypt.CompareHashAndPassword([]byte(stored.Hash), []byte(code)) != nil {
+		left := s.CodePolicy.MaxAttempts - stored.Attempts
+		if left <= 0 {
+			return fmt.Errorf("%w, the code is locked", models.ErrWrongPickupCode)
+		}
+		return fmt.Errorf("%w, %d attempts left", models.ErrWrongPickupCode, left)
+	}
+	return nil
+}
+
+func generatePickupCode(digits int) (string, error) {
+	var code strings.Builder
+	for range digits {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code.WriteString(digit.String())
+	}
+	return code.String(), nil
+}
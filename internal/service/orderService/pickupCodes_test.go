@@ -0,0 +1,227 @@
+package orderService
+
+import (
+	"context"
+	"errors"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/utils/userCtx"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testCodePolicy = models.PickupCodePolicy{Digits: 6, MaxAttempts: 3}
+
+type MockNotifier struct {
+	mock.Mock
+}
+
+func (m *MockNotifier) Notify(ctx context.Context, notification *models.Notification) error {
+	args := m.Called(ctx, notification)
+	return args.Error(0)
+}
+
+func readyOrder(orderId uuid.UUID) *models.OrderAPI {
+	createdAt := time.Now()
+	return &models.OrderAPI{Id: orderId, StatusId: models.OrderStatusReady, CustomerPhone: "+79991234567", PickupCodeCreatedAt: &createdAt}
+}
+
+func hashCode(t *testing.T, code string) string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.MinCost)
+	require.NoError(t, err)
+	return string(hash)
+}
+
+var codeInMessage = regexp.MustCompile(`: ([0-9]+)$`)
+
+func TestGeneratePickupCodes(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockOrderRepo)
+	notifier := new(MockNotifier)
+	service := NewOrderService(repo, notifier, testCodePolicy)
+
+	sent := models.OrderAPI{Id: uuid.New(), ExternalId: "WB-1", CustomerPhone: "+79991234567"}
+	hasCode := models.OrderAPI{Id: uuid.New(), ExternalId: "WB-2", CustomerPhone: "+79991234567"}
+	cancelled := models.OrderAPI{Id: uuid.New(), ExternalId: "WB-3", CustomerPhone: "+79991234567"}
+	failed := models.OrderAPI{Id: uuid.New(), ExternalId: "WB-4", CustomerPhone: "+79991234567"}
+	// created before the phone was required, no code is set and the order is reported
+	noPhone := models.OrderAPI{Id: uuid.New(), ExternalId: "WB-5"}
+
+	var hash string
+	repo.On("ListOrdersWithoutPickupCode", ctx, pickupCodeBatch).Return([]models.OrderAPI{sent, hasCode, cancelled, failed, noPhone}, nil)
+	repo.On("SetPickupCode", ctx, mock.MatchedBy(func(change *models.PickupCodeChange) bool {
+		return change.OrderId == sent.Id
+	})).Run(func(args mock.Arguments) {
+		change := args.Get(1).(*models.PickupCodeChange)
+		require.False(t, change.Replace)
+		require.Equal(t, models.SystemActorId, *change.ActorId)
+		hash = change.Hash
+	}).Return(true, nil)
+	repo.On("SetPickupCode", ctx, mock.MatchedBy(func(change *models.PickupCodeChange) bool {
+		return change.OrderId == hasCode.Id
+	})).Return(false, nil)
+	repo.On("SetPickupCode", ctx, mock.MatchedBy(func(change *models.PickupCodeChange) bool {
+		return change.OrderId == cancelled.Id
+	})).Return(false, models.ErrOrderClosed)
+	repo.On("SetPickupCode", ctx, mock.MatchedBy(func(change *models.PickupCodeChange) bool {
+		return change.OrderId == failed.Id
+	})).Return(false, errors.New("connection lost"))
+	notifier.On("Notify", ctx, mock.MatchedBy(func(n *models.Notification) bool {
+		return n.OrderId == sent.Id && n.Kind == models.NotificationPickupCode && n.Recipient == sent.CustomerPhone
+	})).Run(func(args mock.Arguments) {
+		// the customer gets the code which matches the stored hash
+		message := args.Get(1).(*models.Notification).Message
+		match := codeInMessage.FindStringSubmatch(message)
+		require.NotNil(t, match, message)
+		require.Len(t, match[1], testCodePolicy.Digits)
+		// marked secret so the log notifier can mask it
+		require.Equal(t, match[1], args.Get(1).(*models.Notification).Secret)
+		require.NoError(t, bcrypt.CompareHashAndPassword([]byte(hash), []byte(match[1])))
+	}).Return(nil)
+
+	count, err := service.GeneratePickupCodes(ctx)
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), failed.Id.String())
+	require.ErrorIs(t, err, models.ErrNoCustomerPhone)
+	require.Contains(t, err.Error(), noPhone.Id.String())
+	require.Equal(t, 1, count)
+	notifier.AssertNumberOfCalls(t, "Notify", 1)
+	repo.AssertNumberOfCalls(t, "SetPickupCode", 4)
+}
+
+func TestRegeneratePickupCode(t *testing.T) {
+	ctx := userCtx.WithUser(context.Background(), &models.User{Id: 5})
+	orderId := uuid.New()
+
+	t.Run("replaced and sent", func(t *testing.T) {
+		repo := new(MockOrderRepo)
+		notifier := new(MockNotifier)
+		service := NewOrderService(repo, notifier, testCodePolicy)
+
+		repo.On("GetOrder", ctx, orderId).Return(readyOrder(orderId), nil)
+		repo.On("SetPickupCode", ctx, mock.MatchedBy(func(change *models.PickupCodeChange) bool {
+			return change.OrderId == orderId && change.Replace && *change.ActorId == 5
+		})).Return(true, nil)
+		notifier.On("Notify", ctx, mock.Anything).Return(nil)
+
+		_, err := service.RegeneratePickupCode(ctx, orderId)
+
+		require.NoError(t, err)
+		repo.AssertExpectations(t)
+		notifier.AssertExpectations(t)
+	})
+
+	t.Run("order is not ready", func(t *testing.T) {
+		repo := new(MockOrderRepo)
+		notifier := new(MockNotifier)
+		service := NewOrderService(repo, notifier, testCodePolicy)
+
+		repo.On("GetOrder", ctx, orderId).Return(&models.OrderAPI{Id: orderId, StatusId: models.OrderStatusAwaiting, CustomerPhone: "+79991234567"}, nil)
+		repo.On("SetPickupCode", ctx, mock.Anything).Return(false, models.ErrOrderNotReady)
+
+		_, err := service.RegeneratePickupCode(ctx, orderId)
+
+		require.ErrorIs(t, err, models.ErrOrderNotReady)
+		notifier.AssertNotCalled(t, "Notify", mock.Anything, mock.Anything)
+	})
+}
+
+func TestVerifyPickupCode(t *testing.T) {
+	orderId := uuid.New()
+	hash := hashCode(t, "123456")
+
+	tests := []struct {
+		name      string
+		order     *models.OrderAPI
+		code      string
+		claimed   *models.PickupCode
+		claimErr  error
+		wantError error
+		wantText  string
+	}{
+		{
+			name:      "no code presented",
+			order:     readyOrder(orderId),
+			wantError: models.ErrPickupCodeRequired,
+		},
+		{
+			name:      "code not generated yet",
+			order:     &models.OrderAPI{Id: orderId},
+			code:      "123456",
+			wantError: models.ErrNoPickupCode,
+		},
+		{
+			name: "no attempts left",
+			order: func() *models.OrderAPI {
+				order := readyOrder(orderId)
+				order.PickupCodeAttempts = testCodePolicy.MaxAttempts
+				return order
+			}(),
+			code:      "123456",
+			wantError: models.ErrPickupCodeLocked,
+		},
+		{
+			name:      "locked meanwhile",
+			order:     readyOrder(orderId),
+			code:      "123456",
+			claimed:   (*models.PickupCode)(nil),
+			claimErr:  models.ErrPickupCodeLocked,
+			wantError: models.ErrPickupCodeLocked,
+		},
+		{
+			name:      "wrong code",
+			order:     readyOrder(orderId),
+			code:      "000000",
+			claimed:   &models.PickupCode{Hash: hash, Attempts: 1},
+			wantError: models.ErrWrongPickupCode,
+			wantText:  "2 attempts left",
+		},
+		{
+			name:      "last wrong attempt",
+			order:     readyOrder(orderId),
+			code:      "000000",
+			claimed:   &models.PickupCode{Hash: hash, Attempts: 3},
+			wantError: models.ErrWrongPickupCode,
+			wantText:  "locked",
+		},
+		{
+			name:    "right code",
+			order:   readyOrder(orderId),
+			code:    "123456",
+			claimed: &models.PickupCode{Hash: hash, Attempts: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := new(MockOrderRepo)
+			service := NewOrderService(repo, nil, testCodePolicy)
+
+			if tt.claimed != nil || tt.claimErr != nil {
+				repo.On("ClaimPickupCodeAttempt", ctx, orderId, testCodePolicy.MaxAttempts).Return(tt.claimed, tt.claimErr)
+			}
+
+			err := service.verifyPickupCode(ctx, tt.order, tt.code)
+
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantText != "" {
+				require.Contains(t, err.Error(), tt.wantText)
+			}
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGeneratePickupCode(t *testing.T) {
+	code, err := generatePickupCode(6)
+
+	require.NoError(t, err)
+	require.Regexp(t, `^[0-9]{6}$`, code)
+}
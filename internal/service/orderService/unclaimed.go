@@ -0,0 +1,66 @@
+package orderService
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"slices"
+)
+
+// unclaimed orders handled by one run of the job
+const unclaimedBatch = 100
+
+// orders which are returned to the sender when the storage period expires. Unlike a manual
+// cancellation, a partially issued order is returned too: its waiting items go back to the sender
+// and the issued ones stay with the customer
+var unclaimedStatuses = []int{models.OrderStatusAwaiting, models.OrderStatusReady, models.OrderStatusPartiallyIssued}
+
+// returns the orders not picked up in the storage period to the sender: their items are queued
+// for return and the orders are cancelled, the customers are notified.
+// Returns the number of items queued
+func (s *OrderService) ReturnUnclaimedOrders(ctx context.Context) (int, error) {
+	orders, err := s.OrderRepo.ListUnclaimedOrders(ctx, unclaimedStatuses, unclaimedBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	systemActor := models.SystemActorId
+	queued := 0
+	var errs []error
+	for i := range orders {
+		order := &orders[i]
+		if !slices.Contains(unclaimedStatuses, order.StatusId) {
+			errs = append(errs, fmt.Errorf("order %s: %w: %s -> cancelled", order.Id, models.ErrOrderTransition, order.Status))
+			continue
+		}
+		items, err := s.OrderRepo.ReturnUnclaimedOrder(ctx, &models.OrderTransition{
+			OrderId:      order.Id,
+			FromStatusId: order.StatusId,
+			ToStatusId:   models.OrderStatusCancelled,
+			ActorId:      &systemActor,
+			Comment:      "storage period expired",
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("order %s: %w", order.Id, err))
+			continue
+		}
+		if items == 0 {
+			// picked up or changed meanwhile
+			continue
+		}
+		queued += items
+
+		err = s.Notifier.Notify(ctx, &models.Notification{
+			Kind:      models.NotificationUnclaimed,
+			OrderId:   order.Id,
+			PvzId:     order.PvzId,
+			Recipient: order.CustomerPhone,
+			Message:   fmt.Sprintf("Срок хранения заказа %s истёк, заказ возвращается отправителю", order.ExternalId),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("order %s is returned but not notified: %w", order.Id, err))
+		}
+	}
+	return queued, errors.Join(errs...)
+}
@@ -0,0 +1,91 @@
+package orderService
+
+import (
+	"context"
+	"errors"
+	"orderPickupPoint/internal/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReturnUnclaimedOrders(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockOrderRepo)
+	notifier := new(MockNotifier)
+	service := NewOrderService(repo, notifier, testCodePolicy)
+
+	returned := models.OrderAPI{Id: uuid.New(), ExternalId: "WB-1", StatusId: models.OrderStatusReady, CustomerPhone: "+79991234567"}
+	pickedUp := models.OrderAPI{Id: uuid.New(), ExternalId: "WB-2", StatusId: models.OrderStatusReady}
+	failed := models.OrderAPI{Id: uuid.New(), ExternalId: "WB-3", StatusId: models.OrderStatusPartiallyIssued}
+
+	repo.On("ListUnclaimedOrders", ctx, unclaimedStatuses, unclaimedBatch).Return([]models.OrderAPI{returned, pickedUp, failed}, nil)
+	repo.On("ReturnUnclaimedOrder", ctx, mock.MatchedBy(func(transition *models.OrderTransition) bool {
+		return transition.OrderId == returned.Id
+	})).Run(func(args mock.Arguments) {
+		transition := args.Get(1).(*models.OrderTransition)
+		require.Equal(t, models.OrderStatusReady, transition.FromStatusId)
+		require.Equal(t, models.OrderStatusCancelled, transition.ToStatusId)
+		require.Equal(t, models.SystemActorId, *transition.ActorId)
+	}).Return(2, nil)
+	repo.On("ReturnUnclaimedOrder", ctx, mock.MatchedBy(func(transition *models.OrderTransition) bool {
+		return transition.OrderId == pickedUp.Id
+	})).Return(0, nil)
+	repo.On("ReturnUnclaimedOrder", ctx, mock.MatchedBy(func(transition *models.OrderTransition) bool {
+		return transition.OrderId == failed.Id
+	})).Return(0, errors.New("connection lost"))
+	notifier.On("Notify", ctx, mock.MatchedBy(func(n *models.Notification) bool {
+		return n.OrderId == returned.Id && n.Kind == models.NotificationUnclaimed && n.Recipient == returned.CustomerPhone
+	})).Return(nil)
+
+	count, err := service.ReturnUnclaimedOrders(ctx)
+
+	require.Error(t, err)
+	require.Contains(t, err.Error(), failed.Id.String())
+	require.Equal(t, 2, count)
+	notifier.AssertNumberOfCalls(t, "Notify", 1)
+}
+
+func TestReturnUnclaimedOrdersNotifyFailed(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockOrderRepo)
+	notifier := new(MockNotifier)
+	service := NewOrderService(repo, notifier, testCodePolicy)
+
+	order := models.OrderAPI{Id: uuid.New(), ExternalId: "WB-1", StatusId: models.OrderStatusAwaiting}
+	repo.On("ListUnclaimedOrders", ctx, unclaimedStatuses, unclaimedBatch).Return([]models.OrderAPI{order}, nil)
+	repo.On("ReturnUnclaimedOrder", ctx, mock.Anything).Return(1, nil)
+	notifier.On("Notify", ctx, mock.Anything).Return(errors.New("sms gateway is down"))
+
+	count, err := service.ReturnUnclaimedOrders(ctx)
+
+	// the items are queued anyway
+	require.Error(t, err)
+	require.Equal(t, 1, count)
+}
+
+func TestReturnUnclaimedOrdersPartiallyIssued(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockOrderRepo)
+	notifier := new(MockNotifier)
+	service := NewOrderService(repo, notifier, testCodePolicy)
+
+	partial := models.OrderAPI{Id: uuid.New(), ExternalId: "WB-1", Status: "partially_issued", StatusId: models.OrderStatusPartiallyIssued}
+	issued := models.OrderAPI{Id: uuid.New(), ExternalId: "WB-2", Status: "issued", StatusId: models.OrderStatusIssued}
+	repo.On("ListUnclaimedOrders", ctx, unclaimedStatuses, unclaimedBatch).Return([]models.OrderAPI{partial, issued}, nil)
+	repo.On("ReturnUnclaimedOrder", ctx, mock.MatchedBy(func(transition *models.OrderTransition) bool {
+		return transition.OrderId == partial.Id &&
+			transition.FromStatusId == models.OrderStatusPartiallyIssued &&
+			transition.ToStatusId == models.OrderStatusCancelled
+	})).Return(1, nil)
+	notifier.On("Notify", ctx, mock.Anything).Return(nil)
+
+	count, err := service.ReturnUnclaimedOrders(ctx)
+
+	// the waiting item of the partially issued order is returned, the issued order is left alone
+	require.ErrorIs(t, err, models.ErrOrderTransition)
+	require.Equal(t, 1, count)
+	repo.AssertNumberOfCalls(t, "ReturnUnclaimedOrder", 1)
+}
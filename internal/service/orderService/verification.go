@@ -0,0 +1,76 @@
+package orderService
+
+import (
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// matches the presented product ids and scanned barcodes with the items of the order.
+// Every one of them has to be an issuable item not yet taken by another, a barcode scanned
+// twice takes two items with that barcode. Nothing presented means all issuable items.
+// All mismatches are reported at once
+func selectItems(items []models.OrderItemAPI, request *models.OrderIssueAPI) ([]uuid.UUID, error) {
+	if len(request.ProductIds) == 0 && len(request.Barcodes) == 0 {
+		var all []uuid.UUID
+		for _, item := range items {
+			if item.Issuable {
+				all = append(all, item.ID)
+			}
+		}
+		if len(all) == 0 {
+			return nil, fmt.Errorf("%w: no items to issue", models.ErrItemNotIssuable)
+		}
+		return all, nil
+	}
+
+	byId := make(map[uuid.UUID]*models.OrderItemAPI, len(items))
+	for i := range items {
+		byId[items[i].ID] = &items[i]
+	}
+
+	var (
+		selected []uuid.UUID
+		problems []string
+	)
+	taken := make(map[uuid.UUID]bool)
+	for _, productId := range request.ProductIds {
+		item, ok := byId[productId]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("product %s is not in the order", productId))
+		case taken[productId]:
+			problems = append(problems, fmt.Sprintf("product %s is presented twice", productId))
+		case item.IssuedAt != nil:
+			problems = append(problems, fmt.Sprintf("product %s is already issued", productId))
+		case !item.Issuable:
+			problems = append(problems, fmt.Sprintf("product %s is in a reception which is not closed", productId))
+		default:
+			taken[productId] = true
+			selected = append(selected, productId)
+		}
+	}
+
+	for _, code := range request.Barcodes {
+		code = strings.TrimSpace(code)
+		found := false
+		for _, item := range items {
+			if item.Barcode != nil && *item.Barcode == code && item.Issuable && !taken[item.ID] {
+				taken[item.ID] = true
+				selected = append(selected, item.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, fmt.Sprintf("barcode %q does not match an item to issue", code))
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", models.ErrItemNotIssuable, strings.Join(problems, "; "))
+	}
+	return selected, nil
+}
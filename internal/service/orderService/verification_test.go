@@ -0,0 +1,110 @@
+package orderService
+
+import (
+	"orderPickupPoint/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func orderItem(barcode string, issuable bool, issued bool) models.OrderItemAPI {
+	item := models.OrderItemAPI{Issuable: issuable}
+	item.ID = uuid.New()
+	item.Barcode = &barcode
+	if issued {
+		now := time.Now()
+		item.IssuedAt = &now
+	}
+	return item
+}
+
+func TestSelectItems(t *testing.T) {
+	first := orderItem("4600000000011", true, false)
+	second := orderItem("4600000000011", true, false)
+	issued := orderItem("4600000000028", false, true)
+	open := orderItem("4600000000035", false, false)
+	items := []models.OrderItemAPI{first, second, issued, open}
+
+	tests := []struct {
+		name      string
+		items     []models.OrderItemAPI
+		request   models.OrderIssueAPI
+		want      []uuid.UUID
+		wantError string
+	}{
+		{
+			name:  "nothing presented takes all issuable items",
+			items: items,
+			want:  []uuid.UUID{first.ID, second.ID},
+		},
+		{
+			name:      "nothing left to issue",
+			items:     []models.OrderItemAPI{issued, open},
+			wantError: "no items to issue",
+		},
+		{
+			name:    "product ids",
+			items:   items,
+			request: models.OrderIssueAPI{ProductIds: []uuid.UUID{second.ID}},
+			want:    []uuid.UUID{second.ID},
+		},
+		{
+			name:    "same barcode scanned twice takes two items",
+			items:   items,
+			request: models.OrderIssueAPI{Barcodes: []string{"4600000000011", " 4600000000011 "}},
+			want:    []uuid.UUID{first.ID, second.ID},
+		},
+		{
+			name:    "barcode skips an item presented by id",
+			items:   items,
+			request: models.OrderIssueAPI{ProductIds: []uuid.UUID{first.ID}, Barcodes: []string{"4600000000011"}},
+			want:    []uuid.UUID{first.ID, second.ID},
+		},
+		{
+			name:      "barcode scanned more times than items",
+			items:     items,
+			request:   models.OrderIssueAPI{Barcodes: []string{"4600000000011", "4600000000011", "4600000000011"}},
+			wantError: `barcode "4600000000011" does not match an item to issue`,
+		},
+		{
+			name:      "foreign product",
+			items:     items,
+			request:   models.OrderIssueAPI{ProductIds: []uuid.UUID{first.ID, uuid.Nil}},
+			wantError: "is not in the order",
+		},
+		{
+			name:      "presented twice",
+			items:     items,
+			request:   models.OrderIssueAPI{ProductIds: []uuid.UUID{first.ID, first.ID}},
+			wantError: "is presented twice",
+		},
+		{
+			name:      "already issued",
+			items:     items,
+			request:   models.OrderIssueAPI{ProductIds: []uuid.UUID{issued.ID}},
+			wantError: "is already issued",
+		},
+		{
+			name:      "reception not closed",
+			items:     items,
+			request:   models.OrderIssueAPI{ProductIds: []uuid.UUID{open.ID}},
+			wantError: "is in a reception which is not closed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectItems(tt.items, &tt.request)
+
+			if tt.wantError != "" {
+				require.ErrorIs(t, err, models.ErrItemNotIssuable)
+				require.Contains(t, err.Error(), tt.wantError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -2,12 +2,21 @@ package pickupPointService
 
 import (
 	"context"
+	"fmt"
 	"orderPickupPoint/internal/models"
 	"orderPickupPoint/internal/storage"
+	"orderPickupPoint/internal/utils/cursor"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	// window of the expiring products list
+	defaultExpiringDays = 2
+	maxExpiringDays     = 30
+)
+
 type PickupPointService struct {
 	PickupPointRepo storage.PickupPoint
 }
@@ -39,51 +48,132 @@ func (s *PickupPointService) Create(ctx context.Context, pickupPointAPI *models.
 	return outPickupPoint, nil
 }
 
-func (s *PickupPointService) GetInfo(ctx context.Context, filter *models.PvzFilter) ([]models.PvzInfo, error) {
-	info, err := s.PickupPointRepo.GetFilteredInfo(ctx, filter)
+func (s *PickupPointService) GetInfo(ctx context.Context, filter *models.PvzFilter) (*models.Page[models.PvzInfo], error) {
+	grouper := newPvzGrouper()
+	filteredInfo, err := s.PickupPointRepo.GetFilteredInfo(ctx, filter, grouper.add)
 	if err != nil {
 		return nil, err
 	}
-	pvzMap := make(map[uuid.UUID]*models.PvzInfo)
-
-	for _, item := range info {
-		pvz, exists := pvzMap[item.PvzID]
-		if !exists {
-			pvz = &models.PvzInfo{
-				ID:       item.PvzID,
-				CityName: item.CityName,
-				RegDate:  item.RegDate,
-			}
-			pvzMap[item.PvzID] = pvz
-		}
 
-		var rec *models.ReceptionInfo
-		for i := range pvz.Receptions {
-			if pvz.Receptions[i].ID == item.ReceptionID {
-				rec = &pvz.Receptions[i]
-				break
-			}
-		}
-		if rec == nil {
-			rec = &models.ReceptionInfo{
-				ID:       item.ReceptionID,
-				DateTime: item.ReceptionTime,
-			}
-			pvz.Receptions = append(pvz.Receptions, *rec)
-			rec = &pvz.Receptions[len(pvz.Receptions)-1]
+	page := &models.Page[models.PvzInfo]{
+		Items: grouper.result(),
+		Total: filteredInfo.Total,
+		Limit: filter.PageLimit,
+		Next:  cursor.EncodeOrNil(filteredInfo.Next),
+		Prev:  cursor.EncodeOrNil(filteredInfo.Prev),
+	}
+	if filter.Cursor == nil {
+		page.Page = filter.Page
+	}
+	return page, nil
+}
+
+func (s *PickupPointService) GetDetails(ctx context.Context, pvzId uuid.UUID) (*models.PickupPointDetailsAPI, error) {
+	pickupPoint, err := s.PickupPointRepo.GetById(ctx, pvzId)
+	if err != nil {
+		return nil, err
+	}
+
+	capacity, err := s.PickupPointRepo.GetCapacity(ctx, pvzId)
+	if err != nil {
+		return nil, err
+	}
+
+	periods, err := s.PickupPointRepo.GetStoragePeriods(ctx, pvzId)
+	if err != nil {
+		return nil, err
+	}
+
+	stock, err := s.PickupPointRepo.GetStock(ctx, pvzId)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := s.PickupPointRepo.GetTotals(ctx, pvzId)
+	if err != nil {
+		return nil, err
+	}
+
+	utilization := models.PvzUtilization{
+		MaxItems: capacity.MaxItems,
+		ByType:   stock,
+	}
+	for i := range utilization.ByType {
+		typeStock := &utilization.ByType[i]
+		typeStock.Utilization = utilizationRatio(typeStock.StoredItems, typeStock.MaxItems)
+		utilization.StoredItems += typeStock.StoredItems
+	}
+	utilization.Utilization = utilizationRatio(utilization.StoredItems, utilization.MaxItems)
+
+	return &models.PickupPointDetailsAPI{
+		PickupPointAPI: *pickupPoint,
+		Capacity:       *capacity,
+		StoragePeriods: *periods,
+		Utilization:    utilization,
+		Totals:         *totals,
+	}, nil
+}
+
+func (s *PickupPointService) SetCapacity(ctx context.Context, pvzId uuid.UUID, capacity *models.PvzCapacity) error {
+	if capacity.MaxItems != nil && *capacity.MaxItems < 0 {
+		return fmt.Errorf("%w: max items can't be negative", models.ErrInvalidCapacity)
+	}
+	for typeName, maxItems := range capacity.MaxItemsByType {
+		if maxItems < 0 {
+			return fmt.Errorf("%w: max items of %s can't be negative", models.ErrInvalidCapacity, typeName)
 		}
+	}
+	return s.PickupPointRepo.SetCapacity(ctx, pvzId, capacity)
+}
 
-		product := models.ProductInfo{
-			ID:      item.ProductID,
-			AddedAt: item.AddedAt,
-			Type:    item.ProductType,
+// the periods are replaced entirely, they apply to the products accepted afterwards
+func (s *PickupPointService) SetStoragePeriods(ctx context.Context, pvzId uuid.UUID, periods *models.PvzStoragePeriods) error {
+	if periods.Days != nil && *periods.Days <= 0 {
+		return fmt.Errorf("%w: days must be positive", models.ErrInvalidStorageDays)
+	}
+	for typeName, days := range periods.DaysByType {
+		if days <= 0 {
+			return fmt.Errorf("%w: days of %s must be positive", models.ErrInvalidStorageDays, typeName)
 		}
-		rec.Products = append(rec.Products, product)
 	}
+	return s.PickupPointRepo.SetStoragePeriods(ctx, pvzId, periods)
+}
+
+// products whose storage deadline comes within the days, zero days is defaultExpiringDays
+func (s *PickupPointService) ListExpiring(ctx context.Context, filter *models.StorageDeadlineFilter, days int) (*models.Page[models.PlacementAPI], error) {
+	if days == 0 {
+		days = defaultExpiringDays
+	}
+	if days < 0 || days > maxExpiringDays {
+		return nil, fmt.Errorf("%w: days must be from 1 to %d", models.ErrInvalidFilter, maxExpiringDays)
+	}
+
+	now := time.Now()
+	filter.From = &now
+	filter.Until = now.AddDate(0, 0, days)
+	return s.listByStorageDeadline(ctx, filter)
+}
+
+// products not picked up by the storage deadline and not queued for return yet
+func (s *PickupPointService) ListOverdue(ctx context.Context, filter *models.StorageDeadlineFilter) (*models.Page[models.PlacementAPI], error) {
+	filter.From = nil
+	filter.Until = time.Now()
+	return s.listByStorageDeadline(ctx, filter)
+}
+
+func (s *PickupPointService) listByStorageDeadline(ctx context.Context, filter *models.StorageDeadlineFilter) (*models.Page[models.PlacementAPI], error) {
+	placements, err := s.PickupPointRepo.ListByStorageDeadline(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewPage(placements, filter.Cursor, filter.Page, filter.PageLimit), nil
+}
 
-	result := make([]models.PvzInfo, 0, len(pvzMap))
-	for _, pvz := range pvzMap {
-		result = append(result, *pvz)
+// share of the limit in use, nil if there is no limit
+func utilizationRatio(stored int, maxItems *int) *float64 {
+	if maxItems == nil || *maxItems == 0 {
+		return nil
 	}
-	return result, nil
+	ratio := float64(stored) / float64(*maxItems)
+	return &ratio
 }
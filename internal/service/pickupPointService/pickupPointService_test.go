@@ -0,0 +1,195 @@
+package pickupPointService
+
+import (
+	"context"
+	"errors"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage"
+	"orderPickupPoint/internal/utils/cursor"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockPickupPointRepo struct {
+	mock.Mock
+	storage.PickupPoint
+}
+
+func (m *MockPickupPointRepo) GetById(ctx context.Context, pvzId uuid.UUID) (*models.PickupPointAPI, error) {
+	args := m.Called(ctx, pvzId)
+	return args.Get(0).(*models.PickupPointAPI), args.Error(1)
+}
+
+func (m *MockPickupPointRepo) SetCapacity(ctx context.Context, pvzId uuid.UUID, capacity *models.PvzCapacity) error {
+	return m.Called(ctx, pvzId, capacity).Error(0)
+}
+
+func (m *MockPickupPointRepo) GetCapacity(ctx context.Context, pvzId uuid.UUID) (*models.PvzCapacity, error) {
+	args := m.Called(ctx, pvzId)
+	return args.Get(0).(*models.PvzCapacity), args.Error(1)
+}
+
+func (m *MockPickupPointRepo) GetStoragePeriods(ctx context.Context, pvzId uuid.UUID) (*models.PvzStoragePeriods, error) {
+	args := m.Called(ctx, pvzId)
+	return args.Get(0).(*models.PvzStoragePeriods), args.Error(1)
+}
+
+func (m *MockPickupPointRepo) GetStock(ctx context.Context, pvzId uuid.UUID) ([]models.TypeUtilization, error) {
+	args := m.Called(ctx, pvzId)
+	return args.Get(0).([]models.TypeUtilization), args.Error(1)
+}
+
+func (m *MockPickupPointRepo) GetTotals(ctx context.Context, pvzId uuid.UUID) (*models.ProductTotals, error) {
+	args := m.Called(ctx, pvzId)
+	return args.Get(0).(*models.ProductTotals), args.Error(1)
+}
+
+func (m *MockPickupPointRepo) ListByStorageDeadline(ctx context.Context, filter *models.StorageDeadlineFilter) (*models.ListResult[models.PlacementAPI], error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(*models.ListResult[models.PlacementAPI]), args.Error(1)
+}
+
+func TestListExpiring(t *testing.T) {
+	tests := []struct {
+		name      string
+		days      int
+		wantDays  int
+		wantError error
+	}{
+		{name: "default window", days: 0, wantDays: defaultExpiringDays},
+		{name: "week", days: 7, wantDays: 7},
+		{name: "too long", days: maxExpiringDays + 1, wantError: models.ErrInvalidFilter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := new(MockPickupPointRepo)
+			service := NewPickupPointService(repo)
+			pvzId := uuid.New()
+
+			if tt.wantError == nil {
+				repo.On("ListByStorageDeadline", ctx, mock.MatchedBy(func(filter *models.StorageDeadlineFilter) bool {
+					return filter.PvzId == pvzId && filter.From != nil &&
+						filter.Until.Equal(filter.From.AddDate(0, 0, tt.wantDays))
+				})).Return(&models.ListResult[models.PlacementAPI]{}, nil)
+			}
+
+			_, err := service.ListExpiring(ctx, &models.StorageDeadlineFilter{PvzId: pvzId, Page: 1, PageLimit: 10}, tt.days)
+
+			require.ErrorIs(t, err, tt.wantError)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListOverdue(t *testing.T) {
+	ctx := context.Background()
+	repo := new(MockPickupPointRepo)
+	service := NewPickupPointService(repo)
+	pvzId := uuid.New()
+
+	before := time.Now()
+	after := &cursor.Cursor{SortBy: models.PlacementSortByDeadline, Id: uuid.NewString()}
+	next := &cursor.Cursor{SortBy: models.PlacementSortByDeadline, Id: uuid.NewString()}
+	repo.On("ListByStorageDeadline", ctx, mock.MatchedBy(func(filter *models.StorageDeadlineFilter) bool {
+		return filter.PvzId == pvzId && filter.From == nil && !filter.Until.Before(before) && filter.Cursor == after
+	})).Return(&models.ListResult[models.PlacementAPI]{Total: 12, Next: next}, nil)
+
+	page, err := service.ListOverdue(ctx, &models.StorageDeadlineFilter{PvzId: pvzId, Cursor: after, Page: 1, PageLimit: 10})
+
+	require.NoError(t, err)
+	require.Equal(t, []models.PlacementAPI{}, page.Items)
+	require.Equal(t, 12, page.Total)
+	// the page number is unknown when paged by cursor
+	require.Zero(t, page.Page)
+	require.Equal(t, next.Encode(), *page.Next)
+	repo.AssertExpectations(t)
+}
+
+func TestSetCapacity(t *testing.T) {
+	tests := []struct {
+		name      string
+		capacity  *models.PvzCapacity
+		repoError error
+		wantError error
+	}{
+		{name: "limits set", capacity: &models.PvzCapacity{MaxItems: intPtr(100), MaxItemsByType: map[string]int{"обувь": 10}, Strict: true}},
+		{name: "zero limit", capacity: &models.PvzCapacity{MaxItems: intPtr(0)}},
+		{name: "negative limit", capacity: &models.PvzCapacity{MaxItems: intPtr(-1)}, wantError: models.ErrInvalidCapacity},
+		{name: "negative type limit", capacity: &models.PvzCapacity{MaxItemsByType: map[string]int{"обувь": -5}}, wantError: models.ErrInvalidCapacity},
+		{name: "unknown type", capacity: &models.PvzCapacity{MaxItemsByType: map[string]int{"мебель": 5}},
+			repoError: models.ErrUnknownProductType, wantError: models.ErrUnknownProductType},
+		{name: "unknown pvz", capacity: &models.PvzCapacity{MaxItems: intPtr(100)}, repoError: models.ErrNotFound, wantError: models.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := new(MockPickupPointRepo)
+			service := NewPickupPointService(repo)
+			pvzId := uuid.New()
+
+			if !errors.Is(tt.wantError, models.ErrInvalidCapacity) {
+				repo.On("SetCapacity", ctx, pvzId, tt.capacity).Return(tt.repoError)
+			}
+
+			err := service.SetCapacity(ctx, pvzId, tt.capacity)
+
+			require.ErrorIs(t, err, tt.wantError)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetDetails(t *testing.T) {
+	ctx := context.Background()
+	pvzId := uuid.New()
+
+	t.Run("utilization of the pvz and its types", func(t *testing.T) {
+		repo := new(MockPickupPointRepo)
+		service := NewPickupPointService(repo)
+
+		repo.On("GetById", ctx, pvzId).Return(&models.PickupPointAPI{Id: pvzId, City: "Москва"}, nil)
+		repo.On("GetCapacity", ctx, pvzId).Return(&models.PvzCapacity{MaxItems: intPtr(20), MaxItemsByType: map[string]int{"обувь": 4}}, nil)
+		repo.On("GetStoragePeriods", ctx, pvzId).Return(&models.PvzStoragePeriods{DaysByType: map[string]int{}}, nil)
+		repo.On("GetStock", ctx, pvzId).Return([]models.TypeUtilization{
+			{Type: "обувь", StoredItems: 2, MaxItems: intPtr(4)},
+			{Type: "одежда", StoredItems: 3},
+		}, nil)
+		repo.On("GetTotals", ctx, pvzId).Return(&models.ProductTotals{WeightGrams: 1500}, nil)
+
+		details, err := service.GetDetails(ctx, pvzId)
+
+		require.NoError(t, err)
+		require.Equal(t, "Москва", details.City)
+		require.Equal(t, 5, details.Utilization.StoredItems)
+		require.InDelta(t, 0.25, *details.Utilization.Utilization, 1e-9)
+		require.InDelta(t, 0.5, *details.Utilization.ByType[0].Utilization, 1e-9)
+		// no limit for the type
+		require.Nil(t, details.Utilization.ByType[1].Utilization)
+		require.Equal(t, int64(1500), details.Totals.WeightGrams)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("unknown pvz", func(t *testing.T) {
+		repo := new(MockPickupPointRepo)
+		service := NewPickupPointService(repo)
+
+		repo.On("GetById", ctx, pvzId).Return((*models.PickupPointAPI)(nil), models.ErrNotFound)
+
+		details, err := service.GetDetails(ctx, pvzId)
+
+		require.ErrorIs(t, err, models.ErrNotFound)
+		require.Nil(t, details)
+		repo.AssertNotCalled(t, "GetCapacity", mock.Anything, mock.Anything)
+	})
+}
+
+func intPtr(i int) *int {
+	return &i
+}
@@ -0,0 +1,76 @@
+package pickupPointService
+
+import (
+	"orderPickupPoint/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// groups flat pvz/reception/product rows into nested pickup point info.
+// Rows are consumed one by one and the first appearance of every pickup point,
+// reception and product defines its position, so the sql ordering is kept.
+// Positions are stored by index, so every row costs a couple of map lookups
+type pvzGrouper struct {
+	items        []models.PvzInfo
+	pvzIdx       map[uuid.UUID]int
+	receptionIdx map[uuid.UUID]int
+}
+
+func newPvzGrouper() *pvzGrouper {
+	return &pvzGrouper{
+		items:        []models.PvzInfo{},
+		pvzIdx:       make(map[uuid.UUID]int),
+		receptionIdx: make(map[uuid.UUID]int),
+	}
+}
+
+func (g *pvzGrouper) add(row *models.PvzFilteredInfo) {
+	pvzIdx, exists := g.pvzIdx[row.PvzID]
+	if !exists {
+		g.items = append(g.items, models.PvzInfo{
+			ID:         row.PvzID,
+			CityName:   row.CityName,
+			RegDate:    row.RegDate,
+			Receptions: []models.ReceptionInfo{},
+		})
+		pvzIdx = len(g.items) - 1
+		g.pvzIdx[row.PvzID] = pvzIdx
+	}
+	pvz := &g.items[pvzIdx]
+
+	// pickup point without receptions
+	if row.ReceptionID == nil {
+		return
+	}
+
+	recIdx, exists := g.receptionIdx[*row.ReceptionID]
+	if !exists {
+		pvz.Receptions = append(pvz.Receptions, models.ReceptionInfo{
+			ID:       *row.ReceptionID,
+			DateTime: *row.ReceptionTime,
+			Products: []models.ProductInfo{},
+		})
+		recIdx = len(pvz.Receptions) - 1
+		g.receptionIdx[*row.ReceptionID] = recIdx
+	}
+	rec := &pvz.Receptions[recIdx]
+
+	// reception without products
+	if row.ProductID == nil {
+		return
+	}
+
+	product := models.ProductInfo{
+		ID:      *row.ProductID,
+		AddedAt: *row.AddedAt,
+		Type:    *row.ProductType,
+	}
+	if row.Condition != nil {
+		product.Condition = *row.Condition
+	}
+	rec.Products = append(rec.Products, product)
+}
+
+func (g *pvzGrouper) result() []models.PvzInfo {
+	return g.items
+}
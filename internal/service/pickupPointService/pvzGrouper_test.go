@@ -0,0 +1,99 @@
+package pickupPointService
+
+import (
+	"orderPickupPoint/internal/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func row(pvzId uuid.UUID, receptionId *uuid.UUID, productId *uuid.UUID) *models.PvzFilteredInfo {
+	now := time.Now()
+	productType := "обувь"
+	out := &models.PvzFilteredInfo{PvzID: pvzId, CityName: "Казань", RegDate: now}
+	if receptionId != nil {
+		out.ReceptionID = receptionId
+		out.ReceptionTime = &now
+	}
+	if productId != nil {
+		out.ProductID = productId
+		out.AddedAt = &now
+		out.ProductType = &productType
+	}
+	return out
+}
+
+func ptr(id uuid.UUID) *uuid.UUID {
+	return &id
+}
+
+func TestPvzGrouperKeepsOrder(t *testing.T) {
+	pvzIds := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	recIds := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	prodIds := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+
+	grouper := newPvzGrouper()
+	rows := []*models.PvzFilteredInfo{
+		row(pvzIds[0], ptr(recIds[0]), ptr(prodIds[0])),
+		row(pvzIds[0], ptr(recIds[0]), ptr(prodIds[1])),
+		row(pvzIds[0], ptr(recIds[1]), nil),
+		row(pvzIds[1], nil, nil),
+		row(pvzIds[2], ptr(recIds[2]), ptr(prodIds[2])),
+		row(pvzIds[2], ptr(recIds[2]), ptr(prodIds[3])),
+	}
+	for _, r := range rows {
+		grouper.add(r)
+	}
+
+	result := grouper.result()
+	require.Len(t, result, 3)
+	for i := range pvzIds {
+		require.Equal(t, pvzIds[i], result[i].ID)
+	}
+
+	require.Len(t, result[0].Receptions, 2)
+	require.Equal(t, recIds[0], result[0].Receptions[0].ID)
+	require.Equal(t, prodIds[0], result[0].Receptions[0].Products[0].ID)
+	require.Equal(t, prodIds[1], result[0].Receptions[0].Products[1].ID)
+	require.Equal(t, recIds[1], result[0].Receptions[1].ID)
+	require.NotNil(t, result[0].Receptions[1].Products)
+	require.Empty(t, result[0].Receptions[1].Products)
+
+	require.NotNil(t, result[1].Receptions)
+	require.Empty(t, result[1].Receptions)
+
+	require.Len(t, result[2].Receptions[0].Products, 2)
+	require.Equal(t, prodIds[3], result[2].Receptions[0].Products[1].ID)
+}
+
+func TestPvzGrouperEmpty(t *testing.T) {
+	grouper := newPvzGrouper()
+	require.NotNil(t, grouper.result())
+	require.Empty(t, grouper.result())
+}
+
+func TestPvzGrouperRepeatedResultIsStable(t *testing.T) {
+	pvzIds := make([]uuid.UUID, 50)
+	for i := range pvzIds {
+		pvzIds[i] = uuid.New()
+	}
+
+	var first []uuid.UUID
+	for attempt := 0; attempt < 5; attempt++ {
+		grouper := newPvzGrouper()
+		for _, id := range pvzIds {
+			grouper.add(row(id, ptr(uuid.New()), nil))
+		}
+		var order []uuid.UUID
+		for _, pvz := range grouper.result() {
+			order = append(order, pvz.ID)
+		}
+		if first == nil {
+			first = order
+		}
+		require.Equal(t, first, order)
+		require.Equal(t, pvzIds, order)
+	}
+}
@@ -0,0 +1,147 @@
+package productTypeService
+
+import (
+	"context"
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage"
+	"regexp"
+	"strings"
+)
+
+var (
+	codePattern   = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+	localePattern = regexp.MustCompile(`^[a-z]{2}(-[A-Z]{2})?$`)
+)
+
+type ProductTypeService struct {
+	ProductTypeRepo storage.ProductType
+}
+
+func NewProductTypeService(productTypeRepo storage.ProductType) *ProductTypeService {
+	return &ProductTypeService{
+		ProductTypeRepo: productTypeRepo,
+	}
+}
+
+func (s *ProductTypeService) List(ctx context.Context, includeInactive bool) ([]models.ProductTypeAPI, error) {
+	productTypes, err := s.ProductTypeRepo.List(ctx, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]models.ProductTypeAPI, 0, len(productTypes))
+	for i := range productTypes {
+		out = append(out, *productTypeToAPI(&productTypes[i]))
+	}
+	return out, nil
+}
+
+// new types are active unless stated otherwise
+func (s *ProductTypeService) Create(ctx context.Context, input *models.ProductTypeInputAPI) (*models.ProductTypeAPI, error) {
+	productType := &models.ProductType{
+		Code:   strings.TrimSpace(input.Code),
+		Active: input.Active == nil || *input.Active,
+	}
+	if !codePattern.MatchString(productType.Code) {
+		return nil, fmt.Errorf("%w: code must match %s", models.ErrInvalidProductType, codePattern)
+	}
+	if input.Name == nil || strings.TrimSpace(*input.Name) == "" {
+		return nil, fmt.Errorf("%w: name is required", models.ErrInvalidProductType)
+	}
+	productType.Name = strings.TrimSpace(*input.Name)
+
+	names, err := normalizeNames(input.Names)
+	if err != nil {
+		return nil, err
+	}
+	productType.Names = names
+
+	if input.Rules != nil {
+		if err := validateRules(input.Rules); err != nil {
+			return nil, err
+		}
+		productType.Rules = *input.Rules
+	}
+
+	err = s.ProductTypeRepo.Create(ctx, productType)
+	if err != nil {
+		return nil, err
+	}
+	return s.get(ctx, productType.Code)
+}
+
+// the code is stable and cannot be changed
+func (s *ProductTypeService) Update(ctx context.Context, code string, input *models.ProductTypeInputAPI) (*models.ProductTypeAPI, error) {
+	if input.Code != "" && input.Code != code {
+		return nil, fmt.Errorf("%w: code cannot be changed", models.ErrInvalidProductType)
+	}
+
+	var name *string
+	if input.Name != nil {
+		trimmed := strings.TrimSpace(*input.Name)
+		if trimmed == "" {
+			return nil, fmt.Errorf("%w: name cannot be empty", models.ErrInvalidProductType)
+		}
+		name = &trimmed
+	}
+	names, err := normalizeNames(input.Names)
+	if err != nil {
+		return nil, err
+	}
+	if input.Rules != nil {
+		if err := validateRules(input.Rules); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.ProductTypeRepo.Update(ctx, code, name, names, input.Active, input.Rules)
+	if err != nil {
+		return nil, err
+	}
+	return s.get(ctx, code)
+}
+
+func (s *ProductTypeService) get(ctx context.Context, code string) (*models.ProductTypeAPI, error) {
+	productType, err := s.ProductTypeRepo.GetByCode(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+	return productTypeToAPI(productType), nil
+}
+
+// trims the names, empty ones are kept to remove the locale on update
+func normalizeNames(names map[string]string) (map[string]string, error) {
+	out := make(map[string]string, len(names))
+	for locale, name := range names {
+		if !localePattern.MatchString(locale) {
+			return nil, fmt.Errorf("%w: locale %q", models.ErrInvalidProductType, locale)
+		}
+		out[locale] = strings.TrimSpace(name)
+	}
+	return out, nil
+}
+
+func validateRules(rules *models.ProductTypeRules) error {
+	if (rules.MaxWeightGrams != nil && *rules.MaxWeightGrams <= 0) ||
+		(rules.MaxSideMm != nil && *rules.MaxSideMm <= 0) ||
+		(rules.MaxDeclaredValue != nil && *rules.MaxDeclaredValue <= 0) ||
+		(rules.StorageDays != nil && *rules.StorageDays <= 0) {
+		return fmt.Errorf("%w: limits must be positive", models.ErrInvalidProductType)
+	}
+	return nil
+}
+
+func productTypeToAPI(productType *models.ProductType) *models.ProductTypeAPI {
+	names := productType.Names
+	if names == nil {
+		names = map[string]string{}
+	}
+	return &models.ProductTypeAPI{
+		Code:   productType.Code,
+		Name:   productType.Name,
+		Names:  names,
+		Active: productType.Active,
+		Rules:  productType.Rules,
+	}
+}
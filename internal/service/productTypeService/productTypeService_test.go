@@ -0,0 +1,145 @@
+package productTypeService
+
+import (
+	"context"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type MockProductTypeRepo struct {
+	mock.Mock
+	storage.ProductType
+}
+
+func (m *MockProductTypeRepo) GetByCode(ctx context.Context, code string) (*models.ProductType, error) {
+	args := m.Called(ctx, code)
+	return args.Get(0).(*models.ProductType), args.Error(1)
+}
+
+func (m *MockProductTypeRepo) Create(ctx context.Context, productType *models.ProductType) error {
+	args := m.Called(ctx, productType)
+	return args.Error(0)
+}
+
+func (m *MockProductTypeRepo) Update(ctx context.Context, code string, name *string, names map[string]string, active *bool, rules *models.ProductTypeRules) error {
+	args := m.Called(ctx, code, name, names, active, rules)
+	return args.Error(0)
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestCreate(t *testing.T) {
+	inactive := false
+	tests := []struct {
+		name       string
+		input      *models.ProductTypeInputAPI
+		wantActive bool
+		wantError  error
+	}{
+		{
+			name:       "active by default",
+			input:      &models.ProductTypeInputAPI{Code: "furniture", Name: strPtr(" мебель "), Names: map[string]string{"en": "Furniture"}},
+			wantActive: true,
+		},
+		{
+			name:  "inactive",
+			input: &models.ProductTypeInputAPI{Code: "furniture", Name: strPtr("мебель"), Active: &inactive},
+		},
+		{
+			name:      "invalid code",
+			input:     &models.ProductTypeInputAPI{Code: "Мебель", Name: strPtr("мебель")},
+			wantError: models.ErrInvalidProductType,
+		},
+		{
+			name:      "no name",
+			input:     &models.ProductTypeInputAPI{Code: "furniture"},
+			wantError: models.ErrInvalidProductType,
+		},
+		{
+			name:      "invalid locale",
+			input:     &models.ProductTypeInputAPI{Code: "furniture", Name: strPtr("мебель"), Names: map[string]string{"english": "Furniture"}},
+			wantError: models.ErrInvalidProductType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockRepo := new(MockProductTypeRepo)
+			service := NewProductTypeService(mockRepo)
+
+			if tt.wantError == nil {
+				created := mock.MatchedBy(func(productType *models.ProductType) bool {
+					return productType.Code == "furniture" && productType.Name == "мебель" && productType.Active == tt.wantActive
+				})
+				mockRepo.On("Create", ctx, created).Return(nil)
+				mockRepo.On("GetByCode", ctx, "furniture").Return(&models.ProductType{Code: "furniture", Name: "мебель", Active: tt.wantActive}, nil)
+			}
+
+			productType, err := service.Create(ctx, tt.input)
+
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantError == nil {
+				require.Equal(t, tt.wantActive, productType.Active)
+				require.NotNil(t, productType.Names)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	active := false
+
+	t.Run("deactivate", func(t *testing.T) {
+		mockRepo := new(MockProductTypeRepo)
+		service := NewProductTypeService(mockRepo)
+
+		mockRepo.On("Update", ctx, "shoes", (*string)(nil), map[string]string{"en": ""}, &active, (*models.ProductTypeRules)(nil)).Return(nil)
+		mockRepo.On("GetByCode", ctx, "shoes").Return(&models.ProductType{Code: "shoes", Name: "обувь"}, nil)
+
+		productType, err := service.Update(ctx, "shoes", &models.ProductTypeInputAPI{Names: map[string]string{"en": " "}, Active: &active})
+
+		require.NoError(t, err)
+		require.False(t, productType.Active)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("code is stable", func(t *testing.T) {
+		mockRepo := new(MockProductTypeRepo)
+		service := NewProductTypeService(mockRepo)
+
+		_, err := service.Update(ctx, "shoes", &models.ProductTypeInputAPI{Code: "boots"})
+
+		require.ErrorIs(t, err, models.ErrInvalidProductType)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("non-positive limit", func(t *testing.T) {
+		mockRepo := new(MockProductTypeRepo)
+		service := NewProductTypeService(mockRepo)
+
+		maxWeight := 0
+		_, err := service.Update(ctx, "shoes", &models.ProductTypeInputAPI{Rules: &models.ProductTypeRules{MaxWeightGrams: &maxWeight}})
+
+		require.ErrorIs(t, err, models.ErrInvalidProductType)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("empty name", func(t *testing.T) {
+		mockRepo := new(MockProductTypeRepo)
+		service := NewProductTypeService(mockRepo)
+
+		_, err := service.Update(ctx, "shoes", &models.ProductTypeInputAPI{Name: strPtr(" ")})
+
+		require.ErrorIs(t, err, models.ErrInvalidProductType)
+		mockRepo.AssertExpectations(t)
+	})
+}
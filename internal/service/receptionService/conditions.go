@@ -0,0 +1,87 @@
+package receptionService
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const maxConditionNotes = 1000
+
+var productConditions = newEnum(map[int]string{
+	models.ProductConditionOk:               "ok",
+	models.ProductConditionDamaged:          "damaged",
+	models.ProductConditionWrongItem:        "wrong_item",
+	models.ProductConditionMissingPackaging: "missing_packaging",
+})
+
+// empty condition is ok. Any other condition needs notes describing the problem
+func parseCondition(condition string, notes string) (int, string, error) {
+	condition = strings.TrimSpace(condition)
+	notes = strings.TrimSpace(notes)
+	if condition == "" {
+		condition = productConditions.name(models.ProductConditionOk)
+	}
+
+	conditionId, ok := productConditions.id(condition)
+	if !ok {
+		return 0, "", fmt.Errorf("%w: %q", models.ErrInvalidCondition, condition)
+	}
+	if conditionId != models.ProductConditionOk && notes == "" {
+		return 0, "", fmt.Errorf("%w: notes are required for %s", models.ErrInvalidCondition, condition)
+	}
+	if utf8.RuneCountInString(notes) > maxConditionNotes {
+		return 0, "", fmt.Errorf("%w: notes longer than %d characters", models.ErrInvalidCondition, maxConditionNotes)
+	}
+	return conditionId, notes, nil
+}
+
+// products not in ok condition and duplicate scans of the reception, deleted products are skipped
+func (s *ReceptionService) GetDiscrepancyReport(ctx context.Context, receptionId uuid.UUID) (*models.DiscrepancyReportAPI, error) {
+	reception, err := s.ReceptionRepo.GetReceptionById(ctx, receptionId)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := s.ReceptionRepo.GetReceptionProducts(ctx, receptionId)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.DiscrepancyReportAPI{
+		ReceptionId:   reception.Id,
+		PickupPointId: reception.PickupPointId,
+		Status:        reception.Status,
+		ByCondition:   map[string]int{},
+		Products:      []models.ProductInfo{},
+	}
+	for _, product := range products {
+		if product.DeletedAt != nil {
+			continue
+		}
+		report.ProductsCount++
+		report.ByCondition[product.Condition]++
+		if product.Duplicate {
+			report.Duplicates++
+		}
+		if product.Condition == productConditions.name(models.ProductConditionOk) && !product.Duplicate {
+			continue
+		}
+		report.Products = append(report.Products, product)
+	}
+	report.Problems = report.ProductsCount - report.ByCondition[productConditions.name(models.ProductConditionOk)]
+
+	manifest, err := s.ReceptionRepo.GetManifest(ctx, receptionId)
+	switch {
+	case err == nil:
+		report.Reconciliation = reconcile(manifest, products)
+	case !errors.Is(err, models.ErrNotFound):
+		return nil, err
+	}
+	return report, nil
+}
@@ -0,0 +1,108 @@
+package receptionService
+
+import (
+	"context"
+	"orderPickupPoint/internal/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCondition(t *testing.T) {
+	tests := []struct {
+		name        string
+		condition   string
+		notes       string
+		expectedId  int
+		expectError bool
+	}{
+		{name: "empty is ok", expectedId: models.ProductConditionOk},
+		{name: "ok with notes", condition: "ok", notes: " перемотан скотчем ", expectedId: models.ProductConditionOk},
+		{name: "damaged", condition: "damaged", notes: "вмятина на коробке", expectedId: models.ProductConditionDamaged},
+		{name: "missing packaging", condition: "missing_packaging", notes: "без пломбы", expectedId: models.ProductConditionMissingPackaging},
+		{name: "problem without notes", condition: "wrong_item", notes: "  ", expectError: true},
+		{name: "unknown condition", condition: "broken", notes: "x", expectError: true},
+		{name: "notes too long", condition: "damaged", notes: strings.Repeat("я", maxConditionNotes+1), expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditionId, notes, err := parseCondition(tt.condition, tt.notes)
+			if tt.expectError {
+				require.ErrorIs(t, err, models.ErrInvalidCondition)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expectedId, conditionId)
+			require.Equal(t, strings.TrimSpace(tt.notes), notes)
+		})
+	}
+}
+
+func TestGetDiscrepancyReport(t *testing.T) {
+	ctx := context.Background()
+	receptionId := uuid.New()
+	deletedAt := time.Now()
+	code := "4006381333931"
+
+	products := []models.ProductInfo{
+		{ID: uuid.New(), Type: "обувь", Condition: "ok"},
+		{ID: uuid.New(), Type: "обувь", Condition: "damaged", Notes: "порван пакет"},
+		{ID: uuid.New(), Type: "одежда", Condition: "ok", Barcode: &code, Duplicate: true},
+		{ID: uuid.New(), Type: "одежда", Condition: "wrong_item", Notes: "не тот размер", DeletedAt: &deletedAt},
+	}
+
+	t.Run("without manifest", func(t *testing.T) {
+		mockRepo := new(MockReceptionRepo)
+		service := NewReceptionService(mockRepo)
+
+		mockRepo.On("GetReceptionById", ctx, receptionId).Return(&models.ReceptionDetailsAPI{Id: receptionId, Status: "close"}, nil)
+		mockRepo.On("GetReceptionProducts", ctx, receptionId).Return(products, nil)
+		mockRepo.On("GetManifest", ctx, receptionId).Return((*models.ManifestAPI)(nil), models.ErrNotFound)
+
+		report, err := service.GetDiscrepancyReport(ctx, receptionId)
+
+		require.NoError(t, err)
+		require.Equal(t, 3, report.ProductsCount)
+		require.Equal(t, 1, report.Problems)
+		require.Equal(t, 1, report.Duplicates)
+		require.Equal(t, map[string]int{"ok": 2, "damaged": 1}, report.ByCondition)
+		require.Equal(t, []models.ProductInfo{products[1], products[2]}, report.Products)
+		require.Nil(t, report.Reconciliation)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("with manifest", func(t *testing.T) {
+		mockRepo := new(MockReceptionRepo)
+		service := NewReceptionService(mockRepo)
+
+		manifest := &models.ManifestAPI{
+			ReceptionId: receptionId,
+			Items:       []models.ManifestItemAPI{{Type: "обувь", Quantity: 3}},
+		}
+		mockRepo.On("GetReceptionById", ctx, receptionId).Return(&models.ReceptionDetailsAPI{Id: receptionId, Status: "close"}, nil)
+		mockRepo.On("GetReceptionProducts", ctx, receptionId).Return(products, nil)
+		mockRepo.On("GetManifest", ctx, receptionId).Return(manifest, nil)
+
+		report, err := service.GetDiscrepancyReport(ctx, receptionId)
+
+		require.NoError(t, err)
+		require.NotNil(t, report.Reconciliation)
+		require.Equal(t, 3, report.Reconciliation.Actual)
+		require.Len(t, report.Reconciliation.Shortages, 1)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("reception not found", func(t *testing.T) {
+		mockRepo := new(MockReceptionRepo)
+		service := NewReceptionService(mockRepo)
+
+		mockRepo.On("GetReceptionById", ctx, receptionId).Return((*models.ReceptionDetailsAPI)(nil), models.ErrNotFound)
+
+		_, err := service.GetDiscrepancyReport(ctx, receptionId)
+
+		require.ErrorIs(t, err, models.ErrNotFound)
+	})
+}
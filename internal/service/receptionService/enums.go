@@ -0,0 +1,26 @@
+package receptionService
+
+// API names of the ids of a dictionary table (conditions, statuses, reasons), the ids are the models constants.
+// The names are written once, the reverse lookup is derived from them
+type enum struct {
+	names map[int]string
+	ids   map[string]int
+}
+
+func newEnum(names map[int]string) enum {
+	ids := make(map[string]int, len(names))
+	for id, name := range names {
+		ids[name] = id
+	}
+	return enum{names: names, ids: ids}
+}
+
+func (e enum) name(id int) string {
+	return e.names[id]
+}
+
+// false for an unknown name
+func (e enum) id(name string) (int, bool) {
+	id, ok := e.ids[name]
+	return id, ok
+}
@@ -0,0 +1,19 @@
+package receptionService
+
+import (
+	"orderPickupPoint/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnum(t *testing.T) {
+	id, ok := productConditions.id("wrong_item")
+	require.True(t, ok)
+	require.Equal(t, models.ProductConditionWrongItem, id)
+	require.Equal(t, "wrong_item", productConditions.name(id))
+
+	_, ok = productConditions.id("lost")
+	require.False(t, ok)
+	require.Empty(t, productConditions.name(0))
+}
@@ -0,0 +1,88 @@
+package receptionService
+
+import (
+	"context"
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/utils/barcode"
+	"orderPickupPoint/internal/utils/userCtx"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// attaches the supplier manifest to an open reception, replacing the previous one
+func (s *ReceptionService) SetManifest(ctx context.Context, receptionId uuid.UUID, manifestAPI *models.ManifestAPI) (*models.ManifestAPI, error) {
+	if len(manifestAPI.Items) == 0 {
+		return nil, fmt.Errorf("%w: no items", models.ErrInvalidManifest)
+	}
+	if manifestAPI.MaxDiscrepancy != nil && *manifestAPI.MaxDiscrepancy < 0 {
+		return nil, fmt.Errorf("%w: negative maxDiscrepancy", models.ErrInvalidManifest)
+	}
+
+	var names []string
+	for _, item := range manifestAPI.Items {
+		if item.Type != "" {
+			names = append(names, item.Type)
+		}
+	}
+	productTypes, err := s.ReceptionRepo.GetProductTypesByNames(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &models.Manifest{
+		ReceptionId:    receptionId,
+		SupplierRef:    strings.TrimSpace(manifestAPI.SupplierRef),
+		MaxDiscrepancy: manifestAPI.MaxDiscrepancy,
+		CreatedBy:      userCtx.UserId(ctx),
+		Items:          make([]models.ManifestItem, 0, len(manifestAPI.Items)),
+	}
+	for i, itemAPI := range manifestAPI.Items {
+		if itemAPI.Quantity <= 0 {
+			return nil, fmt.Errorf("%w: item %d: quantity must be positive", models.ErrInvalidManifest, i)
+		}
+		item := models.ManifestItem{Quantity: itemAPI.Quantity}
+
+		if code := strings.TrimSpace(itemAPI.Barcode); code != "" {
+			if _, err := barcode.Validate(code); err != nil {
+				return nil, err
+			}
+			item.Barcode = &code
+		}
+		if itemAPI.Type != "" {
+			productType, ok := productTypes[itemAPI.Type]
+			if !ok {
+				return nil, fmt.Errorf("%w: item %d: %q", models.ErrUnknownProductType, i, itemAPI.Type)
+			}
+			item.TypeId = &productType.Id
+		}
+		if item.Barcode == nil && item.TypeId == nil {
+			return nil, fmt.Errorf("%w: item %d: barcode or type is required", models.ErrInvalidManifest, i)
+		}
+		manifest.Items = append(manifest.Items, item)
+	}
+
+	err = s.ReceptionRepo.SetManifest(ctx, manifest)
+	if err != nil {
+		return nil, err
+	}
+	return s.ReceptionRepo.GetManifest(ctx, receptionId)
+}
+
+func (s *ReceptionService) GetManifest(ctx context.Context, receptionId uuid.UUID) (*models.ManifestAPI, error) {
+	return s.ReceptionRepo.GetManifest(ctx, receptionId)
+}
+
+// current state of the reception against its manifest
+func (s *ReceptionService) GetReconciliation(ctx context.Context, receptionId uuid.UUID) (*models.ReconciliationAPI, error) {
+	manifest, err := s.ReceptionRepo.GetManifest(ctx, receptionId)
+	if err != nil {
+		return nil, err
+	}
+	products, err := s.ReceptionRepo.GetReceptionProducts(ctx, receptionId)
+	if err != nil {
+		return nil, err
+	}
+	return reconcile(manifest, products), nil
+}
@@ -0,0 +1,122 @@
+package receptionService
+
+import (
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/utils/barcode"
+	"strings"
+)
+
+// builds a product to store from the request, the barcode is validated if set,
+// the attributes are checked against the rules of the type and the condition is parsed
+func newProduct(item *models.ProductAPI, productType *models.ProductType, actorId *int) (*models.Product, error) {
+	product := &models.Product{
+		Id:                item.Id,
+		AddedAt:           item.AddedAt,
+		TypeId:            productType.Id,
+		AddedBy:           actorId,
+		AllowDuplicate:    item.AllowDuplicate,
+		ProductAttributes: item.ProductAttributes,
+	}
+
+	if err := validateAttributes(&item.ProductAttributes, &productType.Rules); err != nil {
+		return nil, err
+	}
+
+	conditionId, notes, err := parseCondition(item.Condition, item.Notes)
+	if err != nil {
+		return nil, err
+	}
+	product.ConditionId = conditionId
+	product.Notes = notes
+
+	if code := strings.TrimSpace(item.Barcode); code != "" {
+		if _, err := barcode.Validate(code); err != nil {
+			return nil, err
+		}
+		product.Barcode = &code
+	}
+	if orderId := strings.TrimSpace(item.ExternalOrderId); orderId != "" {
+		product.ExternalOrderId = &orderId
+	}
+	if cell := strings.TrimSpace(item.Cell); cell != "" {
+		product.CellCode = &cell
+	}
+	return product, nil
+}
+
+func productToAPI(product *models.Product, typeName string) *models.ProductAPI {
+	productAPI := &models.ProductAPI{
+		Id:          product.Id,
+		AddedAt:     product.AddedAt,
+		Type:        typeName,
+		ReceptionId: product.ReceptionId,
+		Duplicate:   product.Duplicate,
+		Condition:   productConditions.name(product.ConditionId),
+		Notes:       product.Notes,
+
+		ProductAttributes: product.ProductAttributes,
+	}
+	if product.Barcode != nil {
+		productAPI.Barcode = *product.Barcode
+	}
+	if product.ExternalOrderId != nil {
+		productAPI.ExternalOrderId = *product.ExternalOrderId
+	}
+	if product.CellCode != nil {
+		productAPI.Cell = *product.CellCode
+	}
+	if product.OverCapacity {
+		productAPI.Warning = models.ErrCapacityExceeded.Error()
+	}
+	return productAPI
+}
+
+func validateAttributes(attrs *models.ProductAttributes, rules *models.ProductTypeRules) error {
+	dimensions := []*int{attrs.LengthMm, attrs.WidthMm, attrs.HeightMm}
+	dimensionsSet := 0
+	for _, side := range dimensions {
+		if side != nil {
+			dimensionsSet++
+		}
+	}
+
+	switch {
+	case attrs.WeightGrams == nil && rules.WeightRequired:
+		return fmt.Errorf("%w: weightGrams is required", models.ErrInvalidAttributes)
+	case dimensionsSet == 0 && rules.DimensionsRequired:
+		return fmt.Errorf("%w: lengthMm, widthMm and heightMm are required", models.ErrInvalidAttributes)
+	case dimensionsSet != 0 && dimensionsSet != len(dimensions):
+		return fmt.Errorf("%w: lengthMm, widthMm and heightMm are set together", models.ErrInvalidAttributes)
+	case attrs.DeclaredValue == nil && rules.DeclaredValueRequired:
+		return fmt.Errorf("%w: declaredValue is required", models.ErrInvalidAttributes)
+	}
+
+	if attrs.WeightGrams != nil {
+		if *attrs.WeightGrams <= 0 {
+			return fmt.Errorf("%w: weightGrams must be positive", models.ErrInvalidAttributes)
+		}
+		if rules.MaxWeightGrams != nil && *attrs.WeightGrams > *rules.MaxWeightGrams {
+			return fmt.Errorf("%w: weightGrams above %d", models.ErrInvalidAttributes, *rules.MaxWeightGrams)
+		}
+	}
+	if dimensionsSet != 0 {
+		for _, side := range dimensions {
+			if *side <= 0 {
+				return fmt.Errorf("%w: dimensions must be positive", models.ErrInvalidAttributes)
+			}
+			if rules.MaxSideMm != nil && *side > *rules.MaxSideMm {
+				return fmt.Errorf("%w: dimension above %d mm", models.ErrInvalidAttributes, *rules.MaxSideMm)
+			}
+		}
+	}
+	if attrs.DeclaredValue != nil {
+		if *attrs.DeclaredValue <= 0 {
+			return fmt.Errorf("%w: declaredValue must be positive", models.ErrInvalidAttributes)
+		}
+		if rules.MaxDeclaredValue != nil && *attrs.DeclaredValue > *rules.MaxDeclaredValue {
+			return fmt.Errorf("%w: declaredValue above %d", models.ErrInvalidAttributes, *rules.MaxDeclaredValue)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package receptionService
+
+import (
+	"orderPickupPoint/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAttributes(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	int64Ptr := func(v int64) *int64 { return &v }
+
+	electronics := models.ProductTypeRules{
+		WeightRequired:        true,
+		DimensionsRequired:    true,
+		DeclaredValueRequired: true,
+		MaxWeightGrams:        intPtr(30000),
+		MaxSideMm:             intPtr(1500),
+		MaxDeclaredValue:      int64Ptr(100000000),
+	}
+	valid := models.ProductAttributes{
+		WeightGrams:   intPtr(1200),
+		LengthMm:      intPtr(300),
+		WidthMm:       intPtr(200),
+		HeightMm:      intPtr(50),
+		DeclaredValue: int64Ptr(4999000),
+	}
+
+	tests := []struct {
+		name    string
+		rules   models.ProductTypeRules
+		attrs   func(attrs *models.ProductAttributes)
+		wantErr bool
+	}{
+		{
+			name:  "all set",
+			rules: electronics,
+		},
+		{
+			name:  "optional attributes omitted",
+			attrs: func(attrs *models.ProductAttributes) { *attrs = models.ProductAttributes{} },
+		},
+		{
+			name:    "required weight missing",
+			rules:   electronics,
+			attrs:   func(attrs *models.ProductAttributes) { attrs.WeightGrams = nil },
+			wantErr: true,
+		},
+		{
+			name:    "required declared value missing",
+			rules:   electronics,
+			attrs:   func(attrs *models.ProductAttributes) { attrs.DeclaredValue = nil },
+			wantErr: true,
+		},
+		{
+			name:    "partial dimensions",
+			attrs:   func(attrs *models.ProductAttributes) { attrs.HeightMm = nil },
+			wantErr: true,
+		},
+		{
+			name:    "weight above limit",
+			rules:   electronics,
+			attrs:   func(attrs *models.ProductAttributes) { attrs.WeightGrams = intPtr(30001) },
+			wantErr: true,
+		},
+		{
+			name:    "side above limit",
+			rules:   electronics,
+			attrs:   func(attrs *models.ProductAttributes) { attrs.LengthMm = intPtr(1501) },
+			wantErr: true,
+		},
+		{
+			name:    "non-positive dimension",
+			attrs:   func(attrs *models.ProductAttributes) { attrs.WidthMm = intPtr(0) },
+			wantErr: true,
+		},
+		{
+			name:    "negative declared value",
+			attrs:   func(attrs *models.ProductAttributes) { attrs.DeclaredValue = int64Ptr(-1) },
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attrs := valid
+			if tt.attrs != nil {
+				tt.attrs(&attrs)
+			}
+
+			err := validateAttributes(&attrs, &tt.rules)
+			if tt.wantErr {
+				require.ErrorIs(t, err, models.ErrInvalidAttributes)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -2,8 +2,13 @@ package receptionService
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"orderPickupPoint/internal/models"
 	"orderPickupPoint/internal/storage"
+	"orderPickupPoint/internal/utils/barcode"
+	"orderPickupPoint/internal/utils/userCtx"
+	"strings"
 
 	"github.com/google/uuid"
 )
@@ -19,7 +24,7 @@ func NewReceptionService(receptionRepo storage.Reception) *ReceptionService {
 }
 
 func (s *ReceptionService) CreateReception(ctx context.Context, pvzId uuid.UUID) (*models.ReceptionAPI, error) {
-	reception, err := s.ReceptionRepo.CreateReception(ctx, pvzId)
+	reception, err := s.ReceptionRepo.CreateReception(ctx, pvzId, userCtx.UserId(ctx))
 	if err != nil {
 		return nil, err
 	}
@@ -38,14 +43,13 @@ func (s *ReceptionService) CreateReception(ctx context.Context, pvzId uuid.UUID)
 }
 
 func (s *ReceptionService) AddProduct(ctx context.Context, productAPI *models.ProductAPI) (*models.ProductAPI, error) {
-	typeId, err := s.ReceptionRepo.GetProductTypeIdByName(ctx, productAPI.Type)
+	productType, err := s.ReceptionRepo.GetProductTypeByName(ctx, productAPI.Type)
 	if err != nil {
 		return nil, err
 	}
-	product := &models.Product{
-		Id:      productAPI.Id,
-		AddedAt: productAPI.AddedAt,
-		TypeId:  typeId,
+	product, err := newProduct(productAPI, productType, userCtx.UserId(ctx))
+	if err != nil {
+		return nil, err
 	}
 
 	product, err = s.ReceptionRepo.AddProductToReception(ctx, product, *productAPI.PvzId)
@@ -53,21 +57,308 @@ func (s *ReceptionService) AddProduct(ctx context.Context, productAPI *models.Pr
 		return nil, err
 	}
 
-	productAPI = &models.ProductAPI{
-		Id:          product.Id,
-		AddedAt:     product.AddedAt,
-		Type:        productAPI.Type,
-		ReceptionId: product.ReceptionId,
+	return productToAPI(product, productType.Name), nil
+}
+
+// upper limit of products in one batch request
+const maxBatchSize = 100
+
+// all product types and barcodes are checked before anything is added. If some items are invalid,
+// nothing is added and the per-item result is returned together with the error of the first one.
+// Items rejected by the reception (a repeated scan, no place in strict mode) are reported per item
+// and the rest is added; the error of the first one is returned only if nothing was added
+func (s *ReceptionService) AddProductsBatch(ctx context.Context, receptionId uuid.UUID, items []models.ProductAPI) (*models.ProductBatchResultAPI, error) {
+	if len(items) == 0 || len(items) > maxBatchSize {
+		return nil, fmt.Errorf("%w: expected 1 to %d products", models.ErrInvalidBatch, maxBatchSize)
+	}
+
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.Type)
+	}
+	productTypes, err := s.ReceptionRepo.GetProductTypesByNames(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.ProductBatchResultAPI{
+		ReceptionId: receptionId,
+		Items:       make([]models.ProductBatchItemAPI, len(items)),
+	}
+	actorId := userCtx.UserId(ctx)
+	products := make([]*models.Product, len(items))
+	var invalid error
+	for i := range items {
+		result.Items[i].Index = i
+		err := models.ErrUnknownProductType
+		if productType, ok := productTypes[items[i].Type]; ok {
+			products[i], err = newProduct(&items[i], &productType, actorId)
+		} else {
+			err = fmt.Errorf("%w: %q", err, items[i].Type)
+		}
+		if err != nil {
+			result.Items[i].Error = err.Error()
+			if invalid == nil {
+				invalid = err
+			}
+		}
+	}
+	if invalid != nil {
+		return result, invalid
+	}
+
+	added, err := s.ReceptionRepo.AddProductsToReception(ctx, receptionId, products, actorId)
+	if err != nil {
+		return nil, err
+	}
+
+	var rejected error
+	for i, item := range added {
+		if item.Err != nil {
+			result.Items[i].Error = item.Err.Error()
+			if rejected == nil {
+				rejected = item.Err
+			}
+			continue
+		}
+		result.Items[i].Product = productToAPI(item.Product, productTypes[items[i].Type].Name)
+		result.Accepted++
+	}
+	if result.Accepted == 0 {
+		return result, rejected
+	}
+	return result, nil
+}
+
+func (s *ReceptionService) FindProductsByBarcode(ctx context.Context, code string) ([]models.ProductLocationAPI, error) {
+	code = strings.TrimSpace(code)
+	if _, err := barcode.Validate(code); err != nil {
+		return nil, err
 	}
-	return productAPI, nil
+	return s.ReceptionRepo.FindProductsByBarcode(ctx, code)
 }
 
 func (s *ReceptionService) DeleteLastProductInReception(ctx context.Context, pvzId uuid.UUID) error {
-	err := s.ReceptionRepo.DeleteLastProductInReception(ctx, pvzId)
+	err := s.ReceptionRepo.DeleteLastProductInReception(ctx, pvzId, userCtx.UserId(ctx))
 	return err
 }
 
-func (s *ReceptionService) CloseReception(ctx context.Context, pvzId uuid.UUID) error {
-	err := s.ReceptionRepo.CloseReception(ctx, pvzId)
-	return err
+func (s *ReceptionService) DeleteProductInReception(ctx context.Context, receptionId uuid.UUID, productId uuid.UUID) error {
+	return s.ReceptionRepo.DeleteProductInReception(ctx, receptionId, productId, userCtx.UserId(ctx))
+}
+
+// closes the reception of the pickup point in progress
+func (s *ReceptionService) CloseReception(ctx context.Context, pvzId uuid.UUID) (*models.ReconciliationAPI, error) {
+	reception, err := s.ReceptionRepo.GetOpenReception(ctx, pvzId)
+	if err != nil {
+		return nil, err
+	}
+	return s.close(ctx, reception)
+}
+
+func (s *ReceptionService) CloseReceptionById(ctx context.Context, receptionId uuid.UUID) (*models.ReconciliationAPI, error) {
+	reception, err := s.ReceptionRepo.GetReception(ctx, receptionId)
+	if err != nil {
+		return nil, err
+	}
+	return s.close(ctx, reception)
+}
+
+func (s *ReceptionService) close(ctx context.Context, reception *models.Reception) (*models.ReconciliationAPI, error) {
+	if err := checkTransition(reception.StatusId, models.ReceptionStatusClosed); err != nil {
+		return nil, err
+	}
+
+	return s.closeReconciled(ctx, &models.ReceptionTransition{
+		ReceptionId:  reception.Id,
+		FromStatusId: reception.StatusId,
+		ToStatusId:   models.ReceptionStatusClosed,
+		ActorId:      userCtx.UserId(ctx),
+	})
+}
+
+// a reception with a manifest is reconciled in the closing transaction and is not closed if the report
+// is blocking, the report is returned with ErrDiscrepancyExceeded then. The report is nil for receptions
+// without a manifest
+func (s *ReceptionService) closeReconciled(ctx context.Context, transition *models.ReceptionTransition) (*models.ReconciliationAPI, error) {
+	var report *models.ReconciliationAPI
+	err := s.ReceptionRepo.CloseReception(ctx, transition, func(manifest *models.ManifestAPI, products []models.ProductInfo) error {
+		report = reconcile(manifest, products)
+		if report.Blocking {
+			return fmt.Errorf("%w: %d discrepancies, %d allowed", models.ErrDiscrepancyExceeded, report.Discrepancies, *report.MaxDiscrepancy)
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, models.ErrDiscrepancyExceeded) {
+		return nil, err
+	}
+	return report, err
+}
+
+func (s *ReceptionService) VerifyReception(ctx context.Context, receptionId uuid.UUID) error {
+	reception, err := s.ReceptionRepo.GetReception(ctx, receptionId)
+	if err != nil {
+		return err
+	}
+	return s.changeStatus(ctx, reception, models.ReceptionStatusVerified, "")
+}
+
+// discards a reception opened by mistake together with its products
+func (s *ReceptionService) CancelReception(ctx context.Context, receptionId uuid.UUID, reason string) error {
+	reception, err := s.ReceptionRepo.GetReception(ctx, receptionId)
+	if err != nil {
+		return err
+	}
+	if err := checkTransition(reception.StatusId, models.ReceptionStatusCancelled); err != nil {
+		return err
+	}
+
+	return s.ReceptionRepo.CancelReception(ctx, &models.ReceptionTransition{
+		ReceptionId:  reception.Id,
+		FromStatusId: reception.StatusId,
+		ToStatusId:   models.ReceptionStatusCancelled,
+		ActorId:      userCtx.UserId(ctx),
+		Comment:      reason,
+	})
+}
+
+func (s *ReceptionService) GetReceptionHistory(ctx context.Context, receptionId uuid.UUID) ([]models.ReceptionHistoryItem, error) {
+	// distinguishes unknown reception from empty history
+	if _, err := s.ReceptionRepo.GetReception(ctx, receptionId); err != nil {
+		return nil, err
+	}
+	return s.ReceptionRepo.GetReceptionHistory(ctx, receptionId)
+}
+
+// employee asks to reopen a closed reception, a moderator resolves the request later
+func (s *ReceptionService) RequestReopen(ctx context.Context, receptionId uuid.UUID, reason string) (*models.ReopenRequestAPI, error) {
+	reception, err := s.ReceptionRepo.GetReception(ctx, receptionId)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTransition(reception.StatusId, models.ReceptionStatusInProgress); err != nil {
+		return nil, err
+	}
+
+	actorId := userCtx.UserId(ctx)
+	request, err := s.ReceptionRepo.CreateReopenRequest(ctx, &models.ReopenRequest{
+		ReceptionId: receptionId,
+		Reason:      reason,
+		RequestedBy: actorId,
+	}, &models.ReceptionTransition{
+		ReceptionId:  receptionId,
+		FromStatusId: reception.StatusId,
+		ToStatusId:   reception.StatusId,
+		ActorId:      actorId,
+		Comment:      "reopen requested: " + reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ReopenRequestAPI{
+		Id:          request.Id,
+		ReceptionId: request.ReceptionId,
+		Status:      "pending",
+		Reason:      request.Reason,
+		RequestedAt: request.RequestedAt,
+		RequestedBy: request.RequestedBy,
+	}, nil
+}
+
+// on approval the reception goes back to in_progress, on rejection it stays closed
+func (s *ReceptionService) ResolveReopen(ctx context.Context, requestId uuid.UUID, approve bool, comment string) (*models.ReopenRequestAPI, error) {
+	request, err := s.ReceptionRepo.GetReopenRequest(ctx, requestId)
+	if err != nil {
+		return nil, err
+	}
+	reception, err := s.ReceptionRepo.GetReception(ctx, request.ReceptionId)
+	if err != nil {
+		return nil, err
+	}
+
+	actorId := userCtx.UserId(ctx)
+	resolution := &models.ReopenResolution{
+		RequestId: requestId,
+		StatusId:  models.ReopenRequestRejected,
+		ActorId:   actorId,
+		Comment:   comment,
+	}
+	transition := &models.ReceptionTransition{
+		ReceptionId:  reception.Id,
+		FromStatusId: reception.StatusId,
+		ToStatusId:   reception.StatusId,
+		ActorId:      actorId,
+		Comment:      "reopen rejected",
+	}
+	if approve {
+		if err := checkTransition(reception.StatusId, models.ReceptionStatusInProgress); err != nil {
+			return nil, err
+		}
+		resolution.StatusId = models.ReopenRequestApproved
+		transition.ToStatusId = models.ReceptionStatusInProgress
+		transition.Comment = "reopen approved"
+	}
+	if comment != "" {
+		transition.Comment += ": " + comment
+	}
+
+	err = s.ReceptionRepo.ResolveReopenRequest(ctx, resolution, transition)
+	if err != nil {
+		return nil, err
+	}
+	return s.ReceptionRepo.GetReopenRequest(ctx, requestId)
+}
+
+func (s *ReceptionService) ListReopenRequests(ctx context.Context, receptionId *uuid.UUID, pendingOnly bool) ([]models.ReopenRequestAPI, error) {
+	var statusId *int
+	if pendingOnly {
+		pending := models.ReopenRequestPending
+		statusId = &pending
+	}
+	return s.ReceptionRepo.ListReopenRequests(ctx, receptionId, statusId)
+}
+
+func (s *ReceptionService) changeStatus(ctx context.Context, reception *models.Reception, toStatusId int, comment string) error {
+	if err := checkTransition(reception.StatusId, toStatusId); err != nil {
+		return err
+	}
+
+	return s.ReceptionRepo.ChangeReceptionStatus(ctx, &models.ReceptionTransition{
+		ReceptionId:  reception.Id,
+		FromStatusId: reception.StatusId,
+		ToStatusId:   toStatusId,
+		ActorId:      userCtx.UserId(ctx),
+		Comment:      comment,
+	})
+}
+
+func (s *ReceptionService) GetReception(ctx context.Context, receptionId uuid.UUID) (*models.ReceptionDetailsAPI, error) {
+	reception, err := s.ReceptionRepo.GetReceptionById(ctx, receptionId)
+	if err != nil {
+		return nil, err
+	}
+
+	products, err := s.ReceptionRepo.GetReceptionProducts(ctx, receptionId)
+	if err != nil {
+		return nil, err
+	}
+
+	reception.Products = []models.ProductInfo{}
+	for _, product := range products {
+		if product.DeletedAt != nil {
+			reception.DeletedProducts = append(reception.DeletedProducts, product)
+			continue
+		}
+		reception.Products = append(reception.Products, product)
+	}
+	return reception, nil
+}
+
+func (s *ReceptionService) ListReceptions(ctx context.Context, filter *models.ReceptionFilter) (*models.Page[models.ReceptionDetailsAPI], error) {
+	receptions, err := s.ReceptionRepo.ListReceptions(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewPage(receptions, filter.Cursor, filter.Page, filter.PageLimit), nil
 }
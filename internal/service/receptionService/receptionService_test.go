@@ -3,8 +3,11 @@ package receptionService
 import (
 	"context"
 	"errors"
+	"fmt"
 	"orderPickupPoint/internal/models"
 	"orderPickupPoint/internal/storage"
+	"orderPickupPoint/internal/utils/userCtx"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,13 +21,30 @@ type MockReceptionRepo struct {
 	storage.Reception
 }
 
-func (m *MockReceptionRepo) CloseReception(ctx context.Context, pvzId uuid.UUID) error {
+func (m *MockReceptionRepo) GetOpenReception(ctx context.Context, pvzId uuid.UUID) (*models.Reception, error) {
 	args := m.Called(ctx, pvzId)
+	return args.Get(0).(*models.Reception), args.Error(1)
+}
+
+func (m *MockReceptionRepo) ChangeReceptionStatus(ctx context.Context, transition *models.ReceptionTransition) error {
+	args := m.Called(ctx, transition)
 	return args.Error(0)
 }
 
-func (m *MockReceptionRepo) CreateReception(ctx context.Context, pvzId uuid.UUID) (*models.Reception, error) {
-	args := m.Called(ctx, pvzId)
+// returns the manifest and products to check as the second and third values, check is not called without a manifest
+func (m *MockReceptionRepo) CloseReception(ctx context.Context, transition *models.ReceptionTransition,
+	check func(manifest *models.ManifestAPI, products []models.ProductInfo) error) error {
+	args := m.Called(ctx, transition)
+	if manifest, _ := args.Get(1).(*models.ManifestAPI); manifest != nil {
+		if err := check(manifest, args.Get(2).([]models.ProductInfo)); err != nil {
+			return err
+		}
+	}
+	return args.Error(0)
+}
+
+func (m *MockReceptionRepo) CreateReception(ctx context.Context, pvzId uuid.UUID, actorId *int) (*models.Reception, error) {
+	args := m.Called(ctx, pvzId, actorId)
 	return args.Get(0).(*models.Reception), args.Error(1)
 }
 
@@ -33,14 +53,20 @@ func (m *MockReceptionRepo) GetStatusNameById(ctx context.Context, id int) (stri
 	return args.Get(0).(string), args.Error(1)
 }
 
-func (m *MockReceptionRepo) DeleteLastProductInReception(ctx context.Context, pvzId uuid.UUID) error {
-	args := m.Called(ctx, pvzId)
+func (m *MockReceptionRepo) DeleteLastProductInReception(ctx context.Context, pvzId uuid.UUID, actorId *int) error {
+	args := m.Called(ctx, pvzId, actorId)
 	return args.Error(0)
 }
 
-func (m *MockReceptionRepo) GetProductTypeIdByName(ctx context.Context, name string) (int, error) {
+func actorIs(id int) interface{} {
+	return mock.MatchedBy(func(actorId *int) bool {
+		return actorId != nil && *actorId == id
+	})
+}
+
+func (m *MockReceptionRepo) GetProductTypeByName(ctx context.Context, name string) (*models.ProductType, error) {
 	args := m.Called(ctx, name)
-	return args.Int(0), args.Error(1)
+	return args.Get(0).(*models.ProductType), args.Error(1)
 }
 
 func (m *MockReceptionRepo) AddProductToReception(ctx context.Context, product *models.Product, pvzId uuid.UUID) (*models.Product, error) {
@@ -69,7 +95,7 @@ func TestAddProduct_Success(t *testing.T) {
 		PvzId: &pvzId,
 	}
 
-	mockRepo.On("GetProductTypeIdByName", ctx, productType).Return(typeId, nil)
+	mockRepo.On("GetProductTypeByName", ctx, productType).Return(&models.ProductType{Id: typeId, Name: productType, Active: true}, nil)
 	mockRepo.On("AddProductToReception", ctx, mock.AnythingOfType("*models.Product"), pvzId).Return(expectedProduct, nil)
 
 	result, err := service.AddProduct(ctx, productAPI)
@@ -83,40 +109,116 @@ func TestAddProduct_Success(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestAddProduct_OverCapacityWarning(t *testing.T) {
+	ctx := context.Background()
+
+	mockRepo := new(MockReceptionRepo)
+	service := NewReceptionService(mockRepo)
+
+	pvzId := uuid.New()
+	productAPI := &models.ProductAPI{
+		Type:  "обувь",
+		PvzId: &pvzId,
+	}
+
+	mockRepo.On("GetProductTypeByName", ctx, productAPI.Type).Return(&models.ProductType{Id: 1, Name: productAPI.Type, Active: true}, nil)
+	mockRepo.On("AddProductToReception", ctx, mock.AnythingOfType("*models.Product"), pvzId).Return(&models.Product{TypeId: 1, OverCapacity: true}, nil)
+
+	result, err := service.AddProduct(ctx, productAPI)
+
+	require.NoError(t, err)
+	require.Equal(t, models.ErrCapacityExceeded.Error(), result.Warning)
+}
+
 func TestCloseReception(t *testing.T) {
+	dbErr := errors.New("Bad request")
 	tests := []struct {
 		name      string
-		arg       uuid.UUID
+		openError error
 		mockError error
+		wantError error
 	}{
 		{
-			name:      "valid test",
-			arg:       uuid.New(),
-			mockError: nil,
+			name: "valid test",
 		},
 		{
 			name:      "invalid test",
-			arg:       uuid.New(),
-			mockError: errors.New("Bad request"),
+			mockError: dbErr,
+			wantError: dbErr,
+		},
+		{
+			name:      "no reception in progress",
+			openError: models.ErrReceptionNotOpen,
+			wantError: models.ErrReceptionNotOpen,
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ctx := context.Background()
+			ctx := userCtx.WithUser(context.Background(), &models.User{Id: 5})
 
 			mockRepo := new(MockReceptionRepo)
 			service := NewReceptionService(mockRepo)
 
-			mockRepo.On("CloseReception", ctx, tt.arg).Return(tt.mockError)
+			pvzId := uuid.New()
+			reception := &models.Reception{Id: uuid.New(), PickupPointId: pvzId, StatusId: models.ReceptionStatusInProgress}
+			open := reception
+			if tt.openError != nil {
+				open = nil
+			}
 
-			err := service.CloseReception(ctx, tt.arg)
+			mockRepo.On("GetOpenReception", ctx, pvzId).Return(open, tt.openError)
+			mockRepo.On("CloseReception", ctx, mock.MatchedBy(func(tr *models.ReceptionTransition) bool {
+				return tr.ReceptionId == reception.Id &&
+					tr.FromStatusId == models.ReceptionStatusInProgress &&
+					tr.ToStatusId == models.ReceptionStatusClosed &&
+					tr.ActorId != nil && *tr.ActorId == 5
+			})).Return(tt.mockError, nil, nil).Maybe()
 
-			require.Equal(t, tt.mockError, err)
+			report, err := service.CloseReception(ctx, pvzId)
+			require.Nil(t, report)
+
+			require.ErrorIs(t, err, tt.wantError)
+			mockRepo.AssertExpectations(t)
 		})
 	}
 }
 
+// a reopened reception is closed by pvz even when a newer reception of the pvz exists
+func TestCloseReception_AfterReopen(t *testing.T) {
+	ctx := userCtx.WithUser(context.Background(), &models.User{Id: 1})
+
+	mockRepo := new(MockReceptionRepo)
+	service := NewReceptionService(mockRepo)
+
+	pvzId := uuid.New()
+	requestId := uuid.New()
+	reopened := &models.Reception{Id: uuid.New(), PickupPointId: pvzId, StatusId: models.ReceptionStatusClosed}
+
+	mockRepo.On("GetReopenRequest", ctx, requestId).Return(&models.ReopenRequestAPI{Id: requestId, ReceptionId: reopened.Id, Status: "pending"}, nil)
+	mockRepo.On("GetReception", ctx, reopened.Id).Return(reopened, nil)
+	mockRepo.On("ResolveReopenRequest", ctx, mock.Anything, mock.MatchedBy(func(tr *models.ReceptionTransition) bool {
+		return tr.ReceptionId == reopened.Id && tr.ToStatusId == models.ReceptionStatusInProgress
+	})).Return(nil).Run(func(mock.Arguments) {
+		reopened.StatusId = models.ReceptionStatusInProgress
+	})
+
+	_, err := service.ResolveReopen(ctx, requestId, true, "")
+	require.NoError(t, err)
+
+	mockRepo.On("GetOpenReception", ctx, pvzId).Return(reopened, nil)
+	mockRepo.On("CloseReception", ctx, mock.MatchedBy(func(tr *models.ReceptionTransition) bool {
+		return tr.ReceptionId == reopened.Id &&
+			tr.FromStatusId == models.ReceptionStatusInProgress &&
+			tr.ToStatusId == models.ReceptionStatusClosed
+	})).Return(nil, nil, nil)
+
+	_, err = service.CloseReception(ctx, pvzId)
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestDeleteLastProductInReception(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -137,12 +239,12 @@ func TestDeleteLastProductInReception(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			ctx := context.Background()
+			ctx := userCtx.WithUser(context.Background(), &models.User{Id: 5})
 
 			mockRepo := new(MockReceptionRepo)
 			service := NewReceptionService(mockRepo)
 
-			mockRepo.On("DeleteLastProductInReception", ctx, tt.arg).Return(tt.mockError)
+			mockRepo.On("DeleteLastProductInReception", ctx, tt.arg, actorIs(5)).Return(tt.mockError)
 
 			err := service.DeleteLastProductInReception(ctx, tt.arg)
 
@@ -173,7 +275,7 @@ func TestCreateReception(t *testing.T) {
 			mockRepo := new(MockReceptionRepo)
 			service := NewReceptionService(mockRepo)
 
-			mockRepo.On("CreateReception", ctx, mock.Anything).Return(&models.Reception{}, tt.mockError)
+			mockRepo.On("CreateReception", ctx, mock.Anything, mock.Anything).Return(&models.Reception{}, tt.mockError)
 			mockRepo.On("GetStatusNameById", ctx, mock.Anything).Return(tt.mockReturn.Status, tt.mockError)
 
 			out, err := service.CreateReception(ctx, tt.mockReturn.PickupPointId)
@@ -186,3 +288,653 @@ func TestCreateReception(t *testing.T) {
 		})
 	}
 }
+
+func (m *MockReceptionRepo) GetReceptionById(ctx context.Context, receptionId uuid.UUID) (*models.ReceptionDetailsAPI, error) {
+	args := m.Called(ctx, receptionId)
+	return args.Get(0).(*models.ReceptionDetailsAPI), args.Error(1)
+}
+
+func (m *MockReceptionRepo) GetReceptionProducts(ctx context.Context, receptionId uuid.UUID) ([]models.ProductInfo, error) {
+	args := m.Called(ctx, receptionId)
+	return args.Get(0).([]models.ProductInfo), args.Error(1)
+}
+
+func TestGetReception(t *testing.T) {
+	deletedAt := time.Now()
+	deletedBy := 5
+	tests := []struct {
+		name         string
+		arg          uuid.UUID
+		products     []models.ProductInfo
+		mockError    error
+		wantProducts int
+		wantDeleted  int
+	}{
+		{
+			name:         "valid test",
+			arg:          uuid.New(),
+			products:     []models.ProductInfo{{ID: uuid.New(), Type: "обувь"}},
+			mockError:    nil,
+			wantProducts: 1,
+		},
+		{
+			name: "deleted products are separated",
+			arg:  uuid.New(),
+			products: []models.ProductInfo{
+				{ID: uuid.New(), Type: "обувь"},
+				{ID: uuid.New(), Type: "одежда", DeletedAt: &deletedAt, DeletedBy: &deletedBy},
+			},
+			wantProducts: 1,
+			wantDeleted:  1,
+		},
+		{
+			name:      "not found",
+			arg:       uuid.New(),
+			mockError: models.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			mockRepo := new(MockReceptionRepo)
+			service := NewReceptionService(mockRepo)
+
+			mockRepo.On("GetReceptionById", ctx, tt.arg).Return(&models.ReceptionDetailsAPI{Id: tt.arg}, tt.mockError)
+			mockRepo.On("GetReceptionProducts", ctx, tt.arg).Return(tt.products, nil)
+
+			out, err := service.GetReception(ctx, tt.arg)
+			require.Equal(t, tt.mockError, err)
+			if tt.mockError == nil {
+				require.Equal(t, tt.arg, out.Id)
+				require.Equal(t, tt.wantProducts, len(out.Products))
+				require.Equal(t, tt.wantDeleted, len(out.DeletedProducts))
+				mockRepo.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func (m *MockReceptionRepo) GetReception(ctx context.Context, receptionId uuid.UUID) (*models.Reception, error) {
+	args := m.Called(ctx, receptionId)
+	return args.Get(0).(*models.Reception), args.Error(1)
+}
+
+func (m *MockReceptionRepo) CreateReopenRequest(ctx context.Context, request *models.ReopenRequest, transition *models.ReceptionTransition) (*models.ReopenRequest, error) {
+	args := m.Called(ctx, request, transition)
+	return args.Get(0).(*models.ReopenRequest), args.Error(1)
+}
+
+func (m *MockReceptionRepo) ResolveReopenRequest(ctx context.Context, resolution *models.ReopenResolution, transition *models.ReceptionTransition) error {
+	args := m.Called(ctx, resolution, transition)
+	return args.Error(0)
+}
+
+func (m *MockReceptionRepo) GetReopenRequest(ctx context.Context, requestId uuid.UUID) (*models.ReopenRequestAPI, error) {
+	args := m.Called(ctx, requestId)
+	return args.Get(0).(*models.ReopenRequestAPI), args.Error(1)
+}
+
+func TestRequestReopen(t *testing.T) {
+	tests := []struct {
+		name      string
+		statusId  int
+		wantError error
+	}{
+		{
+			name:     "closed reception",
+			statusId: models.ReceptionStatusClosed,
+		},
+		{
+			name:      "reception in progress",
+			statusId:  models.ReceptionStatusInProgress,
+			wantError: models.ErrIllegalTransition,
+		},
+		{
+			name:      "verified reception",
+			statusId:  models.ReceptionStatusVerified,
+			wantError: models.ErrIllegalTransition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := userCtx.WithUser(context.Background(), &models.User{Id: 7})
+
+			mockRepo := new(MockReceptionRepo)
+			service := NewReceptionService(mockRepo)
+
+			receptionId := uuid.New()
+			mockRepo.On("GetReception", ctx, receptionId).Return(&models.Reception{Id: receptionId, StatusId: tt.statusId}, nil)
+			if tt.wantError == nil {
+				mockRepo.On("CreateReopenRequest", ctx,
+					mock.MatchedBy(func(r *models.ReopenRequest) bool {
+						return r.Reason == "wrong type" && *r.RequestedBy == 7
+					}),
+					mock.MatchedBy(func(tr *models.ReceptionTransition) bool {
+						return tr.FromStatusId == models.ReceptionStatusClosed && tr.ToStatusId == models.ReceptionStatusClosed &&
+							tr.Comment == "reopen requested: wrong type"
+					})).Return(&models.ReopenRequest{Id: uuid.New(), ReceptionId: receptionId, Reason: "wrong type"}, nil)
+			}
+
+			res, err := service.RequestReopen(ctx, receptionId, "wrong type")
+
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantError == nil {
+				require.Equal(t, "pending", res.Status)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestResolveReopen(t *testing.T) {
+	tests := []struct {
+		name        string
+		approve     bool
+		comment     string
+		wantStatus  int
+		wantTo      int
+		wantComment string
+	}{
+		{
+			name:        "approve",
+			approve:     true,
+			wantStatus:  models.ReopenRequestApproved,
+			wantTo:      models.ReceptionStatusInProgress,
+			wantComment: "reopen approved",
+		},
+		{
+			name:        "reject with comment",
+			comment:     "already verified by phone",
+			wantStatus:  models.ReopenRequestRejected,
+			wantTo:      models.ReceptionStatusClosed,
+			wantComment: "reopen rejected: already verified by phone",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := userCtx.WithUser(context.Background(), &models.User{Id: 1})
+
+			mockRepo := new(MockReceptionRepo)
+			service := NewReceptionService(mockRepo)
+
+			requestId := uuid.New()
+			receptionId := uuid.New()
+			mockRepo.On("GetReopenRequest", ctx, requestId).Return(&models.ReopenRequestAPI{Id: requestId, ReceptionId: receptionId, Status: "pending"}, nil)
+			mockRepo.On("GetReception", ctx, receptionId).Return(&models.Reception{Id: receptionId, StatusId: models.ReceptionStatusClosed}, nil)
+			mockRepo.On("ResolveReopenRequest", ctx,
+				mock.MatchedBy(func(r *models.ReopenResolution) bool {
+					return r.RequestId == requestId && r.StatusId == tt.wantStatus && r.Comment == tt.comment
+				}),
+				mock.MatchedBy(func(tr *models.ReceptionTransition) bool {
+					return tr.ReceptionId == receptionId && tr.FromStatusId == models.ReceptionStatusClosed &&
+						tr.ToStatusId == tt.wantTo && tr.Comment == tt.wantComment
+				})).Return(nil)
+
+			_, err := service.ResolveReopen(ctx, requestId, tt.approve, tt.comment)
+
+			require.NoError(t, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func (m *MockReceptionRepo) CancelReception(ctx context.Context, transition *models.ReceptionTransition) error {
+	args := m.Called(ctx, transition)
+	return args.Error(0)
+}
+
+func TestCancelReception(t *testing.T) {
+	tests := []struct {
+		name      string
+		statusId  int
+		wantError error
+	}{
+		{
+			name:     "open reception",
+			statusId: models.ReceptionStatusInProgress,
+		},
+		{
+			name:      "closed reception",
+			statusId:  models.ReceptionStatusClosed,
+			wantError: models.ErrIllegalTransition,
+		},
+		{
+			name:      "already cancelled",
+			statusId:  models.ReceptionStatusCancelled,
+			wantError: models.ErrIllegalTransition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := userCtx.WithUser(context.Background(), &models.User{Id: 3})
+
+			mockRepo := new(MockReceptionRepo)
+			service := NewReceptionService(mockRepo)
+
+			receptionId := uuid.New()
+			mockRepo.On("GetReception", ctx, receptionId).Return(&models.Reception{Id: receptionId, StatusId: tt.statusId}, nil)
+			if tt.wantError == nil {
+				mockRepo.On("CancelReception", ctx, mock.MatchedBy(func(tr *models.ReceptionTransition) bool {
+					return tr.ReceptionId == receptionId && tr.ToStatusId == models.ReceptionStatusCancelled &&
+						*tr.ActorId == 3 && tr.Comment == "opened by mistake"
+				})).Return(nil)
+			}
+
+			err := service.CancelReception(ctx, receptionId, "opened by mistake")
+
+			require.ErrorIs(t, err, tt.wantError)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func (m *MockReceptionRepo) GetProductTypesByNames(ctx context.Context, names []string) (map[string]models.ProductType, error) {
+	args := m.Called(ctx, names)
+	return args.Get(0).(map[string]models.ProductType), args.Error(1)
+}
+
+func (m *MockReceptionRepo) AddProductsToReception(ctx context.Context, receptionId uuid.UUID, products []*models.Product, actorId *int) ([]models.ProductBatchItem, error) {
+	args := m.Called(ctx, receptionId, products, actorId)
+	return args.Get(0).([]models.ProductBatchItem), args.Error(1)
+}
+
+func TestAddProductsBatch(t *testing.T) {
+	ctx := userCtx.WithUser(context.Background(), &models.User{Id: 2})
+	types := map[string]models.ProductType{
+		"обувь":       {Id: 1, Name: "обувь", Active: true},
+		"электроника": {Id: 3, Name: "электроника", Active: true},
+	}
+
+	t.Run("accepted in input order", func(t *testing.T) {
+		mockRepo := new(MockReceptionRepo)
+		service := NewReceptionService(mockRepo)
+
+		receptionId := uuid.New()
+		items := []models.ProductAPI{{Type: "электроника"}, {Type: "обувь"}}
+		added := []models.ProductBatchItem{
+			{Product: &models.Product{Id: uuid.New(), TypeId: 3, ReceptionId: receptionId}},
+			{Product: &models.Product{Id: uuid.New(), TypeId: 1, ReceptionId: receptionId, OverCapacity: true}},
+		}
+
+		mockRepo.On("GetProductTypesByNames", ctx, []string{"электроника", "обувь"}).Return(types, nil)
+		mockRepo.On("AddProductsToReception", ctx, receptionId, mock.MatchedBy(func(products []*models.Product) bool {
+			return len(products) == 2 && products[0].TypeId == 3 && products[1].TypeId == 1
+		}), actorIs(2)).Return(added, nil)
+
+		result, err := service.AddProductsBatch(ctx, receptionId, items)
+
+		require.NoError(t, err)
+		require.Equal(t, 2, result.Accepted)
+		require.Equal(t, added[0].Product.Id, result.Items[0].Product.Id)
+		require.Equal(t, "электроника", result.Items[0].Product.Type)
+		require.Empty(t, result.Items[0].Product.Warning)
+		require.Equal(t, models.ErrCapacityExceeded.Error(), result.Items[1].Product.Warning)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("rejected items are reported alone", func(t *testing.T) {
+		mockRepo := new(MockReceptionRepo)
+		service := NewReceptionService(mockRepo)
+
+		receptionId := uuid.New()
+		items := []models.ProductAPI{{Type: "обувь", Barcode: "4006381333931"}, {Type: "обувь"}}
+		added := []models.ProductBatchItem{
+			{Err: fmt.Errorf("%w: 4006381333931", models.ErrDuplicateBarcode)},
+			{Product: &models.Product{Id: uuid.New(), TypeId: 1, ReceptionId: receptionId}},
+		}
+
+		mockRepo.On("GetProductTypesByNames", ctx, []string{"обувь", "обувь"}).Return(types, nil)
+		mockRepo.On("AddProductsToReception", ctx, receptionId, mock.Anything, actorIs(2)).Return(added, nil)
+
+		result, err := service.AddProductsBatch(ctx, receptionId, items)
+
+		require.NoError(t, err)
+		require.Equal(t, 1, result.Accepted)
+		require.Nil(t, result.Items[0].Product)
+		require.Contains(t, result.Items[0].Error, "4006381333931")
+		require.Equal(t, added[1].Product.Id, result.Items[1].Product.Id)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("nothing accepted", func(t *testing.T) {
+		mockRepo := new(MockReceptionRepo)
+		service := NewReceptionService(mockRepo)
+
+		receptionId := uuid.New()
+		mockRepo.On("GetProductTypesByNames", ctx, []string{"обувь"}).Return(types, nil)
+		mockRepo.On("AddProductsToReception", ctx, receptionId, mock.Anything, actorIs(2)).
+			Return([]models.ProductBatchItem{{Err: models.ErrCapacityExceeded}}, nil)
+
+		result, err := service.AddProductsBatch(ctx, receptionId, []models.ProductAPI{{Type: "обувь"}})
+
+		require.ErrorIs(t, err, models.ErrCapacityExceeded)
+		require.Equal(t, 0, result.Accepted)
+		require.Equal(t, models.ErrCapacityExceeded.Error(), result.Items[0].Error)
+	})
+
+	t.Run("unknown type rejects the whole batch", func(t *testing.T) {
+		mockRepo := new(MockReceptionRepo)
+		service := NewReceptionService(mockRepo)
+
+		items := []models.ProductAPI{{Type: "обувь"}, {Type: "мебель"}}
+		mockRepo.On("GetProductTypesByNames", ctx, []string{"обувь", "мебель"}).Return(types, nil)
+
+		result, err := service.AddProductsBatch(ctx, uuid.New(), items)
+
+		require.ErrorIs(t, err, models.ErrUnknownProductType)
+		require.Equal(t, 0, result.Accepted)
+		require.Empty(t, result.Items[0].Error)
+		require.Contains(t, result.Items[1].Error, "мебель")
+		mockRepo.AssertNotCalled(t, "AddProductsToReception", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("batch size", func(t *testing.T) {
+		service := NewReceptionService(new(MockReceptionRepo))
+
+		_, err := service.AddProductsBatch(ctx, uuid.New(), nil)
+		require.ErrorIs(t, err, models.ErrInvalidBatch)
+
+		_, err = service.AddProductsBatch(ctx, uuid.New(), make([]models.ProductAPI, maxBatchSize+1))
+		require.ErrorIs(t, err, models.ErrInvalidBatch)
+	})
+}
+
+func TestAddProduct_Barcode(t *testing.T) {
+	tests := []struct {
+		name      string
+		barcode   string
+		wantError error
+	}{
+		{
+			name:    "ean13",
+			barcode: " 4006381333931 ",
+		},
+		{
+			name:    "printable",
+			barcode: "WB-100500",
+		},
+		{
+			name:      "bad ean13 check digit",
+			barcode:   "4006381333932",
+			wantError: models.ErrInvalidBarcode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			mockRepo := new(MockReceptionRepo)
+			service := NewReceptionService(mockRepo)
+
+			pvzId := uuid.New()
+			productAPI := &models.ProductAPI{
+				Type:            "обувь",
+				PvzId:           &pvzId,
+				Barcode:         tt.barcode,
+				ExternalOrderId: "order-1",
+			}
+
+			mockRepo.On("GetProductTypeByName", ctx, productAPI.Type).Return(&models.ProductType{Id: 1, Name: productAPI.Type, Active: true}, nil)
+			code := strings.TrimSpace(tt.barcode)
+			if tt.wantError == nil {
+				mockRepo.On("AddProductToReception", ctx, mock.MatchedBy(func(p *models.Product) bool {
+					return *p.Barcode == code && *p.ExternalOrderId == "order-1"
+				}), pvzId).Return(&models.Product{TypeId: 1, Barcode: &code}, nil)
+			}
+
+			result, err := service.AddProduct(ctx, productAPI)
+
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantError == nil {
+				require.Equal(t, code, result.Barcode)
+			}
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func (m *MockReceptionRepo) GetManifest(ctx context.Context, receptionId uuid.UUID) (*models.ManifestAPI, error) {
+	args := m.Called(ctx, receptionId)
+	return args.Get(0).(*models.ManifestAPI), args.Error(1)
+}
+
+func TestCloseReception_Manifest(t *testing.T) {
+	code := "4006381333931"
+	tests := []struct {
+		name           string
+		maxDiscrepancy *int
+		wantError      error
+	}{
+		{
+			name: "no threshold",
+		},
+		{
+			name:           "within threshold",
+			maxDiscrepancy: func() *int { v := 1; return &v }(),
+		},
+		{
+			name:           "above threshold",
+			maxDiscrepancy: func() *int { v := 0; return &v }(),
+			wantError:      models.ErrDiscrepancyExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			mockRepo := new(MockReceptionRepo)
+			service := NewReceptionService(mockRepo)
+
+			reception := &models.Reception{Id: uuid.New(), StatusId: models.ReceptionStatusInProgress}
+			manifest := &models.ManifestAPI{
+				ReceptionId:    reception.Id,
+				MaxDiscrepancy: tt.maxDiscrepancy,
+				Items:          []models.ManifestItemAPI{{Barcode: code, Type: "обувь", Quantity: 2}},
+			}
+			products := []models.ProductInfo{{Type: "обувь", Barcode: &code}}
+
+			mockRepo.On("GetReception", ctx, reception.Id).Return(reception, nil)
+			mockRepo.On("CloseReception", ctx, mock.AnythingOfType("*models.ReceptionTransition")).Return(nil, manifest, products)
+
+			report, err := service.CloseReceptionById(ctx, reception.Id)
+
+			require.ErrorIs(t, err, tt.wantError)
+			require.Equal(t, 1, report.Discrepancies)
+			require.Equal(t, tt.wantError != nil, report.Blocking)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSetManifest_Validation(t *testing.T) {
+	tests := []struct {
+		name      string
+		items     []models.ManifestItemAPI
+		wantError error
+	}{
+		{
+			name:      "no items",
+			wantError: models.ErrInvalidManifest,
+		},
+		{
+			name:      "zero quantity",
+			items:     []models.ManifestItemAPI{{Type: "обувь"}},
+			wantError: models.ErrInvalidManifest,
+		},
+		{
+			name:      "neither barcode nor type",
+			items:     []models.ManifestItemAPI{{Quantity: 1}},
+			wantError: models.ErrInvalidManifest,
+		},
+		{
+			name:      "unknown type",
+			items:     []models.ManifestItemAPI{{Type: "мебель", Quantity: 1}},
+			wantError: models.ErrUnknownProductType,
+		},
+		{
+			name:      "bad barcode",
+			items:     []models.ManifestItemAPI{{Barcode: "4006381333932", Quantity: 1}},
+			wantError: models.ErrInvalidBarcode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			mockRepo := new(MockReceptionRepo)
+			service := NewReceptionService(mockRepo)
+
+			mockRepo.On("GetProductTypesByNames", ctx, mock.Anything).Return(map[string]models.ProductType{"обувь": {Id: 1, Name: "обувь"}}, nil).Maybe()
+
+			_, err := service.SetManifest(ctx, uuid.New(), &models.ManifestAPI{Items: tt.items})
+
+			require.ErrorIs(t, err, tt.wantError)
+		})
+	}
+}
+
+func (m *MockReceptionRepo) ListStaleReceptions(ctx context.Context, openedBefore *time.Time, idleBefore *time.Time) ([]models.StaleReception, error) {
+	args := m.Called(ctx, openedBefore, idleBefore)
+	return args.Get(0).([]models.StaleReception), args.Error(1)
+}
+
+func (m *MockReceptionRepo) FlagStaleReception(ctx context.Context, event *models.ReceptionEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func TestHandleStaleReceptions(t *testing.T) {
+	systemActor := func(toStatusId int, comment string) interface{} {
+		return mock.MatchedBy(func(transition *models.ReceptionTransition) bool {
+			return transition.ActorId != nil && *transition.ActorId == models.SystemActorId &&
+				transition.ToStatusId == toStatusId && strings.HasPrefix(transition.Comment, comment)
+		})
+	}
+	flaggedBySystem := func(comment string) interface{} {
+		return mock.MatchedBy(func(event *models.ReceptionEvent) bool {
+			return event.ActorId != nil && *event.ActorId == models.SystemActorId &&
+				event.Event == models.ReceptionEventFlaggedStale && strings.HasPrefix(event.Comment, comment)
+		})
+	}
+	flaggedAt := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name        string
+		action      string
+		blocking    bool
+		staleSince  *time.Time
+		wantResult  models.StaleReceptionsResult
+		wantFlag    bool
+		wantComment string
+	}{
+		{
+			name:        "close",
+			action:      models.StaleActionClose,
+			wantResult:  models.StaleReceptionsResult{Closed: 1},
+			wantComment: "auto-closed by system: in progress since",
+		},
+		{
+			name:        "blocked by manifest is flagged",
+			action:      models.StaleActionClose,
+			blocking:    true,
+			wantResult:  models.StaleReceptionsResult{Flagged: 1},
+			wantFlag:    true,
+			wantComment: "flagged as stale by system: in progress since",
+		},
+		{
+			name:        "flag",
+			action:      models.StaleActionFlag,
+			wantResult:  models.StaleReceptionsResult{Flagged: 1},
+			wantFlag:    true,
+			wantComment: "flagged as stale by system",
+		},
+		{
+			name:       "already flagged",
+			action:     models.StaleActionFlag,
+			staleSince: &flaggedAt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+
+			mockRepo := new(MockReceptionRepo)
+			service := NewReceptionService(mockRepo)
+
+			reception := models.StaleReception{
+				Reception:    models.Reception{Id: uuid.New(), StatusId: models.ReceptionStatusInProgress},
+				OpenedAt:     time.Now().Add(-48 * time.Hour),
+				LastActivity: time.Now().Add(-48 * time.Hour),
+				StaleSince:   tt.staleSince,
+			}
+			mockRepo.On("ListStaleReceptions", ctx, mock.AnythingOfType("*time.Time"), (*time.Time)(nil)).Return([]models.StaleReception{reception}, nil)
+			if tt.action == models.StaleActionClose {
+				var manifest *models.ManifestAPI
+				if tt.blocking {
+					maxDiscrepancy := 0
+					manifest = &models.ManifestAPI{
+						ReceptionId:    reception.Id,
+						MaxDiscrepancy: &maxDiscrepancy,
+						Items:          []models.ManifestItemAPI{{Type: "обувь", Quantity: 1}},
+					}
+				}
+				mockRepo.On("CloseReception", ctx, systemActor(models.ReceptionStatusClosed, "auto-closed by system: in progress since")).
+					Return(nil, manifest, []models.ProductInfo{})
+			}
+			if tt.wantFlag {
+				mockRepo.On("FlagStaleReception", ctx, flaggedBySystem(tt.wantComment)).Return(nil)
+			}
+
+			result, err := service.HandleStaleReceptions(ctx, &models.StaleReceptionPolicy{MaxOpen: 24 * time.Hour, Action: tt.action})
+
+			require.NoError(t, err)
+			require.Equal(t, tt.wantResult, *result)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleStaleReceptions_ContinuesAfterError(t *testing.T) {
+	ctx := context.Background()
+
+	mockRepo := new(MockReceptionRepo)
+	service := NewReceptionService(mockRepo)
+
+	failing := models.StaleReception{Reception: models.Reception{Id: uuid.New(), StatusId: models.ReceptionStatusInProgress}}
+	closedMeanwhile := models.StaleReception{Reception: models.Reception{Id: uuid.New(), StatusId: models.ReceptionStatusInProgress}}
+	stale := models.StaleReception{Reception: models.Reception{Id: uuid.New(), StatusId: models.ReceptionStatusInProgress}}
+
+	mockRepo.On("ListStaleReceptions", ctx, (*time.Time)(nil), mock.AnythingOfType("*time.Time")).Return([]models.StaleReception{failing, closedMeanwhile, stale}, nil)
+	forReception := func(id uuid.UUID) interface{} {
+		return mock.MatchedBy(func(event *models.ReceptionEvent) bool { return event.ReceptionId == id })
+	}
+	mockRepo.On("FlagStaleReception", ctx, forReception(failing.Id)).Return(errors.New("db error"))
+	mockRepo.On("FlagStaleReception", ctx, forReception(closedMeanwhile.Id)).Return(models.ErrIllegalTransition)
+	mockRepo.On("FlagStaleReception", ctx, forReception(stale.Id)).Return(nil)
+
+	result, err := service.HandleStaleReceptions(ctx, &models.StaleReceptionPolicy{IdleTimeout: time.Hour, Action: models.StaleActionFlag})
+
+	require.ErrorContains(t, err, failing.Id.String())
+	require.Equal(t, models.StaleReceptionsResult{Flagged: 1}, *result)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestHandleStaleReceptions_Disabled(t *testing.T) {
+	mockRepo := new(MockReceptionRepo)
+	service := NewReceptionService(mockRepo)
+
+	result, err := service.HandleStaleReceptions(context.Background(), &models.StaleReceptionPolicy{Action: models.StaleActionClose})
+
+	require.NoError(t, err)
+	require.Equal(t, models.StaleReceptionsResult{}, *result)
+	mockRepo.AssertExpectations(t)
+}
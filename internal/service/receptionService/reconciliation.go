@@ -0,0 +1,113 @@
+package receptionService
+
+import (
+	"orderPickupPoint/internal/models"
+	"sort"
+)
+
+// compares scanned products with the manifest. Products with a barcode from the manifest are counted
+// against that line (a different type is reported as a mismatch), the rest are counted by type.
+// Every missing, extra or mismatched item is one discrepancy
+func reconcile(manifest *models.ManifestAPI, products []models.ProductInfo) *models.ReconciliationAPI {
+	report := &models.ReconciliationAPI{
+		ReceptionId:    manifest.ReceptionId,
+		MaxDiscrepancy: manifest.MaxDiscrepancy,
+		Shortages:      []models.ReconciliationLineAPI{},
+		Surpluses:      []models.ReconciliationLineAPI{},
+		Mismatches:     []models.ReconciliationLineAPI{},
+	}
+
+	expectedByBarcode := make(map[string]*models.ManifestItemAPI)
+	expectedByType := make(map[string]int)
+	for _, item := range manifest.Items {
+		report.Expected += item.Quantity
+		if item.Barcode == "" {
+			expectedByType[item.Type] += item.Quantity
+			continue
+		}
+		if line, ok := expectedByBarcode[item.Barcode]; ok {
+			line.Quantity += item.Quantity
+			continue
+		}
+		line := item
+		expectedByBarcode[item.Barcode] = &line
+	}
+
+	// barcode -> scanned type -> count
+	actualByBarcode := make(map[string]map[string]int)
+	actualByType := make(map[string]int)
+	for _, product := range products {
+		if product.DeletedAt != nil || product.LeftAt != nil {
+			continue
+		}
+		report.Actual++
+		if product.Barcode != nil {
+			if _, ok := expectedByBarcode[*product.Barcode]; ok {
+				if actualByBarcode[*product.Barcode] == nil {
+					actualByBarcode[*product.Barcode] = make(map[string]int)
+				}
+				actualByBarcode[*product.Barcode][product.Type]++
+				continue
+			}
+		}
+		actualByType[product.Type]++
+	}
+
+	for code, item := range expectedByBarcode {
+		actual := 0
+		for productType, count := range actualByBarcode[code] {
+			actual += count
+			if item.Type != "" && productType != item.Type {
+				report.Mismatches = append(report.Mismatches, models.ReconciliationLineAPI{
+					Barcode:    code,
+					Type:       item.Type,
+					ActualType: productType,
+					Expected:   item.Quantity,
+					Actual:     count,
+				})
+				report.Discrepancies += count
+			}
+		}
+		addLine(report, models.ReconciliationLineAPI{Barcode: code, Type: item.Type, Expected: item.Quantity, Actual: actual})
+	}
+
+	for productType, expected := range expectedByType {
+		addLine(report, models.ReconciliationLineAPI{Type: productType, Expected: expected, Actual: actualByType[productType]})
+	}
+	for productType, actual := range actualByType {
+		if _, ok := expectedByType[productType]; !ok {
+			addLine(report, models.ReconciliationLineAPI{Type: productType, Actual: actual})
+		}
+	}
+
+	sortLines(report.Shortages)
+	sortLines(report.Surpluses)
+	sortLines(report.Mismatches)
+
+	report.Blocking = report.MaxDiscrepancy != nil && report.Discrepancies > *report.MaxDiscrepancy
+	return report
+}
+
+// puts the line into shortages or surpluses, matching lines are skipped
+func addLine(report *models.ReconciliationAPI, line models.ReconciliationLineAPI) {
+	switch {
+	case line.Actual < line.Expected:
+		report.Shortages = append(report.Shortages, line)
+		report.Discrepancies += line.Expected - line.Actual
+	case line.Actual > line.Expected:
+		report.Surpluses = append(report.Surpluses, line)
+		report.Discrepancies += line.Actual - line.Expected
+	}
+}
+
+func sortLines(lines []models.ReconciliationLineAPI) {
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Barcode != lines[j].Barcode {
+			return lines[i].Barcode < lines[j].Barcode
+		}
+		if lines[i].Type != lines[j].Type {
+			return lines[i].Type < lines[j].Type
+		}
+		return lines[i].ActualType < lines[j].ActualType
+	})
+}
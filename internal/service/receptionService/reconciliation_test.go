@@ -0,0 +1,78 @@
+package receptionService
+
+import (
+	"orderPickupPoint/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReconcile(t *testing.T) {
+	shoes := "4006381333931"
+	phone := "5901234123457"
+	unknown := "WB-1"
+	deletedAt := time.Now()
+	limit := 3
+
+	manifest := &models.ManifestAPI{
+		MaxDiscrepancy: &limit,
+		Items: []models.ManifestItemAPI{
+			{Barcode: shoes, Type: "обувь", Quantity: 2},
+			{Barcode: phone, Type: "электроника", Quantity: 1},
+			{Type: "одежда", Quantity: 3},
+		},
+	}
+	products := []models.ProductInfo{
+		// one pair of shoes out of two
+		{Type: "обувь", Barcode: &shoes},
+		// right barcode, wrong type
+		{Type: "одежда", Barcode: &phone},
+		// clothes by type: two of three, a deleted scan and a shipped item are not counted
+		{Type: "одежда"},
+		{Type: "одежда", Barcode: &unknown},
+		{Type: "одежда", DeletedAt: &deletedAt},
+		{Type: "одежда", LeftAt: &deletedAt},
+		// not in the manifest at all
+		{Type: "электроника"},
+	}
+
+	report := reconcile(manifest, products)
+
+	require.Equal(t, 6, report.Expected)
+	require.Equal(t, 5, report.Actual)
+	require.Equal(t, []models.ReconciliationLineAPI{
+		{Type: "одежда", Expected: 3, Actual: 2},
+		{Barcode: shoes, Type: "обувь", Expected: 2, Actual: 1},
+	}, report.Shortages)
+	require.Equal(t, []models.ReconciliationLineAPI{
+		{Type: "электроника", Expected: 0, Actual: 1},
+	}, report.Surpluses)
+	require.Equal(t, []models.ReconciliationLineAPI{
+		{Barcode: phone, Type: "электроника", ActualType: "одежда", Expected: 1, Actual: 1},
+	}, report.Mismatches)
+	require.Equal(t, 4, report.Discrepancies)
+	require.True(t, report.Blocking)
+}
+
+func TestReconcile_Match(t *testing.T) {
+	code := "4006381333931"
+	manifest := &models.ManifestAPI{
+		Items: []models.ManifestItemAPI{
+			{Barcode: code, Quantity: 1},
+			{Barcode: code, Quantity: 1},
+		},
+	}
+	products := []models.ProductInfo{
+		{Type: "обувь", Barcode: &code},
+		{Type: "обувь", Barcode: &code},
+	}
+
+	report := reconcile(manifest, products)
+
+	require.Empty(t, report.Shortages)
+	require.Empty(t, report.Surpluses)
+	require.Empty(t, report.Mismatches)
+	require.Zero(t, report.Discrepancies)
+	require.False(t, report.Blocking)
+}
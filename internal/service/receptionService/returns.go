@@ -0,0 +1,132 @@
+package receptionService
+
+import (
+	"context"
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/utils/userCtx"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+const maxReturnComment = 1000
+
+// reasons a customer can give, unclaimed returns are registered by the system only
+var returnReasons = newEnum(map[int]string{
+	models.ReturnReasonChangedMind:       "changed_mind",
+	models.ReturnReasonDefective:         "defective",
+	models.ReturnReasonWrongItem:         "wrong_item",
+	models.ReturnReasonDamagedInDelivery: "damaged_in_delivery",
+	models.ReturnReasonOther:             "other",
+})
+
+// customer brings an issued item of the order back, it waits in the outbound queue of the pvz
+func (s *ReceptionService) RegisterReturn(ctx context.Context, input *models.ReturnInputAPI) (*models.ReturnAPI, error) {
+	ret, err := newReturn(input, userCtx.UserId(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	returnId, err := s.ReceptionRepo.CreateReturn(ctx, ret)
+	if err != nil {
+		return nil, err
+	}
+	return s.ReceptionRepo.GetReturn(ctx, returnId)
+}
+
+func newReturn(input *models.ReturnInputAPI, actorId *int) (*models.Return, error) {
+	ret := &models.Return{
+		OrderId:   input.OrderId,
+		ProductId: input.ProductId,
+		PvzId:     input.PvzId,
+		Comment:   strings.TrimSpace(input.Comment),
+		CreatedBy: actorId,
+	}
+	if input.Barcode != nil {
+		code := strings.TrimSpace(*input.Barcode)
+		ret.Barcode = &code
+	}
+
+	reason := strings.TrimSpace(input.Reason)
+	reasonId, ok := returnReasons.id(reason)
+	switch {
+	case ret.OrderId == uuid.Nil:
+		return nil, fmt.Errorf("%w: orderId is required", models.ErrInvalidReturn)
+	case (ret.ProductId == nil) == (ret.Barcode == nil || *ret.Barcode == ""):
+		return nil, fmt.Errorf("%w: either productId or barcode is required", models.ErrInvalidReturn)
+	case !ok:
+		return nil, fmt.Errorf("%w: unknown reason %q", models.ErrInvalidReturn, reason)
+	case reasonId == models.ReturnReasonOther && ret.Comment == "":
+		return nil, fmt.Errorf("%w: comment is required for reason other", models.ErrInvalidReturn)
+	case utf8.RuneCountInString(ret.Comment) > maxReturnComment:
+		return nil, fmt.Errorf("%w: comment longer than %d characters", models.ErrInvalidReturn, maxReturnComment)
+	}
+	ret.ReasonId = reasonId
+
+	var err error
+	ret.ConditionId, ret.Notes, err = parseCondition(input.Condition, input.Notes)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+func (s *ReceptionService) GetReturn(ctx context.Context, returnId uuid.UUID) (*models.ReturnAPI, error) {
+	return s.ReceptionRepo.GetReturn(ctx, returnId)
+}
+
+// without a status filter only the outbound queue is listed
+func (s *ReceptionService) ListReturns(ctx context.Context, filter *models.ReturnFilter) (*models.Page[models.ReturnAPI], error) {
+	for _, status := range filter.Statuses {
+		if _, ok := returnStatuses.id(status); !ok {
+			return nil, fmt.Errorf("%w: unknown return status %q", models.ErrInvalidFilter, status)
+		}
+	}
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = []string{returnStatuses.name(models.ReturnStatusQueued)}
+	}
+
+	returns, err := s.ReceptionRepo.ListReturns(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewPage(returns, filter.Cursor, filter.Page, filter.PageLimit), nil
+}
+
+// the item leaves the pvz to the sender
+func (s *ReceptionService) DispatchReturn(ctx context.Context, returnId uuid.UUID) error {
+	return s.changeReturnStatus(ctx, returnId, models.ReturnStatusDispatched, "")
+}
+
+// return registered by mistake, the item is not in the pvz
+func (s *ReceptionService) CancelReturn(ctx context.Context, returnId uuid.UUID, reason string) error {
+	return s.changeReturnStatus(ctx, returnId, models.ReturnStatusCancelled, reason)
+}
+
+func (s *ReceptionService) changeReturnStatus(ctx context.Context, returnId uuid.UUID, toStatusId int, comment string) error {
+	ret, err := s.ReceptionRepo.GetReturn(ctx, returnId)
+	if err != nil {
+		return err
+	}
+	if err := checkReturnTransition(ret.StatusId, toStatusId); err != nil {
+		return err
+	}
+
+	return s.ReceptionRepo.ChangeReturnStatus(ctx, &models.ReturnTransition{
+		ReturnId:     returnId,
+		FromStatusId: ret.StatusId,
+		ToStatusId:   toStatusId,
+		ActorId:      userCtx.UserId(ctx),
+		Comment:      comment,
+	})
+}
+
+func (s *ReceptionService) GetReturnHistory(ctx context.Context, returnId uuid.UUID) ([]models.ReturnHistoryItem, error) {
+	// distinguishes unknown return from empty history
+	if _, err := s.ReceptionRepo.GetReturn(ctx, returnId); err != nil {
+		return nil, err
+	}
+	return s.ReceptionRepo.GetReturnHistory(ctx, returnId)
+}
@@ -0,0 +1,212 @@
+package receptionService
+
+import (
+	"context"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/utils/cursor"
+	"orderPickupPoint/internal/utils/userCtx"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func (m *MockReceptionRepo) CreateReturn(ctx context.Context, ret *models.Return) (uuid.UUID, error) {
+	args := m.Called(ctx, ret)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockReceptionRepo) GetReturn(ctx context.Context, returnId uuid.UUID) (*models.ReturnAPI, error) {
+	args := m.Called(ctx, returnId)
+	return args.Get(0).(*models.ReturnAPI), args.Error(1)
+}
+
+func (m *MockReceptionRepo) ListReturns(ctx context.Context, filter *models.ReturnFilter) (*models.ListResult[models.ReturnAPI], error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(*models.ListResult[models.ReturnAPI]), args.Error(1)
+}
+
+func (m *MockReceptionRepo) ChangeReturnStatus(ctx context.Context, transition *models.ReturnTransition) error {
+	args := m.Called(ctx, transition)
+	return args.Error(0)
+}
+
+func TestNewReturn(t *testing.T) {
+	orderId := uuid.New()
+	productId := uuid.New()
+	code := " 4006381333931 "
+	empty := " "
+
+	tests := []struct {
+		name      string
+		input     models.ReturnInputAPI
+		wantError error
+	}{
+		{
+			name:  "by product id",
+			input: models.ReturnInputAPI{OrderId: orderId, ProductId: &productId, Reason: "changed_mind"},
+		},
+		{
+			name:  "by barcode with condition",
+			input: models.ReturnInputAPI{OrderId: orderId, Barcode: &code, Reason: "defective", Condition: "damaged", Notes: "не включается"},
+		},
+		{
+			name:      "no order",
+			input:     models.ReturnInputAPI{ProductId: &productId, Reason: "changed_mind"},
+			wantError: models.ErrInvalidReturn,
+		},
+		{
+			name:      "no product",
+			input:     models.ReturnInputAPI{OrderId: orderId, Barcode: &empty, Reason: "changed_mind"},
+			wantError: models.ErrInvalidReturn,
+		},
+		{
+			name:      "both product id and barcode",
+			input:     models.ReturnInputAPI{OrderId: orderId, ProductId: &productId, Barcode: &code, Reason: "changed_mind"},
+			wantError: models.ErrInvalidReturn,
+		},
+		{
+			name:      "unknown reason",
+			input:     models.ReturnInputAPI{OrderId: orderId, ProductId: &productId, Reason: "bored"},
+			wantError: models.ErrInvalidReturn,
+		},
+		{
+			name:      "other without comment",
+			input:     models.ReturnInputAPI{OrderId: orderId, ProductId: &productId, Reason: "other"},
+			wantError: models.ErrInvalidReturn,
+		},
+		{
+			name:      "too long comment",
+			input:     models.ReturnInputAPI{OrderId: orderId, ProductId: &productId, Reason: "other", Comment: strings.Repeat("я", maxReturnComment+1)},
+			wantError: models.ErrInvalidReturn,
+		},
+		{
+			name:      "damaged without notes",
+			input:     models.ReturnInputAPI{OrderId: orderId, ProductId: &productId, Reason: "defective", Condition: "damaged"},
+			wantError: models.ErrInvalidCondition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actorId := 4
+			ret, err := newReturn(&tt.input, &actorId)
+
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantError == nil {
+				require.Equal(t, orderId, ret.OrderId)
+				reasonId, _ := returnReasons.id(tt.input.Reason)
+				require.Equal(t, reasonId, ret.ReasonId)
+				require.Equal(t, &actorId, ret.CreatedBy)
+				if ret.Barcode != nil {
+					require.Equal(t, "4006381333931", *ret.Barcode)
+				}
+			}
+		})
+	}
+}
+
+func TestRegisterReturn(t *testing.T) {
+	ctx := userCtx.WithUser(context.Background(), &models.User{Id: 4})
+	repo := new(MockReceptionRepo)
+	service := NewReceptionService(repo)
+
+	orderId := uuid.New()
+	productId := uuid.New()
+	returnId := uuid.New()
+
+	repo.On("CreateReturn", ctx, mock.MatchedBy(func(ret *models.Return) bool {
+		return ret.OrderId == orderId && *ret.ProductId == productId && ret.ReasonId == models.ReturnReasonWrongItem &&
+			ret.ConditionId == models.ProductConditionOk && ret.CreatedBy != nil && *ret.CreatedBy == 4
+	})).Return(returnId, nil)
+	repo.On("GetReturn", ctx, returnId).Return(&models.ReturnAPI{Id: returnId, Status: "queued"}, nil)
+
+	ret, err := service.RegisterReturn(ctx, &models.ReturnInputAPI{OrderId: orderId, ProductId: &productId, Reason: "wrong_item"})
+
+	require.NoError(t, err)
+	require.Equal(t, returnId, ret.Id)
+	repo.AssertExpectations(t)
+}
+
+func TestChangeReturnStatus(t *testing.T) {
+	returnId := uuid.New()
+
+	tests := []struct {
+		name      string
+		statusId  int
+		cancel    bool
+		wantError error
+	}{
+		{name: "dispatch queued", statusId: models.ReturnStatusQueued},
+		{name: "cancel queued", statusId: models.ReturnStatusQueued, cancel: true},
+		{name: "dispatch twice", statusId: models.ReturnStatusDispatched, wantError: models.ErrReturnTransition},
+		{name: "cancel dispatched", statusId: models.ReturnStatusDispatched, cancel: true, wantError: models.ErrReturnTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := userCtx.WithUser(context.Background(), &models.User{Id: 4})
+			repo := new(MockReceptionRepo)
+			service := NewReceptionService(repo)
+
+			toStatusId := models.ReturnStatusDispatched
+			if tt.cancel {
+				toStatusId = models.ReturnStatusCancelled
+			}
+
+			repo.On("GetReturn", ctx, returnId).Return(&models.ReturnAPI{Id: returnId, StatusId: tt.statusId}, nil)
+			if tt.wantError == nil {
+				repo.On("ChangeReturnStatus", ctx, &models.ReturnTransition{
+					ReturnId:     returnId,
+					FromStatusId: tt.statusId,
+					ToStatusId:   toStatusId,
+					ActorId:      userCtx.UserId(ctx),
+				}).Return(nil)
+			}
+
+			var err error
+			if tt.cancel {
+				err = service.CancelReturn(ctx, returnId, "")
+			} else {
+				err = service.DispatchReturn(ctx, returnId)
+			}
+
+			require.ErrorIs(t, err, tt.wantError)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListReturns(t *testing.T) {
+	ctx := context.Background()
+	pvzId := uuid.New()
+
+	t.Run("queue by default", func(t *testing.T) {
+		repo := new(MockReceptionRepo)
+		service := NewReceptionService(repo)
+
+		next := &cursor.Cursor{SortBy: models.ReturnSortByDate, Id: uuid.NewString()}
+		repo.On("ListReturns", ctx, &models.ReturnFilter{PvzId: pvzId, Statuses: []string{"queued"}, Page: 1, PageLimit: 10}).
+			Return(&models.ListResult[models.ReturnAPI]{Total: 11, Next: next}, nil)
+
+		page, err := service.ListReturns(ctx, &models.ReturnFilter{PvzId: pvzId, Page: 1, PageLimit: 10})
+
+		require.NoError(t, err)
+		require.Equal(t, []models.ReturnAPI{}, page.Items)
+		require.Equal(t, 1, page.Page)
+		require.Equal(t, next.Encode(), *page.Next)
+		require.Nil(t, page.Prev)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("unknown status", func(t *testing.T) {
+		repo := new(MockReceptionRepo)
+		service := NewReceptionService(repo)
+
+		_, err := service.ListReturns(ctx, &models.ReturnFilter{PvzId: pvzId, Statuses: []string{"lost"}})
+
+		require.ErrorIs(t, err, models.ErrInvalidFilter)
+	})
+}
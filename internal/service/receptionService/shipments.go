@@ -0,0 +1,128 @@
+package receptionService
+
+import (
+	"context"
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/utils/userCtx"
+
+	"github.com/google/uuid"
+)
+
+const maxShipmentProducts = 500
+
+// products stored in a pvz are sent to another pvz, they stay in the source pvz until dispatch
+func (s *ReceptionService) CreateShipment(ctx context.Context, input *models.ShipmentInputAPI) (*models.ShipmentAPI, error) {
+	shipment, err := newShipment(input, userCtx.UserId(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	shipmentId, err := s.ReceptionRepo.CreateShipment(ctx, shipment)
+	if err != nil {
+		return nil, err
+	}
+	return s.ReceptionRepo.GetShipment(ctx, shipmentId)
+}
+
+func newShipment(input *models.ShipmentInputAPI, actorId *int) (*models.Shipment, error) {
+	switch {
+	case input.FromPvzId == uuid.Nil || input.ToPvzId == uuid.Nil:
+		return nil, fmt.Errorf("%w: fromPvzId and toPvzId are required", models.ErrInvalidShipment)
+	case input.FromPvzId == input.ToPvzId:
+		return nil, fmt.Errorf("%w: products are shipped to another pvz", models.ErrInvalidShipment)
+	case len(input.ProductIds) == 0:
+		return nil, fmt.Errorf("%w: no products", models.ErrInvalidShipment)
+	case len(input.ProductIds) > maxShipmentProducts:
+		return nil, fmt.Errorf("%w: at most %d products per shipment", models.ErrInvalidShipment, maxShipmentProducts)
+	}
+
+	seen := make(map[uuid.UUID]bool, len(input.ProductIds))
+	for _, productId := range input.ProductIds {
+		if seen[productId] {
+			return nil, fmt.Errorf("%w: product %s is listed twice", models.ErrInvalidShipment, productId)
+		}
+		seen[productId] = true
+	}
+
+	return &models.Shipment{
+		FromPvzId:  input.FromPvzId,
+		ToPvzId:    input.ToPvzId,
+		ProductIds: input.ProductIds,
+		CreatedBy:  actorId,
+	}, nil
+}
+
+func (s *ReceptionService) GetShipment(ctx context.Context, shipmentId uuid.UUID) (*models.ShipmentAPI, error) {
+	return s.ReceptionRepo.GetShipment(ctx, shipmentId)
+}
+
+// without a status filter only the shipments not arrived or cancelled yet are listed
+func (s *ReceptionService) ListShipments(ctx context.Context, filter *models.ShipmentFilter) (*models.Page[models.ShipmentAPI], error) {
+	for _, status := range filter.Statuses {
+		if _, ok := shipmentStatuses.id(status); !ok {
+			return nil, fmt.Errorf("%w: unknown shipment status %q", models.ErrInvalidFilter, status)
+		}
+	}
+	if len(filter.Statuses) == 0 {
+		filter.Statuses = []string{
+			shipmentStatuses.name(models.ShipmentStatusCreated),
+			shipmentStatuses.name(models.ShipmentStatusDispatched),
+		}
+	}
+
+	shipments, err := s.ReceptionRepo.ListShipments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewPage(shipments, filter.Cursor, filter.Page, filter.PageLimit), nil
+}
+
+// the products leave the source pvz and are in transit
+func (s *ReceptionService) DispatchShipment(ctx context.Context, shipmentId uuid.UUID) (*models.ShipmentAPI, error) {
+	err := s.changeShipmentStatus(ctx, shipmentId, models.ShipmentStatusDispatched, "")
+	if err != nil {
+		return nil, err
+	}
+	return s.ReceptionRepo.GetShipment(ctx, shipmentId)
+}
+
+// the products are accepted at the destination in its open reception or a new one, its id is in the result
+func (s *ReceptionService) ReceiveShipment(ctx context.Context, shipmentId uuid.UUID) (*models.ShipmentAPI, error) {
+	err := s.changeShipmentStatus(ctx, shipmentId, models.ShipmentStatusArrived, "")
+	if err != nil {
+		return nil, err
+	}
+	return s.ReceptionRepo.GetShipment(ctx, shipmentId)
+}
+
+// only a shipment not dispatched yet can be cancelled, its products can be shipped again
+func (s *ReceptionService) CancelShipment(ctx context.Context, shipmentId uuid.UUID, reason string) error {
+	return s.changeShipmentStatus(ctx, shipmentId, models.ShipmentStatusCancelled, reason)
+}
+
+func (s *ReceptionService) changeShipmentStatus(ctx context.Context, shipmentId uuid.UUID, toStatusId int, comment string) error {
+	shipment, err := s.ReceptionRepo.GetShipment(ctx, shipmentId)
+	if err != nil {
+		return err
+	}
+	if err := checkShipmentTransition(shipment.StatusId, toStatusId); err != nil {
+		return err
+	}
+
+	return s.ReceptionRepo.ChangeShipmentStatus(ctx, &models.ShipmentTransition{
+		ShipmentId:   shipmentId,
+		FromStatusId: shipment.StatusId,
+		ToStatusId:   toStatusId,
+		ActorId:      userCtx.UserId(ctx),
+		Comment:      comment,
+	})
+}
+
+func (s *ReceptionService) GetShipmentHistory(ctx context.Context, shipmentId uuid.UUID) ([]models.ShipmentHistoryItem, error) {
+	// distinguishes unknown shipment from empty history
+	if _, err := s.ReceptionRepo.GetShipment(ctx, shipmentId); err != nil {
+		return nil, err
+	}
+	return s.ReceptionRepo.GetShipmentHistory(ctx, shipmentId)
+}
@@ -0,0 +1,168 @@
+package receptionService
+
+import (
+	"context"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/utils/userCtx"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func (m *MockReceptionRepo) CreateShipment(ctx context.Context, shipment *models.Shipment) (uuid.UUID, error) {
+	args := m.Called(ctx, shipment)
+	return args.Get(0).(uuid.UUID), args.Error(1)
+}
+
+func (m *MockReceptionRepo) GetShipment(ctx context.Context, shipmentId uuid.UUID) (*models.ShipmentAPI, error) {
+	args := m.Called(ctx, shipmentId)
+	return args.Get(0).(*models.ShipmentAPI), args.Error(1)
+}
+
+func (m *MockReceptionRepo) ListShipments(ctx context.Context, filter *models.ShipmentFilter) (*models.ListResult[models.ShipmentAPI], error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(*models.ListResult[models.ShipmentAPI]), args.Error(1)
+}
+
+func (m *MockReceptionRepo) ChangeShipmentStatus(ctx context.Context, transition *models.ShipmentTransition) error {
+	args := m.Called(ctx, transition)
+	return args.Error(0)
+}
+
+func TestNewShipment(t *testing.T) {
+	fromPvzId, toPvzId := uuid.New(), uuid.New()
+	productId := uuid.New()
+
+	tests := []struct {
+		name      string
+		input     models.ShipmentInputAPI
+		wantError error
+	}{
+		{
+			name:  "valid",
+			input: models.ShipmentInputAPI{FromPvzId: fromPvzId, ToPvzId: toPvzId, ProductIds: []uuid.UUID{productId, uuid.New()}},
+		},
+		{
+			name:      "no destination",
+			input:     models.ShipmentInputAPI{FromPvzId: fromPvzId, ProductIds: []uuid.UUID{productId}},
+			wantError: models.ErrInvalidShipment,
+		},
+		{
+			name:      "same pvz",
+			input:     models.ShipmentInputAPI{FromPvzId: fromPvzId, ToPvzId: fromPvzId, ProductIds: []uuid.UUID{productId}},
+			wantError: models.ErrInvalidShipment,
+		},
+		{
+			name:      "no products",
+			input:     models.ShipmentInputAPI{FromPvzId: fromPvzId, ToPvzId: toPvzId},
+			wantError: models.ErrInvalidShipment,
+		},
+		{
+			name:      "product listed twice",
+			input:     models.ShipmentInputAPI{FromPvzId: fromPvzId, ToPvzId: toPvzId, ProductIds: []uuid.UUID{productId, productId}},
+			wantError: models.ErrInvalidShipment,
+		},
+		{
+			name:      "too many products",
+			input:     models.ShipmentInputAPI{FromPvzId: fromPvzId, ToPvzId: toPvzId, ProductIds: make([]uuid.UUID, maxShipmentProducts+1)},
+			wantError: models.ErrInvalidShipment,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actorId := 4
+			shipment, err := newShipment(&tt.input, &actorId)
+
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantError == nil {
+				require.Equal(t, fromPvzId, shipment.FromPvzId)
+				require.Equal(t, toPvzId, shipment.ToPvzId)
+				require.Len(t, shipment.ProductIds, 2)
+				require.Equal(t, &actorId, shipment.CreatedBy)
+			}
+		})
+	}
+}
+
+func TestChangeShipmentStatus(t *testing.T) {
+	shipmentId := uuid.New()
+
+	tests := []struct {
+		name       string
+		statusId   int
+		toStatusId int
+		wantError  error
+	}{
+		{name: "dispatch created", statusId: models.ShipmentStatusCreated, toStatusId: models.ShipmentStatusDispatched},
+		{name: "receive dispatched", statusId: models.ShipmentStatusDispatched, toStatusId: models.ShipmentStatusArrived},
+		{name: "cancel created", statusId: models.ShipmentStatusCreated, toStatusId: models.ShipmentStatusCancelled},
+		{name: "receive not dispatched", statusId: models.ShipmentStatusCreated, toStatusId: models.ShipmentStatusArrived, wantError: models.ErrShipmentTransition},
+		{name: "cancel dispatched", statusId: models.ShipmentStatusDispatched, toStatusId: models.ShipmentStatusCancelled, wantError: models.ErrShipmentTransition},
+		{name: "receive twice", statusId: models.ShipmentStatusArrived, toStatusId: models.ShipmentStatusArrived, wantError: models.ErrShipmentTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := userCtx.WithUser(context.Background(), &models.User{Id: 4})
+			repo := new(MockReceptionRepo)
+			service := NewReceptionService(repo)
+
+			repo.On("GetShipment", ctx, shipmentId).Return(&models.ShipmentAPI{Id: shipmentId, StatusId: tt.statusId}, nil)
+			if tt.wantError == nil {
+				repo.On("ChangeShipmentStatus", ctx, &models.ShipmentTransition{
+					ShipmentId:   shipmentId,
+					FromStatusId: tt.statusId,
+					ToStatusId:   tt.toStatusId,
+					ActorId:      userCtx.UserId(ctx),
+				}).Return(nil)
+			}
+
+			var err error
+			switch tt.toStatusId {
+			case models.ShipmentStatusDispatched:
+				_, err = service.DispatchShipment(ctx, shipmentId)
+			case models.ShipmentStatusArrived:
+				_, err = service.ReceiveShipment(ctx, shipmentId)
+			case models.ShipmentStatusCancelled:
+				err = service.CancelShipment(ctx, shipmentId, "")
+			}
+
+			require.ErrorIs(t, err, tt.wantError)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListShipments(t *testing.T) {
+	ctx := context.Background()
+	pvzId := uuid.New()
+
+	t.Run("active by default", func(t *testing.T) {
+		repo := new(MockReceptionRepo)
+		service := NewReceptionService(repo)
+
+		repo.On("ListShipments", ctx, &models.ShipmentFilter{PvzId: pvzId, Inbound: true, Statuses: []string{"created", "dispatched"}, Page: 2, PageLimit: 10}).
+			Return(&models.ListResult[models.ShipmentAPI]{Total: 11}, nil)
+
+		page, err := service.ListShipments(ctx, &models.ShipmentFilter{PvzId: pvzId, Inbound: true, Page: 2, PageLimit: 10})
+
+		require.NoError(t, err)
+		require.Equal(t, 11, page.Total)
+		require.Equal(t, 2, page.Page)
+		require.Equal(t, 10, page.Limit)
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("unknown status", func(t *testing.T) {
+		repo := new(MockReceptionRepo)
+		service := NewReceptionService(repo)
+
+		_, err := service.ListShipments(ctx, &models.ShipmentFilter{PvzId: pvzId, Statuses: []string{"lost"}})
+
+		require.ErrorIs(t, err, models.ErrInvalidFilter)
+		repo.AssertNotCalled(t, "ListShipments", mock.Anything, mock.Anything)
+	})
+}
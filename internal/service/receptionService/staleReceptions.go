@@ -0,0 +1,98 @@
+package receptionService
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"time"
+)
+
+// closes or flags in_progress receptions forgotten by employees, so the pvz is not blocked forever.
+// A reception which manifest reconciliation blocks closing is only flagged.
+// Errors of single receptions do not stop the run and are returned joined
+func (s *ReceptionService) HandleStaleReceptions(ctx context.Context, policy *models.StaleReceptionPolicy) (*models.StaleReceptionsResult, error) {
+	result := &models.StaleReceptionsResult{}
+
+	now := time.Now()
+	var openedBefore, idleBefore *time.Time
+	if policy.MaxOpen > 0 {
+		bound := now.Add(-policy.MaxOpen)
+		openedBefore = &bound
+	}
+	if policy.IdleTimeout > 0 {
+		bound := now.Add(-policy.IdleTimeout)
+		idleBefore = &bound
+	}
+	if openedBefore == nil && idleBefore == nil {
+		return result, nil
+	}
+
+	receptions, err := s.ReceptionRepo.ListStaleReceptions(ctx, openedBefore, idleBefore)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for i := range receptions {
+		reception := &receptions[i]
+		reason := staleReason(reception, openedBefore, idleBefore)
+
+		closed, err := s.handleStale(ctx, reception, policy.Action, reason)
+		if errors.Is(err, models.ErrIllegalTransition) {
+			// changed by an employee meanwhile
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("reception %s: %w", reception.Id, err))
+			continue
+		}
+
+		switch {
+		case closed:
+			result.Closed++
+		case reception.StaleSince == nil:
+			result.Flagged++
+		}
+	}
+	return result, errors.Join(errs...)
+}
+
+// reports whether the reception was closed
+func (s *ReceptionService) handleStale(ctx context.Context, reception *models.StaleReception, action string, reason string) (bool, error) {
+	systemActor := models.SystemActorId
+
+	if action == models.StaleActionClose {
+		report, err := s.closeReconciled(ctx, &models.ReceptionTransition{
+			ReceptionId:  reception.Id,
+			FromStatusId: reception.StatusId,
+			ToStatusId:   models.ReceptionStatusClosed,
+			ActorId:      &systemActor,
+			Comment:      "auto-closed by system: " + reason,
+		})
+		if !errors.Is(err, models.ErrDiscrepancyExceeded) {
+			return err == nil, err
+		}
+		reason += fmt.Sprintf(", not closed: %d manifest discrepancies", report.Discrepancies)
+	}
+
+	if reception.StaleSince != nil {
+		return false, nil
+	}
+	return false, s.ReceptionRepo.FlagStaleReception(ctx, &models.ReceptionEvent{
+		ReceptionId: reception.Id,
+		Event:       models.ReceptionEventFlaggedStale,
+		ActorId:     &systemActor,
+		Comment:     "flagged as stale by system: " + reason,
+	})
+}
+
+func staleReason(reception *models.StaleReception, openedBefore *time.Time, idleBefore *time.Time) string {
+	if openedBefore != nil && reception.OpenedAt.Before(*openedBefore) {
+		return "in progress since " + reception.OpenedAt.Format(time.RFC3339)
+	}
+	if idleBefore != nil && reception.LastActivity.Before(*idleBefore) {
+		return "no product activity since " + reception.LastActivity.Format(time.RFC3339)
+	}
+	return "stale"
+}
@@ -0,0 +1,70 @@
+package receptionService
+
+import (
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"slices"
+)
+
+// allowed reception status changes:
+// in_progress -> close -> verified, in_progress -> cancelled,
+// close -> in_progress only through an approved reopen request
+var receptionTransitions = map[int][]int{
+	models.ReceptionStatusInProgress: {models.ReceptionStatusClosed, models.ReceptionStatusCancelled},
+	models.ReceptionStatusClosed:     {models.ReceptionStatusVerified, models.ReceptionStatusInProgress},
+}
+
+var receptionStatuses = newEnum(map[int]string{
+	models.ReceptionStatusInProgress: "in_progress",
+	models.ReceptionStatusClosed:     "close",
+	models.ReceptionStatusVerified:   "verified",
+	models.ReceptionStatusCancelled:  "cancelled",
+})
+
+// returns ErrIllegalTransition with the statuses in the message if the change is not allowed
+func checkTransition(from, to int) error {
+	if slices.Contains(receptionTransitions[from], to) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s -> %s", models.ErrIllegalTransition, receptionStatuses.name(from), receptionStatuses.name(to))
+}
+
+// allowed return status changes: queued -> dispatched, queued -> cancelled
+var returnTransitions = map[int][]int{
+	models.ReturnStatusQueued: {models.ReturnStatusDispatched, models.ReturnStatusCancelled},
+}
+
+var returnStatuses = newEnum(map[int]string{
+	models.ReturnStatusQueued:     "queued",
+	models.ReturnStatusDispatched: "dispatched",
+	models.ReturnStatusCancelled:  "cancelled",
+})
+
+// returns ErrReturnTransition with the statuses in the message if the change is not allowed
+func checkReturnTransition(from, to int) error {
+	if slices.Contains(returnTransitions[from], to) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s -> %s", models.ErrReturnTransition, returnStatuses.name(from), returnStatuses.name(to))
+}
+
+// allowed shipment status changes: created -> dispatched -> arrived, created -> cancelled
+var shipmentTransitions = map[int][]int{
+	models.ShipmentStatusCreated:    {models.ShipmentStatusDispatched, models.ShipmentStatusCancelled},
+	models.ShipmentStatusDispatched: {models.ShipmentStatusArrived},
+}
+
+var shipmentStatuses = newEnum(map[int]string{
+	models.ShipmentStatusCreated:    "created",
+	models.ShipmentStatusDispatched: "dispatched",
+	models.ShipmentStatusArrived:    "arrived",
+	models.ShipmentStatusCancelled:  "cancelled",
+})
+
+// returns ErrShipmentTransition with the statuses in the message if the change is not allowed
+func checkShipmentTransition(from, to int) error {
+	if slices.Contains(shipmentTransitions[from], to) {
+		return nil
+	}
+	return fmt.Errorf("%w: %s -> %s", models.ErrShipmentTransition, shipmentStatuses.name(from), shipmentStatuses.name(to))
+}
@@ -0,0 +1,65 @@
+package receptionService
+
+import (
+	"orderPickupPoint/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    int
+		to      int
+		allowed bool
+	}{
+		{"close", models.ReceptionStatusInProgress, models.ReceptionStatusClosed, true},
+		{"cancel", models.ReceptionStatusInProgress, models.ReceptionStatusCancelled, true},
+		{"verify", models.ReceptionStatusClosed, models.ReceptionStatusVerified, true},
+		{"reopen", models.ReceptionStatusClosed, models.ReceptionStatusInProgress, true},
+		{"reopen verified", models.ReceptionStatusVerified, models.ReceptionStatusInProgress, false},
+		{"verify open", models.ReceptionStatusInProgress, models.ReceptionStatusVerified, false},
+		{"close twice", models.ReceptionStatusClosed, models.ReceptionStatusClosed, false},
+		{"cancel closed", models.ReceptionStatusClosed, models.ReceptionStatusCancelled, false},
+		{"leave verified", models.ReceptionStatusVerified, models.ReceptionStatusClosed, false},
+		{"leave cancelled", models.ReceptionStatusCancelled, models.ReceptionStatusInProgress, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkTransition(tt.from, tt.to)
+			if tt.allowed {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, models.ErrIllegalTransition)
+			}
+		})
+	}
+}
+
+func TestCheckReturnTransition(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    int
+		to      int
+		allowed bool
+	}{
+		{"dispatch", models.ReturnStatusQueued, models.ReturnStatusDispatched, true},
+		{"cancel", models.ReturnStatusQueued, models.ReturnStatusCancelled, true},
+		{"dispatch twice", models.ReturnStatusDispatched, models.ReturnStatusDispatched, false},
+		{"cancel dispatched", models.ReturnStatusDispatched, models.ReturnStatusCancelled, false},
+		{"dispatch cancelled", models.ReturnStatusCancelled, models.ReturnStatusDispatched, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkReturnTransition(tt.from, tt.to)
+			if tt.allowed {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, models.ErrReturnTransition)
+			}
+		})
+	}
+}
@@ -2,10 +2,16 @@ package service
 
 import (
 	"context"
+	"io"
 	"orderPickupPoint/config"
 	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/notifier"
+	"orderPickupPoint/internal/service/attachmentService"
 	"orderPickupPoint/internal/service/authService"
+	"orderPickupPoint/internal/service/cellService"
+	"orderPickupPoint/internal/service/orderService"
 	"orderPickupPoint/internal/service/pickupPointService"
+	"orderPickupPoint/internal/service/productTypeService"
 	"orderPickupPoint/internal/service/receptionService"
 	"orderPickupPoint/internal/storage"
 
@@ -14,14 +20,81 @@ import (
 
 type PickupPoint interface {
 	Create(ctx context.Context, pickupPoint *models.PickupPointAPI) (*models.PickupPointAPI, error)
-	GetInfo(ctx context.Context, filter *models.PvzFilter) ([]models.PvzInfo, error)
+	GetInfo(ctx context.Context, filter *models.PvzFilter) (*models.Page[models.PvzInfo], error)
+	GetDetails(ctx context.Context, pvzId uuid.UUID) (*models.PickupPointDetailsAPI, error)
+	SetCapacity(ctx context.Context, pvzId uuid.UUID, capacity *models.PvzCapacity) error
+	SetStoragePeriods(ctx context.Context, pvzId uuid.UUID, periods *models.PvzStoragePeriods) error
+	ListExpiring(ctx context.Context, filter *models.StorageDeadlineFilter, days int) (*models.Page[models.PlacementAPI], error)
+	ListOverdue(ctx context.Context, filter *models.StorageDeadlineFilter) (*models.Page[models.PlacementAPI], error)
 }
 
 type Reception interface {
 	CreateReception(ctx context.Context, pvzId uuid.UUID) (*models.ReceptionAPI, error)
 	AddProduct(ctx context.Context, productAPI *models.ProductAPI) (*models.ProductAPI, error)
+	AddProductsBatch(ctx context.Context, receptionId uuid.UUID, items []models.ProductAPI) (*models.ProductBatchResultAPI, error)
+	FindProductsByBarcode(ctx context.Context, code string) ([]models.ProductLocationAPI, error)
 	DeleteLastProductInReception(ctx context.Context, pvzId uuid.UUID) error
-	CloseReception(ctx context.Context, pvzId uuid.UUID) error
+	DeleteProductInReception(ctx context.Context, receptionId uuid.UUID, productId uuid.UUID) error
+	CloseReception(ctx context.Context, pvzId uuid.UUID) (*models.ReconciliationAPI, error)
+	CloseReceptionById(ctx context.Context, receptionId uuid.UUID) (*models.ReconciliationAPI, error)
+	SetManifest(ctx context.Context, receptionId uuid.UUID, manifest *models.ManifestAPI) (*models.ManifestAPI, error)
+	GetManifest(ctx context.Context, receptionId uuid.UUID) (*models.ManifestAPI, error)
+	GetReconciliation(ctx context.Context, receptionId uuid.UUID) (*models.ReconciliationAPI, error)
+	GetDiscrepancyReport(ctx context.Context, receptionId uuid.UUID) (*models.DiscrepancyReportAPI, error)
+	VerifyReception(ctx context.Context, receptionId uuid.UUID) error
+	CancelReception(ctx context.Context, receptionId uuid.UUID, reason string) error
+	HandleStaleReceptions(ctx context.Context, policy *models.StaleReceptionPolicy) (*models.StaleReceptionsResult, error)
+	GetReceptionHistory(ctx context.Context, receptionId uuid.UUID) ([]models.ReceptionHistoryItem, error)
+	RequestReopen(ctx context.Context, receptionId uuid.UUID, reason string) (*models.ReopenRequestAPI, error)
+	ResolveReopen(ctx context.Context, requestId uuid.UUID, approve bool, comment string) (*models.ReopenRequestAPI, error)
+	ListReopenRequests(ctx context.Context, receptionId *uuid.UUID, pendingOnly bool) ([]models.ReopenRequestAPI, error)
+	GetReception(ctx context.Context, receptionId uuid.UUID) (*models.ReceptionDetailsAPI, error)
+	ListReceptions(ctx context.Context, filter *models.ReceptionFilter) (*models.Page[models.ReceptionDetailsAPI], error)
+	RegisterReturn(ctx context.Context, input *models.ReturnInputAPI) (*models.ReturnAPI, error)
+	GetReturn(ctx context.Context, returnId uuid.UUID) (*models.ReturnAPI, error)
+	ListReturns(ctx context.Context, filter *models.ReturnFilter) (*models.Page[models.ReturnAPI], error)
+	DispatchReturn(ctx context.Context, returnId uuid.UUID) error
+	CancelReturn(ctx context.Context, returnId uuid.UUID, reason string) error
+	GetReturnHistory(ctx context.Context, returnId uuid.UUID) ([]models.ReturnHistoryItem, error)
+	CreateShipment(ctx context.Context, input *models.ShipmentInputAPI) (*models.ShipmentAPI, error)
+	GetShipment(ctx context.Context, shipmentId uuid.UUID) (*models.ShipmentAPI, error)
+	ListShipments(ctx context.Context, filter *models.ShipmentFilter) (*models.Page[models.ShipmentAPI], error)
+	DispatchShipment(ctx context.Context, shipmentId uuid.UUID) (*models.ShipmentAPI, error)
+	ReceiveShipment(ctx context.Context, shipmentId uuid.UUID) (*models.ShipmentAPI, error)
+	CancelShipment(ctx context.Context, shipmentId uuid.UUID, reason string) error
+	GetShipmentHistory(ctx context.Context, shipmentId uuid.UUID) ([]models.ShipmentHistoryItem, error)
+}
+
+type ProductType interface {
+	List(ctx context.Context, includeInactive bool) ([]models.ProductTypeAPI, error)
+	Create(ctx context.Context, input *models.ProductTypeInputAPI) (*models.ProductTypeAPI, error)
+	Update(ctx context.Context, code string, input *models.ProductTypeInputAPI) (*models.ProductTypeAPI, error)
+}
+
+type Order interface {
+	CreateOrder(ctx context.Context, input *models.OrderInputAPI) (*models.OrderAPI, error)
+	GetOrder(ctx context.Context, orderId uuid.UUID) (*models.OrderAPI, error)
+	ListOrders(ctx context.Context, filter *models.OrderFilter) (*models.Page[models.OrderAPI], error)
+	IssueOrder(ctx context.Context, orderId uuid.UUID, request *models.OrderIssueAPI) (*models.OrderIssueResultAPI, error)
+	CancelOrder(ctx context.Context, orderId uuid.UUID, reason string) error
+	GetOrderHistory(ctx context.Context, orderId uuid.UUID) ([]models.OrderHistoryItem, error)
+	GeneratePickupCodes(ctx context.Context) (int, error)
+	ReturnUnclaimedOrders(ctx context.Context) (int, error)
+	RegeneratePickupCode(ctx context.Context, orderId uuid.UUID) (*models.OrderAPI, error)
+}
+
+type Cell interface {
+	CreateCells(ctx context.Context, pvzId uuid.UUID, input *models.StorageCellsInputAPI) ([]models.StorageCellAPI, error)
+	ListCells(ctx context.Context, pvzId uuid.UUID) ([]models.StorageCellAPI, error)
+	UpdateCell(ctx context.Context, cellId uuid.UUID, update *models.StorageCellUpdateAPI) error
+	MoveProduct(ctx context.Context, productId uuid.UUID, cellCode string) error
+	FindPlacements(ctx context.Context, filter *models.PlacementFilter) ([]models.PlacementAPI, error)
+}
+
+type Attachment interface {
+	Upload(ctx context.Context, receptionId uuid.UUID, productId *uuid.UUID, fileName string, content io.Reader) (*models.Attachment, error)
+	List(ctx context.Context, receptionId uuid.UUID, productId *uuid.UUID) ([]models.Attachment, error)
+	Open(ctx context.Context, attachmentId uuid.UUID, thumbnail bool) (*models.Attachment, io.ReadCloser, error)
 }
 
 type Auth interface {
@@ -30,16 +103,23 @@ type Auth interface {
 	Login(ctx context.Context, user *models.User) (*models.AuthTokens, error)
 	AvaliableForUser(tokens *models.AuthTokens, avaliableRoles []string) (bool, error)
 	HandleTokens(ctx context.Context, tokens *models.AuthTokens) (*models.AuthTokens, error)
+	GetUserFromTokens(tokens *models.AuthTokens) (*models.User, error)
 }
 
 type Deps struct {
-	Repos *storage.Repositories
-	Cfg   *config.Config
+	Repos    *storage.Repositories
+	Blobs    storage.BlobStore
+	Notifier notifier.Notifier
+	Cfg      *config.Config
 }
 
 type Services struct {
 	PickupPoint PickupPoint
 	Reception   Reception
+	ProductType ProductType
+	Attachment  Attachment
+	Order       Order
+	Cell        Cell
 	Auth        Auth
 }
 
@@ -47,6 +127,10 @@ func NewServices(deps *Deps) *Services {
 	return &Services{
 		PickupPoint: pickupPointService.NewPickupPointService(deps.Repos.PickupPoint),
 		Reception:   receptionService.NewReceptionService(deps.Repos.Reception),
+		ProductType: productTypeService.NewProductTypeService(deps.Repos.ProductType),
+		Attachment:  attachmentService.NewAttachmentService(deps.Repos.Attachment, deps.Blobs, deps.Cfg.AttachmentMaxBytes),
+		Order:       orderService.NewOrderService(deps.Repos.Order, deps.Notifier, deps.Cfg.PickupCodes),
+		Cell:        cellService.NewCellService(deps.Repos.Cell),
 		Auth:        authService.NewAuthService(deps.Repos.Auth, deps.Cfg),
 	}
 }
@@ -0,0 +1,43 @@
+package blobStore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"orderPickupPoint/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalBlobStore(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalBlobStore(t.TempDir())
+	require.NoError(t, err)
+
+	err = store.Put(ctx, "attachments/a.pdf", bytes.NewBufferString("%PDF-1.4"))
+	require.NoError(t, err)
+
+	content, err := store.Open(ctx, "attachments/a.pdf")
+	require.NoError(t, err)
+	data, err := io.ReadAll(content)
+	content.Close()
+	require.NoError(t, err)
+	require.Equal(t, "%PDF-1.4", string(data))
+
+	require.NoError(t, store.Delete(ctx, "attachments/a.pdf"))
+	require.NoError(t, store.Delete(ctx, "attachments/a.pdf"))
+
+	_, err = store.Open(ctx, "attachments/a.pdf")
+	require.ErrorIs(t, err, models.ErrNotFound)
+}
+
+func TestLocalBlobStore_InvalidKey(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewLocalBlobStore(t.TempDir())
+	require.NoError(t, err)
+
+	for _, key := range []string{"", "../escape", "/etc/passwd", "a/../../b"} {
+		require.Error(t, store.Put(ctx, key, bytes.NewBufferString("x")), key)
+	}
+}
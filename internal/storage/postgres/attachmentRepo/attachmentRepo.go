@@ -0,0 +1,115 @@
+package attachmentRepo
+
+import (
+	"context"
+	"errors"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage/postgres"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type AttachmentRepo struct {
+	pool postgres.DBPool
+}
+
+func NewAttachmentRepo(pool postgres.DBPool) *AttachmentRepo {
+	return &AttachmentRepo{
+		pool: pool,
+	}
+}
+
+const attachmentColumns = `id, reception_id, product_id, file_name, content_type, size_bytes, storage_key, width, height,
+					thumbnail_key, thumbnail_content_type, thumbnail_width, thumbnail_height, created_at, created_by`
+
+// stores the metadata, ErrNotFound if the reception does not exist or the product is not in it
+func (r *AttachmentRepo) CreateAttachment(ctx context.Context, attachment *models.Attachment) error {
+	query := `insert into attachments(id, reception_id, product_id, file_name, content_type, size_bytes, storage_key, width, height,
+					thumbnail_key, thumbnail_content_type, thumbnail_width, thumbnail_height, created_by)
+				select $1, r.id, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14
+				from receptions r
+				where r.id = $2
+					and ($3::uuid is null or exists(
+						select 1
+						from reception_products rp
+						where rp.reception_id = r.id and rp.product_id = $3))
+				returning created_at`
+
+	var thumbKey, thumbType *string
+	var thumbWidth, thumbHeight *int
+	if thumb := attachment.Thumbnail; thumb != nil {
+		thumbKey, thumbType = &thumb.StorageKey, &thumb.ContentType
+		thumbWidth, thumbHeight = &thumb.Width, &thumb.Height
+	}
+
+	err := r.pool.QueryRow(ctx, query, attachment.Id, attachment.ReceptionId, attachment.ProductId, attachment.FileName,
+		attachment.ContentType, attachment.SizeBytes, attachment.StorageKey, attachment.Width, attachment.Height,
+		thumbKey, thumbType, thumbWidth, thumbHeight, attachment.CreatedBy).Scan(&attachment.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	return err
+}
+
+func (r *AttachmentRepo) GetAttachment(ctx context.Context, attachmentId uuid.UUID) (*models.Attachment, error) {
+	query := `select ` + attachmentColumns + `
+				from attachments
+				where id = $1`
+
+	attachment, err := scanAttachment(r.pool.QueryRow(ctx, query, attachmentId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return attachment, nil
+}
+
+// attachments of the reception and its products, only of one product if productId is set
+func (r *AttachmentRepo) ListAttachments(ctx context.Context, receptionId uuid.UUID, productId *uuid.UUID) ([]models.Attachment, error) {
+	query := `select ` + attachmentColumns + `
+				from attachments
+				where reception_id = $1 and ($2::uuid is null or product_id = $2)
+				order by created_at, id`
+
+	rows, err := r.pool.Query(ctx, query, receptionId, productId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.Attachment{}
+	for rows.Next() {
+		attachment, err := scanAttachment(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *attachment)
+	}
+	return out, rows.Err()
+}
+
+func scanAttachment(row pgx.Row) (*models.Attachment, error) {
+	var (
+		attachment              models.Attachment
+		thumbKey, thumbType     *string
+		thumbWidth, thumbHeight *int
+	)
+	err := row.Scan(&attachment.Id, &attachment.ReceptionId, &attachment.ProductId, &attachment.FileName, &attachment.ContentType,
+		&attachment.SizeBytes, &attachment.StorageKey, &attachment.Width, &attachment.Height,
+		&thumbKey, &thumbType, &thumbWidth, &thumbHeight, &attachment.CreatedAt, &attachment.CreatedBy)
+	if err != nil {
+		return nil, err
+	}
+	if thumbKey != nil {
+		attachment.Thumbnail = &models.AttachmentThumbnail{
+			Width:       *thumbWidth,
+			Height:      *thumbHeight,
+			ContentType: *thumbType,
+			StorageKey:  *thumbKey,
+		}
+	}
+	return &attachment, nil
+}
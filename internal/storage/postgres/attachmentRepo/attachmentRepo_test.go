@@ -0,0 +1,148 @@
+package attachmentRepo
+
+import (
+	"context"
+	"errors"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage/postgres"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDbPool struct {
+	mock.Mock
+	postgres.DBPool
+}
+
+type mockRow struct {
+	mock.Mock
+}
+
+func (m *mockDbPool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	callArgs := m.Called(append([]interface{}{ctx, sql}, args...)...)
+	return callArgs.Get(0).(pgx.Row)
+}
+
+func (m *mockRow) Scan(dest ...any) error {
+	args := m.Called(dest...)
+	return args.Error(0)
+}
+
+func TestCreateAttachment(t *testing.T) {
+	dbErr := errors.New("connection lost")
+	tests := []struct {
+		name      string
+		thumbnail *models.AttachmentThumbnail
+		scanError error
+		wantError error
+	}{
+		{
+			name:      "image with thumbnail",
+			thumbnail: &models.AttachmentThumbnail{Width: 256, Height: 128, ContentType: "image/jpeg", StorageKey: "attachments/1.thumb"},
+		},
+		{
+			name: "pdf",
+		},
+		{
+			name:      "reception or product not found",
+			scanError: pgx.ErrNoRows,
+			wantError: models.ErrNotFound,
+		},
+		{
+			name:      "db error",
+			scanError: dbErr,
+			wantError: dbErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			row := new(mockRow)
+			repo := NewAttachmentRepo(mockPool)
+
+			attachment := &models.Attachment{
+				Id:          uuid.New(),
+				ReceptionId: uuid.New(),
+				FileName:    "box.png",
+				ContentType: "image/png",
+				SizeBytes:   100,
+				StorageKey:  "attachments/1",
+				Thumbnail:   tt.thumbnail,
+			}
+			var thumbKey, thumbType any = (*string)(nil), (*string)(nil)
+			var thumbWidth, thumbHeight any = (*int)(nil), (*int)(nil)
+			if tt.thumbnail != nil {
+				thumbKey, thumbType = &tt.thumbnail.StorageKey, &tt.thumbnail.ContentType
+				thumbWidth, thumbHeight = &tt.thumbnail.Width, &tt.thumbnail.Height
+			}
+			createdAt := time.Now()
+
+			mockPool.On("QueryRow", ctx, mock.Anything, attachment.Id, attachment.ReceptionId, attachment.ProductId, attachment.FileName,
+				attachment.ContentType, attachment.SizeBytes, attachment.StorageKey, attachment.Width, attachment.Height,
+				thumbKey, thumbType, thumbWidth, thumbHeight, attachment.CreatedBy).Return(row)
+			row.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(0).(*time.Time) = createdAt
+			}).Return(tt.scanError)
+
+			err := repo.CreateAttachment(ctx, attachment)
+
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantError == nil {
+				require.Equal(t, createdAt, attachment.CreatedAt)
+			}
+			mockPool.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetAttachment(t *testing.T) {
+	ctx := context.Background()
+	attachmentId := uuid.New()
+
+	t.Run("with thumbnail", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		row := new(mockRow)
+		repo := NewAttachmentRepo(mockPool)
+
+		mockPool.On("QueryRow", ctx, mock.Anything, attachmentId).Return(row)
+		row.On("Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				*args.Get(0).(*uuid.UUID) = attachmentId
+				thumbKey, thumbType := "attachments/1.thumb", "image/jpeg"
+				thumbWidth, thumbHeight := 256, 128
+				*args.Get(9).(**string) = &thumbKey
+				*args.Get(10).(**string) = &thumbType
+				*args.Get(11).(**int) = &thumbWidth
+				*args.Get(12).(**int) = &thumbHeight
+			}).Return(nil)
+
+		attachment, err := repo.GetAttachment(ctx, attachmentId)
+
+		require.NoError(t, err)
+		require.Equal(t, attachmentId, attachment.Id)
+		require.Equal(t, &models.AttachmentThumbnail{Width: 256, Height: 128, ContentType: "image/jpeg", StorageKey: "attachments/1.thumb"}, attachment.Thumbnail)
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		row := new(mockRow)
+		repo := NewAttachmentRepo(mockPool)
+
+		mockPool.On("QueryRow", ctx, mock.Anything, attachmentId).Return(row)
+		row.On("Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(pgx.ErrNoRows)
+
+		_, err := repo.GetAttachment(ctx, attachmentId)
+
+		require.ErrorIs(t, err, models.ErrNotFound)
+	})
+}
@@ -0,0 +1,243 @@
+package cellRepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage/postgres"
+	"orderPickupPoint/internal/storage/postgres/sharedSql"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type CellRepo struct {
+	pool postgres.DBPool
+}
+
+func NewCellRepo(pool postgres.DBPool) *CellRepo {
+	return &CellRepo{
+		pool: pool,
+	}
+}
+
+// adds the cells to the layout of the pvz in one transaction, the reserved type is given by its code
+// or name. ErrUnknownProductType if a cell is reserved for a type that does not exist
+func (r *CellRepo) CreateCells(ctx context.Context, pvzId uuid.UUID, cells []models.StorageCellInputAPI) ([]models.StorageCellAPI, error) {
+	queryPvz := `select 1
+					from pvzs
+					where id = $1`
+
+	queryCreate := `insert into storage_cells(pvz_id, rack, shelf, cell, capacity, max_side_mm, type_id)
+					select $1, t.rack, t.shelf, t.cell, t.capacity, t.max_side_mm, pt.id
+					from unnest($2::text[], $3::text[], $4::text[], $5::int[], $6::int[], $7::text[])
+						with ordinality t(rack, shelf, cell, capacity, max_side_mm, type_key, n)
+					left join product_types pt on pt.id = ` + sharedSql.ProductTypeIdByKey("t.type_key") + `
+					where t.type_key is null or pt.id is not null
+					returning id, code, rack, shelf, cell`
+
+	n := len(cells)
+	racks, shelves, codes := make([]string, n), make([]string, n), make([]string, n)
+	capacities, maxSides, types := make([]int, n), make([]*int, n), make([]*string, n)
+	for i, cell := range cells {
+		racks[i], shelves[i], codes[i] = cell.Rack, cell.Shelf, cell.Cell
+		capacities[i], maxSides[i], types[i] = cell.Capacity, cell.MaxSideMm, cell.Type
+	}
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var one int
+	err = tx.QueryRow(ctx, queryPvz, pvzId).Scan(&one)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, queryCreate, pvzId, racks, shelves, codes, capacities, maxSides, types)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	// returned rows come in no particular order, they are matched to the input by position in the layout
+	byPosition := make(map[[3]string]int, n)
+	for i, cell := range cells {
+		byPosition[[3]string{cell.Rack, cell.Shelf, cell.Cell}] = i
+	}
+
+	out := make([]models.StorageCellAPI, n)
+	created := 0
+	for rows.Next() {
+		var (
+			id                 uuid.UUID
+			code               string
+			rack, shelf, place string
+		)
+		if err := rows.Scan(&id, &code, &rack, &shelf, &place); err != nil {
+			return nil, err
+		}
+		i, ok := byPosition[[3]string{rack, shelf, place}]
+		if !ok {
+			return nil, fmt.Errorf("created cell %s is not in the input", code)
+		}
+		cell := cells[i]
+		out[i] = models.StorageCellAPI{
+			Id:        id,
+			PvzId:     pvzId,
+			Rack:      cell.Rack,
+			Shelf:     cell.Shelf,
+			Cell:      cell.Cell,
+			Code:      code,
+			Capacity:  cell.Capacity,
+			MaxSideMm: cell.MaxSideMm,
+			Type:      cell.Type,
+			Active:    true,
+		}
+		created++
+	}
+	if err := rows.Err(); postgres.IsUniqueViolation(err) {
+		return nil, models.ErrCellExists
+	} else if err != nil {
+		return nil, err
+	}
+	if created != n {
+		// rows of cells with an unknown type were filtered out
+		return nil, models.ErrUnknownProductType
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// layout of the pvz with the number of products stored in every cell
+func (r *CellRepo) ListCells(ctx context.Context, pvzId uuid.UUID) ([]models.StorageCellAPI, error) {
+	query := `select c.id, c.pvz_id, c.code, c.rack, c.shelf, c.cell, c.capacity, c.max_side_mm, pt.name, c.active,
+					` + sharedSql.CellItems + `
+				from storage_cells c
+				left join product_types pt on pt.id = c.type_id
+				where c.pvz_id = $1
+				order by c.rack, c.shelf, c.cell`
+
+	rows, err := r.pool.Query(ctx, query, pvzId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.StorageCellAPI{}
+	for rows.Next() {
+		var cell models.StorageCellAPI
+		err := rows.Scan(&cell.Id, &cell.PvzId, &cell.Code, &cell.Rack, &cell.Shelf, &cell.Cell, &cell.Capacity,
+			&cell.MaxSideMm, &cell.Type, &cell.Active, &cell.Items)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, cell)
+	}
+	return out, rows.Err()
+}
+
+// changes the capacity and the activity of the cell. The capacity can not go below the products
+// already stored, a deactivated cell keeps its products but takes no new ones
+func (r *CellRepo) UpdateCell(ctx context.Context, cellId uuid.UUID, update *models.StorageCellUpdateAPI) error {
+	queryCell := `select ` + sharedSql.CellItems + `
+					from storage_cells c
+					where c.id = $1
+					for update of c`
+
+	queryUpdate := `update storage_cells
+					set capacity = coalesce($2, capacity), active = coalesce($3, active)
+					where id = $1`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var items int
+	err = tx.QueryRow(ctx, queryCell, cellId).Scan(&items)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if update.Capacity != nil && *update.Capacity < items {
+		return models.ErrCellFull
+	}
+
+	_, err = tx.Exec(ctx, queryUpdate, cellId, update.Capacity, update.Active)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// moves a product present in the pvz to another cell of the same pvz
+func (r *CellRepo) MoveProduct(ctx context.Context, move *models.ProductMove) error {
+	queryProduct := `select 1
+						from products
+						where id = $1 and deleted_at is null and issued_at is null
+						for update`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var one int
+	err = tx.QueryRow(ctx, queryProduct, move.ProductId).Scan(&one)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = sharedSql.PlaceProduct(ctx, tx, move.ProductId, &move.CellCode, move.ActorId)
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// products present in the pvz matching the filter with their cells
+func (r *CellRepo) FindPlacements(ctx context.Context, filter *models.PlacementFilter) ([]models.PlacementAPI, error) {
+	query := `select ` + sharedSql.PlacementColumns + `
+				` + sharedSql.PlacementFrom + `
+				where prod.pvz_id = $1 and prod.deleted_at is null and prod.issued_at is null
+					and ($2::text is null or prod.barcode = $2)
+					and ($3::text is null or prod.external_order_id = $3)
+					and ($4::uuid is null or exists(
+						select 1
+						from orders o
+						where o.id = $4 and o.pvz_id = prod.pvz_id and o.external_id = prod.external_order_id))
+				order by prod.added_at, prod.id`
+
+	rows, err := r.pool.Query(ctx, query, filter.PvzId, filter.Barcode, filter.ExternalOrderId, filter.OrderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.PlacementAPI{}
+	for rows.Next() {
+		placement, err := sharedSql.ScanPlacement(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *placement)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,272 @@
+package cellRepo
+
+import (
+	"context"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage/postgres"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDbPool struct {
+	mock.Mock
+	postgres.DBPool
+}
+
+type mockDbTx struct {
+	mock.Mock
+	postgres.Tx
+}
+
+type mockRow struct {
+	mock.Mock
+}
+
+func (m *mockDbPool) Begin(ctx context.Context) (postgres.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(postgres.Tx), args.Error(1)
+}
+
+func (m *mockDbTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	callArgs := m.Called(append([]interface{}{ctx, sql}, args...)...)
+	return callArgs.Get(0).(pgx.Row)
+}
+
+func (m *mockDbTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	callArgs := m.Called(append([]interface{}{ctx, sql}, args...)...)
+	return callArgs.Get(0).(pgconn.CommandTag), callArgs.Error(1)
+}
+
+func (m *mockDbTx) Commit(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func (m *mockDbTx) Rollback(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func (m *mockRow) Scan(dest ...any) error {
+	args := m.Called(dest...)
+	return args.Error(0)
+}
+
+func (m *mockDbTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	callArgs := m.Called(append([]interface{}{ctx, sql}, args...)...)
+	return callArgs.Get(0).(pgx.Rows), callArgs.Error(1)
+}
+
+// returns the given rows one by one, values are assigned to the scan destinations in order
+type fakeRows struct {
+	pgx.Rows
+	rows [][]any
+	next int
+}
+
+func (r *fakeRows) Next() bool {
+	r.next++
+	return r.next <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	for i, value := range r.rows[r.next-1] {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+
+func (r *fakeRows) Close() {}
+
+func TestCreateCells(t *testing.T) {
+	ctx := context.Background()
+	mockPool := new(mockDbPool)
+	mockTx := new(mockDbTx)
+	pvzRow := new(mockRow)
+	repo := NewCellRepo(mockPool)
+
+	pvzId := uuid.New()
+	cells := []models.StorageCellInputAPI{
+		{Rack: "A", Shelf: "1", Cell: "1", Capacity: 4},
+		{Rack: "A", Shelf: "1", Cell: "2", Capacity: 1},
+		{Rack: "B", Shelf: "2", Cell: "1", Capacity: 2},
+	}
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+
+	mockPool.On("Begin", ctx).Return(mockTx, nil)
+	mockTx.On("Rollback", ctx).Return(nil)
+	mockTx.On("QueryRow", ctx, mock.Anything, pvzId).Return(pvzRow)
+	pvzRow.On("Scan", mock.Anything).Return(nil)
+	// the reserved type is looked up by its code or name
+	typeByKey := mock.MatchedBy(func(sql string) bool {
+		return strings.Contains(sql, "kt.code = t.type_key or kt.name = t.type_key")
+	})
+	// insert ... returning gives no order guarantee
+	mockTx.On("Query", ctx, typeByKey, pvzId, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(&fakeRows{rows: [][]any{
+			{ids[2], "B-2-1", "B", "2", "1"},
+			{ids[0], "A-1-1", "A", "1", "1"},
+			{ids[1], "A-1-2", "A", "1", "2"},
+		}}, nil)
+	mockTx.On("Commit", ctx).Return(nil)
+
+	created, err := repo.CreateCells(ctx, pvzId, cells)
+
+	require.NoError(t, err)
+	require.Len(t, created, 3)
+	for i, cell := range created {
+		require.Equal(t, ids[i], cell.Id)
+		require.Equal(t, cells[i].Rack+"-"+cells[i].Shelf+"-"+cells[i].Cell, cell.Code)
+		require.Equal(t, cells[i].Capacity, cell.Capacity)
+	}
+	mockTx.AssertExpectations(t)
+}
+
+func TestUpdateCell(t *testing.T) {
+	tests := []struct {
+		name      string
+		capacity  int
+		items     int
+		mockError error
+		wantError error
+	}{
+		{
+			name:     "capacity above the stored products",
+			capacity: 5,
+			items:    3,
+		},
+		{
+			name:     "capacity equal to the stored products",
+			capacity: 3,
+			items:    3,
+		},
+		{
+			name:      "capacity below the stored products",
+			capacity:  2,
+			items:     3,
+			wantError: models.ErrCellFull,
+		},
+		{
+			name:      "unknown cell",
+			capacity:  5,
+			mockError: pgx.ErrNoRows,
+			wantError: models.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			repo := NewCellRepo(mockPool)
+			pgxRow := new(mockRow)
+			cellId := uuid.New()
+			update := &models.StorageCellUpdateAPI{Capacity: &tt.capacity}
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("QueryRow", ctx, mock.Anything, cellId).Return(pgxRow)
+			pgxRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+				reflect.ValueOf(args[0]).Elem().Set(reflect.ValueOf(tt.items))
+			}).Return(tt.mockError)
+			if tt.wantError == nil {
+				mockTx.On("Exec", ctx, mock.Anything, cellId, update.Capacity, update.Active).Return(pgconn.CommandTag{}, nil)
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			err := repo.UpdateCell(ctx, cellId, update)
+			require.ErrorIs(t, err, tt.wantError)
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMoveProduct(t *testing.T) {
+	tests := []struct {
+		name         string
+		productError error
+		fits         bool
+		items        int
+		sameCell     bool
+		wantError    error
+	}{
+		{
+			name:  "free cell",
+			fits:  true,
+			items: 1,
+		},
+		{
+			name:     "already in the cell",
+			fits:     true,
+			items:    2,
+			sameCell: true,
+		},
+		{
+			name:      "full cell",
+			fits:      true,
+			items:     2,
+			wantError: models.ErrCellFull,
+		},
+		{
+			name:      "product does not fit",
+			items:     0,
+			wantError: models.ErrCellMismatch,
+		},
+		{
+			name:         "product not present",
+			productError: pgx.ErrNoRows,
+			wantError:    models.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			repo := NewCellRepo(mockPool)
+			productRow := new(mockRow)
+			cellRow := new(mockRow)
+			actorId := 5
+			move := &models.ProductMove{ProductId: uuid.New(), CellCode: "A-1-2", ActorId: &actorId}
+			cellId, fromCell := uuid.New(), uuid.New()
+			if tt.sameCell {
+				fromCell = cellId
+			}
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("QueryRow", ctx, mock.Anything, move.ProductId).Return(productRow)
+			productRow.On("Scan", mock.Anything).Return(tt.productError)
+			if tt.productError == nil {
+				mockTx.On("QueryRow", ctx, mock.Anything, move.ProductId, "A-1-2").Return(cellRow)
+				cellRow.On("Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					reflect.ValueOf(args[0]).Elem().Set(reflect.ValueOf(cellId))
+					reflect.ValueOf(args[1]).Elem().Set(reflect.ValueOf(tt.fits))
+					reflect.ValueOf(args[2]).Elem().Set(reflect.ValueOf(tt.items))
+					reflect.ValueOf(args[3]).Elem().Set(reflect.ValueOf(2))
+					reflect.ValueOf(args[4]).Elem().Set(reflect.ValueOf(&fromCell))
+				}).Return(nil)
+			}
+			if tt.wantError == nil && !tt.sameCell {
+				mockTx.On("Exec", ctx, mock.Anything, move.ProductId, cellId).Return(pgconn.CommandTag{}, nil)
+				mockTx.On("Exec", ctx, mock.Anything, move.ProductId, &fromCell, cellId, &actorId).Return(pgconn.CommandTag{}, nil)
+			}
+			if tt.wantError == nil {
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			err := repo.MoveProduct(ctx, move)
+			require.ErrorIs(t, err, tt.wantError)
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
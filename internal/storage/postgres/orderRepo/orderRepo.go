@@ -0,0 +1,512 @@
+package orderRepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage/postgres"
+	"orderPickupPoint/internal/utils/cursor"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type OrderRepo struct {
+	pool postgres.DBPool
+}
+
+func NewOrderRepo(pool postgres.DBPool) *OrderRepo {
+	return &OrderRepo{
+		pool: pool,
+	}
+}
+
+// order fields with the numbers of arrived and issued items
+const orderColumns = `o.id, o.external_id, o.pvz_id, o.customer_name, o.customer_phone, os.name, o.status_id,
+					o.expected_items, coalesce(i.arrived, 0), coalesce(i.issued, 0), o.created_at, o.created_by, o.issued_at,
+					o.pickup_code_created_at, o.pickup_code_attempts`
+
+const orderFrom = `from orders o
+				join order_statuses os on os.id = o.status_id
+				left join lateral (
+					select count(*) as arrived, count(prod.issued_at) as issued
+					from products prod
+					where prod.external_order_id = o.external_id and prod.pvz_id = o.pvz_id and prod.deleted_at is null) i on true`
+
+// creates the order as ready if all its items are already accepted in the pvz.
+// ErrNotFound if the pvz does not exist
+func (r *OrderRepo) CreateOrder(ctx context.Context, order *models.Order) (uuid.UUID, error) {
+	query := `insert into orders(external_id, pvz_id, customer_name, customer_phone, expected_items, created_by, status_id)
+				select $1, p.id, $3, $4, $5, $6,
+					case when (
+						select count(*)
+						from products prod
+						where prod.pvz_id = p.id and prod.external_order_id = $1 and prod.deleted_at is null) >= $5
+					then $8::int else $7::int end
+				from pvzs p
+				where p.id = $2
+				returning id, status_id`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		orderId  uuid.UUID
+		statusId int
+	)
+	err = tx.QueryRow(ctx, query, order.ExternalId, order.PvzId, order.CustomerName, order.CustomerPhone,
+		order.ExpectedItems, order.CreatedBy, models.OrderStatusAwaiting, models.OrderStatusReady).Scan(&orderId, &statusId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, models.ErrNotFound
+	}
+	if postgres.IsUniqueViolation(err) {
+		return uuid.Nil, models.ErrOrderExists
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = writeHistory(ctx, tx, &models.OrderTransition{
+		OrderId:    orderId,
+		ToStatusId: statusId,
+		ActorId:    order.CreatedBy,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return orderId, tx.Commit(ctx)
+}
+
+func (r *OrderRepo) GetOrder(ctx context.Context, orderId uuid.UUID) (*models.OrderAPI, error) {
+	query := fmt.Sprintf(`select %s
+				%s
+				where o.id = $1`, orderColumns, orderFrom)
+
+	order, err := scanOrder(r.pool.QueryRow(ctx, query, orderId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// orders of a pickup point from newest to oldest, page by offset or by cursor
+func (r *OrderRepo) ListOrders(ctx context.Context, filter *models.OrderFilter) (*models.ListResult[models.OrderAPI], error) {
+	queryFilter := `where o.pvz_id = $1
+					and ($2::text[] is null or os.name = any($2))`
+
+	args := []any{filter.PvzId, filter.Statuses}
+
+	result := &models.ListResult[models.OrderAPI]{}
+	err := r.pool.QueryRow(ctx, `select count(*)
+				from orders o
+				join order_statuses os on os.id = o.status_id
+				`+queryFilter, args...).Scan(&result.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	keysetMatch := ""
+	direction := "desc"
+	offset := 0
+	if filter.Cursor != nil {
+		if !filter.Cursor.Matches(models.OrderSortByDate, true) {
+			return nil, models.ErrInvalidFilter
+		}
+		op := "<"
+		if filter.Cursor.Backward {
+			op = ">"
+			direction = "asc"
+		}
+		keysetMatch = fmt.Sprintf("\n\t\t\t\t\tand (o.created_at, o.id) %s ($5::timestamptz, $6::uuid)", op)
+		args = append(args, filter.PageLimit+1, offset, filter.Cursor.SortKey, filter.Cursor.Id)
+	} else {
+		offset = filter.PageLimit * (filter.Page - 1)
+		args = append(args, filter.PageLimit+1, offset)
+	}
+
+	query := fmt.Sprintf(`select %s
+				%s
+				%s%s
+				order by o.created_at %s, o.id %s
+				limit $3 offset $4`, orderColumns, orderFrom, queryFilter, keysetMatch, direction, direction)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orders []models.OrderAPI
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, *order)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result.Items, result.Next, result.Prev = cursor.Slice(orders, filter.PageLimit, filter.Cursor, offset, func(order models.OrderAPI) cursor.Cursor {
+		return cursor.Cursor{
+			SortBy:  models.OrderSortByDate,
+			Desc:    true,
+			SortKey: order.CreatedAt.Format(time.RFC3339Nano),
+			Id:      order.Id.String(),
+		}
+	})
+	return result, nil
+}
+
+func scanOrder(row pgx.Row) (*models.OrderAPI, error) {
+	order := &models.OrderAPI{}
+	err := row.Scan(&order.Id, &order.ExternalId, &order.PvzId, &order.CustomerName, &order.CustomerPhone, &order.Status, &order.StatusId,
+		&order.ExpectedItems, &order.ArrivedItems, &order.IssuedItems, &order.CreatedAt, &order.CreatedBy, &order.IssuedAt,
+		&order.PickupCodeCreatedAt, &order.PickupCodeAttempts)
+	if err != nil {
+		return nil, err
+	}
+	return order, nil
+}
+
+// accepted products of the order in the order of acceptance
+func (r *OrderRepo) GetOrderItems(ctx context.Context, orderId uuid.UUID) ([]models.OrderItemAPI, error) {
+	query := `select prod.id, prod.added_at, pt.name, prod.added_by, prod.barcode, prod.external_order_id, prod.duplicate,
+					prod.weight_grams, prod.length_mm, prod.width_mm, prod.height_mm, prod.declared_value,
+					pc.name, prod.condition_notes, rp.reception_id, prod.issued_at, prod.issued_by,
+					prod.issued_at is null and r.status_id in ($2, $3),
+					case when prod.issued_at is null then sc.code end
+				from orders o
+				join products prod on prod.external_order_id = o.external_id and prod.pvz_id = o.pvz_id
+				join product_types pt on pt.id = prod.type_id
+				join product_conditions pc on pc.id = prod.condition_id
+				join reception_products rp on rp.product_id = prod.id
+				join receptions r on r.id = rp.reception_id
+				left join storage_cells sc on sc.id = prod.cell_id
+				where o.id = $1 and prod.deleted_at is null
+				order by prod.added_at, prod.id`
+
+	rows, err := r.pool.Query(ctx, query, orderId, models.ReceptionStatusClosed, models.ReceptionStatusVerified)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.OrderItemAPI{}
+	for rows.Next() {
+		var item models.OrderItemAPI
+		err := rows.Scan(&item.ID, &item.AddedAt, &item.Type, &item.AddedBy, &item.Barcode, &item.ExternalOrderId, &item.Duplicate,
+			&item.WeightGrams, &item.LengthMm, &item.WidthMm, &item.HeightMm, &item.DeclaredValue,
+			&item.Condition, &item.Notes, &item.ReceptionId, &item.IssuedAt, &item.IssuedBy, &item.Issuable,
+			&item.Cell)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+// hands the products to the customer and takes them out of the pvz stock.
+// The order becomes issued when all expected items are issued, partially_issued otherwise.
+// ErrItemNotIssuable if any of the products is not an issuable item of the order anymore
+func (r *OrderRepo) IssueProducts(ctx context.Context, issue *models.OrderIssue) error {
+	// locks the order so concurrent issuances are serialized
+	queryOrder := `select status_id, pvz_id, external_id, expected_items
+					from orders
+					where id = $1
+					for update`
+
+	queryIssue := `update products prod
+					set issued_at = now(), issued_by = $4
+					from reception_products rp
+					join receptions r on r.id = rp.reception_id
+					where prod.id = any($1) and rp.product_id = prod.id
+						and prod.pvz_id = $2 and prod.external_order_id = $3
+						and prod.deleted_at is null and prod.issued_at is null and r.status_id in ($5, $6)
+					returning prod.type_id`
+
+	queryDecStock := `update pvz_stock s
+						set items = s.items - c.items
+						from (
+							select type_id, count(*) as items
+							from unnest($2::int[]) t(type_id)
+							group by type_id) c
+						where s.pvz_id = $1 and s.type_id = c.type_id`
+
+	queryIssued := `select count(*)
+					from products
+					where pvz_id = $1 and external_order_id = $2 and deleted_at is null and issued_at is not null`
+
+	// the pickup code is used up once the whole order is issued
+	queryStatus := `update orders
+					set status_id = $2, issued_at = case when $2 = $3 then now() end,
+						pickup_code_hash = case when $2 = $3 then null else pickup_code_hash end,
+						pickup_code_created_at = case when $2 = $3 then null else pickup_code_created_at end,
+						pickup_code_attempts = 0
+					where id = $1`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		statusId      int
+		pvzId         uuid.UUID
+		externalId    string
+		expectedItems int
+	)
+	err = tx.QueryRow(ctx, queryOrder, issue.OrderId).Scan(&statusId, &pvzId, &externalId, &expectedItems)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	switch statusId {
+	case models.OrderStatusReady, models.OrderStatusPartiallyIssued:
+	case models.OrderStatusAwaiting:
+		return models.ErrOrderNotReady
+	default:
+		return models.ErrOrderClosed
+	}
+
+	rows, err := tx.Query(ctx, queryIssue, issue.ProductIds, pvzId, externalId, issue.ActorId,
+		models.ReceptionStatusClosed, models.ReceptionStatusVerified)
+	if err != nil {
+		return err
+	}
+	var typeIds []int
+	for rows.Next() {
+		var typeId int
+		if err := rows.Scan(&typeId); err != nil {
+			rows.Close()
+			return err
+		}
+		typeIds = append(typeIds, typeId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(typeIds) != len(issue.ProductIds) {
+		// issued, removed or moved meanwhile
+		return models.ErrItemNotIssuable
+	}
+
+	_, err = tx.Exec(ctx, queryDecStock, pvzId, typeIds)
+	if err != nil {
+		return err
+	}
+
+	var issued int
+	err = tx.QueryRow(ctx, queryIssued, pvzId, externalId).Scan(&issued)
+	if err != nil {
+		return err
+	}
+	toStatusId := models.OrderStatusPartiallyIssued
+	if issued >= expectedItems {
+		toStatusId = models.OrderStatusIssued
+	}
+
+	_, err = tx.Exec(ctx, queryStatus, issue.OrderId, toStatusId, models.OrderStatusIssued)
+	if err != nil {
+		return err
+	}
+
+	err = writeHistory(ctx, tx, &models.OrderTransition{
+		OrderId:      issue.OrderId,
+		FromStatusId: statusId,
+		ToStatusId:   toStatusId,
+		ActorId:      issue.ActorId,
+		Comment:      fmt.Sprintf("issued %d of %d items", issued, expectedItems),
+	})
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// moves the order to the new status only if it still has the expected one
+// and writes the change to the history
+func (r *OrderRepo) ChangeOrderStatus(ctx context.Context, transition *models.OrderTransition) error {
+	query := `update orders
+				set status_id = $3,
+					pickup_code_hash = case when $3 in ($4, $5) then null else pickup_code_hash end,
+					pickup_code_created_at = case when $3 in ($4, $5) then null else pickup_code_created_at end
+				where id = $1 and status_id = $2`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, query, transition.OrderId, transition.FromStatusId, transition.ToStatusId,
+		models.OrderStatusIssued, models.OrderStatusCancelled)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		// changed meanwhile
+		return models.ErrOrderTransition
+	}
+
+	err = writeHistory(ctx, tx, transition)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ready orders which have not got a pickup code yet, oldest first
+func (r *OrderRepo) ListOrdersWithoutPickupCode(ctx context.Context, limit int) ([]models.OrderAPI, error) {
+	query := fmt.Sprintf(`select %s
+				%s
+				where o.status_id = $2 and o.pickup_code_hash is null
+				order by o.created_at, o.id
+				limit $1`, orderColumns, orderFrom)
+
+	rows, err := r.pool.Query(ctx, query, limit, models.OrderStatusReady)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.OrderAPI{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *order)
+	}
+	return out, rows.Err()
+}
+
+// stores the hash of a new pickup code and resets the attempts, the change is written to the order events.
+// Reports false if the order already has a code and the change does not replace it
+func (r *OrderRepo) SetPickupCode(ctx context.Context, change *models.PickupCodeChange) (bool, error) {
+	queryOrder := `select status_id, pickup_code_hash is not null
+					from orders
+					where id = $1
+					for update`
+
+	query := `update orders
+				set pickup_code_hash = $2, pickup_code_created_at = now(), pickup_code_attempts = 0
+				where id = $1`
+
+	queryEvent := `insert into order_events(order_id, event, created_by, comment)
+					values ($1, $2, $3, $4)`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		statusId int
+		hasCode  bool
+	)
+	err = tx.QueryRow(ctx, queryOrder, change.OrderId).Scan(&statusId, &hasCode)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, models.ErrNotFound
+	}
+	if err != nil {
+		return false, err
+	}
+	switch statusId {
+	case models.OrderStatusReady, models.OrderStatusPartiallyIssued:
+	case models.OrderStatusAwaiting:
+		return false, models.ErrOrderNotReady
+	default:
+		return false, models.ErrOrderClosed
+	}
+	if hasCode && !change.Replace {
+		return false, nil
+	}
+
+	_, err = tx.Exec(ctx, query, change.OrderId, change.Hash)
+	if err != nil {
+		return false, err
+	}
+
+	event := models.OrderEventPickupCodeSet
+	if hasCode {
+		event = models.OrderEventPickupCodeReplaced
+	}
+	_, err = tx.Exec(ctx, queryEvent, change.OrderId, event, change.ActorId, change.Comment)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit(ctx)
+}
+
+// counts an attempt to present the pickup code before it is checked, so parallel guesses
+// cannot exceed the limit. ErrPickupCodeLocked if the order has no code or no attempts left
+func (r *OrderRepo) ClaimPickupCodeAttempt(ctx context.Context, orderId uuid.UUID, maxAttempts int) (*models.PickupCode, error) {
+	query := `update orders
+				set pickup_code_attempts = pickup_code_attempts + 1
+				where id = $1 and pickup_code_hash is not null and pickup_code_attempts < $2
+				returning pickup_code_hash, pickup_code_attempts`
+
+	code := &models.PickupCode{}
+	err := r.pool.QueryRow(ctx, query, orderId, maxAttempts).Scan(&code.Hash, &code.Attempts)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrPickupCodeLocked
+	}
+	if err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+// zero FromStatusId is written as the initial record
+func writeHistory(ctx context.Context, tx postgres.Tx, transition *models.OrderTransition) error {
+	query := `insert into order_status_history(order_id, from_status_id, to_status_id, changed_by, comment)
+				values ($1, nullif($2, 0), $3, $4, $5)`
+
+	_, err := tx.Exec(ctx, query, transition.OrderId, transition.FromStatusId, transition.ToStatusId, transition.ActorId, transition.Comment)
+	return err
+}
+
+func (r *OrderRepo) GetOrderHistory(ctx context.Context, orderId uuid.UUID) ([]models.OrderHistoryItem, error) {
+	query := `select fs.name, ts.name, h.changed_at, h.changed_by, h.comment
+				from order_status_history h
+				left join order_statuses fs on fs.id = h.from_status_id
+				join order_statuses ts on ts.id = h.to_status_id
+				where h.order_id = $1
+				order by h.changed_at, h.id`
+
+	rows, err := r.pool.Query(ctx, query, orderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.OrderHistoryItem{}
+	for rows.Next() {
+		var item models.OrderHistoryItem
+		if err := rows.Scan(&item.From, &item.To, &item.ChangedAt, &item.ChangedBy, &item.Comment); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
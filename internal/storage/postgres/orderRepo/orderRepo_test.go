@@ -0,0 +1,364 @@
+package orderRepo
+
+import (
+	"context"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage/postgres"
+	"orderPickupPoint/internal/utils/cursor"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDbPool struct {
+	mock.Mock
+	postgres.DBPool
+}
+
+type mockDbTx struct {
+	mock.Mock
+	postgres.Tx
+}
+
+type mockRow struct {
+	mock.Mock
+}
+
+func (m *mockDbPool) Begin(ctx context.Context) (postgres.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(postgres.Tx), args.Error(1)
+}
+
+func (m *mockDbPool) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	callArgs := m.Called(append([]interface{}{ctx, sql}, args...)...)
+	return callArgs.Get(0).(pgx.Row)
+}
+
+func (m *mockDbTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	callArgs := m.Called(append([]interface{}{ctx, sql}, args...)...)
+	return callArgs.Get(0).(pgx.Row)
+}
+
+func (m *mockDbTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	callArgs := m.Called(append([]interface{}{ctx, sql}, args...)...)
+	return callArgs.Get(0).(pgconn.CommandTag), callArgs.Error(1)
+}
+
+func (m *mockDbTx) Commit(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *mockDbTx) Rollback(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *mockRow) Scan(dest ...any) error {
+	args := m.Called(dest...)
+	return args.Error(0)
+}
+
+func (m *mockDbPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	callArgs := m.Called(append([]interface{}{ctx, sql}, args...)...)
+	return callArgs.Get(0).(pgx.Rows), callArgs.Error(1)
+}
+
+// rows of a query that found nothing
+type fakeRows struct {
+	pgx.Rows
+}
+
+func (r *fakeRows) Next() bool { return false }
+
+func (r *fakeRows) Err() error { return nil }
+
+func (r *fakeRows) Close() {}
+
+func TestCreateOrder(t *testing.T) {
+	tests := []struct {
+		name      string
+		scanErr   error
+		wantError error
+	}{
+		{
+			name: "created",
+		},
+		{
+			name:      "unknown pvz",
+			scanErr:   pgx.ErrNoRows,
+			wantError: models.ErrNotFound,
+		},
+		{
+			name:      "active order with the same external id",
+			scanErr:   &pgconn.PgError{Code: "23505"},
+			wantError: models.ErrOrderExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			row := new(mockRow)
+			repo := NewOrderRepo(mockPool)
+
+			actorId := 2
+			order := &models.Order{ExternalId: "WB-100500", PvzId: uuid.New(), ExpectedItems: 2, CreatedBy: &actorId}
+			orderId := uuid.New()
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("QueryRow", ctx, mock.Anything, order.ExternalId, order.PvzId, "", "", 2, &actorId, models.OrderStatusAwaiting, models.OrderStatusReady).Return(row)
+			row.On("Scan", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				*args[0].(*uuid.UUID) = orderId
+				*args[1].(*int) = models.OrderStatusAwaiting
+			}).Return(tt.scanErr)
+			if tt.wantError == nil {
+				// initial history record
+				mockTx.On("Exec", ctx, mock.Anything, orderId, 0, models.OrderStatusAwaiting, &actorId, "").Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			id, err := repo.CreateOrder(ctx, order)
+
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantError == nil {
+				require.Equal(t, orderId, id)
+			}
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListOrders(t *testing.T) {
+	ctx := context.Background()
+	pvzId := uuid.New()
+	position := &cursor.Cursor{SortBy: models.OrderSortByDate, Desc: true, SortKey: "2025-05-01T10:00:00Z", Id: uuid.NewString()}
+
+	tests := []struct {
+		name      string
+		cursor    *cursor.Cursor
+		page      int
+		wantArgs  []any
+		wantOrder string
+		wantError error
+	}{
+		{
+			name:      "by offset",
+			page:      3,
+			wantArgs:  []any{6, 10},
+			wantOrder: "o.created_at desc, o.id desc",
+		},
+		{
+			name:      "after the cursor",
+			cursor:    position,
+			wantArgs:  []any{6, 0, position.SortKey, position.Id},
+			wantOrder: "o.created_at desc, o.id desc",
+		},
+		{
+			name:      "before the cursor",
+			cursor:    &cursor.Cursor{SortBy: position.SortBy, Desc: true, SortKey: position.SortKey, Id: position.Id, Backward: true},
+			wantArgs:  []any{6, 0, position.SortKey, position.Id},
+			wantOrder: "o.created_at asc, o.id asc",
+		},
+		{
+			name:      "cursor of another list",
+			cursor:    &cursor.Cursor{SortBy: models.ReceptionSortByDate, Desc: true, Id: position.Id},
+			wantError: models.ErrInvalidFilter,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockPool := new(mockDbPool)
+			countRow := new(mockRow)
+			repo := NewOrderRepo(mockPool)
+
+			filter := &models.OrderFilter{PvzId: pvzId, Cursor: tt.cursor, Page: tt.page, PageLimit: 5}
+
+			mockPool.On("QueryRow", ctx, mock.Anything, pvzId, filter.Statuses).Return(countRow)
+			countRow.On("Scan", mock.Anything).Return(nil)
+			if tt.wantError == nil {
+				isPageQuery := mock.MatchedBy(func(sql string) bool { return strings.Contains(sql, tt.wantOrder) })
+				mockPool.On("Query", append([]any{ctx, isPageQuery, pvzId, filter.Statuses}, tt.wantArgs...)...).Return(&fakeRows{}, nil)
+			}
+
+			result, err := repo.ListOrders(ctx, filter)
+
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantError == nil {
+				require.Empty(t, result.Items)
+				require.Nil(t, result.Next)
+			}
+			mockPool.AssertExpectations(t)
+		})
+	}
+}
+
+func TestIssueProducts_OrderStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		statusId  int
+		scanErr   error
+		wantError error
+	}{
+		{name: "unknown order", scanErr: pgx.ErrNoRows, wantError: models.ErrNotFound},
+		{name: "awaiting items", statusId: models.OrderStatusAwaiting, wantError: models.ErrOrderNotReady},
+		{name: "issued", statusId: models.OrderStatusIssued, wantError: models.ErrOrderClosed},
+		{name: "cancelled", statusId: models.OrderStatusCancelled, wantError: models.ErrOrderClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			row := new(mockRow)
+			repo := NewOrderRepo(mockPool)
+
+			issue := &models.OrderIssue{OrderId: uuid.New(), ProductIds: []uuid.UUID{uuid.New()}}
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("QueryRow", ctx, mock.Anything, issue.OrderId).Return(row)
+			row.On("Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				*args[0].(*int) = tt.statusId
+			}).Return(tt.scanErr)
+
+			err := repo.IssueProducts(ctx, issue)
+
+			require.ErrorIs(t, err, tt.wantError)
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChangeOrderStatus(t *testing.T) {
+	ctx := context.Background()
+	transition := &models.OrderTransition{
+		OrderId:      uuid.New(),
+		FromStatusId: models.OrderStatusReady,
+		ToStatusId:   models.OrderStatusCancelled,
+		Comment:      "отказ клиента",
+	}
+
+	t.Run("changed", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		mockTx := new(mockDbTx)
+		repo := NewOrderRepo(mockPool)
+
+		mockPool.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+		mockTx.On("Exec", ctx, mock.Anything, transition.OrderId, transition.FromStatusId, transition.ToStatusId, models.OrderStatusIssued, models.OrderStatusCancelled).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+		mockTx.On("Exec", ctx, mock.Anything, transition.OrderId, transition.FromStatusId, transition.ToStatusId, transition.ActorId, transition.Comment).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+		mockTx.On("Commit", ctx).Return(nil)
+
+		require.NoError(t, repo.ChangeOrderStatus(ctx, transition))
+		mockTx.AssertExpectations(t)
+	})
+
+	t.Run("changed meanwhile", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		mockTx := new(mockDbTx)
+		repo := NewOrderRepo(mockPool)
+
+		mockPool.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+		mockTx.On("Exec", ctx, mock.Anything, transition.OrderId, transition.FromStatusId, transition.ToStatusId, models.OrderStatusIssued, models.OrderStatusCancelled).Return(pgconn.NewCommandTag("UPDATE 0"), nil)
+
+		require.ErrorIs(t, repo.ChangeOrderStatus(ctx, transition), models.ErrOrderTransition)
+		mockTx.AssertExpectations(t)
+	})
+}
+
+func TestSetPickupCode(t *testing.T) {
+	tests := []struct {
+		name      string
+		statusId  int
+		hasCode   bool
+		replace   bool
+		wantSet   bool
+		wantEvent string
+		wantError error
+	}{
+		{name: "first code", statusId: models.OrderStatusReady, wantSet: true, wantEvent: models.OrderEventPickupCodeSet},
+		{name: "code is kept", statusId: models.OrderStatusReady, hasCode: true},
+		{name: "code is replaced", statusId: models.OrderStatusPartiallyIssued, hasCode: true, replace: true, wantSet: true, wantEvent: models.OrderEventPickupCodeReplaced},
+		{name: "awaiting items", statusId: models.OrderStatusAwaiting, replace: true, wantError: models.ErrOrderNotReady},
+		{name: "cancelled", statusId: models.OrderStatusCancelled, replace: true, wantError: models.ErrOrderClosed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			row := new(mockRow)
+			repo := NewOrderRepo(mockPool)
+
+			actorId := 5
+			change := &models.PickupCodeChange{OrderId: uuid.New(), Hash: "hash", Replace: tt.replace, ActorId: &actorId, Comment: "pickup code regenerated"}
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("QueryRow", ctx, mock.Anything, change.OrderId).Return(row)
+			row.On("Scan", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				*args[0].(*int) = tt.statusId
+				*args[1].(*bool) = tt.hasCode
+			}).Return(nil)
+			if tt.wantSet {
+				mockTx.On("Exec", ctx, mock.Anything, change.OrderId, "hash").Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+				mockTx.On("Exec", ctx, mock.Anything, change.OrderId, tt.wantEvent, &actorId, change.Comment).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			set, err := repo.SetPickupCode(ctx, change)
+
+			require.ErrorIs(t, err, tt.wantError)
+			require.Equal(t, tt.wantSet, set)
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestClaimPickupCodeAttempt(t *testing.T) {
+	ctx := context.Background()
+	orderId := uuid.New()
+
+	t.Run("claimed", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		row := new(mockRow)
+		repo := NewOrderRepo(mockPool)
+
+		mockPool.On("QueryRow", ctx, mock.Anything, orderId, 5).Return(row)
+		row.On("Scan", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+			*args[0].(*string) = "hash"
+			*args[1].(*int) = 2
+		}).Return(nil)
+
+		code, err := repo.ClaimPickupCodeAttempt(ctx, orderId, 5)
+
+		require.NoError(t, err)
+		require.Equal(t, &models.PickupCode{Hash: "hash", Attempts: 2}, code)
+	})
+
+	t.Run("no attempts left", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		row := new(mockRow)
+		repo := NewOrderRepo(mockPool)
+
+		mockPool.On("QueryRow", ctx, mock.Anything, orderId, 5).Return(row)
+		row.On("Scan", mock.Anything, mock.Anything).Return(pgx.ErrNoRows)
+
+		_, err := repo.ClaimPickupCodeAttempt(ctx, orderId, 5)
+
+		require.ErrorIs(t, err, models.ErrPickupCodeLocked)
+	})
+}
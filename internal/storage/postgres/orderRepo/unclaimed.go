@@ -0,0 +1,119 @@
+package orderRepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orderPickupPoint/internal/models"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// items of the order waiting for the customer in the pvz. Items with a return, even a cancelled one,
+// are left to the employees
+const waitingItem = `prod.external_order_id = o.external_id and prod.pvz_id = o.pvz_id
+					and prod.deleted_at is null and prod.issued_at is null
+					and not exists (
+						select 1
+						from returns ret
+						where ret.product_id = prod.id)`
+
+// the order is unclaimed when it has waiting items and all of them are past the storage deadline.
+// Items accepted before the storage periods have no deadline and keep the order
+const unclaimedOrder = `exists (
+							select 1
+							from products prod
+							where ` + waitingItem + `)
+						and not exists (
+							select 1
+							from products prod
+							where ` + waitingItem + ` and (prod.storage_until is null or prod.storage_until >= now()))`
+
+// orders with one of the statuses not picked up in the storage period, oldest first
+func (r *OrderRepo) ListUnclaimedOrders(ctx context.Context, statusIds []int, limit int) ([]models.OrderAPI, error) {
+	query := fmt.Sprintf(`select %s
+				%s
+				where o.status_id = any($1) and %s
+				order by o.created_at, o.id
+				limit $2`, orderColumns, orderFrom, unclaimedOrder)
+
+	rows, err := r.pool.Query(ctx, query, statusIds, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.OrderAPI{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *order)
+	}
+	return out, rows.Err()
+}
+
+// queues the waiting items of the unclaimed order for return to the sender and moves the order
+// to the new status if it still has the expected one. The items stay in the pvz stock and cells until the returns
+// are dispatched. Reports the number of queued items, zero if the order changed meanwhile
+func (r *OrderRepo) ReturnUnclaimedOrder(ctx context.Context, transition *models.OrderTransition) (int, error) {
+	queryOrder := `select 1
+					from orders o
+					where o.id = $1 and o.status_id = $2 and ` + unclaimedOrder + `
+					for update of o`
+
+	queryQueue := `with items as (
+						select prod.id, o.pvz_id, prod.condition_id, prod.condition_notes
+						from orders o
+						join products prod on ` + waitingItem + `
+						where o.id = $1
+						for update of prod
+					), queued as (
+						insert into returns(pvz_id, order_id, product_id, reason_id, comment, condition_id, condition_notes, created_by)
+						select pvz_id, $1, id, $4, $3, condition_id, condition_notes, $2
+						from items
+						returning id
+					)
+					insert into return_status_history(return_id, to_status_id, changed_by, comment)
+					select id, $5, $2, $3
+					from queued`
+
+	queryStatus := `update orders
+					set status_id = $2, pickup_code_hash = null, pickup_code_created_at = null
+					where id = $1`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+
+	var one int
+	err = tx.QueryRow(ctx, queryOrder, transition.OrderId, transition.FromStatusId).Scan(&one)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// picked up or changed meanwhile
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	tag, err := tx.Exec(ctx, queryQueue, transition.OrderId, transition.ActorId, transition.Comment,
+		models.ReturnReasonUnclaimed, models.ReturnStatusQueued)
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = tx.Exec(ctx, queryStatus, transition.OrderId, transition.ToStatusId)
+	if err != nil {
+		return 0, err
+	}
+
+	err = writeHistory(ctx, tx, transition)
+	if err != nil {
+		return 0, err
+	}
+
+	return int(tag.RowsAffected()), tx.Commit(ctx)
+}
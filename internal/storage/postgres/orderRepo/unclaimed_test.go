@@ -0,0 +1,66 @@
+package orderRepo
+
+import (
+	"context"
+	"orderPickupPoint/internal/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReturnUnclaimedOrder(t *testing.T) {
+	ctx := context.Background()
+	actorId := models.SystemActorId
+	transition := &models.OrderTransition{
+		OrderId:      uuid.New(),
+		FromStatusId: models.OrderStatusReady,
+		ToStatusId:   models.OrderStatusCancelled,
+		ActorId:      &actorId,
+		Comment:      "storage period expired",
+	}
+
+	t.Run("returned", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		mockTx := new(mockDbTx)
+		row := new(mockRow)
+		repo := NewOrderRepo(mockPool)
+
+		mockPool.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+		mockTx.On("QueryRow", ctx, mock.Anything, transition.OrderId, transition.FromStatusId).Return(row)
+		row.On("Scan", mock.Anything).Return(nil)
+		mockTx.On("Exec", ctx, mock.Anything, transition.OrderId, transition.ActorId, transition.Comment,
+			models.ReturnReasonUnclaimed, models.ReturnStatusQueued).Return(pgconn.NewCommandTag("INSERT 0 2"), nil).Once()
+		mockTx.On("Exec", ctx, mock.Anything, transition.OrderId, models.OrderStatusCancelled).Return(pgconn.NewCommandTag("UPDATE 1"), nil).Once()
+		mockTx.On("Exec", ctx, mock.Anything, transition.OrderId, transition.FromStatusId, transition.ToStatusId, transition.ActorId, transition.Comment).Return(pgconn.NewCommandTag("INSERT 0 1"), nil).Once()
+		mockTx.On("Commit", ctx).Return(nil)
+
+		queued, err := repo.ReturnUnclaimedOrder(ctx, transition)
+
+		require.NoError(t, err)
+		require.Equal(t, 2, queued)
+		mockTx.AssertExpectations(t)
+	})
+
+	t.Run("changed meanwhile", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		mockTx := new(mockDbTx)
+		row := new(mockRow)
+		repo := NewOrderRepo(mockPool)
+
+		mockPool.On("Begin", ctx).Return(mockTx, nil)
+		mockTx.On("Rollback", ctx).Return(nil)
+		mockTx.On("QueryRow", ctx, mock.Anything, transition.OrderId, transition.FromStatusId).Return(row)
+		row.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+
+		queued, err := repo.ReturnUnclaimedOrder(ctx, transition)
+
+		require.NoError(t, err)
+		require.Zero(t, queued)
+		mockTx.AssertNotCalled(t, "Commit", ctx)
+	})
+}
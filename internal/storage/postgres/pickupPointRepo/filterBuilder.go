@@ -0,0 +1,66 @@
+package pickupPointRepo
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// collects sql conditions with "?" placeholders and turns them into numbered
+// parameters, so user input never gets into the query text
+type filterBuilder struct {
+	conditions []string
+	args       []any
+
+	// groups created by sub() share parameters with their parent
+	parent *filterBuilder
+}
+
+// adds value to the query params and returns its placeholder
+func (b *filterBuilder) arg(value any) string {
+	if b.parent != nil {
+		return b.parent.arg(value)
+	}
+	b.args = append(b.args, value)
+	return "$" + strconv.Itoa(len(b.args))
+}
+
+// adds a condition, every "?" in it is replaced by the next value
+func (b *filterBuilder) where(condition string, values ...any) {
+	var sb strings.Builder
+	valueIdx := 0
+	for _, ch := range condition {
+		if ch == '?' && valueIdx < len(values) {
+			sb.WriteString(b.arg(values[valueIdx]))
+			valueIdx++
+			continue
+		}
+		sb.WriteRune(ch)
+	}
+	b.conditions = append(b.conditions, sb.String())
+}
+
+// separate group of conditions which can be placed in another part of the query
+func (b *filterBuilder) sub() *filterBuilder {
+	return &filterBuilder{parent: b}
+}
+
+// copy of the builder, used to run several queries with the same conditions
+func (b *filterBuilder) clone() *filterBuilder {
+	return &filterBuilder{
+		conditions: slices.Clone(b.conditions),
+		args:       slices.Clone(b.args),
+	}
+}
+
+func (b *filterBuilder) empty() bool {
+	return len(b.conditions) == 0
+}
+
+// conditions joined with "and", "true" if there are none
+func (b *filterBuilder) and() string {
+	if len(b.conditions) == 0 {
+		return "true"
+	}
+	return strings.Join(b.conditions, "\n\tand ")
+}
@@ -0,0 +1,72 @@
+package pickupPointRepo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterBuilder(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions [][]any
+		wantAnd    string
+		wantArgs   []any
+	}{
+		{
+			name:       "no conditions",
+			conditions: nil,
+			wantAnd:    "true",
+			wantArgs:   nil,
+		},
+		{
+			name:       "single condition",
+			conditions: [][]any{{"c.name = any(?)", []string{"Москва"}}},
+			wantAnd:    "c.name = any($1)",
+			wantArgs:   []any{[]string{"Москва"}},
+		},
+		{
+			name: "several conditions",
+			conditions: [][]any{
+				{"prod.added_at >= ?", 1},
+				{"prod.added_at between ? and ?", 2, 3},
+			},
+			wantAnd:  "prod.added_at >= $1\n\tand prod.added_at between $2 and $3",
+			wantArgs: []any{1, 2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &filterBuilder{}
+			for _, cond := range tt.conditions {
+				b.where(cond[0].(string), cond[1:]...)
+			}
+			require.Equal(t, tt.wantAnd, b.and())
+			require.Equal(t, tt.wantArgs, b.args)
+		})
+	}
+}
+
+func TestFilterBuilderGroups(t *testing.T) {
+	root := &filterBuilder{}
+	products := root.sub()
+	receptions := root.sub()
+
+	root.where("c.name = any(?)", []string{"Казань"})
+	products.where("pt.name = any(?)", []string{"обувь"})
+	receptions.where("rs.name = any(?)", []string{"close"})
+
+	require.Equal(t, "c.name = any($1)", root.and())
+	require.Equal(t, "pt.name = any($2)", products.and())
+	require.Equal(t, "rs.name = any($3)", receptions.and())
+	require.Equal(t, []any{[]string{"Казань"}, []string{"обувь"}, []string{"close"}}, root.args)
+
+	page := root.clone()
+	require.Equal(t, "$4", page.arg(10))
+	require.Len(t, root.args, 3)
+
+	empty := root.sub()
+	require.True(t, empty.empty())
+	require.Equal(t, "true", empty.and())
+}
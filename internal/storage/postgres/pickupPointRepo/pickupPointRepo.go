@@ -2,10 +2,16 @@ package pickupPointRepo
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"orderPickupPoint/internal/models"
 	"orderPickupPoint/internal/storage/postgres"
-	"strconv"
+	"orderPickupPoint/internal/storage/postgres/sharedSql"
+	"orderPickupPoint/internal/utils/cursor"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 type PickupPointRepo struct {
@@ -46,43 +52,215 @@ func (r *PickupPointRepo) Create(ctx context.Context, pickupPoint *models.Pickup
 	return outPickupPoint, nil
 }
 
-func (r *PickupPointRepo) GetFilteredInfo(ctx context.Context, filter *models.PvzFilter) ([]models.PvzFilteredInfo, error) {
-	queryData := []interface{}{}
+// sql expression of a sort field and its type, the type is needed to compare
+// with the sort key stored in a cursor as text
+type sortColumn struct {
+	expr    string
+	sqlType string
+}
+
+// sort fields available for the client, mapped to sql expressions over a pickup point.
+// Expressions must not be null, otherwise keyset comparison skips rows
+var pvzSortColumns = map[string]sortColumn{
+	models.PvzSortByReceptionDate: {"coalesce((select max(r.reception_start_datetime) from receptions r where r.pvz_id = p.id), '-infinity')", "timestamptz"},
+	models.PvzSortByProductDate:   {"coalesce((select max(prod.added_at) from products prod where prod.pvz_id = p.id and prod.deleted_at is null), '-infinity')", "timestamptz"},
+	models.PvzSortByRegDate:       {"p.reg_date", "date"},
+	models.PvzSortByCity:          {"c.name", "text"},
+}
+
+type pageKey struct {
+	id      uuid.UUID
+	sortKey string
+}
+
+// selects a page of pickup points first and then loads their receptions and products,
+// so a page always contains whole pickup points. Rows are passed to onRow as they are read,
+// ordered by page position, reception start and product time. Returns the number
+// of pickup points matching the filter and cursors of the neighbouring pages
+func (r *PickupPointRepo) GetFilteredInfo(ctx context.Context, filter *models.PvzFilter, onRow func(row *models.PvzFilteredInfo)) (*models.PvzFilteredResult, error) {
+	sortBy := filter.SortBy
+	if sortBy == "" {
+		sortBy = models.PvzSortByReceptionDate
+	}
+	column, ok := pvzSortColumns[sortBy]
+	if !ok {
+		return nil, models.ErrInvalidFilter
+	}
+
+	pvzConds := &filterBuilder{}
+	receptionConds := pvzConds.sub()
+	productConds := pvzConds.sub()
+
+	if len(filter.Cities) > 0 {
+		pvzConds.where("c.name = any(?)", filter.Cities)
+	}
+	if len(filter.PvzIds) > 0 {
+		pvzConds.where("p.id = any(?)", filter.PvzIds)
+	}
+	if len(filter.Statuses) > 0 {
+		receptionConds.where("rs.name = any(?)", filter.Statuses)
+	}
+	if filter.StartDate != nil {
+		productConds.where("prod.added_at >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		productConds.where("prod.added_at <= ?", *filter.EndDate)
+	}
+	if len(filter.ProductTypes) > 0 {
+		productConds.where("pt.name = any(?)", filter.ProductTypes)
+	}
+	if len(filter.Conditions) > 0 {
+		productConds.where("pc.name = any(?)", filter.Conditions)
+	}
+	if filter.WithProblems {
+		receptionConds.where(fmt.Sprintf(`exists(
+						select 1
+						from reception_products prp
+						join products pp on pp.id = prp.product_id
+						where prp.reception_id = r.id and pp.deleted_at is null and pp.condition_id <> %d)`, models.ProductConditionOk))
+	}
+
+	// with product filters only receptions having matching products are shown
+	receptionMatch := receptionConds.and()
+	if len(filter.Statuses) == 0 && !filter.IncludeCancelled {
+		receptionMatch += fmt.Sprintf(" and r.status_id <> %d", models.ReceptionStatusCancelled)
+	}
+	if !productConds.empty() {
+		receptionMatch += fmt.Sprintf(`
+					and exists(
+						select 1
+						from reception_products rp
+						join products prod on prod.id = rp.product_id
+						join product_types pt on pt.id = prod.type_id
+						join product_conditions pc on pc.id = prod.condition_id
+						where rp.reception_id = r.id and prod.deleted_at is null and %s)`, productConds.and())
+	}
+
+	// with reception or product filters a pickup point needs at least one matching reception
+	pvzMatch := pvzConds.and()
+	if !receptionConds.empty() || !productConds.empty() {
+		pvzMatch += fmt.Sprintf(`
+				and exists(
+					select 1
+					from receptions r
+					join reception_statuses rs on rs.id = r.status_id
+					where r.pvz_id = p.id and %s)`, receptionMatch)
+	}
+
+	queryCount := fmt.Sprintf(`select count(*)
+				from pvzs p
+				join cities c on p.city_id = c.id
+				where %s`, pvzMatch)
+
+	result := &models.PvzFilteredResult{}
+	err := r.pool.QueryRow(ctx, queryCount, pvzConds.args...).Scan(&result.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	pageArgs := pvzConds.clone()
+	keysetMatch := ""
+	offset := 0
+	backward := false
+	if filter.Cursor != nil {
+		if !filter.Cursor.Matches(sortBy, filter.SortDesc) {
+			return nil, models.ErrInvalidFilter
+		}
+		backward = filter.Cursor.Backward
+
+		// rows after the cursor in the requested direction
+		op := ">"
+		if filter.SortDesc != backward {
+			op = "<"
+		}
+		keysetMatch = fmt.Sprintf("\n\t\t\t\tand (%s, p.id) %s (%s::%s, %s::uuid)",
+			column.expr, op, pageArgs.arg(filter.Cursor.SortKey), column.sqlType, pageArgs.arg(filter.Cursor.Id))
+	} else {
+		offset = filter.PageLimit * (filter.Page - 1)
+	}
+
+	direction := "asc"
+	if filter.SortDesc != backward {
+		direction = "desc"
+	}
+
+	// one extra row shows if there is a page after this one
+	queryPage := fmt.Sprintf(`select p.id, (%s)::text
+				from pvzs p
+				join cities c on p.city_id = c.id
+				where %s%s
+				order by %s %s, p.id %s
+				limit %s offset %s`, column.expr, pvzMatch, keysetMatch, column.expr, direction, direction,
+		pageArgs.arg(filter.PageLimit+1), pageArgs.arg(offset))
+
+	rows, err := r.pool.Query(ctx, queryPage, pageArgs.args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []pageKey
+	for rows.Next() {
+		var key pageKey
+		if err := rows.Scan(&key.id, &key.sortKey); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
 
-	query := `select 	p.id, 
+	keys, result.Next, result.Prev = cursor.Slice(keys, filter.PageLimit, filter.Cursor, offset, func(key pageKey) cursor.Cursor {
+		return cursor.Cursor{
+			SortBy:  sortBy,
+			Desc:    filter.SortDesc,
+			SortKey: key.sortKey,
+			Id:      key.id.String(),
+		}
+	})
+	if len(keys) == 0 {
+		return result, nil
+	}
+
+	pvzIds := make([]uuid.UUID, 0, len(keys))
+	for _, key := range keys {
+		pvzIds = append(pvzIds, key.id)
+	}
+
+	infoArgs := pvzConds.clone()
+	idsParam := infoArgs.arg(pvzIds)
+	query := fmt.Sprintf(`select 	p.id, 
 						c.name, 
 						p.reg_date, 
 						r.id,
 						r.reception_start_datetime, 
 						prod.id, 
 						prod.added_at, 
-						pt.name 
+						pt.name,
+						pc.name
 				from pvzs p 
-				join receptions r on p.id = r.pvz_id
-				join reception_products rp on rp.reception_id = r.id
-				join products prod on prod.id = rp.product_id
-				join product_types pt on pt.id = prod.type_id
-				join cities c on p.city_id = c.id`
-
-	if filter.EndDate != nil && filter.StartDate != nil {
-		query += "\nwhere prod.added_at between $1 and $2"
-		queryData = append(queryData, filter.StartDate, filter.EndDate)
-	}
+				join cities c on p.city_id = c.id
+				left join (receptions r
+					join reception_statuses rs on rs.id = r.status_id)
+					on r.pvz_id = p.id and %s
+				left join (reception_products rp
+					join products prod on prod.id = rp.product_id
+					join product_types pt on pt.id = prod.type_id
+					join product_conditions pc on pc.id = prod.condition_id)
+					on rp.reception_id = r.id and prod.deleted_at is null and %s
+				where p.id = any(%s) and %s
+				order by array_position(%s, p.id), r.reception_start_datetime, prod.added_at, prod.id`,
+		receptionMatch, productConds.and(), idsParam, pvzConds.and(), idsParam)
 
-	query += fmt.Sprintf("\norder by r.reception_start_datetime\nlimit $%s offset $%s;", strconv.Itoa(len(queryData)+1), strconv.Itoa(len(queryData)+2))
-
-	offset := filter.PageLimit * (filter.Page - 1)
-
-	queryData = append(queryData, filter.PageLimit, offset)
-
-	rows, err := r.pool.Query(ctx, query, queryData...)
+	rows, err = r.pool.Query(ctx, query, infoArgs.args...)
 
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var out []models.PvzFilteredInfo
 	for rows.Next() {
 		var row models.PvzFilteredInfo
 		err := rows.Scan(
@@ -94,12 +272,322 @@ func (r *PickupPointRepo) GetFilteredInfo(ctx context.Context, filter *models.Pv
 			&row.ProductID,
 			&row.AddedAt,
 			&row.ProductType,
+			&row.Condition,
 		)
 		if err != nil {
-			continue
+			return nil, err
+		}
+		onRow(&row)
+	}
+	return result, rows.Err()
+
+}
+
+func (r *PickupPointRepo) GetById(ctx context.Context, pvzId uuid.UUID) (*models.PickupPointAPI, error) {
+	query := `select p.id, p.reg_date, c.name
+				from pvzs p
+				join cities c on c.id = p.city_id
+				where p.id = $1`
+
+	outPickupPoint := &models.PickupPointAPI{}
+	err := r.pool.QueryRow(ctx, query, pvzId).Scan(&outPickupPoint.Id, &outPickupPoint.RegDate, &outPickupPoint.City)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return outPickupPoint, nil
+}
+
+func (r *PickupPointRepo) SetCapacity(ctx context.Context, pvzId uuid.UUID, capacity *models.PvzCapacity) error {
+	queryPvz := `update pvzs
+					set max_items = $2, strict_capacity = $3
+					where id = $1`
+
+	queryClearTypes := `delete from pvz_type_capacities
+						where pvz_id = $1`
+
+	queryAddType := `insert into pvz_type_capacities(pvz_id, type_id, max_items)
+						select $1, pt.id, $3
+						from product_types pt
+						where pt.id = ` + sharedSql.ProductTypeIdByKey("$2")
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, queryPvz, pvzId, capacity.MaxItems, capacity.Strict)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	_, err = tx.Exec(ctx, queryClearTypes, pvzId)
+	if err != nil {
+		return err
+	}
+
+	for typeKey, maxItems := range capacity.MaxItemsByType {
+		tag, err := tx.Exec(ctx, queryAddType, pvzId, typeKey, maxItems)
+		if postgres.IsUniqueViolation(err) {
+			// both the code and the name of the type are given
+			return fmt.Errorf("%w: type %s is given twice", models.ErrInvalidCapacity, typeKey)
+		}
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return models.ErrUnknownProductType
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *PickupPointRepo) GetCapacity(ctx context.Context, pvzId uuid.UUID) (*models.PvzCapacity, error) {
+	queryPvz := `select max_items, strict_capacity
+					from pvzs
+					where id = $1`
+
+	queryTypes := `select pt.name, tc.max_items
+					from pvz_type_capacities tc
+					join product_types pt on pt.id = tc.type_id
+					where tc.pvz_id = $1`
+
+	capacity := &models.PvzCapacity{MaxItemsByType: map[string]int{}}
+	err := r.pool.QueryRow(ctx, queryPvz, pvzId).Scan(&capacity.MaxItems, &capacity.Strict)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx, queryTypes, pvzId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			typeName string
+			maxItems int
+		)
+		if err := rows.Scan(&typeName, &maxItems); err != nil {
+			return nil, err
+		}
+		capacity.MaxItemsByType[typeName] = maxItems
+	}
+
+	return capacity, rows.Err()
+}
+
+// products already accepted keep their storage deadlines
+func (r *PickupPointRepo) SetStoragePeriods(ctx context.Context, pvzId uuid.UUID, periods *models.PvzStoragePeriods) error {
+	queryPvz := `update pvzs
+					set storage_days = $2
+					where id = $1`
+
+	queryClearTypes := `delete from pvz_type_storage_days
+						where pvz_id = $1`
+
+	queryAddType := `insert into pvz_type_storage_days(pvz_id, type_id, days)
+						select $1, pt.id, $3
+						from product_types pt
+						where pt.id = ` + sharedSql.ProductTypeIdByKey("$2")
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, queryPvz, pvzId, periods.Days)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrNotFound
+	}
+
+	_, err = tx.Exec(ctx, queryClearTypes, pvzId)
+	if err != nil {
+		return err
+	}
+
+	for typeKey, days := range periods.DaysByType {
+		tag, err := tx.Exec(ctx, queryAddType, pvzId, typeKey, days)
+		if postgres.IsUniqueViolation(err) {
+			return fmt.Errorf("%w: type %s is given twice", models.ErrInvalidStorageDays, typeKey)
+		}
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return models.ErrUnknownProductType
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *PickupPointRepo) GetStoragePeriods(ctx context.Context, pvzId uuid.UUID) (*models.PvzStoragePeriods, error) {
+	queryPvz := `select storage_days
+					from pvzs
+					where id = $1`
+
+	queryTypes := `select pt.name, sd.days
+					from pvz_type_storage_days sd
+					join product_types pt on pt.id = sd.type_id
+					where sd.pvz_id = $1`
+
+	periods := &models.PvzStoragePeriods{DaysByType: map[string]int{}}
+	err := r.pool.QueryRow(ctx, queryPvz, pvzId).Scan(&periods.Days)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx, queryTypes, pvzId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			typeName string
+			days     int
+		)
+		if err := rows.Scan(&typeName, &days); err != nil {
+			return nil, err
+		}
+		periods.DaysByType[typeName] = days
+	}
+
+	return periods, rows.Err()
+}
+
+// products waiting for the customer by the storage deadline, the ones already queued for return are skipped
+func (r *PickupPointRepo) ListByStorageDeadline(ctx context.Context, filter *models.StorageDeadlineFilter) (*models.ListResult[models.PlacementAPI], error) {
+	queryFilter := `where prod.pvz_id = $1 and prod.deleted_at is null and prod.issued_at is null
+					and prod.storage_until < $2
+					and ($3::timestamptz is null or prod.storage_until >= $3)
+					and not exists (
+						select 1
+						from returns ret
+						where ret.product_id = prod.id and ret.status_id = $4)`
+
+	args := []any{filter.PvzId, filter.Until, filter.From, models.ReturnStatusQueued}
+
+	result := &models.ListResult[models.PlacementAPI]{}
+	err := r.pool.QueryRow(ctx, `select count(*)
+				from products prod
+				`+queryFilter, args...).Scan(&result.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	keysetMatch := ""
+	direction := "asc"
+	offset := 0
+	if filter.Cursor != nil {
+		if !filter.Cursor.Matches(models.PlacementSortByDeadline, false) {
+			return nil, models.ErrInvalidFilter
+		}
+		op := ">"
+		if filter.Cursor.Backward {
+			op = "<"
+			direction = "desc"
+		}
+		keysetMatch = fmt.Sprintf("\n\t\t\t\t\tand (prod.storage_until, prod.id) %s ($7::timestamptz, $8::uuid)", op)
+		args = append(args, filter.PageLimit+1, offset, filter.Cursor.SortKey, filter.Cursor.Id)
+	} else {
+		offset = filter.PageLimit * (filter.Page - 1)
+		args = append(args, filter.PageLimit+1, offset)
+	}
+
+	query := fmt.Sprintf(`select %s
+				%s
+				%s%s
+				order by prod.storage_until %s, prod.id %s
+				limit $5 offset $6`, sharedSql.PlacementColumns, sharedSql.PlacementFrom, queryFilter, keysetMatch, direction, direction)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var placements []models.PlacementAPI
+	for rows.Next() {
+		placement, err := sharedSql.ScanPlacement(rows)
+		if err != nil {
+			return nil, err
+		}
+		placements = append(placements, *placement)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// the filter leaves only products with a deadline
+	result.Items, result.Next, result.Prev = cursor.Slice(placements, filter.PageLimit, filter.Cursor, offset, func(placement models.PlacementAPI) cursor.Cursor {
+		return cursor.Cursor{
+			SortBy:  models.PlacementSortByDeadline,
+			SortKey: placement.StorageUntil.Format(time.RFC3339Nano),
+			Id:      placement.ProductId.String(),
+		}
+	})
+	return result, nil
+}
+
+// returns the running count of stored goods for every product type
+func (r *PickupPointRepo) GetStock(ctx context.Context, pvzId uuid.UUID) ([]models.TypeUtilization, error) {
+	query := `select pt.name, coalesce(s.items, 0), tc.max_items
+				from product_types pt
+				left join pvz_stock s on s.type_id = pt.id and s.pvz_id = $1
+				left join pvz_type_capacities tc on tc.type_id = pt.id and tc.pvz_id = $1
+				order by pt.id`
+
+	rows, err := r.pool.Query(ctx, query, pvzId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.TypeUtilization
+	for rows.Next() {
+		var row models.TypeUtilization
+		if err := rows.Scan(&row.Type, &row.StoredItems, &row.MaxItems); err != nil {
+			return nil, err
 		}
 		out = append(out, row)
 	}
-	return out, nil
+	return out, rows.Err()
+}
+
+// attribute totals of the products currently stored in the pvz
+func (r *PickupPointRepo) GetTotals(ctx context.Context, pvzId uuid.UUID) (*models.ProductTotals, error) {
+	query := `select coalesce(sum(weight_grams), 0),
+					coalesce(sum(length_mm::bigint * width_mm * height_mm), 0) / 1000,
+					coalesce(sum(declared_value), 0)
+				from products
+				where pvz_id = $1 and deleted_at is null and issued_at is null`
 
+	totals := &models.ProductTotals{}
+	err := r.pool.QueryRow(ctx, query, pvzId).Scan(&totals.WeightGrams, &totals.VolumeCm3, &totals.DeclaredValue)
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
 }
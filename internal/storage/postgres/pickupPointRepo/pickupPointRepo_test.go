@@ -5,12 +5,15 @@ import (
 	"errors"
 	"orderPickupPoint/internal/models"
 	"orderPickupPoint/internal/storage/postgres"
+	"orderPickupPoint/internal/utils/cursor"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -20,6 +23,11 @@ type mockDbPool struct {
 	postgres.DBPool
 }
 
+type mockDbTx struct {
+	mock.Mock
+	postgres.Tx
+}
+
 type mockRow struct {
 	mock.Mock
 }
@@ -34,6 +42,54 @@ func (m *mockRow) Scan(dest ...any) error {
 	return args.Error(0)
 }
 
+func (m *mockDbPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	callArgs := m.Called(append([]interface{}{ctx, sql}, args...)...)
+	return callArgs.Get(0).(pgx.Rows), callArgs.Error(1)
+}
+
+func (m *mockDbPool) Begin(ctx context.Context) (postgres.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(postgres.Tx), args.Error(1)
+}
+
+func (m *mockDbTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	callArgs := m.Called(append([]interface{}{ctx, sql}, args...)...)
+	return callArgs.Get(0).(pgconn.CommandTag), callArgs.Error(1)
+}
+
+func (m *mockDbTx) Commit(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func (m *mockDbTx) Rollback(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+// rows of the query result, scanned into the destinations in order
+type fakeRows struct {
+	pgx.Rows
+	rows [][]any
+	next int
+}
+
+func (r *fakeRows) Next() bool {
+	r.next++
+	return r.next <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	for i, value := range r.rows[r.next-1] {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+
+func (r *fakeRows) Close() {}
+
 func TestGetCityIdByName(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -118,3 +174,307 @@ func TestCreate(t *testing.T) {
 		})
 	}
 }
+
+func TestGetTotals(t *testing.T) {
+	ctx := context.Background()
+	mockPool := new(mockDbPool)
+	repo := NewPickupPointRepo(mockPool)
+	pgxRow := new(mockRow)
+	pvzId := uuid.New()
+
+	mockPool.On("QueryRow", ctx, mock.Anything, pvzId).Return(pgxRow)
+	pgxRow.On("Scan", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		*args[0].(*int64) = 2500
+		*args[1].(*int64) = 12000
+		*args[2].(*int64) = 990000
+	}).Return(nil)
+
+	totals, err := repo.GetTotals(ctx, pvzId)
+
+	require.NoError(t, err)
+	require.Equal(t, models.ProductTotals{WeightGrams: 2500, VolumeCm3: 12000, DeclaredValue: 990000}, *totals)
+	mockPool.AssertExpectations(t)
+}
+
+func TestListByStorageDeadline(t *testing.T) {
+	ctx := context.Background()
+	pvzId := uuid.New()
+	until := time.Now()
+
+	deadline := until.Add(-48 * time.Hour)
+	rows := [][]any{}
+	for i := range 3 {
+		storageUntil := deadline.Add(time.Duration(i) * time.Hour)
+		rows = append(rows, []any{uuid.New(), "обувь", (*string)(nil), (*string)(nil), deadline.AddDate(0, 0, -7),
+			(*string)(nil), &storageUntil})
+	}
+
+	t.Run("first page", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		countRow := new(mockRow)
+		repo := NewPickupPointRepo(mockPool)
+
+		filter := &models.StorageDeadlineFilter{PvzId: pvzId, Until: until, Page: 1, PageLimit: 2}
+		mockPool.On("QueryRow", ctx, mock.Anything, pvzId, until, (*time.Time)(nil), models.ReturnStatusQueued).Return(countRow)
+		countRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*args[0].(*int) = 3
+		}).Return(nil)
+		// one row over the limit tells there is a next page
+		mockPool.On("Query", ctx, mock.Anything, pvzId, until, (*time.Time)(nil), models.ReturnStatusQueued, 3, 0).
+			Return(&fakeRows{rows: rows}, nil)
+
+		result, err := repo.ListByStorageDeadline(ctx, filter)
+
+		require.NoError(t, err)
+		require.Equal(t, 3, result.Total)
+		require.Len(t, result.Items, 2)
+		require.Equal(t, rows[0][0], result.Items[0].ProductId)
+		require.NotNil(t, result.Next)
+		require.Equal(t, models.PlacementSortByDeadline, result.Next.SortBy)
+		require.Equal(t, rows[1][0].(uuid.UUID).String(), result.Next.Id)
+		require.Nil(t, result.Prev)
+		mockPool.AssertExpectations(t)
+	})
+
+	t.Run("cursor of another list", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		countRow := new(mockRow)
+		repo := NewPickupPointRepo(mockPool)
+
+		filter := &models.StorageDeadlineFilter{PvzId: pvzId, Until: until, Page: 1, PageLimit: 2,
+			Cursor: &cursor.Cursor{SortBy: models.ReceptionSortByDate, Desc: true, Id: uuid.NewString()}}
+		mockPool.On("QueryRow", ctx, mock.Anything, pvzId, until, (*time.Time)(nil), models.ReturnStatusQueued).Return(countRow)
+		countRow.On("Scan", mock.Anything).Return(nil)
+
+		_, err := repo.ListByStorageDeadline(ctx, filter)
+
+		require.ErrorIs(t, err, models.ErrInvalidFilter)
+		mockPool.AssertNotCalled(t, "Query", mock.Anything, mock.Anything)
+	})
+}
+
+func TestSetCapacity(t *testing.T) {
+	tests := []struct {
+		name       string
+		capacity   *models.PvzCapacity
+		pvzUpdated string
+		typeAdded  string
+		typeError  error
+		wantError  error
+	}{
+		{name: "limits set", capacity: &models.PvzCapacity{MaxItems: intPtr(100), MaxItemsByType: map[string]int{"обувь": 10}, Strict: true},
+			pvzUpdated: "UPDATE 1", typeAdded: "INSERT 0 1"},
+		{name: "type given by code", capacity: &models.PvzCapacity{MaxItemsByType: map[string]int{"shoes": 10}},
+			pvzUpdated: "UPDATE 1", typeAdded: "INSERT 0 1"},
+		{name: "type given by code and name", capacity: &models.PvzCapacity{MaxItemsByType: map[string]int{"shoes": 10}},
+			pvzUpdated: "UPDATE 1", typeError: &pgconn.PgError{Code: "23505"}, wantError: models.ErrInvalidCapacity},
+		{name: "limits removed", capacity: &models.PvzCapacity{}, pvzUpdated: "UPDATE 1"},
+		{name: "unknown pvz", capacity: &models.PvzCapacity{MaxItems: intPtr(100)}, pvzUpdated: "UPDATE 0", wantError: models.ErrNotFound},
+		{name: "unknown type", capacity: &models.PvzCapacity{MaxItemsByType: map[string]int{"мебель": 10}},
+			pvzUpdated: "UPDATE 1", typeAdded: "INSERT 0 0", wantError: models.ErrUnknownProductType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			repo := NewPickupPointRepo(mockPool)
+			pvzId := uuid.New()
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("Exec", ctx, mock.Anything, pvzId, tt.capacity.MaxItems, tt.capacity.Strict).Return(pgconn.NewCommandTag(tt.pvzUpdated), nil)
+			if tt.pvzUpdated == "UPDATE 1" {
+				mockTx.On("Exec", ctx, mock.Anything, pvzId).Return(pgconn.NewCommandTag("DELETE 1"), nil)
+			}
+			for typeKey, maxItems := range tt.capacity.MaxItemsByType {
+				mockTx.On("Exec", ctx, typeByKey, pvzId, typeKey, maxItems).Return(pgconn.NewCommandTag(tt.typeAdded), tt.typeError)
+			}
+			if tt.wantError == nil {
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			err := repo.SetCapacity(ctx, pvzId, tt.capacity)
+
+			require.ErrorIs(t, err, tt.wantError)
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSetStoragePeriods(t *testing.T) {
+	tests := []struct {
+		name       string
+		periods    *models.PvzStoragePeriods
+		pvzUpdated string
+		typeAdded  string
+		typeError  error
+		wantError  error
+	}{
+		{name: "type given by name", periods: &models.PvzStoragePeriods{Days: intPtr(5), DaysByType: map[string]int{"электроника": 10}},
+			pvzUpdated: "UPDATE 1", typeAdded: "INSERT 0 1"},
+		{name: "type given by code", periods: &models.PvzStoragePeriods{DaysByType: map[string]int{"electronics": 10}},
+			pvzUpdated: "UPDATE 1", typeAdded: "INSERT 0 1"},
+		{name: "type given by code and name", periods: &models.PvzStoragePeriods{DaysByType: map[string]int{"electronics": 10}},
+			pvzUpdated: "UPDATE 1", typeError: &pgconn.PgError{Code: "23505"}, wantError: models.ErrInvalidStorageDays},
+		{name: "unknown type", periods: &models.PvzStoragePeriods{DaysByType: map[string]int{"мебель": 10}},
+			pvzUpdated: "UPDATE 1", typeAdded: "INSERT 0 0", wantError: models.ErrUnknownProductType},
+		{name: "unknown pvz", periods: &models.PvzStoragePeriods{Days: intPtr(5)}, pvzUpdated: "UPDATE 0", wantError: models.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			repo := NewPickupPointRepo(mockPool)
+			pvzId := uuid.New()
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("Exec", ctx, mock.Anything, pvzId, tt.periods.Days).Return(pgconn.NewCommandTag(tt.pvzUpdated), nil)
+			if tt.pvzUpdated == "UPDATE 1" {
+				mockTx.On("Exec", ctx, mock.Anything, pvzId).Return(pgconn.NewCommandTag("DELETE 1"), nil)
+			}
+			for typeKey, days := range tt.periods.DaysByType {
+				mockTx.On("Exec", ctx, typeByKey, pvzId, typeKey, days).Return(pgconn.NewCommandTag(tt.typeAdded), tt.typeError)
+			}
+			if tt.wantError == nil {
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			err := repo.SetStoragePeriods(ctx, pvzId, tt.periods)
+
+			require.ErrorIs(t, err, tt.wantError)
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetCapacity(t *testing.T) {
+	tests := []struct {
+		name      string
+		pvzError  error
+		typeRows  [][]any
+		want      *models.PvzCapacity
+		wantError error
+	}{
+		{name: "limits", typeRows: [][]any{{"обувь", 10}, {"электроника", 5}},
+			want: &models.PvzCapacity{MaxItems: intPtr(100), MaxItemsByType: map[string]int{"обувь": 10, "электроника": 5}, Strict: true}},
+		{name: "no type limits", want: &models.PvzCapacity{MaxItems: intPtr(100), MaxItemsByType: map[string]int{}, Strict: true}},
+		{name: "unknown pvz", pvzError: pgx.ErrNoRows, wantError: models.ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			pvzRow := new(mockRow)
+			repo := NewPickupPointRepo(mockPool)
+			pvzId := uuid.New()
+
+			mockPool.On("QueryRow", ctx, mock.Anything, pvzId).Return(pvzRow)
+			pvzCall := pvzRow.On("Scan", mock.Anything, mock.Anything)
+			if tt.pvzError != nil {
+				pvzCall.Return(tt.pvzError)
+			} else {
+				pvzCall.Run(func(args mock.Arguments) {
+					*args[0].(**int) = intPtr(100)
+					*args[1].(*bool) = true
+				}).Return(nil)
+				mockPool.On("Query", ctx, mock.Anything, pvzId).Return(&fakeRows{rows: tt.typeRows}, nil)
+			}
+
+			capacity, err := repo.GetCapacity(ctx, pvzId)
+
+			require.ErrorIs(t, err, tt.wantError)
+			require.Equal(t, tt.want, capacity)
+			mockPool.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetStock(t *testing.T) {
+	ctx := context.Background()
+	mockPool := new(mockDbPool)
+	repo := NewPickupPointRepo(mockPool)
+	pvzId := uuid.New()
+
+	// a type without stock and limit is listed with zero items
+	mockPool.On("Query", ctx, mock.Anything, pvzId).Return(&fakeRows{rows: [][]any{
+		{"электроника", 3, intPtr(5)},
+		{"одежда", 0, (*int)(nil)},
+	}}, nil)
+
+	stock, err := repo.GetStock(ctx, pvzId)
+
+	require.NoError(t, err)
+	require.Equal(t, []models.TypeUtilization{
+		{Type: "электроника", StoredItems: 3, MaxItems: intPtr(5)},
+		{Type: "одежда", StoredItems: 0},
+	}, stock)
+	mockPool.AssertExpectations(t)
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+// the type of a per type setting is looked up by its code or name
+var typeByKey = mock.MatchedBy(func(sql string) bool {
+	return strings.Contains(sql, "kt.code = $2 or kt.name = $2")
+})
+
+func TestGetFilteredInfo(t *testing.T) {
+	ctx := context.Background()
+	mockPool := new(mockDbPool)
+	countRow := new(mockRow)
+	repo := NewPickupPointRepo(mockPool)
+
+	busyPvz, emptyPvz := uuid.New(), uuid.New()
+	receptionId, productId := uuid.New(), uuid.New()
+	started := time.Now()
+	productType, condition := "обувь", "ok"
+
+	var queries []string
+	mockPool.On("QueryRow", ctx, mock.Anything).Return(countRow)
+	countRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+		*args[0].(*int) = 2
+	}).Return(nil)
+	// the page of pickup points, limit+1 and offset
+	mockPool.On("Query", ctx, mock.Anything, 3, 0).Run(func(args mock.Arguments) {
+		queries = append(queries, "page")
+	}).Return(&fakeRows{rows: [][]any{
+		{busyPvz, started.Format(time.RFC3339Nano)},
+		{emptyPvz, "-infinity"},
+	}}, nil)
+	// receptions and products of the pickup points on the page are left joined,
+	// the one without receptions has a single row of nils
+	infoQuery := mock.MatchedBy(func(sql string) bool {
+		return strings.Contains(sql, "left join (receptions r")
+	})
+	mockPool.On("Query", ctx, infoQuery, []uuid.UUID{busyPvz, emptyPvz}).Run(func(args mock.Arguments) {
+		queries = append(queries, "info")
+	}).Return(&fakeRows{rows: [][]any{
+		{busyPvz, "Москва", started, &receptionId, &started, &productId, &started, &productType, &condition},
+		{emptyPvz, "Казань", started, (*uuid.UUID)(nil), (*time.Time)(nil), (*uuid.UUID)(nil), (*time.Time)(nil), (*string)(nil), (*string)(nil)},
+	}}, nil)
+
+	var got []models.PvzFilteredInfo
+	result, err := repo.GetFilteredInfo(ctx, &models.PvzFilter{Page: 1, PageLimit: 2}, func(row *models.PvzFilteredInfo) {
+		got = append(got, *row)
+	})
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"page", "info"}, queries)
+	require.Equal(t, 2, result.Total)
+	require.Nil(t, result.Next)
+	require.Nil(t, result.Prev)
+	require.Len(t, got, 2)
+	require.Equal(t, busyPvz, got[0].PvzID)
+	require.Equal(t, &productId, got[0].ProductID)
+	require.Equal(t, emptyPvz, got[1].PvzID)
+	require.Nil(t, got[1].ReceptionID)
+	mockPool.AssertExpectations(t)
+}
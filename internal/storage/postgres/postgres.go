@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"log"
 	"orderPickupPoint/config"
 	"time"
@@ -23,9 +24,21 @@ type Tx interface {
 	Commit(ctx context.Context) error
 	Rollback(ctx context.Context) error
 	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
 	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
 }
 
+// the reading part of DBPool and Tx, for queries run both inside and outside a transaction
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+func IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
 type PgxDBPool struct {
 	pool *pgxpool.Pool
 }
@@ -61,6 +74,10 @@ func (p *PgxTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
 	return p.tx.QueryRow(ctx, sql, args...)
 }
 
+func (p *PgxTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return p.tx.Query(ctx, sql, args...)
+}
+
 func (p *PgxTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
 	return p.tx.Exec(ctx, sql, args...)
 }
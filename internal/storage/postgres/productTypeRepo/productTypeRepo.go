@@ -0,0 +1,204 @@
+package productTypeRepo
+
+import (
+	"context"
+	"errors"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage/postgres"
+	"orderPickupPoint/internal/storage/postgres/sharedSql"
+
+	"github.com/jackc/pgx/v5"
+)
+
+type ProductTypeRepo struct {
+	pool postgres.DBPool
+}
+
+func NewProductTypeRepo(pool postgres.DBPool) *ProductTypeRepo {
+	return &ProductTypeRepo{
+		pool: pool,
+	}
+}
+
+func (r *ProductTypeRepo) List(ctx context.Context, includeInactive bool) ([]models.ProductType, error) {
+	query := `select ` + sharedSql.ProductTypeColumns + `,
+					n.locale, n.name
+				from product_types pt
+				left join product_type_names n on n.type_id = pt.id
+				where $1 or pt.active
+				order by pt.id, n.locale`
+
+	rows, err := r.pool.Query(ctx, query, includeInactive)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.ProductType
+	for rows.Next() {
+		var (
+			productType           models.ProductType
+			locale, localizedName *string
+		)
+		err := rows.Scan(append(sharedSql.ProductTypeFields(&productType), &locale, &localizedName)...)
+		if err != nil {
+			return nil, err
+		}
+		// one row per locale
+		if len(out) == 0 || out[len(out)-1].Id != productType.Id {
+			productType.Names = map[string]string{}
+			out = append(out, productType)
+		}
+		if locale != nil {
+			out[len(out)-1].Names[*locale] = *localizedName
+		}
+	}
+	return out, rows.Err()
+}
+
+func (r *ProductTypeRepo) GetByCode(ctx context.Context, code string) (*models.ProductType, error) {
+	query := `select ` + sharedSql.ProductTypeColumns + `
+				from product_types pt
+				where pt.code = $1`
+
+	queryNames := `select locale, name
+					from product_type_names
+					where type_id = $1`
+
+	productType := &models.ProductType{Names: map[string]string{}}
+	err := r.pool.QueryRow(ctx, query, code).Scan(sharedSql.ProductTypeFields(productType)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.pool.Query(ctx, queryNames, productType.Id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var locale, name string
+		if err := rows.Scan(&locale, &name); err != nil {
+			return nil, err
+		}
+		productType.Names[locale] = name
+	}
+	return productType, rows.Err()
+}
+
+func (r *ProductTypeRepo) Create(ctx context.Context, productType *models.ProductType) error {
+	query := `insert into product_types(code, name, active,
+					weight_required, dimensions_required, declared_value_required,
+					max_weight_grams, max_side_mm, max_declared_value, storage_days)
+				values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+				returning id`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	rules := &productType.Rules
+	err = tx.QueryRow(ctx, query, productType.Code, productType.Name, productType.Active,
+		rules.WeightRequired, rules.DimensionsRequired, rules.DeclaredValueRequired,
+		rules.MaxWeightGrams, rules.MaxSideMm, rules.MaxDeclaredValue, rules.StorageDays).Scan(&productType.Id)
+	if postgres.IsUniqueViolation(err) {
+		// code or default name is taken
+		return models.ErrProductTypeExists
+	}
+	if err != nil {
+		return err
+	}
+
+	err = setNames(ctx, tx, productType.Id, productType.Names)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// nil name, active and rules are kept, names are merged into the existing ones
+func (r *ProductTypeRepo) Update(ctx context.Context, code string, name *string, names map[string]string, active *bool, rules *models.ProductTypeRules) error {
+	query := `update product_types
+				set name = coalesce($2, name), active = coalesce($3, active)
+				where code = $1
+				returning id`
+
+	queryRules := `update product_types
+					set weight_required = $2, dimensions_required = $3, declared_value_required = $4,
+						max_weight_grams = $5, max_side_mm = $6, max_declared_value = $7, storage_days = $8
+					where id = $1`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var id int
+	err = tx.QueryRow(ctx, query, code, name, active).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	if postgres.IsUniqueViolation(err) {
+		return models.ErrProductTypeExists
+	}
+	if err != nil {
+		return err
+	}
+
+	if rules != nil {
+		_, err = tx.Exec(ctx, queryRules, id, rules.WeightRequired, rules.DimensionsRequired, rules.DeclaredValueRequired,
+			rules.MaxWeightGrams, rules.MaxSideMm, rules.MaxDeclaredValue, rules.StorageDays)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = setNames(ctx, tx, id, names)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// upserts the localized names, an empty name removes the locale
+func setNames(ctx context.Context, tx postgres.Tx, typeId int, names map[string]string) error {
+	queryUpsert := `insert into product_type_names(type_id, locale, name)
+					select $1, unnest($2::text[]), unnest($3::text[])
+					on conflict (type_id, locale) do update set name = excluded.name`
+
+	queryDelete := `delete from product_type_names
+					where type_id = $1 and locale = any($2)`
+
+	var locales, localized, removed []string
+	for locale, name := range names {
+		if name == "" {
+			removed = append(removed, locale)
+			continue
+		}
+		locales = append(locales, locale)
+		localized = append(localized, name)
+	}
+
+	if len(locales) > 0 {
+		_, err := tx.Exec(ctx, queryUpsert, typeId, locales, localized)
+		if err != nil {
+			return err
+		}
+	}
+	if len(removed) > 0 {
+		_, err := tx.Exec(ctx, queryDelete, typeId, removed)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
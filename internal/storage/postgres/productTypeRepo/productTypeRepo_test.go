@@ -0,0 +1,165 @@
+package productTypeRepo
+
+import (
+	"context"
+	"errors"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage/postgres"
+	"reflect"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockDbPool struct {
+	mock.Mock
+	postgres.DBPool
+}
+
+type mockDbTx struct {
+	mock.Mock
+	postgres.Tx
+}
+
+type mockRow struct {
+	mock.Mock
+}
+
+func (m *mockDbPool) Begin(ctx context.Context) (postgres.Tx, error) {
+	args := m.Called(ctx)
+	return args.Get(0).(postgres.Tx), args.Error(1)
+}
+
+func (m *mockDbTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	callArgs := m.Called(append([]interface{}{ctx, sql}, args...)...)
+	return callArgs.Get(0).(pgx.Row)
+}
+
+func (m *mockDbTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	callArgs := m.Called(append([]interface{}{ctx, sql}, args...)...)
+	return callArgs.Get(0).(pgconn.CommandTag), callArgs.Error(1)
+}
+
+func (m *mockDbTx) Commit(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func (m *mockDbTx) Rollback(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
+func (m *mockRow) Scan(dest ...any) error {
+	args := m.Called(dest...)
+	return args.Error(0)
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name      string
+		names     map[string]string
+		mockError error
+		wantError error
+	}{
+		{
+			name:  "with localized names",
+			names: map[string]string{"en": "Furniture"},
+		},
+		{
+			name: "without localized names",
+		},
+		{
+			name:      "code or name taken",
+			mockError: &pgconn.PgError{Code: "23505"},
+			wantError: models.ErrProductTypeExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			repo := NewProductTypeRepo(mockPool)
+			pgxRow := new(mockRow)
+
+			productType := &models.ProductType{Code: "furniture", Name: "мебель", Names: tt.names, Active: true}
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("QueryRow", ctx, mock.Anything, "furniture", "мебель", true, false, false, false, (*int)(nil), (*int)(nil), (*int64)(nil), (*int)(nil)).Return(pgxRow)
+			pgxRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+				reflect.ValueOf(args[0]).Elem().Set(reflect.ValueOf(4))
+			}).Return(tt.mockError)
+			if len(tt.names) > 0 {
+				mockTx.On("Exec", ctx, mock.Anything, 4, []string{"en"}, []string{"Furniture"}).Return(pgconn.CommandTag{}, nil)
+			}
+			if tt.wantError == nil {
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			err := repo.Create(ctx, productType)
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantError == nil {
+				require.Equal(t, 4, productType.Id)
+			}
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name      string
+		names     map[string]string
+		mockError error
+		wantError error
+	}{
+		{
+			name:  "rules, upsert and remove names",
+			names: map[string]string{"en": "Footwear", "de": ""},
+		},
+		{
+			name:      "unknown code",
+			mockError: pgx.ErrNoRows,
+			wantError: models.ErrNotFound,
+		},
+		{
+			name:      "db error",
+			mockError: errors.New("error"),
+			wantError: errors.New("error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			repo := NewProductTypeRepo(mockPool)
+			pgxRow := new(mockRow)
+
+			active := false
+			maxWeight := 5000
+			rules := &models.ProductTypeRules{WeightRequired: true, MaxWeightGrams: &maxWeight}
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("QueryRow", ctx, mock.Anything, "shoes", (*string)(nil), &active).Return(pgxRow)
+			pgxRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+				reflect.ValueOf(args[0]).Elem().Set(reflect.ValueOf(1))
+			}).Return(tt.mockError)
+			if tt.wantError == nil {
+				mockTx.On("Exec", ctx, mock.Anything, 1, []string{"en"}, []string{"Footwear"}).Return(pgconn.CommandTag{}, nil)
+				mockTx.On("Exec", ctx, mock.Anything, 1, []string{"de"}).Return(pgconn.CommandTag{}, nil)
+				mockTx.On("Exec", ctx, mock.Anything, 1, true, false, false, &maxWeight, (*int)(nil), (*int64)(nil), (*int)(nil)).Return(pgconn.CommandTag{}, nil)
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			err := repo.Update(ctx, "shoes", nil, tt.names, &active, rules)
+			require.Equal(t, tt.wantError, err)
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
@@ -2,12 +2,17 @@ package receptionRepo
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"orderPickupPoint/internal/models"
 	"orderPickupPoint/internal/storage/postgres"
-
+	"orderPickupPoint/internal/storage/postgres/sharedSql"
+	"orderPickupPoint/internal/utils/cursor"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
 type ReceptionRepo struct {
@@ -31,31 +36,92 @@ func (r *ReceptionRepo) GetStatusNameById(ctx context.Context, id int) (string,
 	return name, err
 }
 
-func (r *ReceptionRepo) GetProductTypeIdByName(ctx context.Context, name string) (int, error) {
-	query := `select id
-				from product_types
-				where name = $1`
+// the type is looked up by its code or default name, only active types can be used
+func (r *ReceptionRepo) GetProductTypeByName(ctx context.Context, name string) (*models.ProductType, error) {
+	query := `select ` + sharedSql.ProductTypeColumns + `
+				from product_types pt
+				where pt.code = $1 or pt.name = $1
+				order by pt.code = $1 desc
+				limit 1`
 
-	var id int
-	err := r.pool.QueryRow(ctx, query, name).Scan(&id)
+	productType := &models.ProductType{}
+	err := r.pool.QueryRow(ctx, query, name).Scan(sharedSql.ProductTypeFields(productType)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %q", models.ErrUnknownProductType, name)
+	}
 	if err != nil {
-		return -1, err
+		return nil, err
+	}
+	if !productType.Active {
+		return nil, fmt.Errorf("%w: %q", models.ErrInactiveProductType, name)
 	}
 
-	return id, nil
+	return productType, nil
 }
 
-func (r *ReceptionRepo) CreateReception(ctx context.Context, pvzId uuid.UUID) (*models.Reception, error) {
-	query := `insert into receptions(pvz_id)
-				select $1
-				where not exists(
-					select 1
-					from receptions
-					where pvz_id = $1 and status_id = 1)
-				returning id, reception_start_datetime, pvz_id, status_id`
+// active types by code or default name, unknown and inactive ones are absent in the result
+func (r *ReceptionRepo) GetProductTypesByNames(ctx context.Context, names []string) (map[string]models.ProductType, error) {
+	query := `select ` + sharedSql.ProductTypeColumns + `
+				from product_types pt
+				where pt.active and (pt.code = any($1) or pt.name = any($1))`
+
+	rows, err := r.pool.Query(ctx, query, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requested := make(map[string]bool, len(names))
+	for _, name := range names {
+		requested[name] = true
+	}
+
+	out := make(map[string]models.ProductType, len(names))
+	for rows.Next() {
+		var productType models.ProductType
+		if err := rows.Scan(sharedSql.ProductTypeFields(&productType)...); err != nil {
+			return nil, err
+		}
+		if requested[productType.Name] {
+			out[productType.Name] = productType
+		}
+		if requested[productType.Code] {
+			out[productType.Code] = productType
+		}
+	}
+	return out, rows.Err()
+}
+
+// opens a reception in the pvz and writes the initial history record,
+// no rows if the pvz already has an in_progress reception
+const queryCreateReception = `with created as (
+					insert into receptions(pvz_id, opened_by)
+					select $1, $2
+					where not exists(
+						select 1
+						from receptions
+						where pvz_id = $1 and status_id = $3)
+					returning id, reception_start_datetime, pvz_id, status_id, opened_by
+				), history as (
+					insert into reception_status_history(reception_id, to_status_id, changed_by)
+					select id, status_id, opened_by
+					from created
+				)
+				select id, reception_start_datetime, pvz_id, status_id, opened_by
+				from created`
 
+// ErrReceptionOpen if the pvz already has an in_progress reception
+func (r *ReceptionRepo) CreateReception(ctx context.Context, pvzId uuid.UUID, actorId *int) (*models.Reception, error) {
+	return scanCreatedReception(r.pool.QueryRow(ctx, queryCreateReception, pvzId, actorId, models.ReceptionStatusInProgress))
+}
+
+func scanCreatedReception(row pgx.Row) (*models.Reception, error) {
 	outReception := &models.Reception{}
-	err := r.pool.QueryRow(ctx, query, pvzId).Scan(&outReception.Id, &outReception.DateTime, &outReception.PickupPointId, &outReception.StatusId)
+	err := row.Scan(&outReception.Id, &outReception.DateTime, &outReception.PickupPointId, &outReception.StatusId, &outReception.OpenedBy)
+	// an open reception was there before or was opened concurrently
+	if errors.Is(err, pgx.ErrNoRows) || postgres.IsUniqueViolation(err) {
+		return nil, models.ErrReceptionOpen
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -63,21 +129,51 @@ func (r *ReceptionRepo) CreateReception(ctx context.Context, pvzId uuid.UUID) (*
 }
 
 func (r *ReceptionRepo) AddProductToReception(ctx context.Context, product *models.Product, pvzId uuid.UUID) (*models.Product, error) {
+	// locks the reception so it cannot be closed meanwhile
 	queryOpenReception := `select id
 							from receptions
-							where pvz_id = $1 and status_id = 1`
+							where pvz_id = $1 and status_id = $2
+							for update`
+
+	// locks the pvz row so concurrent acceptances see each other's stock
+	queryCapacity := `select p.max_items,
+							p.strict_capacity,
+							tc.max_items,
+							coalesce((select sum(items) from pvz_stock where pvz_id = p.id), 0),
+							coalesce((select items from pvz_stock where pvz_id = p.id and type_id = $2), 0)
+						from pvzs p
+						left join pvz_type_capacities tc on tc.pvz_id = p.id and tc.type_id = $2
+						where p.id = $1
+						for update of p`
 
-	queryAddProduct := `insert into products(type_id)
-						values ($1)
+	queryDuplicate := `select exists(
+							select 1
+							from reception_products rp
+							join products prod on prod.id = rp.product_id
+							where rp.reception_id = $1 and prod.barcode = $2 and prod.deleted_at is null)`
+
+	queryAddProduct := `insert into products(type_id, pvz_id, added_by, barcode, external_order_id, duplicate,
+							weight_grams, length_mm, width_mm, height_mm, declared_value, condition_id, condition_notes, storage_until)
+						values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, storage_deadline($2, $1, $14))
 						returning id, added_at`
 
 	query_reception_product := `insert into reception_products(reception_id, product_id)
 								values($1, $2)`
 
+	queryIncStock := `insert into pvz_stock(pvz_id, type_id, items)
+						values ($1, $2, 1)
+						on conflict (pvz_id, type_id) do update
+						set items = pvz_stock.items + 1`
+
 	var (
-		receptionId uuid.UUID
-		productId   uuid.UUID
-		addedAt     time.Time
+		receptionId  uuid.UUID
+		productId    uuid.UUID
+		addedAt      time.Time
+		maxItems     *int
+		strict       bool
+		maxTypeItems *int
+		storedItems  int
+		storedType   int
 	)
 
 	tx, err := r.pool.Begin(ctx)
@@ -86,13 +182,39 @@ func (r *ReceptionRepo) AddProductToReception(ctx context.Context, product *mode
 	}
 	defer tx.Rollback(ctx)
 
-	err = tx.QueryRow(ctx, queryOpenReception, pvzId).Scan(&receptionId)
+	err = tx.QueryRow(ctx, queryOpenReception, pvzId, models.ReceptionStatusInProgress).Scan(&receptionId)
 
 	if err != nil {
 		return nil, err
 	}
 
-	err = tx.QueryRow(ctx, queryAddProduct, product.TypeId).Scan(&productId, &addedAt)
+	err = tx.QueryRow(ctx, queryCapacity, pvzId, product.TypeId).Scan(&maxItems, &strict, &maxTypeItems, &storedItems, &storedType)
+	if err != nil {
+		return nil, err
+	}
+
+	overCapacity := (maxItems != nil && storedItems+1 > *maxItems) ||
+		(maxTypeItems != nil && storedType+1 > *maxTypeItems)
+	if overCapacity && strict {
+		return nil, models.ErrCapacityExceeded
+	}
+
+	// checked after the pvz lock, so the same barcode scanned twice at once is seen here
+	duplicate := false
+	if product.Barcode != nil {
+		err = tx.QueryRow(ctx, queryDuplicate, receptionId, *product.Barcode).Scan(&duplicate)
+		if err != nil {
+			return nil, err
+		}
+		if duplicate && !product.AllowDuplicate {
+			return nil, models.ErrDuplicateBarcode
+		}
+	}
+
+	attrs := product.ProductAttributes
+	err = tx.QueryRow(ctx, queryAddProduct, product.TypeId, pvzId, product.AddedBy, product.Barcode, product.ExternalOrderId, duplicate,
+		attrs.WeightGrams, attrs.LengthMm, attrs.WidthMm, attrs.HeightMm, attrs.DeclaredValue, product.ConditionId, product.Notes,
+		models.DefaultStorageDays).Scan(&productId, &addedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -101,32 +223,335 @@ func (r *ReceptionRepo) AddProductToReception(ctx context.Context, product *mode
 		return nil, err
 	}
 
-	tx.Commit(ctx)
+	_, err = tx.Exec(ctx, queryIncStock, pvzId, product.TypeId)
+	if err != nil {
+		return nil, err
+	}
+
+	cellCode, err := sharedSql.PlaceProduct(ctx, tx, productId, product.CellCode, product.AddedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if product.ExternalOrderId != nil {
+		err = sharedSql.RefreshOrdersOfProducts(ctx, tx, []uuid.UUID{productId}, product.AddedBy)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	outReception := &models.Product{
-		Id:          productId,
-		AddedAt:     addedAt,
-		TypeId:      product.TypeId,
-		ReceptionId: receptionId,
+		Id:                productId,
+		AddedAt:           addedAt,
+		TypeId:            product.TypeId,
+		ReceptionId:       receptionId,
+		AddedBy:           product.AddedBy,
+		Barcode:           product.Barcode,
+		ExternalOrderId:   product.ExternalOrderId,
+		ProductAttributes: product.ProductAttributes,
+		ConditionId:       product.ConditionId,
+		Notes:             product.Notes,
+		CellCode:          cellCode,
+		Duplicate:         duplicate,
+		OverCapacity:      overCapacity,
 	}
 
 	return outReception, nil
 }
 
-func (r *ReceptionRepo) DeleteLastProductInReception(ctx context.Context, pvzId uuid.UUID) error {
+// adds the products to an open reception in one transaction with a single multi-row insert.
+// An item is rejected on its own if its barcode was already scanned without AllowDuplicate or,
+// in strict mode, it does not fit the capacity; the other items are still added. Without strict mode
+// the items beyond the limit are marked OverCapacity. Results are returned in input order
+func (r *ReceptionRepo) AddProductsToReception(ctx context.Context, receptionId uuid.UUID, products []*models.Product, actorId *int) ([]models.ProductBatchItem, error) {
+	// locks the reception so it cannot be closed meanwhile
+	queryReception := `select pvz_id, status_id
+						from receptions
+						where id = $1
+						for update`
+
+	// locks the pvz row so concurrent acceptances see each other's stock
+	queryCapacity := `select p.max_items,
+							p.strict_capacity,
+							coalesce((select sum(items) from pvz_stock where pvz_id = p.id), 0)
+						from pvzs p
+						where p.id = $1
+						for update of p`
+
+	queryTypeCapacity := `select t.type_id, tc.max_items, coalesce(s.items, 0)
+							from unnest($2::int[]) t(type_id)
+							left join pvz_type_capacities tc on tc.pvz_id = $1 and tc.type_id = t.type_id
+							left join pvz_stock s on s.pvz_id = $1 and s.type_id = t.type_id`
+
+	queryScanned := `select prod.barcode
+						from reception_products rp
+						join products prod on prod.id = rp.product_id
+						where rp.reception_id = $1 and prod.barcode = any($2) and prod.deleted_at is null`
+
+	// clock_timestamp keeps the scan order in added_at for delete_last_product
+	queryAddProducts := `with added as (
+							insert into products(id, type_id, pvz_id, added_by, added_at, barcode, external_order_id, duplicate,
+								weight_grams, length_mm, width_mm, height_mm, declared_value, condition_id, condition_notes, storage_until)
+							select t.id, t.type_id, $3, $4, clock_timestamp(), t.barcode, t.external_order_id, t.duplicate,
+								t.weight_grams, t.length_mm, t.width_mm, t.height_mm, t.declared_value, t.condition_id, t.condition_notes,
+								storage_deadline($3, t.type_id, $16)
+							from unnest($1::uuid[], $2::int[], $6::text[], $7::text[], $8::boolean[],
+									$9::int[], $10::int[], $11::int[], $12::int[], $13::bigint[], $14::int[], $15::text[])
+								with ordinality t(id, type_id, barcode, external_order_id, duplicate,
+									weight_grams, length_mm, width_mm, height_mm, declared_value, condition_id, condition_notes, n)
+							order by t.n
+							returning id, added_at
+						), linked as (
+							insert into reception_products(reception_id, product_id)
+							select $5, id
+							from added
+						)
+						select id, added_at
+						from added`
+
+	queryIncStock := `insert into pvz_stock(pvz_id, type_id, items)
+						select $1, type_id, count(*)
+						from unnest($2::int[]) t(type_id)
+						group by type_id
+						on conflict (pvz_id, type_id) do update
+						set items = pvz_stock.items + excluded.items`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		pvzId    uuid.UUID
+		statusId int
+	)
+	err = tx.QueryRow(ctx, queryReception, receptionId).Scan(&pvzId, &statusId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if statusId != models.ReceptionStatusInProgress {
+		return nil, models.ErrReceptionNotOpen
+	}
+
+	var (
+		maxItems    *int
+		strict      bool
+		storedItems int
+	)
+	err = tx.QueryRow(ctx, queryCapacity, pvzId).Scan(&maxItems, &strict, &storedItems)
+	if err != nil {
+		return nil, err
+	}
+
+	barcodes := make([]*string, len(products))
+	typeIds := make([]int, len(products))
+	for i, product := range products {
+		barcodes[i] = product.Barcode
+		typeIds[i] = product.TypeId
+	}
+
+	scanned, err := scannedBarcodes(ctx, tx, queryScanned, receptionId, barcodes)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, queryTypeCapacity, pvzId, typeIds)
+	if err != nil {
+		return nil, err
+	}
+	maxTypeItems := make(map[int]*int)
+	storedType := make(map[int]int)
+	for rows.Next() {
+		var (
+			typeId  int
+			maxType *int
+			stored  int
+		)
+		if err := rows.Scan(&typeId, &maxType, &stored); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		maxTypeItems[typeId] = maxType
+		storedType[typeId] = stored
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// rejected items take no place and do not count as scanned
+	out := make([]models.ProductBatchItem, len(products))
+	var accepted []int
+	for i, product := range products {
+		// duplicates both with the reception and inside the batch
+		duplicate := product.Barcode != nil && scanned[*product.Barcode]
+		if duplicate && !product.AllowDuplicate {
+			out[i].Err = fmt.Errorf("%w: %s", models.ErrDuplicateBarcode, *product.Barcode)
+			continue
+		}
+
+		maxType := maxTypeItems[product.TypeId]
+		overCapacity := (maxItems != nil && storedItems+1 > *maxItems) ||
+			(maxType != nil && storedType[product.TypeId]+1 > *maxType)
+		if overCapacity && strict {
+			out[i].Err = models.ErrCapacityExceeded
+			continue
+		}
+		storedItems++
+		storedType[product.TypeId]++
+		if product.Barcode != nil {
+			scanned[*product.Barcode] = true
+		}
+
+		out[i].Product = &models.Product{
+			Id:                uuid.New(),
+			TypeId:            product.TypeId,
+			ReceptionId:       receptionId,
+			AddedBy:           actorId,
+			Barcode:           product.Barcode,
+			ExternalOrderId:   product.ExternalOrderId,
+			ProductAttributes: product.ProductAttributes,
+			ConditionId:       product.ConditionId,
+			Notes:             product.Notes,
+			Duplicate:         duplicate,
+			OverCapacity:      overCapacity,
+		}
+		accepted = append(accepted, i)
+	}
+	if len(accepted) == 0 {
+		return out, nil
+	}
+
+	ids := make([]uuid.UUID, len(accepted))
+	addedTypeIds := make([]int, len(accepted))
+	addedBarcodes := make([]*string, len(accepted))
+	externalOrderIds := make([]*string, len(accepted))
+	duplicates := make([]bool, len(accepted))
+	weights := make([]*int, len(accepted))
+	lengths := make([]*int, len(accepted))
+	widths := make([]*int, len(accepted))
+	heights := make([]*int, len(accepted))
+	declaredValues := make([]*int64, len(accepted))
+	conditionIds := make([]int, len(accepted))
+	notes := make([]string, len(accepted))
+	for n, i := range accepted {
+		product := out[i].Product
+		ids[n] = product.Id
+		addedTypeIds[n] = product.TypeId
+		addedBarcodes[n] = product.Barcode
+		externalOrderIds[n] = product.ExternalOrderId
+		duplicates[n] = product.Duplicate
+		weights[n] = product.WeightGrams
+		lengths[n] = product.LengthMm
+		widths[n] = product.WidthMm
+		heights[n] = product.HeightMm
+		declaredValues[n] = product.DeclaredValue
+		conditionIds[n] = product.ConditionId
+		notes[n] = product.Notes
+	}
+
+	rows, err = tx.Query(ctx, queryAddProducts, ids, addedTypeIds, pvzId, actorId, receptionId, addedBarcodes, externalOrderIds, duplicates,
+		weights, lengths, widths, heights, declaredValues, conditionIds, notes, models.DefaultStorageDays)
+	if err != nil {
+		return nil, err
+	}
+	addedAt := make(map[uuid.UUID]time.Time, len(accepted))
+	for rows.Next() {
+		var (
+			id      uuid.UUID
+			addedTs time.Time
+		)
+		if err := rows.Scan(&id, &addedTs); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		addedAt[id] = addedTs
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, queryIncStock, pvzId, addedTypeIds)
+	if err != nil {
+		return nil, err
+	}
+
+	// in input order, so items of one order end up together
+	for _, i := range accepted {
+		product := out[i].Product
+		product.AddedAt = addedAt[product.Id]
+		product.CellCode, err = sharedSql.PlaceProduct(ctx, tx, product.Id, products[i].CellCode, actorId)
+		if err != nil {
+			return nil, fmt.Errorf("product %d: %w", i+1, err)
+		}
+	}
+
+	if slices.ContainsFunc(externalOrderIds, func(orderId *string) bool { return orderId != nil }) {
+		err = sharedSql.RefreshOrdersOfProducts(ctx, tx, ids, actorId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// barcodes of the list already present in the reception
+func scannedBarcodes(ctx context.Context, tx postgres.Tx, query string, receptionId uuid.UUID, barcodes []*string) (map[string]bool, error) {
+	out := make(map[string]bool)
+	rows, err := tx.Query(ctx, query, receptionId, barcodes)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		out[code] = true
+	}
+	return out, rows.Err()
+}
+
+// the product is marked as deleted and stays in the reception for the audit
+func (r *ReceptionRepo) DeleteLastProductInReception(ctx context.Context, pvzId uuid.UUID, actorId *int) error {
+	// locks the reception so it cannot be closed meanwhile
 	queryReceptionIsOpen := `select id
 							from receptions
-							where pvz_id = $1 and status_id = 1`
+							where pvz_id = $1 and status_id = $2
+							for update`
 
 	queryProductIndex := `select id
 							from reception_products rp
 							left join products p on p.id = rp.product_id
-							where reception_id = $1
+							where reception_id = $1 and p.deleted_at is null and p.issued_at is null and p.left_at is null
 							order by p.added_at desc
 							limit 1`
 
-	queryDeleteProduct := `delete from products p
-							where id = $1`
+	queryDecStock := `update pvz_stock s
+						set items = s.items - 1
+						from products p
+						where p.id = $1 and s.pvz_id = p.pvz_id and s.type_id = p.type_id`
+
+	queryDeleteProduct := `update products
+							set deleted_at = now(), deleted_by = $2
+							where id = $1 and deleted_at is null`
 
 	tx, err := r.pool.Begin(ctx)
 	if err != nil {
@@ -138,7 +563,7 @@ func (r *ReceptionRepo) DeleteLastProductInReception(ctx context.Context, pvzId
 		receptionId uuid.UUID
 		productId   uuid.UUID
 	)
-	err = tx.QueryRow(ctx, queryReceptionIsOpen, pvzId).Scan(&receptionId)
+	err = tx.QueryRow(ctx, queryReceptionIsOpen, pvzId, models.ReceptionStatusInProgress).Scan(&receptionId)
 	if err != nil {
 		return err
 	}
@@ -148,20 +573,870 @@ func (r *ReceptionRepo) DeleteLastProductInReception(ctx context.Context, pvzId
 		return err
 	}
 
-	_, err = tx.Exec(ctx, queryDeleteProduct, productId)
+	_, err = tx.Exec(ctx, queryDecStock, productId)
 	if err != nil {
 		return err
 	}
 
-	tx.Commit(ctx)
+	_, err = tx.Exec(ctx, queryDeleteProduct, productId, actorId)
+	if err != nil {
+		return err
+	}
+
+	err = sharedSql.RefreshOrdersOfProducts(ctx, tx, []uuid.UUID{productId}, actorId)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	return tx.Commit(ctx)
 }
 
-func (r *ReceptionRepo) CloseReception(ctx context.Context, pvzId uuid.UUID) error {
-	query := `update receptions
-				set status_id = 2
-				where pvz_id = $1 and status_id = 1`
-	_, err := r.pool.Exec(ctx, query, pvzId)
+// soft-deletes the given product of an open reception, products shipped to another pvz are kept
+func (r *ReceptionRepo) DeleteProductInReception(ctx context.Context, receptionId uuid.UUID, productId uuid.UUID, actorId *int) error {
+	// locks the reception so it cannot be closed meanwhile
+	queryReceptionStatus := `select status_id
+								from receptions
+								where id = $1
+								for update`
+
+	queryDeleteProduct := `update products
+							set deleted_at = now(), deleted_by = $3
+							where id = $2 and deleted_at is null and issued_at is null and left_at is null
+								and id in (select product_id from reception_products where reception_id = $1)
+							returning pvz_id, type_id`
+
+	queryDecStock := `update pvz_stock
+						set items = items - 1
+						where pvz_id = $1 and type_id = $2`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var statusId int
+	err = tx.QueryRow(ctx, queryReceptionStatus, receptionId).Scan(&statusId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if statusId != models.ReceptionStatusInProgress {
+		return models.ErrReceptionNotOpen
+	}
+
+	var (
+		pvzId  *uuid.UUID
+		typeId int
+	)
+	err = tx.QueryRow(ctx, queryDeleteProduct, receptionId, productId, actorId).Scan(&pvzId, &typeId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// not in this reception or already deleted
+		return models.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, queryDecStock, pvzId, typeId)
+	if err != nil {
+		return err
+	}
+
+	err = sharedSql.RefreshOrdersOfProducts(ctx, tx, []uuid.UUID{productId}, actorId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *ReceptionRepo) GetReception(ctx context.Context, receptionId uuid.UUID) (*models.Reception, error) {
+	query := `select id, reception_start_datetime, pvz_id, status_id, opened_by
+				from receptions
+				where id = $1`
+
+	outReception := &models.Reception{}
+	err := r.pool.QueryRow(ctx, query, receptionId).Scan(&outReception.Id, &outReception.DateTime, &outReception.PickupPointId, &outReception.StatusId, &outReception.OpenedBy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return outReception, nil
+}
+
+// the reception of the pickup point in progress, a reopened one included
+func (r *ReceptionRepo) GetOpenReception(ctx context.Context, pvzId uuid.UUID) (*models.Reception, error) {
+	query := `select id, reception_start_datetime, pvz_id, status_id, opened_by
+				from receptions
+				where pvz_id = $1 and status_id = $2`
+
+	outReception := &models.Reception{}
+	err := r.pool.QueryRow(ctx, query, pvzId, models.ReceptionStatusInProgress).Scan(&outReception.Id, &outReception.DateTime, &outReception.PickupPointId, &outReception.StatusId, &outReception.OpenedBy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrReceptionNotOpen
+	}
+	if err != nil {
+		return nil, err
+	}
+	return outReception, nil
+}
+
+// moves the reception to the new status only if it still has the expected one
+// and writes the change to the history
+func (r *ReceptionRepo) ChangeReceptionStatus(ctx context.Context, transition *models.ReceptionTransition) error {
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = changeStatus(ctx, tx, transition)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// closes the reception together with its reconciliation. The reception is locked so its products
+// cannot change meanwhile, then its manifest and products are passed to check and an error of check
+// keeps the reception open. check is not called for a reception without a manifest
+func (r *ReceptionRepo) CloseReception(ctx context.Context, transition *models.ReceptionTransition,
+	check func(manifest *models.ManifestAPI, products []models.ProductInfo) error) error {
+	queryLock := `select status_id
+					from receptions
+					where id = $1
+					for update`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var statusId int
+	err = tx.QueryRow(ctx, queryLock, transition.ReceptionId).Scan(&statusId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if statusId != transition.FromStatusId {
+		// status was changed by someone else
+		return models.ErrIllegalTransition
+	}
+
+	manifest, err := getManifest(ctx, tx, transition.ReceptionId)
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+	case err != nil:
+		return err
+	default:
+		products, err := getReceptionProducts(ctx, tx, transition.ReceptionId)
+		if err != nil {
+			return err
+		}
+		if err := check(manifest, products); err != nil {
+			return err
+		}
+	}
+
+	err = changeStatus(ctx, tx, transition)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// same as ChangeReceptionStatus inside an outer transaction.
+// a transition to the same status only checks it and leaves a history record
+func changeStatus(ctx context.Context, tx postgres.Tx, transition *models.ReceptionTransition) error {
+	queryStatus := `update receptions
+					set status_id = $3
+					where id = $1 and status_id = $2`
+
+	queryClose := `update receptions
+					set status_id = $3, closed_at = now(), closed_by = $4
+					where id = $1 and status_id = $2`
+
+	queryReopen := `update receptions
+					set status_id = $3, closed_at = null, closed_by = null, stale_since = null
+					where id = $1 and status_id = $2`
+
+	args := []any{transition.ReceptionId, transition.FromStatusId, transition.ToStatusId}
+	query := queryStatus
+	switch {
+	case transition.FromStatusId == transition.ToStatusId:
+	case transition.ToStatusId == models.ReceptionStatusClosed:
+		query = queryClose
+		args = append(args, transition.ActorId)
+	case transition.ToStatusId == models.ReceptionStatusInProgress:
+		query = queryReopen
+	}
+
+	tag, err := tx.Exec(ctx, query, args...)
+	if postgres.IsUniqueViolation(err) {
+		// another reception of the pvz was opened meanwhile
+		return models.ErrReceptionOpen
+	}
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		// status was changed by someone else
+		return models.ErrIllegalTransition
+	}
+
+	return writeHistory(ctx, tx, transition)
+}
+
+func writeHistory(ctx context.Context, tx postgres.Tx, transition *models.ReceptionTransition) error {
+	query := `insert into reception_status_history(reception_id, from_status_id, to_status_id, changed_by, comment)
+						values ($1, $2, $3, $4, $5)`
+
+	_, err := tx.Exec(ctx, query, transition.ReceptionId, transition.FromStatusId, transition.ToStatusId, transition.ActorId, transition.Comment)
 	return err
 }
+
+// in_progress receptions opened before openedBefore or without product activity since idleBefore,
+// nil bound disables the check. Deletion of a product counts as activity too
+func (r *ReceptionRepo) ListStaleReceptions(ctx context.Context, openedBefore *time.Time, idleBefore *time.Time) ([]models.StaleReception, error) {
+	query := `select r.id, r.reception_start_datetime, r.pvz_id, r.status_id, r.opened_by, r.stale_since, o.opened_at, a.last_activity
+				from receptions r
+				cross join lateral (
+					select coalesce(max(h.changed_at), r.reception_start_datetime) as opened_at
+					from reception_status_history h
+					where h.reception_id = r.id and h.to_status_id = $3 and h.from_status_id is distinct from $3) o
+				cross join lateral (
+					select greatest(o.opened_at, max(prod.added_at), max(prod.deleted_at)) as last_activity
+					from reception_products rp
+					join products prod on prod.id = rp.product_id
+					where rp.reception_id = r.id) a
+				where r.status_id = $3
+					and (($1::timestamptz is not null and o.opened_at < $1)
+						or ($2::timestamptz is not null and a.last_activity < $2))
+				order by o.opened_at`
+
+	rows, err := r.pool.Query(ctx, query, openedBefore, idleBefore, models.ReceptionStatusInProgress)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var receptions []models.StaleReception
+	for rows.Next() {
+		var reception models.StaleReception
+		err := rows.Scan(&reception.Id, &reception.DateTime, &reception.PickupPointId, &reception.StatusId, &reception.OpenedBy,
+			&reception.StaleSince, &reception.OpenedAt, &reception.LastActivity)
+		if err != nil {
+			return nil, err
+		}
+		receptions = append(receptions, reception)
+	}
+	return receptions, rows.Err()
+}
+
+// marks an in_progress reception as stale once and records the event,
+// the flag is cleared when the reception is reopened
+func (r *ReceptionRepo) FlagStaleReception(ctx context.Context, event *models.ReceptionEvent) error {
+	query := `update receptions
+				set stale_since = now()
+				where id = $1 and status_id = $2 and stale_since is null`
+
+	queryEvent := `insert into reception_events(reception_id, event, created_by, comment)
+					values ($1, $2, $3, $4)`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, query, event.ReceptionId, models.ReceptionStatusInProgress)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		// closed meanwhile or already flagged
+		return models.ErrIllegalTransition
+	}
+
+	_, err = tx.Exec(ctx, queryEvent, event.ReceptionId, event.Event, event.ActorId, event.Comment)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// cancels the reception, soft-deletes its products still in the pvz and takes them out of the pvz stock
+func (r *ReceptionRepo) CancelReception(ctx context.Context, transition *models.ReceptionTransition) error {
+	queryDecStock := `update pvz_stock s
+						set items = s.items - c.items
+						from (
+							select prod.pvz_id, prod.type_id, count(*) as items
+							from reception_products rp
+							join products prod on prod.id = rp.product_id
+							where rp.reception_id = $1 and prod.deleted_at is null and prod.issued_at is null and prod.left_at is null
+							group by prod.pvz_id, prod.type_id) c
+						where s.pvz_id = c.pvz_id and s.type_id = c.type_id`
+
+	queryDeleteProducts := `update products
+							set deleted_at = now(), deleted_by = $2
+							where deleted_at is null and issued_at is null and left_at is null
+								and id in (select product_id from reception_products where reception_id = $1)`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	err = changeStatus(ctx, tx, transition)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, queryDecStock, transition.ReceptionId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, queryDeleteProducts, transition.ReceptionId, transition.ActorId)
+	if err != nil {
+		return err
+	}
+
+	err = sharedSql.RefreshOrdersOfReception(ctx, tx, transition.ReceptionId, transition.ActorId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *ReceptionRepo) GetReceptionHistory(ctx context.Context, receptionId uuid.UUID) ([]models.ReceptionHistoryItem, error) {
+	query := `select fs.name, ts.name, h.changed_at, h.changed_by, h.comment
+				from reception_status_history h
+				left join reception_statuses fs on fs.id = h.from_status_id
+				join reception_statuses ts on ts.id = h.to_status_id
+				where h.reception_id = $1
+				order by h.changed_at, h.id`
+
+	rows, err := r.pool.Query(ctx, query, receptionId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.ReceptionHistoryItem{}
+	for rows.Next() {
+		var item models.ReceptionHistoryItem
+		if err := rows.Scan(&item.From, &item.To, &item.ChangedAt, &item.ChangedBy, &item.Comment); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
+
+func (r *ReceptionRepo) GetReceptionById(ctx context.Context, receptionId uuid.UUID) (*models.ReceptionDetailsAPI, error) {
+	query := `select r.id, r.pvz_id, rs.name, r.reception_start_datetime, r.closed_at, r.opened_by, r.closed_by, r.stale_since
+				from receptions r
+				join reception_statuses rs on rs.id = r.status_id
+				where r.id = $1`
+
+	reception := &models.ReceptionDetailsAPI{}
+	err := r.pool.QueryRow(ctx, query, receptionId).Scan(&reception.Id, &reception.PickupPointId, &reception.Status, &reception.DateTime, &reception.ClosedAt, &reception.OpenedBy, &reception.ClosedBy, &reception.StaleSince)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := r.getProductCounts(ctx, []uuid.UUID{receptionId})
+	if err != nil {
+		return nil, err
+	}
+	setProductCounts(reception, counts[receptionId])
+
+	return reception, nil
+}
+
+func (r *ReceptionRepo) GetReceptionProducts(ctx context.Context, receptionId uuid.UUID) ([]models.ProductInfo, error) {
+	return getReceptionProducts(ctx, r.pool, receptionId)
+}
+
+func getReceptionProducts(ctx context.Context, q postgres.Querier, receptionId uuid.UUID) ([]models.ProductInfo, error) {
+	query := `select prod.id, prod.added_at, pt.name, prod.added_by, prod.deleted_at, prod.deleted_by,
+					prod.barcode, prod.external_order_id, prod.duplicate,
+					prod.weight_grams, prod.length_mm, prod.width_mm, prod.height_mm, prod.declared_value,
+					pc.name, prod.condition_notes, prod.left_at,
+					case when prod.deleted_at is null and prod.issued_at is null and prod.left_at is null then sc.code end
+				from reception_products rp
+				join products prod on prod.id = rp.product_id
+				join product_types pt on pt.id = prod.type_id
+				join product_conditions pc on pc.id = prod.condition_id
+				left join storage_cells sc on sc.id = prod.cell_id
+				where rp.reception_id = $1
+				order by prod.added_at, prod.id`
+
+	rows, err := q.Query(ctx, query, receptionId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.ProductInfo{}
+	for rows.Next() {
+		var product models.ProductInfo
+		err := rows.Scan(&product.ID, &product.AddedAt, &product.Type, &product.AddedBy, &product.DeletedAt, &product.DeletedBy,
+			&product.Barcode, &product.ExternalOrderId, &product.Duplicate,
+			&product.WeightGrams, &product.LengthMm, &product.WidthMm, &product.HeightMm, &product.DeclaredValue,
+			&product.Condition, &product.Notes, &product.LeftAt, &product.Cell)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, product)
+	}
+	return out, rows.Err()
+}
+
+// receptions of a pickup point from newest to oldest, page by offset or by cursor.
+// ErrNotFound if the pvz does not exist
+func (r *ReceptionRepo) ListReceptions(ctx context.Context, filter *models.ReceptionFilter) (*models.ListResult[models.ReceptionDetailsAPI], error) {
+	queryPvz := `select exists(select 1 from pvzs where id = $1)`
+
+	queryFilter := `from receptions r
+				join reception_statuses rs on rs.id = r.status_id
+				where r.pvz_id = $1
+					and ($2::text[] is null or rs.name = any($2))
+					and ($3::timestamptz is null or r.reception_start_datetime >= $3)
+					and ($4::timestamptz is null or r.reception_start_datetime <= $4)`
+
+	var exists bool
+	err := r.pool.QueryRow(ctx, queryPvz, filter.PvzId).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, models.ErrNotFound
+	}
+
+	args := []any{filter.PvzId, filter.Statuses, filter.StartDate, filter.EndDate}
+
+	result := &models.ListResult[models.ReceptionDetailsAPI]{}
+	err = r.pool.QueryRow(ctx, "select count(*)\n"+queryFilter, args...).Scan(&result.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	keysetMatch := ""
+	direction := "desc"
+	offset := 0
+	if filter.Cursor != nil {
+		if !filter.Cursor.Matches(models.ReceptionSortByDate, true) {
+			return nil, models.ErrInvalidFilter
+		}
+		op := "<"
+		if filter.Cursor.Backward {
+			op = ">"
+			direction = "asc"
+		}
+		keysetMatch = fmt.Sprintf("\n\t\t\t\t\tand (r.reception_start_datetime, r.id) %s ($7::timestamptz, $8::uuid)", op)
+		args = append(args, filter.PageLimit+1, offset, filter.Cursor.SortKey, filter.Cursor.Id)
+	} else {
+		offset = filter.PageLimit * (filter.Page - 1)
+		args = append(args, filter.PageLimit+1, offset)
+	}
+
+	query := fmt.Sprintf(`select r.id, r.pvz_id, rs.name, r.reception_start_datetime, r.closed_at, r.opened_by, r.closed_by, r.stale_since
+				%s%s
+				order by r.reception_start_datetime %s, r.id %s
+				limit $5 offset $6`, queryFilter, keysetMatch, direction, direction)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var receptions []models.ReceptionDetailsAPI
+	for rows.Next() {
+		var reception models.ReceptionDetailsAPI
+		if err := rows.Scan(&reception.Id, &reception.PickupPointId, &reception.Status, &reception.DateTime, &reception.ClosedAt, &reception.OpenedBy, &reception.ClosedBy, &reception.StaleSince); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		receptions = append(receptions, reception)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result.Items, result.Next, result.Prev = cursor.Slice(receptions, filter.PageLimit, filter.Cursor, offset, func(reception models.ReceptionDetailsAPI) cursor.Cursor {
+		return cursor.Cursor{
+			SortBy:  models.ReceptionSortByDate,
+			Desc:    true,
+			SortKey: reception.DateTime.Format(time.RFC3339Nano),
+			Id:      reception.Id.String(),
+		}
+	})
+
+	ids := make([]uuid.UUID, 0, len(result.Items))
+	for _, reception := range result.Items {
+		ids = append(ids, reception.Id)
+	}
+	counts, err := r.getProductCounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range result.Items {
+		setProductCounts(&result.Items[i], counts[result.Items[i].Id])
+	}
+
+	return result, nil
+}
+
+// number of products of every type in the given receptions and the totals of their attributes
+func (r *ReceptionRepo) getProductCounts(ctx context.Context, receptionIds []uuid.UUID) (map[uuid.UUID]*productCounts, error) {
+	query := `select rp.reception_id, pt.name, count(*),
+					coalesce(sum(prod.weight_grams), 0),
+					coalesce(sum(prod.length_mm::bigint * prod.width_mm * prod.height_mm), 0),
+					coalesce(sum(prod.declared_value), 0)
+				from reception_products rp
+				join products prod on prod.id = rp.product_id
+				join product_types pt on pt.id = prod.type_id
+				where rp.reception_id = any($1) and prod.deleted_at is null and prod.left_at is null
+				group by rp.reception_id, pt.name`
+
+	out := make(map[uuid.UUID]*productCounts)
+	if len(receptionIds) == 0 {
+		return out, nil
+	}
+
+	rows, err := r.pool.Query(ctx, query, receptionIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			receptionId              uuid.UUID
+			typeName                 string
+			count                    int
+			weight, volume, declared int64
+		)
+		if err := rows.Scan(&receptionId, &typeName, &count, &weight, &volume, &declared); err != nil {
+			return nil, err
+		}
+		counts := out[receptionId]
+		if counts == nil {
+			counts = &productCounts{byType: make(map[string]int)}
+			out[receptionId] = counts
+		}
+		counts.byType[typeName] = count
+		counts.weightGrams += weight
+		counts.volumeMm3 += volume
+		counts.declaredValue += declared
+	}
+	return out, rows.Err()
+}
+
+type productCounts struct {
+	byType        map[string]int
+	weightGrams   int64
+	volumeMm3     int64
+	declaredValue int64
+}
+
+func setProductCounts(reception *models.ReceptionDetailsAPI, counts *productCounts) {
+	reception.CountsByType = make(map[string]int)
+	if counts == nil {
+		return
+	}
+	for typeName, count := range counts.byType {
+		reception.CountsByType[typeName] = count
+		reception.ProductsCount += count
+	}
+	reception.Totals = models.ProductTotals{
+		WeightGrams:   counts.weightGrams,
+		VolumeCm3:     counts.volumeMm3 / 1000,
+		DeclaredValue: counts.declaredValue,
+	}
+}
+
+// records the request together with a history entry; transition must keep the reception closed
+func (r *ReceptionRepo) CreateReopenRequest(ctx context.Context, request *models.ReopenRequest, transition *models.ReceptionTransition) (*models.ReopenRequest, error) {
+	query := `insert into reception_reopen_requests(reception_id, reason, requested_by)
+				values ($1, $2, $3)
+				returning id, requested_at`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	err = changeStatus(ctx, tx, transition)
+	if err != nil {
+		return nil, err
+	}
+
+	outRequest := *request
+	err = tx.QueryRow(ctx, query, request.ReceptionId, request.Reason, request.RequestedBy).Scan(&outRequest.Id, &outRequest.RequestedAt)
+	if postgres.IsUniqueViolation(err) {
+		return nil, models.ErrReopenPending
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &outRequest, nil
+}
+
+// resolves a pending request and applies the transition in one transaction:
+// closed -> in_progress on approval, closed -> closed (history only) on rejection
+func (r *ReceptionRepo) ResolveReopenRequest(ctx context.Context, resolution *models.ReopenResolution, transition *models.ReceptionTransition) error {
+	queryResolve := `update reception_reopen_requests
+						set status_id = $2, resolved_at = now(), resolved_by = $3, comment = $4
+						where id = $1 and status_id = $5`
+
+	queryOpenExists := `select exists(
+							select 1
+							from receptions
+							where pvz_id = (select pvz_id from receptions where id = $1) and status_id = $2)`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, queryResolve, resolution.RequestId, resolution.StatusId, resolution.ActorId, resolution.Comment, models.ReopenRequestPending)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return models.ErrReopenResolved
+	}
+
+	if transition.ToStatusId == models.ReceptionStatusInProgress {
+		var openExists bool
+		err = tx.QueryRow(ctx, queryOpenExists, transition.ReceptionId, models.ReceptionStatusInProgress).Scan(&openExists)
+		if err != nil {
+			return err
+		}
+		if openExists {
+			return models.ErrReceptionOpen
+		}
+	}
+
+	err = changeStatus(ctx, tx, transition)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *ReceptionRepo) GetReopenRequest(ctx context.Context, requestId uuid.UUID) (*models.ReopenRequestAPI, error) {
+	query := `select rr.id, rr.reception_id, s.name, rr.reason, rr.requested_at, rr.requested_by, rr.resolved_at, rr.resolved_by, rr.comment
+				from reception_reopen_requests rr
+				join reopen_request_statuses s on s.id = rr.status_id
+				where rr.id = $1`
+
+	request := &models.ReopenRequestAPI{}
+	err := r.pool.QueryRow(ctx, query, requestId).Scan(&request.Id, &request.ReceptionId, &request.Status, &request.Reason, &request.RequestedAt,
+		&request.RequestedBy, &request.ResolvedAt, &request.ResolvedBy, &request.Comment)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// requests of one reception or, when receptionId is nil, of all receptions; statusId nil means any status
+func (r *ReceptionRepo) ListReopenRequests(ctx context.Context, receptionId *uuid.UUID, statusId *int) ([]models.ReopenRequestAPI, error) {
+	query := `select rr.id, rr.reception_id, s.name, rr.reason, rr.requested_at, rr.requested_by, rr.resolved_at, rr.resolved_by, rr.comment
+				from reception_reopen_requests rr
+				join reopen_request_statuses s on s.id = rr.status_id
+				where ($1::uuid is null or rr.reception_id = $1)
+					and ($2::int is null or rr.status_id = $2)
+				order by rr.requested_at, rr.id`
+
+	rows, err := r.pool.Query(ctx, query, receptionId, statusId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.ReopenRequestAPI{}
+	for rows.Next() {
+		var request models.ReopenRequestAPI
+		err := rows.Scan(&request.Id, &request.ReceptionId, &request.Status, &request.Reason, &request.RequestedAt,
+			&request.RequestedBy, &request.ResolvedAt, &request.ResolvedBy, &request.Comment)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, request)
+	}
+	return out, rows.Err()
+}
+
+// accepted products with the barcode, newest first
+func (r *ReceptionRepo) FindProductsByBarcode(ctx context.Context, barcode string) ([]models.ProductLocationAPI, error) {
+	query := `select prod.id, prod.added_at, pt.name, prod.added_by, prod.barcode, prod.external_order_id, prod.duplicate,
+					prod.weight_grams, prod.length_mm, prod.width_mm, prod.height_mm, prod.declared_value,
+					pc.name, prod.condition_notes, prod.left_at, rp.reception_id, prod.pvz_id,
+					case when prod.issued_at is null and prod.left_at is null then sc.code end
+				from products prod
+				join product_types pt on pt.id = prod.type_id
+				join product_conditions pc on pc.id = prod.condition_id
+				join reception_products rp on rp.product_id = prod.id
+				left join storage_cells sc on sc.id = prod.cell_id
+				where prod.barcode = $1 and prod.deleted_at is null
+				order by prod.added_at desc, prod.id`
+
+	rows, err := r.pool.Query(ctx, query, barcode)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.ProductLocationAPI{}
+	for rows.Next() {
+		var product models.ProductLocationAPI
+		err := rows.Scan(&product.ID, &product.AddedAt, &product.Type, &product.AddedBy, &product.Barcode, &product.ExternalOrderId, &product.Duplicate,
+			&product.WeightGrams, &product.LengthMm, &product.WidthMm, &product.HeightMm, &product.DeclaredValue,
+			&product.Condition, &product.Notes, &product.LeftAt, &product.ReceptionId, &product.PvzId, &product.Cell)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, product)
+	}
+	return out, rows.Err()
+}
+
+// replaces the manifest of an open reception
+func (r *ReceptionRepo) SetManifest(ctx context.Context, manifest *models.Manifest) error {
+	// locks the reception so it cannot be closed meanwhile
+	queryReceptionStatus := `select status_id
+								from receptions
+								where id = $1
+								for update`
+
+	queryManifest := `insert into reception_manifests(reception_id, supplier_ref, max_discrepancy, created_by)
+						values ($1, $2, $3, $4)
+						on conflict (reception_id) do update
+						set supplier_ref = excluded.supplier_ref,
+							max_discrepancy = excluded.max_discrepancy,
+							created_at = now(),
+							created_by = excluded.created_by`
+
+	queryDeleteItems := `delete from manifest_items
+						where reception_id = $1`
+
+	queryAddItems := `insert into manifest_items(reception_id, barcode, type_id, quantity)
+						select $1, t.barcode, t.type_id, t.quantity
+						from unnest($2::text[], $3::int[], $4::int[]) t(barcode, type_id, quantity)`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var statusId int
+	err = tx.QueryRow(ctx, queryReceptionStatus, manifest.ReceptionId).Scan(&statusId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if statusId != models.ReceptionStatusInProgress {
+		return models.ErrReceptionNotOpen
+	}
+
+	_, err = tx.Exec(ctx, queryManifest, manifest.ReceptionId, manifest.SupplierRef, manifest.MaxDiscrepancy, manifest.CreatedBy)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, queryDeleteItems, manifest.ReceptionId)
+	if err != nil {
+		return err
+	}
+
+	barcodes := make([]*string, len(manifest.Items))
+	typeIds := make([]*int, len(manifest.Items))
+	quantities := make([]int, len(manifest.Items))
+	for i, item := range manifest.Items {
+		barcodes[i] = item.Barcode
+		typeIds[i] = item.TypeId
+		quantities[i] = item.Quantity
+	}
+	_, err = tx.Exec(ctx, queryAddItems, manifest.ReceptionId, barcodes, typeIds, quantities)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func (r *ReceptionRepo) GetManifest(ctx context.Context, receptionId uuid.UUID) (*models.ManifestAPI, error) {
+	return getManifest(ctx, r.pool, receptionId)
+}
+
+func getManifest(ctx context.Context, q postgres.Querier, receptionId uuid.UUID) (*models.ManifestAPI, error) {
+	queryManifest := `select reception_id, supplier_ref, max_discrepancy, created_at, created_by
+						from reception_manifests
+						where reception_id = $1`
+
+	queryItems := `select coalesce(mi.barcode, ''), coalesce(pt.name, ''), mi.quantity
+					from manifest_items mi
+					left join product_types pt on pt.id = mi.type_id
+					where mi.reception_id = $1
+					order by mi.id`
+
+	manifest := &models.ManifestAPI{}
+	err := q.QueryRow(ctx, queryManifest, receptionId).Scan(&manifest.ReceptionId, &manifest.SupplierRef, &manifest.MaxDiscrepancy, &manifest.CreatedAt, &manifest.CreatedBy)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := q.Query(ctx, queryItems, receptionId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	manifest.Items = []models.ManifestItemAPI{}
+	for rows.Next() {
+		var item models.ManifestItemAPI
+		if err := rows.Scan(&item.Barcode, &item.Type, &item.Quantity); err != nil {
+			return nil, err
+		}
+		manifest.Items = append(manifest.Items, item)
+	}
+	return manifest, rows.Err()
+}
@@ -6,6 +6,7 @@ import (
 	"orderPickupPoint/internal/models"
 	"orderPickupPoint/internal/storage/postgres"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -68,21 +69,186 @@ func (m *mockDbTx) Rollback(ctx context.Context) error {
 	return args.Error(0)
 }
 
+func (m *mockDbPool) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	callArgs := m.Called(append([]interface{}{ctx, sql}, args...)...)
+	return callArgs.Get(0).(pgx.Rows), callArgs.Error(1)
+}
+
+func (m *mockDbTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	callArgs := m.Called(append([]interface{}{ctx, sql}, args...)...)
+	return callArgs.Get(0).(pgx.Rows), callArgs.Error(1)
+}
+
+type fakeRows struct {
+	pgx.Rows
+	rows [][]any
+	next int
+}
+
+func (r *fakeRows) Next() bool {
+	r.next++
+	return r.next <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	for i, value := range r.rows[r.next-1] {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+
+func (r *fakeRows) Close() {}
+
+func TestChangeReceptionStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		toStatusId int
+		tag        pgconn.CommandTag
+		mockError  error
+		wantError  error
+	}{
+		{
+			name:       "close",
+			toStatusId: models.ReceptionStatusClosed,
+			tag:        pgconn.NewCommandTag("UPDATE 1"),
+		},
+		{
+			name:       "verify",
+			toStatusId: models.ReceptionStatusVerified,
+			tag:        pgconn.NewCommandTag("UPDATE 1"),
+		},
+		{
+			name:       "status changed concurrently",
+			toStatusId: models.ReceptionStatusClosed,
+			tag:        pgconn.NewCommandTag("UPDATE 0"),
+			wantError:  models.ErrIllegalTransition,
+		},
+		{
+			name:       "db error",
+			toStatusId: models.ReceptionStatusVerified,
+			mockError:  errors.New("Bad request"),
+			wantError:  errors.New("Bad request"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			repo := NewReceptionRepo(mockPool)
+
+			transition := &models.ReceptionTransition{
+				ReceptionId:  uuid.New(),
+				FromStatusId: models.ReceptionStatusInProgress,
+				ToStatusId:   tt.toStatusId,
+			}
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			if tt.toStatusId == models.ReceptionStatusClosed {
+				mockTx.On("Exec", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(tt.tag, tt.mockError).Once()
+			} else {
+				mockTx.On("Exec", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(tt.tag, tt.mockError).Once()
+			}
+			if tt.wantError == nil {
+				mockTx.On("Exec", ctx, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, nil).Once()
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			err := repo.ChangeReceptionStatus(ctx, transition)
+			require.Equal(t, tt.wantError, err)
+
+			mockPool.AssertExpectations(t)
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
 func TestCloseReception(t *testing.T) {
 	tests := []struct {
 		name      string
-		pvzId     uuid.UUID
+		statusId  int
+		wantError error
+	}{
+		{
+			name:     "without manifest",
+			statusId: models.ReceptionStatusInProgress,
+		},
+		{
+			name:      "closed meanwhile",
+			statusId:  models.ReceptionStatusClosed,
+			wantError: models.ErrIllegalTransition,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			lockRow := new(mockRow)
+			manifestRow := new(mockRow)
+			repo := NewReceptionRepo(mockPool)
+
+			actorId := 5
+			transition := &models.ReceptionTransition{
+				ReceptionId:  uuid.New(),
+				FromStatusId: models.ReceptionStatusInProgress,
+				ToStatusId:   models.ReceptionStatusClosed,
+				ActorId:      &actorId,
+			}
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("QueryRow", ctx, mock.MatchedBy(func(sql string) bool {
+				return strings.Contains(sql, "for update")
+			}), transition.ReceptionId).Return(lockRow).Once()
+			lockRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(0).(*int) = tt.statusId
+			}).Return(nil)
+			if tt.wantError == nil {
+				mockTx.On("QueryRow", ctx, mock.Anything, transition.ReceptionId).Return(manifestRow).Once()
+				manifestRow.On("Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(pgx.ErrNoRows)
+				// status change and history
+				mockTx.On("Exec", ctx, mock.Anything, transition.ReceptionId, transition.FromStatusId, transition.ToStatusId, transition.ActorId).Return(pgconn.NewCommandTag("UPDATE 1"), nil).Once()
+				mockTx.On("Exec", ctx, mock.Anything, transition.ReceptionId, transition.FromStatusId, transition.ToStatusId, transition.ActorId, "").Return(pgconn.CommandTag{}, nil).Once()
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			err := repo.CloseReception(ctx, transition, func(*models.ManifestAPI, []models.ProductInfo) error {
+				t.Fatal("check is called without a manifest")
+				return nil
+			})
+
+			require.ErrorIs(t, err, tt.wantError)
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFlagStaleReception(t *testing.T) {
+	tests := []struct {
+		name      string
+		tag       pgconn.CommandTag
 		mockError error
+		wantError error
 	}{
 		{
-			name:      "valid request",
-			pvzId:     uuid.New(),
-			mockError: nil,
+			name: "flagged",
+			tag:  pgconn.NewCommandTag("UPDATE 1"),
 		},
 		{
-			name:      "invalid request",
-			pvzId:     uuid.New(),
+			name:      "already flagged or closed",
+			tag:       pgconn.NewCommandTag("UPDATE 0"),
+			wantError: models.ErrIllegalTransition,
+		},
+		{
+			name:      "db error",
 			mockError: errors.New("Bad request"),
+			wantError: errors.New("Bad request"),
 		},
 	}
 
@@ -90,14 +256,30 @@ func TestCloseReception(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			ctx := context.Background()
 			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
 			repo := NewReceptionRepo(mockPool)
 
-			mockPool.On("Exec", mock.Anything, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, tt.mockError)
+			systemActor := models.SystemActorId
+			event := &models.ReceptionEvent{
+				ReceptionId: uuid.New(),
+				Event:       models.ReceptionEventFlaggedStale,
+				ActorId:     &systemActor,
+				Comment:     "flagged as stale by system",
+			}
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("Exec", ctx, mock.Anything, event.ReceptionId, models.ReceptionStatusInProgress).Return(tt.tag, tt.mockError).Once()
+			if tt.wantError == nil {
+				mockTx.On("Exec", ctx, mock.Anything, event.ReceptionId, event.Event, event.ActorId, event.Comment).Return(pgconn.CommandTag{}, nil).Once()
+				mockTx.On("Commit", ctx).Return(nil)
+			}
 
-			err := repo.CloseReception(ctx, tt.pvzId)
-			require.Equal(t, tt.mockError, err)
+			err := repo.FlagStaleReception(ctx, event)
+			require.Equal(t, tt.wantError, err)
 
 			mockPool.AssertExpectations(t)
+			mockTx.AssertExpectations(t)
 		})
 	}
 }
@@ -108,12 +290,14 @@ func TestCreateReception(t *testing.T) {
 		pvzId      uuid.UUID
 		mockReturn *models.Reception
 		mockError  error
+		wantError  error
 	}{
 		{
 			name:       "invalid request",
 			pvzId:      uuid.New(),
 			mockReturn: nil,
 			mockError:  errors.New("Bad request"),
+			wantError:  errors.New("Bad request"),
 		},
 		{
 			name:       "valid request",
@@ -121,6 +305,18 @@ func TestCreateReception(t *testing.T) {
 			mockReturn: &models.Reception{},
 			mockError:  nil,
 		},
+		{
+			name:      "reception in progress",
+			pvzId:     uuid.New(),
+			mockError: pgx.ErrNoRows,
+			wantError: models.ErrReceptionOpen,
+		},
+		{
+			name:      "opened concurrently",
+			pvzId:     uuid.New(),
+			mockError: &pgconn.PgError{Code: "23505"},
+			wantError: models.ErrReceptionOpen,
+		},
 	}
 
 	for _, tt := range tests {
@@ -130,13 +326,13 @@ func TestCreateReception(t *testing.T) {
 			mockPool := new(mockDbPool)
 			repo := NewReceptionRepo(mockPool)
 
-			mockPool.On("QueryRow", ctx, mock.Anything, mock.Anything).Return(pgxRow)
+			mockPool.On("QueryRow", ctx, mock.Anything, mock.Anything, mock.Anything, models.ReceptionStatusInProgress).Return(pgxRow)
 
-			pgxRow.On("Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(tt.mockError)
+			pgxRow.On("Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(tt.mockError)
 
-			res, err := repo.CreateReception(ctx, tt.pvzId)
+			res, err := repo.CreateReception(ctx, tt.pvzId, nil)
 
-			require.Equal(t, err, tt.mockError)
+			require.Equal(t, tt.wantError, err)
 			require.IsType(t, &models.Reception{}, res)
 
 			mockPool.AssertExpectations(t)
@@ -186,18 +382,60 @@ func TestGetStatusNameById(t *testing.T) {
 	}
 }
 
+func TestGetOpenReception(t *testing.T) {
+	tests := []struct {
+		name      string
+		scanError error
+		wantError error
+	}{
+		{
+			name: "in progress",
+		},
+		{
+			name:      "none in progress",
+			scanError: pgx.ErrNoRows,
+			wantError: models.ErrReceptionNotOpen,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			repo := NewReceptionRepo(mockPool)
+			pgxRow := new(mockRow)
+
+			pvzId := uuid.New()
+			mockPool.On("QueryRow", ctx, mock.MatchedBy(func(sql string) bool {
+				return strings.Contains(sql, "status_id = $2")
+			}), pvzId, models.ReceptionStatusInProgress).Return(pgxRow)
+			pgxRow.On("Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(tt.scanError)
+
+			res, err := repo.GetOpenReception(ctx, pvzId)
+
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantError == nil {
+				require.NotNil(t, res)
+			}
+			mockPool.AssertExpectations(t)
+		})
+	}
+}
+
 func TestDeleteLastProductInReception(t *testing.T) {
 	tests := []struct {
-		name       string
-		args       []uuid.UUID
-		mockReturn []uuid.UUID
-		mockError  error
+		name        string
+		args        []uuid.UUID
+		mockReturn  []uuid.UUID
+		mockError   error
+		commitError error
+		wantError   error
 	}{
 		{
 			name:       "invalid test",
 			args:       []uuid.UUID{uuid.New(), uuid.New(), uuid.New()},
 			mockReturn: []uuid.UUID{uuid.New(), uuid.New()},
 			mockError:  errors.New("error"),
+			wantError:  errors.New("error"),
 		},
 		{
 			name:       "valid test",
@@ -205,6 +443,13 @@ func TestDeleteLastProductInReception(t *testing.T) {
 			mockReturn: []uuid.UUID{uuid.New(), uuid.New()},
 			mockError:  nil,
 		},
+		{
+			name:        "commit fails",
+			args:        []uuid.UUID{uuid.New(), uuid.New(), uuid.New()},
+			mockReturn:  []uuid.UUID{uuid.New(), uuid.New()},
+			commitError: errors.New("commit failed"),
+			wantError:   errors.New("commit failed"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -219,7 +464,7 @@ func TestDeleteLastProductInReception(t *testing.T) {
 			mockPool.On("Begin", ctx).Return(mockTx, tt.mockError)
 			require.NotNil(t, mockTx)
 
-			mockTx.On("QueryRow", ctx, mock.Anything, mock.Anything).Return(pgxRow1)
+			mockTx.On("QueryRow", ctx, mock.Anything, mock.Anything, models.ReceptionStatusInProgress).Return(pgxRow1)
 			pgxRow1.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
 				reflect.ValueOf(args[0]).Elem().Set(reflect.ValueOf(tt.mockReturn[0]))
 			}).Return(tt.mockError)
@@ -229,31 +474,67 @@ func TestDeleteLastProductInReception(t *testing.T) {
 				reflect.ValueOf(args[0]).Elem().Set(reflect.ValueOf(tt.mockReturn[1]))
 			}).Return(tt.mockError)
 
-			mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("DELETE 1"), tt.mockError)
-			mockTx.On("Commit", ctx).Return(nil)
+			mockTx.On("Exec", ctx, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("UPDATE 1"), tt.mockError)
+			mockTx.On("Exec", ctx, mock.Anything, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("UPDATE 1"), tt.mockError)
+			mockTx.On("Exec", ctx, mock.Anything, mock.Anything, mock.Anything, models.OrderStatusAwaiting, models.OrderStatusReady).Return(pgconn.NewCommandTag("UPDATE 1"), tt.mockError)
+			mockTx.On("Commit", ctx).Return(tt.commitError)
 
 			mockTx.On("Rollback", ctx).Return(nil)
 
-			err := repo.DeleteLastProductInReception(ctx, tt.args[0])
+			err := repo.DeleteLastProductInReception(ctx, tt.args[0], nil)
 
-			require.Equal(t, err, tt.mockError)
+			require.Equal(t, tt.wantError, err)
 			mockPool.AssertExpectations(t)
 		})
 	}
 }
 
 func TestAddProductToReception(t *testing.T) {
+	limit := 1
+	code := "4006381333931"
 	tests := []struct {
-		name       string
-		argProd    *models.Product
-		mockReturn *models.Product
-		mockError  error
+		name        string
+		argProd     *models.Product
+		mockReturn  *models.Product
+		mockError   error
+		maxItems    *int
+		strict      bool
+		storedItems int
+		scanned     bool
+		cell        string
+		commitError error
+		expectError error
 	}{
 		{
-			name:       "invalid test",
+			name:       "placed into a cell",
 			argProd:    &models.Product{},
-			mockReturn: &models.Product{ReceptionId: uuid.New(), Id: uuid.New(), AddedAt: time.Now()},
-			mockError:  errors.New("error"),
+			mockReturn: &models.Product{Id: uuid.New()},
+			cell:       "A-1-1",
+		},
+		{
+			name:        "duplicate barcode",
+			argProd:     &models.Product{Barcode: &code},
+			mockReturn:  &models.Product{},
+			scanned:     true,
+			expectError: models.ErrDuplicateBarcode,
+		},
+		{
+			name:       "allowed duplicate barcode",
+			argProd:    &models.Product{Barcode: &code, AllowDuplicate: true},
+			mockReturn: &models.Product{Duplicate: true},
+			scanned:    true,
+		},
+		{
+			name:       "new barcode",
+			argProd:    &models.Product{Barcode: &code},
+			mockReturn: &models.Product{},
+		},
+		{
+			name:        "invalid test",
+			argProd:     &models.Product{},
+			mockReturn:  &models.Product{ReceptionId: uuid.New(), Id: uuid.New(), AddedAt: time.Now()},
+			mockError:   errors.New("error"),
+			expectError: errors.New("error"),
 		},
 		{
 			name:       "valid test",
@@ -261,6 +542,30 @@ func TestAddProductToReception(t *testing.T) {
 			mockReturn: &models.Product{},
 			mockError:  nil,
 		},
+		{
+			name:        "strict capacity exceeded",
+			argProd:     &models.Product{},
+			mockReturn:  &models.Product{},
+			maxItems:    &limit,
+			strict:      true,
+			storedItems: 1,
+			expectError: models.ErrCapacityExceeded,
+		},
+		{
+			name:        "soft capacity exceeded",
+			argProd:     &models.Product{},
+			mockReturn:  &models.Product{OverCapacity: true},
+			maxItems:    &limit,
+			strict:      false,
+			storedItems: 1,
+		},
+		{
+			name:        "commit fails",
+			argProd:     &models.Product{},
+			mockReturn:  &models.Product{},
+			commitError: errors.New("commit failed"),
+			expectError: errors.New("commit failed"),
+		},
 	}
 
 	for _, tt := range tests {
@@ -271,64 +576,263 @@ func TestAddProductToReception(t *testing.T) {
 			mockTx := new(mockDbTx)
 			pgxRow1 := new(mockRow)
 			pgxRow2 := new(mockRow)
+			pgxRow3 := new(mockRow)
 
 			mockPool.On("Begin", ctx).Return(mockTx, tt.mockError)
 			require.NotNil(t, mockTx)
 
 			queryOpenReception := `select id
 							from receptions
-							where pvz_id = $1 and status_id = 1`
-			mockTx.On("QueryRow", ctx, queryOpenReception, mock.Anything).Return(pgxRow1)
+							where pvz_id = $1 and status_id = $2
+							for update`
+			mockTx.On("QueryRow", ctx, queryOpenReception, mock.Anything, models.ReceptionStatusInProgress).Return(pgxRow1)
 			pgxRow1.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
 				reflect.ValueOf(args[0]).Elem().Set(reflect.ValueOf(tt.mockReturn.ReceptionId))
 			}).Return(tt.mockError)
 
-			queryAddProduct := `insert into products(type_id)
-						values ($1)
+			queryCapacity := `select p.max_items,
+							p.strict_capacity,
+							tc.max_items,
+							coalesce((select sum(items) from pvz_stock where pvz_id = p.id), 0),
+							coalesce((select items from pvz_stock where pvz_id = p.id and type_id = $2), 0)
+						from pvzs p
+						left join pvz_type_capacities tc on tc.pvz_id = p.id and tc.type_id = $2
+						where p.id = $1
+						for update of p`
+			mockTx.On("QueryRow", ctx, queryCapacity, mock.Anything, mock.Anything).Return(pgxRow3)
+			pgxRow3.On("Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				reflect.ValueOf(args[0]).Elem().Set(reflect.ValueOf(tt.maxItems))
+				reflect.ValueOf(args[1]).Elem().Set(reflect.ValueOf(tt.strict))
+				reflect.ValueOf(args[3]).Elem().Set(reflect.ValueOf(tt.storedItems))
+			}).Return(nil)
+
+			queryDuplicate := `select exists(
+							select 1
+							from reception_products rp
+							join products prod on prod.id = rp.product_id
+							where rp.reception_id = $1 and prod.barcode = $2 and prod.deleted_at is null)`
+			pgxRow4 := new(mockRow)
+			mockTx.On("QueryRow", ctx, queryDuplicate, mock.Anything, code).Return(pgxRow4)
+			pgxRow4.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+				*args[0].(*bool) = tt.scanned
+			}).Return(nil)
+
+			queryAddProduct := `insert into products(type_id, pvz_id, added_by, barcode, external_order_id, duplicate,
+							weight_grams, length_mm, width_mm, height_mm, declared_value, condition_id, condition_notes, storage_until)
+						values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, storage_deadline($2, $1, $14))
 						returning id, added_at`
-			mockTx.On("QueryRow", ctx, queryAddProduct, mock.Anything).Return(pgxRow2)
+			mockTx.On("QueryRow", ctx, queryAddProduct, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+				mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, models.DefaultStorageDays).Return(pgxRow2)
 			pgxRow2.On("Scan", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
 				reflect.ValueOf(args[0]).Elem().Set(reflect.ValueOf(tt.mockReturn.Id))
 				reflect.ValueOf(args[1]).Elem().Set(reflect.ValueOf(tt.mockReturn.AddedAt))
 			}).Return(tt.mockError)
 
-			mockTx.On("Exec", ctx, mock.Anything, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("DELETE 1"), tt.mockError)
+			mockTx.On("Exec", ctx, mock.Anything, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("INSERT 0 1"), tt.mockError)
+
+			// automatic placement, no fitting cell unless the case has one
+			cellRow := new(mockRow)
+			isCellQuery := mock.MatchedBy(func(sql string) bool { return strings.Contains(sql, "storage_cells") })
+			mockTx.On("QueryRow", ctx, isCellQuery, tt.mockReturn.Id).Return(cellRow)
+			if tt.cell == "" {
+				cellRow.On("Scan", mock.Anything, mock.Anything).Return(pgx.ErrNoRows)
+			} else {
+				cellRow.On("Scan", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+					*args[0].(*uuid.UUID) = uuid.New()
+					*args[1].(*string) = tt.cell
+				}).Return(nil)
+				// the move
+				mockTx.On("Exec", ctx, mock.Anything, tt.mockReturn.Id, (*uuid.UUID)(nil), mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("INSERT 0 1"), nil).Once()
+			}
 
-			mockTx.On("Commit", ctx).Return(nil)
+			mockTx.On("Commit", ctx).Return(tt.commitError)
 			mockTx.On("Rollback", ctx).Return(nil)
 
 			out, err := repo.AddProductToReception(ctx, tt.argProd, uuid.New())
 
-			require.Equal(t, err, tt.mockError)
+			require.Equal(t, tt.expectError, err)
 			if err == nil {
 				mockPool.AssertExpectations(t)
 				require.Equal(t, out.AddedAt, tt.mockReturn.AddedAt)
 				require.Equal(t, out.Id, tt.mockReturn.Id)
 				require.Equal(t, out.ReceptionId, tt.mockReturn.ReceptionId)
+				require.Equal(t, tt.mockReturn.OverCapacity, out.OverCapacity)
+				require.Equal(t, tt.mockReturn.Duplicate, out.Duplicate)
+				if tt.cell != "" {
+					require.Equal(t, tt.cell, *out.CellCode)
+				} else {
+					require.Nil(t, out.CellCode)
+				}
 			}
 
 		})
 	}
 }
 
-func TestGetProductTypeIdByName(t *testing.T) {
+func TestAddProductsToReception(t *testing.T) {
+	one, three := 1, 3
+	code := "4006381333931"
 	tests := []struct {
-		name       string
-		arg        string
-		mockReturn int
-		mockError  error
+		name         string
+		products     []*models.Product
+		maxItems     *int
+		strict       bool
+		storedItems  int
+		typeRows     [][]any
+		scannedRows  [][]any
+		wantErrors   []error
+		wantOver     []bool
+		addedTypeIds []int
 	}{
 		{
-			name:       "valid test",
-			arg:        "Одежда",
-			mockReturn: 1,
-			mockError:  nil,
+			// the second item of the batch does not fit, the first one is still added
+			name:         "strict pvz capacity",
+			products:     []*models.Product{{TypeId: 1}, {TypeId: 1}},
+			maxItems:     &three,
+			strict:       true,
+			storedItems:  2,
+			typeRows:     [][]any{{1, (*int)(nil), 2}},
+			wantErrors:   []error{nil, models.ErrCapacityExceeded},
+			wantOver:     []bool{false, false},
+			addedTypeIds: []int{1},
 		},
 		{
-			name:       "invalid test",
-			arg:        "еда",
-			mockReturn: -1,
-			mockError:  errors.New("error"),
+			// type 2 may hold one item, the pvz itself has no limit
+			name:         "strict type rule",
+			products:     []*models.Product{{TypeId: 2}, {TypeId: 1}, {TypeId: 2}},
+			strict:       true,
+			typeRows:     [][]any{{1, (*int)(nil), 5}, {2, &one, 0}},
+			wantErrors:   []error{nil, nil, models.ErrCapacityExceeded},
+			wantOver:     []bool{false, false, false},
+			addedTypeIds: []int{2, 1},
+		},
+		{
+			name:         "soft type rule",
+			products:     []*models.Product{{TypeId: 1}, {TypeId: 2}, {TypeId: 2}},
+			typeRows:     [][]any{{1, (*int)(nil), 5}, {2, &one, 0}},
+			wantErrors:   []error{nil, nil, nil},
+			wantOver:     []bool{false, false, true},
+			addedTypeIds: []int{1, 2, 2},
+		},
+		{
+			// already scanned in the reception, only the allowed repeat is added
+			name:         "repeated scan",
+			products:     []*models.Product{{TypeId: 1, Barcode: &code}, {TypeId: 1, Barcode: &code, AllowDuplicate: true}},
+			typeRows:     [][]any{{1, (*int)(nil), 0}},
+			scannedRows:  [][]any{{code}},
+			wantErrors:   []error{models.ErrDuplicateBarcode, nil},
+			wantOver:     []bool{false, false},
+			addedTypeIds: []int{1},
+		},
+		{
+			name:        "nothing fits",
+			products:    []*models.Product{{TypeId: 1}},
+			maxItems:    &one,
+			strict:      true,
+			storedItems: 1,
+			typeRows:    [][]any{{1, (*int)(nil), 1}},
+			wantErrors:  []error{models.ErrCapacityExceeded},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			receptionRow := new(mockRow)
+			capacityRow := new(mockRow)
+			repo := NewReceptionRepo(mockPool)
+
+			receptionId, pvzId := uuid.New(), uuid.New()
+			typeIds := make([]int, len(tt.products))
+			for i, product := range tt.products {
+				typeIds[i] = product.TypeId
+			}
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("QueryRow", ctx, mock.Anything, receptionId).Return(receptionRow)
+			receptionRow.On("Scan", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				*args[0].(*uuid.UUID) = pvzId
+				*args[1].(*int) = models.ReceptionStatusInProgress
+			}).Return(nil)
+			mockTx.On("QueryRow", ctx, mock.Anything, pvzId).Return(capacityRow)
+			capacityRow.On("Scan", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				*args[0].(**int) = tt.maxItems
+				*args[1].(*bool) = tt.strict
+				*args[2].(*int) = tt.storedItems
+			}).Return(nil)
+			// already scanned barcodes
+			mockTx.On("Query", ctx, mock.Anything, receptionId, mock.Anything).Return(&fakeRows{rows: tt.scannedRows}, nil)
+			mockTx.On("Query", ctx, mock.Anything, pvzId, typeIds).Return(&fakeRows{rows: tt.typeRows}, nil)
+			if tt.addedTypeIds != nil {
+				// only the accepted items are inserted and counted in the stock
+				mockTx.On("Query", ctx, mock.Anything, mock.Anything, tt.addedTypeIds, pvzId, mock.Anything, receptionId, mock.Anything, mock.Anything, mock.Anything,
+					mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, models.DefaultStorageDays).
+					Return(&fakeRows{}, nil)
+				mockTx.On("Exec", ctx, mock.Anything, pvzId, tt.addedTypeIds).Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+				// a pvz without cells
+				cellRow := new(mockRow)
+				mockTx.On("QueryRow", ctx, mock.Anything, mock.Anything).Return(cellRow)
+				cellRow.On("Scan", mock.Anything, mock.Anything).Return(pgx.ErrNoRows)
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			out, err := repo.AddProductsToReception(ctx, receptionId, tt.products, nil)
+
+			require.NoError(t, err)
+			require.Len(t, out, len(tt.products))
+			for i, item := range out {
+				require.ErrorIs(t, item.Err, tt.wantErrors[i])
+				if tt.wantErrors[i] != nil {
+					require.Nil(t, item.Product)
+					continue
+				}
+				require.Equal(t, tt.wantOver[i], item.Product.OverCapacity)
+			}
+			if tt.addedTypeIds == nil {
+				mockTx.AssertNotCalled(t, "Commit", ctx)
+			}
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetProductTypeByName(t *testing.T) {
+	tests := []struct {
+		name      string
+		arg       string
+		active    bool
+		mockError error
+		wantError error
+	}{
+		{
+			name:   "by name",
+			arg:    "одежда",
+			active: true,
+		},
+		{
+			name:   "by code",
+			arg:    "clothing",
+			active: true,
+		},
+		{
+			name:      "inactive",
+			arg:       "clothing",
+			wantError: models.ErrInactiveProductType,
+		},
+		{
+			name:      "unknown",
+			arg:       "еда",
+			mockError: pgx.ErrNoRows,
+			wantError: models.ErrUnknownProductType,
+		},
+		{
+			name:      "db error",
+			arg:       "еда",
+			mockError: errors.New("error"),
+			wantError: errors.New("error"),
 		},
 	}
 
@@ -339,15 +843,260 @@ func TestGetProductTypeIdByName(t *testing.T) {
 			repo := NewReceptionRepo(mockPool)
 			pgxRow := new(mockRow)
 
-			mockPool.On("QueryRow", ctx, mock.Anything, mock.Anything).Return(pgxRow)
-			pgxRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
-				reflect.ValueOf(args[0]).Elem().Set(reflect.ValueOf(tt.mockReturn))
+			fields := make([]interface{}, 11)
+			for i := range fields {
+				fields[i] = mock.Anything
+			}
+			mockPool.On("QueryRow", ctx, mock.Anything, tt.arg).Return(pgxRow)
+			pgxRow.On("Scan", fields...).Run(func(args mock.Arguments) {
+				reflect.ValueOf(args[0]).Elem().Set(reflect.ValueOf(2))
+				reflect.ValueOf(args[3]).Elem().Set(reflect.ValueOf(tt.active))
 			}).Return(tt.mockError)
 
-			id, err := repo.GetProductTypeIdByName(ctx, tt.arg)
-			require.Equal(t, err, tt.mockError)
-			require.Equal(t, id, tt.mockReturn)
+			productType, err := repo.GetProductTypeByName(ctx, tt.arg)
+			if errors.Is(tt.wantError, models.ErrUnknownProductType) || errors.Is(tt.wantError, models.ErrInactiveProductType) {
+				require.ErrorIs(t, err, tt.wantError)
+			} else {
+				require.Equal(t, tt.wantError, err)
+			}
+			if tt.wantError == nil {
+				require.Equal(t, 2, productType.Id)
+			}
 			mockPool.AssertExpectations(t)
 		})
 	}
 }
+
+func TestResolveReopenRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		resolved   bool
+		openExists bool
+		wantError  error
+	}{
+		{
+			name: "approved",
+		},
+		{
+			name:      "already resolved",
+			resolved:  true,
+			wantError: models.ErrReopenResolved,
+		},
+		{
+			name:       "pvz has an open reception",
+			openExists: true,
+			wantError:  models.ErrReceptionOpen,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			row := new(mockRow)
+			repo := NewReceptionRepo(mockPool)
+
+			receptionId := uuid.New()
+			resolution := &models.ReopenResolution{RequestId: uuid.New(), StatusId: models.ReopenRequestApproved}
+			transition := &models.ReceptionTransition{
+				ReceptionId:  receptionId,
+				FromStatusId: models.ReceptionStatusClosed,
+				ToStatusId:   models.ReceptionStatusInProgress,
+			}
+
+			resolveTag := pgconn.NewCommandTag("UPDATE 1")
+			if tt.resolved {
+				resolveTag = pgconn.NewCommandTag("UPDATE 0")
+			}
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			// resolving the request
+			mockTx.On("Exec", ctx, mock.Anything, resolution.RequestId, mock.Anything, mock.Anything, mock.Anything, models.ReopenRequestPending).Return(resolveTag, nil).Once()
+			if !tt.resolved {
+				mockTx.On("QueryRow", ctx, mock.Anything, receptionId, models.ReceptionStatusInProgress).Return(row)
+				row.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).(*bool) = tt.openExists
+				}).Return(nil)
+			}
+			if tt.wantError == nil {
+				// status change and history
+				mockTx.On("Exec", ctx, mock.Anything, receptionId, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("UPDATE 1"), nil).Once()
+				mockTx.On("Exec", ctx, mock.Anything, receptionId, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(pgconn.CommandTag{}, nil).Once()
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			err := repo.ResolveReopenRequest(ctx, resolution, transition)
+			require.Equal(t, tt.wantError, err)
+
+			mockPool.AssertExpectations(t)
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCancelReception(t *testing.T) {
+	ctx := context.Background()
+	mockPool := new(mockDbPool)
+	mockTx := new(mockDbTx)
+	repo := NewReceptionRepo(mockPool)
+
+	actorId := 3
+	transition := &models.ReceptionTransition{
+		ReceptionId:  uuid.New(),
+		FromStatusId: models.ReceptionStatusInProgress,
+		ToStatusId:   models.ReceptionStatusCancelled,
+		ActorId:      &actorId,
+	}
+
+	mockPool.On("Begin", ctx).Return(mockTx, nil)
+	mockTx.On("Rollback", ctx).Return(nil)
+	// status change
+	mockTx.On("Exec", ctx, mock.Anything, transition.ReceptionId, transition.FromStatusId, transition.ToStatusId).Return(pgconn.NewCommandTag("UPDATE 1"), nil).Once()
+	// history
+	mockTx.On("Exec", ctx, mock.Anything, transition.ReceptionId, transition.FromStatusId, transition.ToStatusId, transition.ActorId, "").Return(pgconn.CommandTag{}, nil).Once()
+	// stock
+	mockTx.On("Exec", ctx, mock.Anything, transition.ReceptionId).Return(pgconn.NewCommandTag("UPDATE 2"), nil).Once()
+	// products
+	mockTx.On("Exec", ctx, mock.Anything, transition.ReceptionId, transition.ActorId).Return(pgconn.NewCommandTag("UPDATE 5"), nil).Once()
+	// orders
+	mockTx.On("Exec", ctx, mock.Anything, transition.ReceptionId, transition.ActorId, models.OrderStatusAwaiting, models.OrderStatusReady).Return(pgconn.NewCommandTag("INSERT 0 0"), nil).Once()
+	mockTx.On("Commit", ctx).Return(nil)
+
+	err := repo.CancelReception(ctx, transition)
+	require.NoError(t, err)
+
+	mockPool.AssertExpectations(t)
+	mockTx.AssertExpectations(t)
+}
+
+func TestDeleteProductInReception(t *testing.T) {
+	tests := []struct {
+		name         string
+		statusErr    error
+		statusId     int
+		productErr   error
+		wantError    error
+		deleteCalled bool
+	}{
+		{
+			name:         "open reception",
+			statusId:     models.ReceptionStatusInProgress,
+			deleteCalled: true,
+		},
+		{
+			name:      "unknown reception",
+			statusErr: pgx.ErrNoRows,
+			wantError: models.ErrNotFound,
+		},
+		{
+			name:      "closed reception",
+			statusId:  models.ReceptionStatusClosed,
+			wantError: models.ErrReceptionNotOpen,
+		},
+		{
+			name:         "product not in reception",
+			statusId:     models.ReceptionStatusInProgress,
+			productErr:   pgx.ErrNoRows,
+			wantError:    models.ErrNotFound,
+			deleteCalled: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			statusRow := new(mockRow)
+			productRow := new(mockRow)
+			repo := NewReceptionRepo(mockPool)
+
+			receptionId := uuid.New()
+			productId := uuid.New()
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("QueryRow", ctx, mock.Anything, receptionId).Return(statusRow)
+			statusRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(0).(*int) = tt.statusId
+			}).Return(tt.statusErr)
+			if tt.deleteCalled {
+				mockTx.On("QueryRow", ctx, mock.Anything, receptionId, productId, mock.Anything).Return(productRow)
+				productRow.On("Scan", mock.Anything, mock.Anything).Return(tt.productErr)
+			}
+			if tt.wantError == nil {
+				mockTx.On("Exec", ctx, mock.Anything, mock.Anything, mock.Anything).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+				mockTx.On("Exec", ctx, mock.Anything, mock.Anything, mock.Anything, models.OrderStatusAwaiting, models.OrderStatusReady).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			err := repo.DeleteProductInReception(ctx, receptionId, productId, nil)
+			require.Equal(t, tt.wantError, err)
+
+			mockPool.AssertExpectations(t)
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListReceptions(t *testing.T) {
+	ctx := context.Background()
+	pvzId := uuid.New()
+
+	t.Run("unknown pvz", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		pvzRow := new(mockRow)
+		repo := NewReceptionRepo(mockPool)
+
+		mockPool.On("QueryRow", ctx, mock.Anything, pvzId).Return(pvzRow)
+		pvzRow.On("Scan", mock.Anything).Return(nil)
+
+		result, err := repo.ListReceptions(ctx, &models.ReceptionFilter{PvzId: pvzId, Page: 1, PageLimit: 2})
+
+		require.ErrorIs(t, err, models.ErrNotFound)
+		require.Nil(t, result)
+		mockPool.AssertExpectations(t)
+	})
+
+	t.Run("first page", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		pvzRow := new(mockRow)
+		countRow := new(mockRow)
+		repo := NewReceptionRepo(mockPool)
+
+		filter := &models.ReceptionFilter{PvzId: pvzId, Statuses: []string{"close"}, Page: 1, PageLimit: 2}
+		started := time.Now()
+		rows := [][]any{}
+		for i := range 3 {
+			rows = append(rows, []any{uuid.New(), pvzId, "close", started.Add(-time.Duration(i) * time.Hour),
+				(*time.Time)(nil), (*int)(nil), (*int)(nil), (*time.Time)(nil)})
+		}
+
+		mockPool.On("QueryRow", ctx, mock.Anything, pvzId).Return(pvzRow)
+		pvzRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*args[0].(*bool) = true
+		}).Return(nil)
+		mockPool.On("QueryRow", ctx, mock.Anything, pvzId, filter.Statuses, filter.StartDate, filter.EndDate).Return(countRow)
+		countRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*args[0].(*int) = 3
+		}).Return(nil)
+		// one row over the limit tells there is a next page
+		mockPool.On("Query", ctx, mock.Anything, pvzId, filter.Statuses, filter.StartDate, filter.EndDate, 3, 0).Return(&fakeRows{rows: rows}, nil)
+		// products of the receptions on the page
+		mockPool.On("Query", ctx, mock.Anything, []uuid.UUID{rows[0][0].(uuid.UUID), rows[1][0].(uuid.UUID)}).
+			Return(&fakeRows{rows: [][]any{{rows[0][0], "обувь", 2, int64(0), int64(0), int64(0)}}}, nil)
+
+		result, err := repo.ListReceptions(ctx, filter)
+
+		require.NoError(t, err)
+		require.Equal(t, 3, result.Total)
+		require.Len(t, result.Items, 2)
+		require.Equal(t, rows[0][0], result.Items[0].Id)
+		require.Equal(t, 2, result.Items[0].ProductsCount)
+		require.Zero(t, result.Items[1].ProductsCount)
+		require.NotNil(t, result.Next)
+		mockPool.AssertExpectations(t)
+	})
+}
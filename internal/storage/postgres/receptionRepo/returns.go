@@ -0,0 +1,313 @@
+package receptionRepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage/postgres"
+	"orderPickupPoint/internal/utils/cursor"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const returnColumns = `ret.id, ret.pvz_id, ret.order_id, o.external_id, ret.product_id, pt.name, prod.barcode,
+					rr.name, ret.comment, pc.name, ret.condition_notes, rs.name, ret.status_id,
+					ret.created_at, ret.created_by, ret.dispatched_at`
+
+const returnFrom = `from returns ret
+				join orders o on o.id = ret.order_id
+				join products prod on prod.id = ret.product_id
+				join product_types pt on pt.id = prod.type_id
+				join return_reasons rr on rr.id = ret.reason_id
+				join product_conditions pc on pc.id = ret.condition_id
+				join return_statuses rs on rs.id = ret.status_id`
+
+// registers an issued product of the order as returned and puts it into the outbound queue of the pvz.
+// The product is found by id or by barcode, a barcode takes the earliest issued item not returned yet.
+// ErrNotReturnable if the order has no such issued product, ErrNotFound if the pvz does not exist
+func (r *ReceptionRepo) CreateReturn(ctx context.Context, ret *models.Return) (uuid.UUID, error) {
+	queryProduct := `select prod.id, prod.type_id, coalesce($4::uuid, o.pvz_id)
+					from orders o
+					join products prod on prod.external_order_id = o.external_id and prod.pvz_id = o.pvz_id
+					where o.id = $1 and prod.deleted_at is null and prod.issued_at is not null
+						and ($2::uuid is null or prod.id = $2)
+						and ($3::text is null or prod.barcode = $3)
+						and not exists (
+							select 1
+							from returns ret
+							where ret.product_id = prod.id and ret.status_id <> $5)
+					order by prod.issued_at, prod.id
+					limit 1
+					for update of prod`
+
+	queryPvz := `select exists(select 1 from pvzs where id = $1)`
+
+	query := `insert into returns(pvz_id, order_id, product_id, reason_id, comment, condition_id, condition_notes, created_by)
+				values ($1, $2, $3, $4, $5, $6, $7, $8)
+				returning id`
+
+	queryIncStock := `insert into pvz_stock(pvz_id, type_id, items)
+						values ($1, $2, 1)
+						on conflict (pvz_id, type_id) do update
+						set items = pvz_stock.items + 1`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		productId uuid.UUID
+		typeId    int
+		pvzId     uuid.UUID
+	)
+	err = tx.QueryRow(ctx, queryProduct, ret.OrderId, ret.ProductId, ret.Barcode, ret.PvzId, models.ReturnStatusCancelled).Scan(&productId, &typeId, &pvzId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return uuid.Nil, models.ErrNotReturnable
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if ret.PvzId != nil {
+		var exists bool
+		if err := tx.QueryRow(ctx, queryPvz, pvzId).Scan(&exists); err != nil {
+			return uuid.Nil, err
+		}
+		if !exists {
+			return uuid.Nil, models.ErrNotFound
+		}
+	}
+
+	var returnId uuid.UUID
+	err = tx.QueryRow(ctx, query, pvzId, ret.OrderId, productId, ret.ReasonId, ret.Comment, ret.ConditionId, ret.Notes,
+		ret.CreatedBy).Scan(&returnId)
+	if postgres.IsUniqueViolation(err) {
+		// returned concurrently
+		return uuid.Nil, models.ErrReturnExists
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// the returned item takes place in the pvz until it is dispatched
+	_, err = tx.Exec(ctx, queryIncStock, pvzId, typeId)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = writeReturnHistory(ctx, tx, &models.ReturnTransition{
+		ReturnId:   returnId,
+		ToStatusId: models.ReturnStatusQueued,
+		ActorId:    ret.CreatedBy,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return returnId, tx.Commit(ctx)
+}
+
+func (r *ReceptionRepo) GetReturn(ctx context.Context, returnId uuid.UUID) (*models.ReturnAPI, error) {
+	query := fmt.Sprintf(`select %s
+				%s
+				where ret.id = $1`, returnColumns, returnFrom)
+
+	ret, err := scanReturn(r.pool.QueryRow(ctx, query, returnId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// returns of the pvz, oldest first as they are dispatched, page by offset or by cursor
+func (r *ReceptionRepo) ListReturns(ctx context.Context, filter *models.ReturnFilter) (*models.ListResult[models.ReturnAPI], error) {
+	queryFilter := `where ret.pvz_id = $1
+					and ($2::text[] is null or rs.name = any($2))`
+
+	args := []any{filter.PvzId, filter.Statuses}
+
+	result := &models.ListResult[models.ReturnAPI]{}
+	err := r.pool.QueryRow(ctx, `select count(*)
+				from returns ret
+				join return_statuses rs on rs.id = ret.status_id
+				`+queryFilter, args...).Scan(&result.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	keysetMatch := ""
+	direction := "asc"
+	offset := 0
+	if filter.Cursor != nil {
+		if !filter.Cursor.Matches(models.ReturnSortByDate, false) {
+			return nil, models.ErrInvalidFilter
+		}
+		op := ">"
+		if filter.Cursor.Backward {
+			op = "<"
+			direction = "desc"
+		}
+		keysetMatch = fmt.Sprintf("\n\t\t\t\t\tand (ret.created_at, ret.id) %s ($5::timestamptz, $6::uuid)", op)
+		args = append(args, filter.PageLimit+1, offset, filter.Cursor.SortKey, filter.Cursor.Id)
+	} else {
+		offset = filter.PageLimit * (filter.Page - 1)
+		args = append(args, filter.PageLimit+1, offset)
+	}
+
+	query := fmt.Sprintf(`select %s
+				%s
+				%s%s
+				order by ret.created_at %s, ret.id %s
+				limit $3 offset $4`, returnColumns, returnFrom, queryFilter, keysetMatch, direction, direction)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var returns []models.ReturnAPI
+	for rows.Next() {
+		ret, err := scanReturn(rows)
+		if err != nil {
+			return nil, err
+		}
+		returns = append(returns, *ret)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result.Items, result.Next, result.Prev = cursor.Slice(returns, filter.PageLimit, filter.Cursor, offset, func(ret models.ReturnAPI) cursor.Cursor {
+		return cursor.Cursor{
+			SortBy:  models.ReturnSortByDate,
+			SortKey: ret.CreatedAt.Format(time.RFC3339Nano),
+			Id:      ret.Id.String(),
+		}
+	})
+	return result, nil
+}
+
+func scanReturn(row pgx.Row) (*models.ReturnAPI, error) {
+	ret := &models.ReturnAPI{}
+	err := row.Scan(&ret.Id, &ret.PvzId, &ret.OrderId, &ret.ExternalOrderId, &ret.ProductId, &ret.Type, &ret.Barcode,
+		&ret.Reason, &ret.Comment, &ret.Condition, &ret.Notes, &ret.Status, &ret.StatusId,
+		&ret.CreatedAt, &ret.CreatedBy, &ret.DispatchedAt)
+	if err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// moves the return to the new status only if it still has the expected one.
+// A return leaving the queue is taken out of the pvz stock. An unclaimed product never left the pvz:
+// it leaves the stock and its cell on dispatch only, a cancelled return keeps it stored
+func (r *ReceptionRepo) ChangeReturnStatus(ctx context.Context, transition *models.ReturnTransition) error {
+	query := `update returns
+				set status_id = $3, dispatched_at = case when $3 = $4 then now() end
+				where id = $1 and status_id = $2
+				returning pvz_id, product_id, reason_id`
+
+	queryDecStock := `update pvz_stock s
+						set items = s.items - 1
+						from products prod
+						where prod.id = $2 and s.pvz_id = $1 and s.type_id = prod.type_id`
+
+	queryLeaveCell := `insert into cell_moves(product_id, from_cell_id, moved_by)
+						select id, cell_id, $2
+						from products
+						where id = $1 and cell_id is not null`
+
+	queryLeave := `update products
+					set pvz_id = null, cell_id = null, left_at = now()
+					where id = $1`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var (
+		pvzId, productId uuid.UUID
+		reasonId         int
+	)
+	err = tx.QueryRow(ctx, query, transition.ReturnId, transition.FromStatusId, transition.ToStatusId,
+		models.ReturnStatusDispatched).Scan(&pvzId, &productId, &reasonId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		// changed meanwhile
+		return models.ErrReturnTransition
+	}
+	if err != nil {
+		return err
+	}
+
+	unclaimed := reasonId == models.ReturnReasonUnclaimed
+	dispatched := transition.ToStatusId == models.ReturnStatusDispatched
+	if transition.FromStatusId == models.ReturnStatusQueued && (dispatched || !unclaimed) {
+		_, err = tx.Exec(ctx, queryDecStock, pvzId, productId)
+		if err != nil {
+			return err
+		}
+	}
+
+	if unclaimed && dispatched {
+		_, err = tx.Exec(ctx, queryLeaveCell, productId, transition.ActorId)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, queryLeave, productId)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = writeReturnHistory(ctx, tx, transition)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// zero FromStatusId is written as the initial record
+func writeReturnHistory(ctx context.Context, tx postgres.Tx, transition *models.ReturnTransition) error {
+	query := `insert into return_status_history(return_id, from_status_id, to_status_id, changed_by, comment)
+				values ($1, nullif($2, 0), $3, $4, $5)`
+
+	_, err := tx.Exec(ctx, query, transition.ReturnId, transition.FromStatusId, transition.ToStatusId, transition.ActorId, transition.Comment)
+	return err
+}
+
+func (r *ReceptionRepo) GetReturnHistory(ctx context.Context, returnId uuid.UUID) ([]models.ReturnHistoryItem, error) {
+	query := `select fs.name, ts.name, h.changed_at, h.changed_by, h.comment
+				from return_status_history h
+				left join return_statuses fs on fs.id = h.from_status_id
+				join return_statuses ts on ts.id = h.to_status_id
+				where h.return_id = $1
+				order by h.changed_at, h.id`
+
+	rows, err := r.pool.Query(ctx, query, returnId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.ReturnHistoryItem{}
+	for rows.Next() {
+		var item models.ReturnHistoryItem
+		if err := rows.Scan(&item.From, &item.To, &item.ChangedAt, &item.ChangedBy, &item.Comment); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
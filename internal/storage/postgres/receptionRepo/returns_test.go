@@ -0,0 +1,172 @@
+package receptionRepo
+
+import (
+	"context"
+	"orderPickupPoint/internal/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateReturn(t *testing.T) {
+	tests := []struct {
+		name       string
+		productErr error
+		pvzId      *uuid.UUID
+		pvzExists  bool
+		insertErr  error
+		wantError  error
+	}{
+		{
+			name: "returned to the pvz of the order",
+		},
+		{
+			name:      "returned to another pvz",
+			pvzId:     func() *uuid.UUID { id := uuid.New(); return &id }(),
+			pvzExists: true,
+		},
+		{
+			name:      "unknown pvz",
+			pvzId:     func() *uuid.UUID { id := uuid.New(); return &id }(),
+			wantError: models.ErrNotFound,
+		},
+		{
+			name:       "product not issued in the order",
+			productErr: pgx.ErrNoRows,
+			wantError:  models.ErrNotReturnable,
+		},
+		{
+			name:      "returned concurrently",
+			insertErr: &pgconn.PgError{Code: "23505"},
+			wantError: models.ErrReturnExists,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			productRow := new(mockRow)
+			pvzRow := new(mockRow)
+			insertRow := new(mockRow)
+			repo := NewReceptionRepo(mockPool)
+
+			actorId := 4
+			code := "4006381333931"
+			ret := &models.Return{
+				OrderId:     uuid.New(),
+				Barcode:     &code,
+				PvzId:       tt.pvzId,
+				ReasonId:    models.ReturnReasonDefective,
+				ConditionId: models.ProductConditionDamaged,
+				Notes:       "не включается",
+				CreatedBy:   &actorId,
+			}
+			productId := uuid.New()
+			returnId := uuid.New()
+			pvzId := uuid.New()
+			if tt.pvzId != nil {
+				pvzId = *tt.pvzId
+			}
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("QueryRow", ctx, mock.Anything, ret.OrderId, ret.ProductId, ret.Barcode, ret.PvzId, models.ReturnStatusCancelled).Return(productRow)
+			productRow.On("Scan", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(0).(*uuid.UUID) = productId
+				*args.Get(1).(*int) = 2
+				*args.Get(2).(*uuid.UUID) = pvzId
+			}).Return(tt.productErr)
+			if tt.pvzId != nil {
+				mockTx.On("QueryRow", ctx, mock.Anything, pvzId).Return(pvzRow)
+				pvzRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).(*bool) = tt.pvzExists
+				}).Return(nil)
+			}
+			if tt.productErr == nil && (tt.pvzId == nil || tt.pvzExists) {
+				mockTx.On("QueryRow", ctx, mock.Anything, pvzId, ret.OrderId, productId, ret.ReasonId, "", ret.ConditionId, ret.Notes, &actorId).Return(insertRow)
+				insertRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).(*uuid.UUID) = returnId
+				}).Return(tt.insertErr)
+			}
+			if tt.wantError == nil {
+				// stock
+				mockTx.On("Exec", ctx, mock.Anything, pvzId, 2).Return(pgconn.NewCommandTag("INSERT 0 1"), nil).Once()
+				// history
+				mockTx.On("Exec", ctx, mock.Anything, returnId, 0, models.ReturnStatusQueued, &actorId, "").Return(pgconn.NewCommandTag("INSERT 0 1"), nil).Once()
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			id, err := repo.CreateReturn(ctx, ret)
+
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantError == nil {
+				require.Equal(t, returnId, id)
+			}
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChangeReturnStatus(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      int
+		to        int
+		reasonId  int
+		scanErr   error
+		decStock  bool
+		leave     bool
+		wantError error
+	}{
+		{name: "dispatched", from: models.ReturnStatusQueued, to: models.ReturnStatusDispatched, reasonId: models.ReturnReasonDefective, decStock: true},
+		{name: "cancelled", from: models.ReturnStatusQueued, to: models.ReturnStatusCancelled, reasonId: models.ReturnReasonDefective, decStock: true},
+		{name: "unclaimed dispatched", from: models.ReturnStatusQueued, to: models.ReturnStatusDispatched, reasonId: models.ReturnReasonUnclaimed, decStock: true, leave: true},
+		{name: "unclaimed cancelled", from: models.ReturnStatusQueued, to: models.ReturnStatusCancelled, reasonId: models.ReturnReasonUnclaimed},
+		{name: "changed meanwhile", from: models.ReturnStatusQueued, to: models.ReturnStatusDispatched, scanErr: pgx.ErrNoRows, wantError: models.ErrReturnTransition},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			row := new(mockRow)
+			repo := NewReceptionRepo(mockPool)
+
+			transition := &models.ReturnTransition{ReturnId: uuid.New(), FromStatusId: tt.from, ToStatusId: tt.to}
+			pvzId := uuid.New()
+			productId := uuid.New()
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("QueryRow", ctx, mock.Anything, transition.ReturnId, tt.from, tt.to, models.ReturnStatusDispatched).Return(row)
+			row.On("Scan", mock.Anything, mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(0).(*uuid.UUID) = pvzId
+				*args.Get(1).(*uuid.UUID) = productId
+				*args.Get(2).(*int) = tt.reasonId
+			}).Return(tt.scanErr)
+			if tt.decStock {
+				mockTx.On("Exec", ctx, mock.Anything, pvzId, productId).Return(pgconn.NewCommandTag("UPDATE 1"), nil).Once()
+			}
+			if tt.leave {
+				mockTx.On("Exec", ctx, mock.Anything, productId, transition.ActorId).Return(pgconn.NewCommandTag("INSERT 0 1"), nil).Once()
+				mockTx.On("Exec", ctx, mock.Anything, productId).Return(pgconn.NewCommandTag("UPDATE 1"), nil).Once()
+			}
+			if tt.wantError == nil {
+				mockTx.On("Exec", ctx, mock.Anything, transition.ReturnId, tt.from, tt.to, transition.ActorId, "").Return(pgconn.NewCommandTag("INSERT 0 1"), nil).Once()
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			err := repo.ChangeReturnStatus(ctx, transition)
+
+			require.ErrorIs(t, err, tt.wantError)
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,455 @@
+package receptionRepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage/postgres"
+	"orderPickupPoint/internal/storage/postgres/sharedSql"
+	"orderPickupPoint/internal/utils/cursor"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const shipmentColumns = `s.id, s.from_pvz_id, s.to_pvz_id, ss.name, s.status_id,
+					array(select sp.product_id from shipment_products sp where sp.shipment_id = s.id order by sp.product_id),
+					s.created_at, s.created_by, s.dispatched_at, s.arrived_at, s.reception_id`
+
+const shipmentFrom = `from shipments s
+				join shipment_statuses ss on ss.id = s.status_id`
+
+// products present in the pvz whose reception is not in progress anymore and not queued for return,
+// the placeholders are bound to the in_progress reception status and the queued return status
+func shippableProduct(inProgress, queued string) string {
+	return `prod.deleted_at is null and prod.issued_at is null and prod.left_at is null
+						and not exists (
+							select 1
+							from reception_products rp
+							join receptions r on r.id = rp.reception_id
+							where rp.product_id = prod.id and r.status_id = ` + inProgress + `)
+						and not exists (
+							select 1
+							from returns ret
+							where ret.product_id = prod.id and ret.status_id = ` + queued + `)`
+}
+
+// creates a shipment of products present in the source pvz, ErrNotShippable if one of them is not there,
+// is in an open reception, queued for return or already is in another shipment. The products stay in the pvz until dispatch
+func (r *ReceptionRepo) CreateShipment(ctx context.Context, shipment *models.Shipment) (uuid.UUID, error) {
+	queryPvzs := `select count(*)
+					from pvzs
+					where id in ($1, $2)`
+
+	queryProducts := `with locked as (
+						select prod.id
+						from products prod
+						where prod.id = any($2) and prod.pvz_id = $1 and ` + shippableProduct("$3", "$4") + `
+						for update of prod
+					)
+					select count(*)
+					from locked`
+
+	query := `insert into shipments(from_pvz_id, to_pvz_id, created_by)
+				values ($1, $2, $3)
+				returning id`
+
+	queryAddProducts := `insert into shipment_products(shipment_id, product_id)
+							select $1, unnest($2::uuid[])`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	var pvzs int
+	err = tx.QueryRow(ctx, queryPvzs, shipment.FromPvzId, shipment.ToPvzId).Scan(&pvzs)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if pvzs != 2 {
+		return uuid.Nil, models.ErrNotFound
+	}
+
+	var shippable int
+	err = tx.QueryRow(ctx, queryProducts, shipment.FromPvzId, shipment.ProductIds,
+		models.ReceptionStatusInProgress, models.ReturnStatusQueued).Scan(&shippable)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if shippable != len(shipment.ProductIds) {
+		return uuid.Nil, models.ErrNotShippable
+	}
+
+	var shipmentId uuid.UUID
+	err = tx.QueryRow(ctx, query, shipment.FromPvzId, shipment.ToPvzId, shipment.CreatedBy).Scan(&shipmentId)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	_, err = tx.Exec(ctx, queryAddProducts, shipmentId, shipment.ProductIds)
+	if postgres.IsUniqueViolation(err) {
+		// in another shipment
+		return uuid.Nil, models.ErrNotShippable
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	err = writeShipmentHistory(ctx, tx, &models.ShipmentTransition{
+		ShipmentId: shipmentId,
+		ToStatusId: models.ShipmentStatusCreated,
+		ActorId:    shipment.CreatedBy,
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return shipmentId, tx.Commit(ctx)
+}
+
+func (r *ReceptionRepo) GetShipment(ctx context.Context, shipmentId uuid.UUID) (*models.ShipmentAPI, error) {
+	query := fmt.Sprintf(`select %s
+				%s
+				where s.id = $1`, shipmentColumns, shipmentFrom)
+
+	shipment, err := scanShipment(r.pool.QueryRow(ctx, query, shipmentId))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, models.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// shipments sent from the pvz or, for inbound, coming to it, oldest first
+func (r *ReceptionRepo) ListShipments(ctx context.Context, filter *models.ShipmentFilter) (*models.ListResult[models.ShipmentAPI], error) {
+	queryFilter := `where case when $2 then s.to_pvz_id else s.from_pvz_id end = $1
+					and ($3::text[] is null or ss.name = any($3))`
+
+	args := []any{filter.PvzId, filter.Inbound, filter.Statuses}
+
+	result := &models.ListResult[models.ShipmentAPI]{}
+	err := r.pool.QueryRow(ctx, `select count(*)
+				`+shipmentFrom+`
+				`+queryFilter, args...).Scan(&result.Total)
+	if err != nil {
+		return nil, err
+	}
+
+	keysetMatch := ""
+	direction := "asc"
+	offset := 0
+	if filter.Cursor != nil {
+		if !filter.Cursor.Matches(models.ShipmentSortByDate, false) {
+			return nil, models.ErrInvalidFilter
+		}
+		op := ">"
+		if filter.Cursor.Backward {
+			op = "<"
+			direction = "desc"
+		}
+		keysetMatch = fmt.Sprintf("\n\t\t\t\t\tand (s.created_at, s.id) %s ($6::timestamptz, $7::uuid)", op)
+		args = append(args, filter.PageLimit+1, offset, filter.Cursor.SortKey, filter.Cursor.Id)
+	} else {
+		offset = filter.PageLimit * (filter.Page - 1)
+		args = append(args, filter.PageLimit+1, offset)
+	}
+
+	query := fmt.Sprintf(`select %s
+				%s
+				%s%s
+				order by s.created_at %s, s.id %s
+				limit $4 offset $5`, shipmentColumns, shipmentFrom, queryFilter, keysetMatch, direction, direction)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var shipments []models.ShipmentAPI
+	for rows.Next() {
+		shipment, err := scanShipment(rows)
+		if err != nil {
+			return nil, err
+		}
+		shipments = append(shipments, *shipment)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result.Items, result.Next, result.Prev = cursor.Slice(shipments, filter.PageLimit, filter.Cursor, offset, func(shipment models.ShipmentAPI) cursor.Cursor {
+		return cursor.Cursor{
+			SortBy:  models.ShipmentSortByDate,
+			SortKey: shipment.CreatedAt.Format(time.RFC3339Nano),
+			Id:      shipment.Id.String(),
+		}
+	})
+	return result, nil
+}
+
+func scanShipment(row pgx.Row) (*models.ShipmentAPI, error) {
+	shipment := &models.ShipmentAPI{}
+	err := row.Scan(&shipment.Id, &shipment.FromPvzId, &shipment.ToPvzId, &shipment.Status, &shipment.StatusId,
+		&shipment.ProductIds, &shipment.CreatedAt, &shipment.CreatedBy, &shipment.DispatchedAt, &shipment.ArrivedAt,
+		&shipment.ReceptionId)
+	if err != nil {
+		return nil, err
+	}
+	return shipment, nil
+}
+
+// moves the shipment to the new status only if it still has the expected one.
+// Dispatched products leave the source pvz, on arrival they are accepted at the destination
+func (r *ReceptionRepo) ChangeShipmentStatus(ctx context.Context, transition *models.ShipmentTransition) error {
+	query := `update shipments
+				set status_id = $3,
+					dispatched_at = case when $3 = $4 then now() else dispatched_at end,
+					arrived_at = case when $3 = $5 then now() else arrived_at end
+				where id = $1 and status_id = $2`
+
+	queryCancel := `update shipment_products
+					set active = false
+					where shipment_id = $1`
+
+	tx, err := r.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	tag, err := tx.Exec(ctx, query, transition.ShipmentId, transition.FromStatusId, transition.ToStatusId,
+		models.ShipmentStatusDispatched, models.ShipmentStatusArrived)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		// changed meanwhile
+		return models.ErrShipmentTransition
+	}
+
+	switch transition.ToStatusId {
+	case models.ShipmentStatusDispatched:
+		err = dispatchShipment(ctx, tx, transition)
+	case models.ShipmentStatusArrived:
+		err = receiveShipment(ctx, tx, transition)
+	case models.ShipmentStatusCancelled:
+		_, err = tx.Exec(ctx, queryCancel, transition.ShipmentId)
+	}
+	if err != nil {
+		return err
+	}
+
+	err = writeShipmentHistory(ctx, tx, transition)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// takes the products out of the source pvz: out of its stock and cells, its orders are refreshed.
+// ErrNotShippable if a product left the pvz or got into an open reception since the shipment was created
+func dispatchShipment(ctx context.Context, tx postgres.Tx, transition *models.ShipmentTransition) error {
+	queryProducts := `with locked as (
+						select prod.id
+						from shipments s
+						join shipment_products sp on sp.shipment_id = s.id
+						join products prod on prod.id = sp.product_id
+						where s.id = $1 and prod.pvz_id = s.from_pvz_id and ` + shippableProduct("$2", "$3") + `
+						for update of prod
+					)
+					select (select count(*) from locked),
+						(select count(*) from shipment_products where shipment_id = $1)`
+
+	queryDecStock := `update pvz_stock st
+						set items = st.items - c.items
+						from (
+							select s.from_pvz_id, prod.type_id, count(*) as items
+							from shipments s
+							join shipment_products sp on sp.shipment_id = s.id
+							join products prod on prod.id = sp.product_id
+							where s.id = $1
+							group by s.from_pvz_id, prod.type_id) c
+						where st.pvz_id = c.from_pvz_id and st.type_id = c.type_id`
+
+	queryLeaveCells := `insert into cell_moves(product_id, from_cell_id, moved_by)
+						select prod.id, prod.cell_id, $2
+						from shipment_products sp
+						join products prod on prod.id = sp.product_id
+						where sp.shipment_id = $1 and prod.cell_id is not null`
+
+	queryShip := `update products
+					set pvz_id = null, cell_id = null, left_at = now()
+					where id in (select product_id from shipment_products where shipment_id = $1)`
+
+	var shippable, total int
+	err := tx.QueryRow(ctx, queryProducts, transition.ShipmentId, models.ReceptionStatusInProgress, models.ReturnStatusQueued).Scan(&shippable, &total)
+	if err != nil {
+		return err
+	}
+	if shippable != total {
+		return models.ErrNotShippable
+	}
+
+	_, err = tx.Exec(ctx, queryDecStock, transition.ShipmentId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, queryLeaveCells, transition.ShipmentId, transition.ActorId)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, queryShip, transition.ShipmentId)
+	if err != nil {
+		return err
+	}
+
+	return sharedSql.RefreshOrdersOfShipment(ctx, tx, transition.ShipmentId, transition.ActorId)
+}
+
+// adds copies of the shipped products to the in_progress reception of the destination or opens one,
+// the employee checks and closes it as any other reception. The copies are placed into cells
+// and refresh the orders of the pvz
+func receiveShipment(ctx context.Context, tx postgres.Tx, transition *models.ShipmentTransition) error {
+	queryDestination := `select to_pvz_id
+							from shipments
+							where id = $1`
+
+	// locks the reception so it cannot be closed meanwhile
+	queryOpenReception := `select id
+							from receptions
+							where pvz_id = $1 and status_id = $2
+							for update`
+
+	queryLinkReception := `update shipments
+							set reception_id = $2
+							where id = $1`
+
+	// the products have physically arrived, so the capacity of the pvz is not checked.
+	// A copy is a duplicate if its barcode is already scanned in the destination reception or repeats inside the shipment
+	queryAddProducts := `with copies as (
+							select sp.product_id, gen_random_uuid() as id,
+								prod.barcode is not null and (
+									row_number() over (partition by prod.barcode order by prod.added_at, prod.id) > 1
+									or exists (
+										select 1
+										from reception_products rp
+										join products scanned on scanned.id = rp.product_id
+										where rp.reception_id = $4 and scanned.barcode = prod.barcode and scanned.deleted_at is null)) as duplicate
+							from shipment_products sp
+							join products prod on prod.id = sp.product_id
+							where sp.shipment_id = $1
+						), added as (
+							insert into products(id, type_id, pvz_id, added_by, added_at, barcode, external_order_id, duplicate,
+								weight_grams, length_mm, width_mm, height_mm, declared_value, condition_id, condition_notes, storage_until)
+							select c.id, prod.type_id, $2, $3, clock_timestamp(), prod.barcode, prod.external_order_id, c.duplicate,
+								prod.weight_grams, prod.length_mm, prod.width_mm, prod.height_mm, prod.declared_value,
+								prod.condition_id, prod.condition_notes, storage_deadline($2, prod.type_id, $5)
+							from copies c
+							join products prod on prod.id = c.product_id
+							order by prod.added_at, prod.id
+							returning id, type_id
+						), linked as (
+							insert into reception_products(reception_id, product_id)
+							select $4, id
+							from added
+						), stocked as (
+							insert into pvz_stock(pvz_id, type_id, items)
+							select $2, type_id, count(*)
+							from added
+							group by type_id
+							on conflict (pvz_id, type_id) do update
+							set items = pvz_stock.items + excluded.items
+						), received as (
+							update shipment_products sp
+							set received_product_id = c.id
+							from copies c
+							where sp.shipment_id = $1 and sp.product_id = c.product_id
+						)
+						select array_agg(id)
+						from added`
+
+	var pvzId uuid.UUID
+	err := tx.QueryRow(ctx, queryDestination, transition.ShipmentId).Scan(&pvzId)
+	if err != nil {
+		return err
+	}
+
+	var receptionId uuid.UUID
+	err = tx.QueryRow(ctx, queryOpenReception, pvzId, models.ReceptionStatusInProgress).Scan(&receptionId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		var reception *models.Reception
+		// ErrReceptionOpen if opened meanwhile, the arrival can be repeated
+		reception, err = scanCreatedReception(tx.QueryRow(ctx, queryCreateReception, pvzId, transition.ActorId, models.ReceptionStatusInProgress))
+		if err == nil {
+			receptionId = reception.Id
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(ctx, queryLinkReception, transition.ShipmentId, receptionId)
+	if err != nil {
+		return err
+	}
+
+	var productIds []uuid.UUID
+	err = tx.QueryRow(ctx, queryAddProducts, transition.ShipmentId, pvzId, transition.ActorId, receptionId,
+		models.DefaultStorageDays).Scan(&productIds)
+	if err != nil {
+		return err
+	}
+
+	for _, productId := range productIds {
+		_, err = sharedSql.PlaceProduct(ctx, tx, productId, nil, transition.ActorId)
+		if err != nil {
+			return err
+		}
+	}
+
+	return sharedSql.RefreshOrdersOfProducts(ctx, tx, productIds, transition.ActorId)
+}
+
+// zero FromStatusId is written as the initial record
+func writeShipmentHistory(ctx context.Context, tx postgres.Tx, transition *models.ShipmentTransition) error {
+	query := `insert into shipment_status_history(shipment_id, from_status_id, to_status_id, changed_by, comment)
+				values ($1, nullif($2, 0), $3, $4, $5)`
+
+	_, err := tx.Exec(ctx, query, transition.ShipmentId, transition.FromStatusId, transition.ToStatusId, transition.ActorId, transition.Comment)
+	return err
+}
+
+func (r *ReceptionRepo) GetShipmentHistory(ctx context.Context, shipmentId uuid.UUID) ([]models.ShipmentHistoryItem, error) {
+	query := `select fs.name, ts.name, h.changed_at, h.changed_by, h.comment
+				from shipment_status_history h
+				left join shipment_statuses fs on fs.id = h.from_status_id
+				join shipment_statuses ts on ts.id = h.to_status_id
+				where h.shipment_id = $1
+				order by h.changed_at, h.id`
+
+	rows, err := r.pool.Query(ctx, query, shipmentId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []models.ShipmentHistoryItem{}
+	for rows.Next() {
+		var item models.ShipmentHistoryItem
+		if err := rows.Scan(&item.From, &item.To, &item.ChangedAt, &item.ChangedBy, &item.Comment); err != nil {
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,279 @@
+package receptionRepo
+
+import (
+	"context"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/utils/cursor"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateShipment(t *testing.T) {
+	tests := []struct {
+		name      string
+		pvzs      int
+		shippable int
+		insertErr error
+		wantError error
+	}{
+		{
+			name:      "all products shippable",
+			pvzs:      2,
+			shippable: 2,
+		},
+		{
+			name:      "unknown pvz",
+			pvzs:      1,
+			wantError: models.ErrNotFound,
+		},
+		{
+			name:      "product not in the pvz",
+			pvzs:      2,
+			shippable: 1,
+			wantError: models.ErrNotShippable,
+		},
+		{
+			name:      "product in another shipment",
+			pvzs:      2,
+			shippable: 2,
+			insertErr: &pgconn.PgError{Code: "23505"},
+			wantError: models.ErrNotShippable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			pvzRow := new(mockRow)
+			productsRow := new(mockRow)
+			insertRow := new(mockRow)
+			repo := NewReceptionRepo(mockPool)
+
+			actorId := 4
+			shipment := &models.Shipment{
+				FromPvzId:  uuid.New(),
+				ToPvzId:    uuid.New(),
+				ProductIds: []uuid.UUID{uuid.New(), uuid.New()},
+				CreatedBy:  &actorId,
+			}
+			shipmentId := uuid.New()
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("QueryRow", ctx, mock.Anything, shipment.FromPvzId, shipment.ToPvzId).Return(pvzRow)
+			pvzRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+				*args.Get(0).(*int) = tt.pvzs
+			}).Return(nil)
+			if tt.pvzs == 2 {
+				mockTx.On("QueryRow", ctx, mock.Anything, shipment.FromPvzId, shipment.ProductIds,
+					models.ReceptionStatusInProgress, models.ReturnStatusQueued).Return(productsRow)
+				productsRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).(*int) = tt.shippable
+				}).Return(nil)
+			}
+			if tt.shippable == 2 {
+				mockTx.On("QueryRow", ctx, mock.Anything, shipment.FromPvzId, shipment.ToPvzId, &actorId).Return(insertRow)
+				insertRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+					*args.Get(0).(*uuid.UUID) = shipmentId
+				}).Return(nil)
+				mockTx.On("Exec", ctx, mock.Anything, shipmentId, shipment.ProductIds).Return(pgconn.NewCommandTag("INSERT 0 2"), tt.insertErr)
+			}
+			if tt.wantError == nil {
+				// history
+				mockTx.On("Exec", ctx, mock.Anything, shipmentId, 0, models.ShipmentStatusCreated, &actorId, "").Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			id, err := repo.CreateShipment(ctx, shipment)
+
+			require.ErrorIs(t, err, tt.wantError)
+			if tt.wantError == nil {
+				require.Equal(t, shipmentId, id)
+			}
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestChangeShipmentStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		from    int
+		to      int
+		updated string
+		// the destination has an in_progress reception
+		openReception bool
+		// a reception was opened between the lookup and the insert
+		raced     bool
+		wantError error
+	}{
+		{name: "cancelled", from: models.ShipmentStatusCreated, to: models.ShipmentStatusCancelled, updated: "UPDATE 1"},
+		{name: "changed meanwhile", from: models.ShipmentStatusCreated, to: models.ShipmentStatusCancelled, updated: "UPDATE 0", wantError: models.ErrShipmentTransition},
+		{name: "product left the pvz before dispatch", from: models.ShipmentStatusCreated, to: models.ShipmentStatusDispatched, updated: "UPDATE 1", wantError: models.ErrNotShippable},
+		{name: "arrived into a new reception", from: models.ShipmentStatusDispatched, to: models.ShipmentStatusArrived, updated: "UPDATE 1"},
+		{name: "arrived into the open reception", from: models.ShipmentStatusDispatched, to: models.ShipmentStatusArrived, updated: "UPDATE 1", openReception: true},
+		{name: "reception opened meanwhile", from: models.ShipmentStatusDispatched, to: models.ShipmentStatusArrived, updated: "UPDATE 1", raced: true, wantError: models.ErrReceptionOpen},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockPool := new(mockDbPool)
+			mockTx := new(mockDbTx)
+			row := new(mockRow)
+			openRow := new(mockRow)
+			receptionRow := new(mockRow)
+			addedRow := new(mockRow)
+			repo := NewReceptionRepo(mockPool)
+
+			actorId := 4
+			transition := &models.ShipmentTransition{ShipmentId: uuid.New(), FromStatusId: tt.from, ToStatusId: tt.to, ActorId: &actorId}
+			pvzId := uuid.New()
+			receptionId := uuid.New()
+
+			mockPool.On("Begin", ctx).Return(mockTx, nil)
+			mockTx.On("Rollback", ctx).Return(nil)
+			mockTx.On("Exec", ctx, mock.Anything, transition.ShipmentId, tt.from, tt.to,
+				models.ShipmentStatusDispatched, models.ShipmentStatusArrived).Return(pgconn.NewCommandTag(tt.updated), nil)
+			if tt.updated == "UPDATE 1" {
+				switch tt.to {
+				case models.ShipmentStatusCancelled:
+					mockTx.On("Exec", ctx, mock.Anything, transition.ShipmentId).Return(pgconn.NewCommandTag("UPDATE 2"), nil)
+				case models.ShipmentStatusDispatched:
+					mockTx.On("QueryRow", ctx, mock.Anything, transition.ShipmentId, models.ReceptionStatusInProgress, models.ReturnStatusQueued).Return(row)
+					row.On("Scan", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+						*args.Get(0).(*int) = 1
+						*args.Get(1).(*int) = 2
+					}).Return(nil)
+				case models.ShipmentStatusArrived:
+					mockTx.On("QueryRow", ctx, mock.Anything, transition.ShipmentId).Return(row)
+					row.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+						*args.Get(0).(*uuid.UUID) = pvzId
+					}).Return(nil)
+					mockTx.On("QueryRow", ctx, mock.Anything, pvzId, models.ReceptionStatusInProgress).Return(openRow)
+					if tt.openReception {
+						openRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+							*args.Get(0).(*uuid.UUID) = receptionId
+						}).Return(nil)
+					} else {
+						openRow.On("Scan", mock.Anything).Return(pgx.ErrNoRows)
+						mockTx.On("QueryRow", ctx, mock.Anything, pvzId, &actorId, models.ReceptionStatusInProgress).Return(receptionRow)
+						receptionCall := receptionRow.On("Scan", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+						if tt.raced {
+							receptionCall.Return(pgx.ErrNoRows)
+						} else {
+							receptionCall.Run(func(args mock.Arguments) {
+								*args.Get(0).(*uuid.UUID) = receptionId
+							}).Return(nil)
+						}
+					}
+					if !tt.raced {
+						mockTx.On("Exec", ctx, mock.Anything, transition.ShipmentId, receptionId).Return(pgconn.NewCommandTag("UPDATE 1"), nil)
+						// duplicates are checked against the destination reception, not copied from the source
+						addQuery := mock.MatchedBy(func(sql string) bool {
+							return strings.Contains(sql, "rp.reception_id = $4") && strings.Contains(sql, "c.duplicate") &&
+								!strings.Contains(sql, "prod.duplicate")
+						})
+						mockTx.On("QueryRow", ctx, addQuery, transition.ShipmentId, pvzId, &actorId, receptionId, models.DefaultStorageDays).Return(addedRow)
+						// an empty shipment, nothing to place into cells
+						addedRow.On("Scan", mock.Anything).Return(nil)
+						mockTx.On("Exec", ctx, mock.Anything, []uuid.UUID(nil), &actorId, models.OrderStatusAwaiting, models.OrderStatusReady).Return(pgconn.NewCommandTag("UPDATE 0"), nil)
+					}
+				}
+			}
+			if tt.wantError == nil {
+				// history
+				mockTx.On("Exec", ctx, mock.Anything, transition.ShipmentId, tt.from, tt.to, &actorId, "").Return(pgconn.NewCommandTag("INSERT 0 1"), nil)
+				mockTx.On("Commit", ctx).Return(nil)
+			}
+
+			err := repo.ChangeShipmentStatus(ctx, transition)
+
+			require.ErrorIs(t, err, tt.wantError)
+			mockTx.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListShipments(t *testing.T) {
+	ctx := context.Background()
+	pvzId := uuid.New()
+	statuses := []string{"created", "dispatched"}
+
+	created := time.Now()
+	rows := [][]any{}
+	for i := range 3 {
+		rows = append(rows, []any{uuid.New(), pvzId, uuid.New(), "created", models.ShipmentStatusCreated, []uuid.UUID{uuid.New()},
+			created.Add(time.Duration(i) * time.Hour), (*int)(nil), (*time.Time)(nil), (*time.Time)(nil), (*uuid.UUID)(nil)})
+	}
+
+	t.Run("first page", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		countRow := new(mockRow)
+		repo := NewReceptionRepo(mockPool)
+
+		mockPool.On("QueryRow", ctx, mock.Anything, pvzId, false, statuses).Return(countRow)
+		countRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*args[0].(*int) = 3
+		}).Return(nil)
+		// one row over the limit tells there is a next page
+		mockPool.On("Query", ctx, mock.Anything, pvzId, false, statuses, 3, 0).Return(&fakeRows{rows: rows}, nil)
+
+		result, err := repo.ListShipments(ctx, &models.ShipmentFilter{PvzId: pvzId, Statuses: statuses, Page: 1, PageLimit: 2})
+
+		require.NoError(t, err)
+		require.Equal(t, 3, result.Total)
+		require.Len(t, result.Items, 2)
+		require.Equal(t, rows[0][0], result.Items[0].Id)
+		require.NotNil(t, result.Next)
+		require.Nil(t, result.Prev)
+		mockPool.AssertExpectations(t)
+	})
+
+	t.Run("next page by cursor", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		countRow := new(mockRow)
+		repo := NewReceptionRepo(mockPool)
+
+		after := &cursor.Cursor{SortBy: models.ShipmentSortByDate, SortKey: created.Add(time.Hour).Format(time.RFC3339Nano), Id: rows[1][0].(uuid.UUID).String()}
+		mockPool.On("QueryRow", ctx, mock.Anything, pvzId, true, statuses).Return(countRow)
+		countRow.On("Scan", mock.Anything).Run(func(args mock.Arguments) {
+			*args[0].(*int) = 3
+		}).Return(nil)
+		mockPool.On("Query", ctx, mock.Anything, pvzId, true, statuses, 3, 0, after.SortKey, after.Id).
+			Return(&fakeRows{rows: rows[2:]}, nil)
+
+		result, err := repo.ListShipments(ctx, &models.ShipmentFilter{PvzId: pvzId, Inbound: true, Statuses: statuses, Cursor: after, Page: 1, PageLimit: 2})
+
+		require.NoError(t, err)
+		require.Len(t, result.Items, 1)
+		require.Equal(t, rows[2][0], result.Items[0].Id)
+		require.Nil(t, result.Next)
+		require.NotNil(t, result.Prev)
+		mockPool.AssertExpectations(t)
+	})
+
+	t.Run("cursor of another list", func(t *testing.T) {
+		mockPool := new(mockDbPool)
+		countRow := new(mockRow)
+		repo := NewReceptionRepo(mockPool)
+
+		mockPool.On("QueryRow", ctx, mock.Anything, pvzId, false, statuses).Return(countRow)
+		countRow.On("Scan", mock.Anything).Return(nil)
+
+		_, err := repo.ListShipments(ctx, &models.ShipmentFilter{PvzId: pvzId, Statuses: statuses,
+			Cursor: &cursor.Cursor{SortBy: models.ShipmentSortByDate, Desc: true, Id: uuid.NewString()}, Page: 1, PageLimit: 2})
+
+		require.ErrorIs(t, err, models.ErrInvalidFilter)
+		mockPool.AssertNotCalled(t, "Query", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,123 @@
+package sharedSql
+
+import (
+	"context"
+	"errors"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage/postgres"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// products present in the pvz occupy their cells, issued and deleted ones do not
+const CellItems = `(select count(*)
+					from products cp
+					where cp.cell_id = c.id and cp.deleted_at is null and cp.issued_at is null)`
+
+// the cell takes the product by its type and longest side, products without dimensions fit any size
+const cellFits = `(c.type_id is null or c.type_id = prod.type_id)
+					and (c.max_side_mm is null
+						or coalesce(greatest(prod.length_mm, prod.width_mm, prod.height_mm), 0) <= c.max_side_mm)`
+
+// cells reserved for the type first, then cells already holding items of the same order,
+// then the smallest cells the product fits in
+const queryAutoCell = `select c.id, c.code
+						from products prod
+						join storage_cells c on c.pvz_id = prod.pvz_id
+						where prod.id = $1 and c.active and ` + cellFits + `
+							and ` + CellItems + ` < c.capacity
+						order by c.type_id is null,
+							not exists (
+								select 1
+								from products op
+								where op.cell_id = c.id and op.external_order_id = prod.external_order_id
+									and op.pvz_id = prod.pvz_id and op.deleted_at is null and op.issued_at is null),
+							c.max_side_mm nulls last, c.rack, c.shelf, c.cell
+						limit 1
+						for update of c`
+
+// locks the cell, so concurrent placements see each other
+const queryCell = `select c.id, c.active and ` + cellFits + `, ` + CellItems + `, c.capacity, prod.cell_id
+					from products prod
+					join storage_cells c on c.pvz_id = prod.pvz_id
+					where prod.id = $1 and c.code = $2
+					for update of c`
+
+// puts the product into a cell of its pvz and records the move. The cell with the code has to be active,
+// fit the product and have free place. Without a code the best fitting cell is chosen, nil code is returned
+// when there is none: a pvz without a layout still accepts products
+func PlaceProduct(ctx context.Context, tx postgres.Tx, productId uuid.UUID, cellCode *string, actorId *int) (*string, error) {
+	queryPlace := `update products
+					set cell_id = $2
+					where id = $1`
+
+	queryMove := `insert into cell_moves(product_id, from_cell_id, to_cell_id, moved_by)
+					values ($1, $2, $3, $4)`
+
+	var (
+		cellId   uuid.UUID
+		fromCell *uuid.UUID
+	)
+	if cellCode == nil {
+		var code string
+		err := tx.QueryRow(ctx, queryAutoCell, productId).Scan(&cellId, &code)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		cellCode = &code
+	} else {
+		var (
+			fits     bool
+			items    int
+			capacity int
+		)
+		err := tx.QueryRow(ctx, queryCell, productId, *cellCode).Scan(&cellId, &fits, &items, &capacity, &fromCell)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, models.ErrUnknownCell
+		}
+		if err != nil {
+			return nil, err
+		}
+		if fromCell != nil && *fromCell == cellId {
+			return cellCode, nil
+		}
+		if !fits {
+			return nil, models.ErrCellMismatch
+		}
+		if items >= capacity {
+			return nil, models.ErrCellFull
+		}
+	}
+
+	_, err := tx.Exec(ctx, queryPlace, productId, cellId)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(ctx, queryMove, productId, fromCell, cellId, actorId)
+	if err != nil {
+		return nil, err
+	}
+	return cellCode, nil
+}
+
+// the product with its type and cell, scanned by ScanPlacement
+const PlacementColumns = `prod.id, pt.name, prod.barcode, prod.external_order_id, prod.added_at, sc.code, prod.storage_until`
+
+const PlacementFrom = `from products prod
+				join product_types pt on pt.id = prod.type_id
+				left join storage_cells sc on sc.id = prod.cell_id`
+
+func ScanPlacement(row pgx.Row) (*models.PlacementAPI, error) {
+	placement := &models.PlacementAPI{}
+	err := row.Scan(&placement.ProductId, &placement.Type, &placement.Barcode, &placement.ExternalOrderId,
+		&placement.AddedAt, &placement.Cell, &placement.StorageUntil)
+	if err != nil {
+		return nil, err
+	}
+	return placement, nil
+}
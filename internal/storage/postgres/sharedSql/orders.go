@@ -0,0 +1,62 @@
+// queries that change the data of one repo and are run by other repos in their own transactions
+package sharedSql
+
+import (
+	"context"
+	"fmt"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/storage/postgres"
+
+	"github.com/google/uuid"
+)
+
+// open orders (awaiting, ready) become ready when all their items are accepted in the pvz
+// and go back to awaiting when an item is removed. The status change is written to the history
+const queryRefreshOrders = `with arrived as (
+					select o.id, o.status_id as from_status_id,
+						case when count(prod.id) >= o.expected_items then $4::int else $3::int end as to_status_id
+					from orders o
+					left join products prod on prod.external_order_id = o.external_id
+						and prod.pvz_id = o.pvz_id and prod.deleted_at is null
+					where o.status_id in ($3, $4) and (o.pvz_id, o.external_id) in (%s)
+					group by o.id
+				), changed as (
+					update orders o
+					set status_id = a.to_status_id
+					from arrived a
+					where o.id = a.id and o.status_id <> a.to_status_id
+					returning o.id, a.from_status_id, a.to_status_id
+				)
+				insert into order_status_history(order_id, from_status_id, to_status_id, changed_by)
+				select id, from_status_id, to_status_id, $2
+				from changed`
+
+// refreshes the statuses of the orders the products belong to
+func RefreshOrdersOfProducts(ctx context.Context, tx postgres.Tx, productIds []uuid.UUID, actorId *int) error {
+	query := fmt.Sprintf(queryRefreshOrders, `select pvz_id, external_order_id
+						from products
+						where id = any($1) and external_order_id is not null`)
+	_, err := tx.Exec(ctx, query, productIds, actorId, models.OrderStatusAwaiting, models.OrderStatusReady)
+	return err
+}
+
+// refreshes the statuses of the orders with products in the reception
+func RefreshOrdersOfReception(ctx context.Context, tx postgres.Tx, receptionId uuid.UUID, actorId *int) error {
+	query := fmt.Sprintf(queryRefreshOrders, `select prod.pvz_id, prod.external_order_id
+						from reception_products rp
+						join products prod on prod.id = rp.product_id
+						where rp.reception_id = $1 and prod.external_order_id is not null`)
+	_, err := tx.Exec(ctx, query, receptionId, actorId, models.OrderStatusAwaiting, models.OrderStatusReady)
+	return err
+}
+
+// refreshes the statuses of the orders the shipped products leave in the pvz they are sent from
+func RefreshOrdersOfShipment(ctx context.Context, tx postgres.Tx, shipmentId uuid.UUID, actorId *int) error {
+	query := fmt.Sprintf(queryRefreshOrders, `select s.from_pvz_id, prod.external_order_id
+						from shipment_products sp
+						join shipments s on s.id = sp.shipment_id
+						join products prod on prod.id = sp.product_id
+						where sp.shipment_id = $1 and prod.external_order_id is not null`)
+	_, err := tx.Exec(ctx, query, shipmentId, actorId, models.OrderStatusAwaiting, models.OrderStatusReady)
+	return err
+}
@@ -0,0 +1,25 @@
+package sharedSql
+
+import "orderPickupPoint/internal/models"
+
+// columns of product_types aliased as pt, scanned with ProductTypeFields
+const ProductTypeColumns = `pt.id, pt.code, pt.name, pt.active,
+					pt.weight_required, pt.dimensions_required, pt.declared_value_required,
+					pt.max_weight_grams, pt.max_side_mm, pt.max_declared_value, pt.storage_days`
+
+func ProductTypeFields(productType *models.ProductType) []any {
+	rules := &productType.Rules
+	return []any{&productType.Id, &productType.Code, &productType.Name, &productType.Active,
+		&rules.WeightRequired, &rules.DimensionsRequired, &rules.DeclaredValueRequired,
+		&rules.MaxWeightGrams, &rules.MaxSideMm, &rules.MaxDeclaredValue, &rules.StorageDays}
+}
+
+// id of the product type given by its code or default name, null for an unknown one.
+// The code wins when it is the name of another type
+func ProductTypeIdByKey(key string) string {
+	return `(select kt.id
+				from product_types kt
+				where kt.code = ` + key + ` or kt.name = ` + key + `
+				order by kt.code = ` + key + ` desc
+				limit 1)`
+}
@@ -2,10 +2,15 @@ package storage
 
 import (
 	"context"
+	"io"
 	"orderPickupPoint/internal/models"
 	"orderPickupPoint/internal/storage/postgres"
+	"orderPickupPoint/internal/storage/postgres/attachmentRepo"
 	"orderPickupPoint/internal/storage/postgres/authRepo"
+	"orderPickupPoint/internal/storage/postgres/cellRepo"
+	"orderPickupPoint/internal/storage/postgres/orderRepo"
 	"orderPickupPoint/internal/storage/postgres/pickupPointRepo"
+	"orderPickupPoint/internal/storage/postgres/productTypeRepo"
 	"orderPickupPoint/internal/storage/postgres/receptionRepo"
 	"time"
 
@@ -15,16 +20,97 @@ import (
 type PickupPoint interface {
 	Create(ctx context.Context, pickupPoint *models.PickupPoint) (*models.PickupPoint, error)
 	GetCityIdByName(ctx context.Context, name string) (int, error)
-	GetFilteredInfo(ctx context.Context, filter *models.PvzFilter) ([]models.PvzFilteredInfo, error)
+	GetFilteredInfo(ctx context.Context, filter *models.PvzFilter, onRow func(row *models.PvzFilteredInfo)) (*models.PvzFilteredResult, error)
+	GetById(ctx context.Context, pvzId uuid.UUID) (*models.PickupPointAPI, error)
+	SetCapacity(ctx context.Context, pvzId uuid.UUID, capacity *models.PvzCapacity) error
+	GetCapacity(ctx context.Context, pvzId uuid.UUID) (*models.PvzCapacity, error)
+	SetStoragePeriods(ctx context.Context, pvzId uuid.UUID, periods *models.PvzStoragePeriods) error
+	GetStoragePeriods(ctx context.Context, pvzId uuid.UUID) (*models.PvzStoragePeriods, error)
+	ListByStorageDeadline(ctx context.Context, filter *models.StorageDeadlineFilter) (*models.ListResult[models.PlacementAPI], error)
+	GetStock(ctx context.Context, pvzId uuid.UUID) ([]models.TypeUtilization, error)
+	GetTotals(ctx context.Context, pvzId uuid.UUID) (*models.ProductTotals, error)
 }
 
 type Reception interface {
-	CreateReception(ctx context.Context, pvzId uuid.UUID) (*models.Reception, error)
+	CreateReception(ctx context.Context, pvzId uuid.UUID, actorId *int) (*models.Reception, error)
 	GetStatusNameById(ctx context.Context, id int) (string, error)
-	GetProductTypeIdByName(ctx context.Context, name string) (int, error)
+	GetProductTypeByName(ctx context.Context, name string) (*models.ProductType, error)
+	GetProductTypesByNames(ctx context.Context, names []string) (map[string]models.ProductType, error)
 	AddProductToReception(ctx context.Context, product *models.Product, pvzId uuid.UUID) (*models.Product, error)
-	DeleteLastProductInReception(ctx context.Context, pvzId uuid.UUID) error
-	CloseReception(ctx context.Context, pvzId uuid.UUID) error
+	AddProductsToReception(ctx context.Context, receptionId uuid.UUID, products []*models.Product, actorId *int) ([]models.ProductBatchItem, error)
+	DeleteLastProductInReception(ctx context.Context, pvzId uuid.UUID, actorId *int) error
+	DeleteProductInReception(ctx context.Context, receptionId uuid.UUID, productId uuid.UUID, actorId *int) error
+	GetReception(ctx context.Context, receptionId uuid.UUID) (*models.Reception, error)
+	GetOpenReception(ctx context.Context, pvzId uuid.UUID) (*models.Reception, error)
+	ChangeReceptionStatus(ctx context.Context, transition *models.ReceptionTransition) error
+	CloseReception(ctx context.Context, transition *models.ReceptionTransition, check func(manifest *models.ManifestAPI, products []models.ProductInfo) error) error
+	CancelReception(ctx context.Context, transition *models.ReceptionTransition) error
+	ListStaleReceptions(ctx context.Context, openedBefore *time.Time, idleBefore *time.Time) ([]models.StaleReception, error)
+	FlagStaleReception(ctx context.Context, event *models.ReceptionEvent) error
+	GetReceptionHistory(ctx context.Context, receptionId uuid.UUID) ([]models.ReceptionHistoryItem, error)
+	CreateReopenRequest(ctx context.Context, request *models.ReopenRequest, transition *models.ReceptionTransition) (*models.ReopenRequest, error)
+	ResolveReopenRequest(ctx context.Context, resolution *models.ReopenResolution, transition *models.ReceptionTransition) error
+	GetReopenRequest(ctx context.Context, requestId uuid.UUID) (*models.ReopenRequestAPI, error)
+	ListReopenRequests(ctx context.Context, receptionId *uuid.UUID, statusId *int) ([]models.ReopenRequestAPI, error)
+	GetReceptionById(ctx context.Context, receptionId uuid.UUID) (*models.ReceptionDetailsAPI, error)
+	GetReceptionProducts(ctx context.Context, receptionId uuid.UUID) ([]models.ProductInfo, error)
+	FindProductsByBarcode(ctx context.Context, barcode string) ([]models.ProductLocationAPI, error)
+	SetManifest(ctx context.Context, manifest *models.Manifest) error
+	GetManifest(ctx context.Context, receptionId uuid.UUID) (*models.ManifestAPI, error)
+	ListReceptions(ctx context.Context, filter *models.ReceptionFilter) (*models.ListResult[models.ReceptionDetailsAPI], error)
+	CreateReturn(ctx context.Context, ret *models.Return) (uuid.UUID, error)
+	GetReturn(ctx context.Context, returnId uuid.UUID) (*models.ReturnAPI, error)
+	ListReturns(ctx context.Context, filter *models.ReturnFilter) (*models.ListResult[models.ReturnAPI], error)
+	ChangeReturnStatus(ctx context.Context, transition *models.ReturnTransition) error
+	GetReturnHistory(ctx context.Context, returnId uuid.UUID) ([]models.ReturnHistoryItem, error)
+	CreateShipment(ctx context.Context, shipment *models.Shipment) (uuid.UUID, error)
+	GetShipment(ctx context.Context, shipmentId uuid.UUID) (*models.ShipmentAPI, error)
+	ListShipments(ctx context.Context, filter *models.ShipmentFilter) (*models.ListResult[models.ShipmentAPI], error)
+	ChangeShipmentStatus(ctx context.Context, transition *models.ShipmentTransition) error
+	GetShipmentHistory(ctx context.Context, shipmentId uuid.UUID) ([]models.ShipmentHistoryItem, error)
+}
+
+type ProductType interface {
+	List(ctx context.Context, includeInactive bool) ([]models.ProductType, error)
+	GetByCode(ctx context.Context, code string) (*models.ProductType, error)
+	Create(ctx context.Context, productType *models.ProductType) error
+	Update(ctx context.Context, code string, name *string, names map[string]string, active *bool, rules *models.ProductTypeRules) error
+}
+
+type Order interface {
+	CreateOrder(ctx context.Context, order *models.Order) (uuid.UUID, error)
+	GetOrder(ctx context.Context, orderId uuid.UUID) (*models.OrderAPI, error)
+	ListOrders(ctx context.Context, filter *models.OrderFilter) (*models.ListResult[models.OrderAPI], error)
+	GetOrderItems(ctx context.Context, orderId uuid.UUID) ([]models.OrderItemAPI, error)
+	IssueProducts(ctx context.Context, issue *models.OrderIssue) error
+	ChangeOrderStatus(ctx context.Context, transition *models.OrderTransition) error
+	GetOrderHistory(ctx context.Context, orderId uuid.UUID) ([]models.OrderHistoryItem, error)
+	ListOrdersWithoutPickupCode(ctx context.Context, limit int) ([]models.OrderAPI, error)
+	SetPickupCode(ctx context.Context, change *models.PickupCodeChange) (bool, error)
+	ClaimPickupCodeAttempt(ctx context.Context, orderId uuid.UUID, maxAttempts int) (*models.PickupCode, error)
+	ListUnclaimedOrders(ctx context.Context, statusIds []int, limit int) ([]models.OrderAPI, error)
+	ReturnUnclaimedOrder(ctx context.Context, transition *models.OrderTransition) (int, error)
+}
+
+type Cell interface {
+	CreateCells(ctx context.Context, pvzId uuid.UUID, cells []models.StorageCellInputAPI) ([]models.StorageCellAPI, error)
+	ListCells(ctx context.Context, pvzId uuid.UUID) ([]models.StorageCellAPI, error)
+	UpdateCell(ctx context.Context, cellId uuid.UUID, update *models.StorageCellUpdateAPI) error
+	MoveProduct(ctx context.Context, move *models.ProductMove) error
+	FindPlacements(ctx context.Context, filter *models.PlacementFilter) ([]models.PlacementAPI, error)
+}
+
+type Attachment interface {
+	CreateAttachment(ctx context.Context, attachment *models.Attachment) error
+	GetAttachment(ctx context.Context, attachmentId uuid.UUID) (*models.Attachment, error)
+	ListAttachments(ctx context.Context, receptionId uuid.UUID, productId *uuid.UUID) ([]models.Attachment, error)
+}
+
+// storage of file contents by key, Open returns ErrNotFound for a missing key
+type BlobStore interface {
+	Put(ctx context.Context, key string, content io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
 }
 
 type Auth interface {
@@ -41,6 +127,10 @@ type Auth interface {
 type Repositories struct {
 	PickupPoint PickupPoint
 	Reception   Reception
+	ProductType ProductType
+	Attachment  Attachment
+	Order       Order
+	Cell        Cell
 	Auth        Auth
 }
 
@@ -48,6 +138,10 @@ func NewRepositories(db postgres.DBPool) *Repositories {
 	return &Repositories{
 		PickupPoint: pickupPointRepo.NewPickupPointRepo(db),
 		Reception:   receptionRepo.NewReceptionRepo(db),
+		ProductType: productTypeRepo.NewProductTypeRepo(db),
+		Attachment:  attachmentRepo.NewAttachmentRepo(db),
+		Order:       orderRepo.NewOrderRepo(db),
+		Cell:        cellRepo.NewCellRepo(db),
 		Auth:        authRepo.NewAuthRepo(db),
 	}
 }
@@ -0,0 +1,143 @@
+package attachmentHandler
+
+import (
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"orderPickupPoint/internal/service"
+	"orderPickupPoint/internal/utils/errorsHandl"
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type AttachmentHandler struct {
+	attachmentService service.Attachment
+}
+
+func NewAttachmentHandler(attachmentService service.Attachment) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachmentService: attachmentService,
+	}
+}
+
+// multipart/form-data with the file in the "file" field, the product is taken from the path if present
+func (h *AttachmentHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	receptionId, err := uuid.Parse(vars["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	var productId *uuid.UUID
+	if value, ok := vars["productId"]; ok {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		productId = &id
+	}
+
+	part, err := filePart(r)
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	defer part.Close()
+
+	attachment, err := h.attachmentService.Upload(r.Context(), receptionId, productId, part.FileName(), part)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(attachment)
+}
+
+func (h *AttachmentHandler) List(w http.ResponseWriter, r *http.Request) {
+	receptionId, err := uuid.Parse(mux.Vars(r)["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	var productId *uuid.UUID
+	if value := r.URL.Query().Get("productId"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		productId = &id
+	}
+
+	attachments, err := h.attachmentService.List(r.Context(), receptionId, productId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(attachments)
+}
+
+func (h *AttachmentHandler) Download(w http.ResponseWriter, r *http.Request) {
+	h.download(w, r, false)
+}
+
+func (h *AttachmentHandler) DownloadThumbnail(w http.ResponseWriter, r *http.Request) {
+	h.download(w, r, true)
+}
+
+func (h *AttachmentHandler) download(w http.ResponseWriter, r *http.Request, thumbnail bool) {
+	attachmentId, err := uuid.Parse(mux.Vars(r)["attachmentId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	attachment, content, err := h.attachmentService.Open(r.Context(), attachmentId, thumbnail)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+	defer content.Close()
+
+	contentType, disposition := attachment.ContentType, "attachment"
+	if thumbnail {
+		contentType, disposition = attachment.Thumbnail.ContentType, "inline"
+	} else {
+		w.Header().Set("Content-Length", strconv.FormatInt(attachment.SizeBytes, 10))
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", disposition+"; filename*=UTF-8''"+url.PathEscape(attachment.FileName))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.WriteHeader(http.StatusOK)
+	io.Copy(w, content)
+}
+
+// streams the first "file" part without buffering the whole body
+func filePart(r *http.Request) (*multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
@@ -0,0 +1,164 @@
+package attachmentHandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/service"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockAttachmentService struct {
+	mock.Mock
+	service.Attachment
+}
+
+func (m *mockAttachmentService) Upload(ctx context.Context, receptionId uuid.UUID, productId *uuid.UUID, fileName string, content io.Reader) (*models.Attachment, error) {
+	data, _ := io.ReadAll(content)
+	args := m.Called(ctx, receptionId, productId, fileName, string(data))
+	return args.Get(0).(*models.Attachment), args.Error(1)
+}
+
+func (m *mockAttachmentService) Open(ctx context.Context, attachmentId uuid.UUID, thumbnail bool) (*models.Attachment, io.ReadCloser, error) {
+	args := m.Called(ctx, attachmentId, thumbnail)
+	content, _ := args.Get(1).(io.ReadCloser)
+	return args.Get(0).(*models.Attachment), content, args.Error(2)
+}
+
+func multipartBody(t *testing.T, field, fileName, content string) (*bytes.Buffer, string) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile(field, fileName)
+	require.NoError(t, err)
+	part.Write([]byte(content))
+	require.NoError(t, writer.Close())
+	return body, writer.FormDataContentType()
+}
+
+func TestUpload(t *testing.T) {
+	tests := []struct {
+		name         string
+		receptionId  string
+		field        string
+		mockError    error
+		answerStatus int
+	}{
+		{
+			name:         "valid request",
+			receptionId:  uuid.NewString(),
+			field:        "file",
+			answerStatus: http.StatusCreated,
+		},
+		{
+			name:         "invalid reception id",
+			receptionId:  "42",
+			field:        "file",
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "no file field",
+			receptionId:  uuid.NewString(),
+			field:        "photo",
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "too large",
+			receptionId:  uuid.NewString(),
+			field:        "file",
+			mockError:    models.ErrAttachmentTooLarge,
+			answerStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:         "unsupported media",
+			receptionId:  uuid.NewString(),
+			field:        "file",
+			mockError:    models.ErrUnsupportedMedia,
+			answerStatus: http.StatusUnsupportedMediaType,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockAttachmentService)
+			handler := NewAttachmentHandler(mockService)
+
+			productId := uuid.New()
+			body, contentType := multipartBody(t, tt.field, "act.pdf", "%PDF-1.4")
+			httpRequest := httptest.NewRequest("POST", "/receptions/"+tt.receptionId+"/products/"+productId.String()+"/attachments", body)
+			httpRequest.Header.Set("Content-Type", contentType)
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"receptionId": tt.receptionId, "productId": productId.String()})
+			rec := httptest.NewRecorder()
+
+			receptionId, err := uuid.Parse(tt.receptionId)
+			if err == nil && tt.field == "file" {
+				mockService.On("Upload", mock.Anything, receptionId, &productId, "act.pdf", "%PDF-1.4").
+					Return(&models.Attachment{Id: uuid.New(), ReceptionId: receptionId, ProductId: &productId}, tt.mockError)
+			}
+
+			handler.Upload(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			if rec.Code == http.StatusCreated {
+				var response models.Attachment
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+				require.Equal(t, productId, *response.ProductId)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDownload(t *testing.T) {
+	attachmentId := uuid.New()
+	attachment := &models.Attachment{
+		Id:          attachmentId,
+		FileName:    "акт 1.pdf",
+		ContentType: "application/pdf",
+		SizeBytes:   8,
+	}
+
+	t.Run("content", func(t *testing.T) {
+		mockService := new(mockAttachmentService)
+		handler := NewAttachmentHandler(mockService)
+
+		mockService.On("Open", mock.Anything, attachmentId, false).Return(attachment, io.NopCloser(bytes.NewBufferString("%PDF-1.4")), nil)
+
+		httpRequest := mux.SetURLVars(httptest.NewRequest("GET", "/attachments/"+attachmentId.String()+"/content", nil),
+			map[string]string{"attachmentId": attachmentId.String()})
+		rec := httptest.NewRecorder()
+
+		handler.Download(rec, httpRequest)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "application/pdf", rec.Header().Get("Content-Type"))
+		require.Equal(t, "8", rec.Header().Get("Content-Length"))
+		require.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+		require.Equal(t, "attachment; filename*=UTF-8''%D0%B0%D0%BA%D1%82%201.pdf", rec.Header().Get("Content-Disposition"))
+		require.Equal(t, "%PDF-1.4", rec.Body.String())
+	})
+
+	t.Run("no thumbnail", func(t *testing.T) {
+		mockService := new(mockAttachmentService)
+		handler := NewAttachmentHandler(mockService)
+
+		mockService.On("Open", mock.Anything, attachmentId, true).Return((*models.Attachment)(nil), nil, models.ErrNotFound)
+
+		httpRequest := mux.SetURLVars(httptest.NewRequest("GET", "/attachments/"+attachmentId.String()+"/thumbnail", nil),
+			map[string]string{"attachmentId": attachmentId.String()})
+		rec := httptest.NewRecorder()
+
+		handler.DownloadThumbnail(rec, httpRequest)
+
+		require.Equal(t, http.StatusNotFound, rec.Code)
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"orderPickupPoint/internal/models"
 	"orderPickupPoint/internal/service"
 	"orderPickupPoint/internal/utils/errorsHandl"
+	"orderPickupPoint/internal/utils/userCtx"
 	"time"
 )
 
@@ -155,6 +156,18 @@ func (h *authHandler) IsSignedInMiddleware(next http.HandlerFunc) http.HandlerFu
 			return
 		}
 
+		// services take the user who performs the request from the context
+		accessToken := accessTokenCookie.Value
+		if tokens.NewAccessToken {
+			accessToken = tokens.AccessToken
+		}
+		user, err := h.authService.GetUserFromTokens(&models.AuthTokens{AccessToken: accessToken})
+		if err != nil {
+			errorsHandl.SendJsonError(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		r = r.WithContext(userCtx.WithUser(r.Context(), user))
+
 		if tokens.NewRefreshToken {
 			http.SetCookie(w, &http.Cookie{
 				Name:     "refreshToken",
@@ -0,0 +1,146 @@
+package cellHandler
+
+import (
+	"encoding/json"
+	"net/http"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/service"
+	"orderPickupPoint/internal/utils/errorsHandl"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type CellHandler struct {
+	cellService service.Cell
+}
+
+func NewCellHandler(cellService service.Cell) *CellHandler {
+	return &CellHandler{
+		cellService: cellService,
+	}
+}
+
+func (h *CellHandler) CreateCells(w http.ResponseWriter, r *http.Request) {
+	pvzId, err := uuid.Parse(mux.Vars(r)["pvzId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	var input models.StorageCellsInputAPI
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	cells, err := h.cellService.CreateCells(r.Context(), pvzId, &input)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(cells)
+}
+
+func (h *CellHandler) ListCells(w http.ResponseWriter, r *http.Request) {
+	pvzId, err := uuid.Parse(mux.Vars(r)["pvzId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	cells, err := h.cellService.ListCells(r.Context(), pvzId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(cells)
+}
+
+func (h *CellHandler) UpdateCell(w http.ResponseWriter, r *http.Request) {
+	cellId, err := uuid.Parse(mux.Vars(r)["cellId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	var update models.StorageCellUpdateAPI
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	err = h.cellService.UpdateCell(r.Context(), cellId, &update)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+}
+
+func (h *CellHandler) MoveProduct(w http.ResponseWriter, r *http.Request) {
+	productId, err := uuid.Parse(mux.Vars(r)["productId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	var body struct {
+		Cell string `json:"cell"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	err = h.cellService.MoveProduct(r.Context(), productId, body.Cell)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+}
+
+// products of the pvz by barcode, order id or external order id with the cells they are stored in
+func (h *CellHandler) FindPlacements(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	pvzId, err := uuid.Parse(mux.Vars(r)["pvzId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	filter := &models.PlacementFilter{PvzId: pvzId}
+	if query.Has("barcode") {
+		barcode := strings.TrimSpace(query.Get("barcode"))
+		filter.Barcode = &barcode
+	}
+	if query.Has("externalOrderId") {
+		externalId := strings.TrimSpace(query.Get("externalOrderId"))
+		filter.ExternalOrderId = &externalId
+	}
+	if query.Has("orderId") {
+		orderId, err := uuid.Parse(query.Get("orderId"))
+		if err != nil {
+			errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+			return
+		}
+		filter.OrderId = &orderId
+	}
+
+	placements, err := h.cellService.FindPlacements(r.Context(), filter)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(placements)
+}
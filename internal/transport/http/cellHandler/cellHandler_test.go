@@ -0,0 +1,191 @@
+package cellHandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/service"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockCellService struct {
+	mock.Mock
+	service.Cell
+}
+
+func (m *mockCellService) CreateCells(ctx context.Context, pvzId uuid.UUID, input *models.StorageCellsInputAPI) ([]models.StorageCellAPI, error) {
+	args := m.Called(ctx, pvzId, input)
+	return args.Get(0).([]models.StorageCellAPI), args.Error(1)
+}
+
+func (m *mockCellService) MoveProduct(ctx context.Context, productId uuid.UUID, cellCode string) error {
+	return m.Called(ctx, productId, cellCode).Error(0)
+}
+
+func (m *mockCellService) FindPlacements(ctx context.Context, filter *models.PlacementFilter) ([]models.PlacementAPI, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]models.PlacementAPI), args.Error(1)
+}
+
+func TestCreateCells(t *testing.T) {
+	tests := []struct {
+		name         string
+		pvzId        string
+		requestBody  string
+		mockError    error
+		answerStatus int
+	}{
+		{
+			name:         "valid request",
+			pvzId:        uuid.NewString(),
+			requestBody:  `{"cells":[{"rack":"A","shelf":"1","cell":"1","capacity":4}]}`,
+			answerStatus: http.StatusCreated,
+		},
+		{
+			name:         "invalid pvz id",
+			pvzId:        "42",
+			requestBody:  `{"cells":[]}`,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid json",
+			pvzId:        uuid.NewString(),
+			requestBody:  `{"cells":`,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "cell exists",
+			pvzId:        uuid.NewString(),
+			requestBody:  `{"cells":[{"rack":"A","shelf":"1","cell":"1","capacity":4}]}`,
+			mockError:    models.ErrCellExists,
+			answerStatus: http.StatusConflict,
+		},
+		{
+			name:         "unknown type",
+			pvzId:        uuid.NewString(),
+			requestBody:  `{"cells":[{"rack":"A","shelf":"1","cell":"1","capacity":4,"type":"мебель"}]}`,
+			mockError:    models.ErrUnknownProductType,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown pvz",
+			pvzId:        uuid.NewString(),
+			requestBody:  `{"cells":[{"rack":"A","shelf":"1","cell":"1","capacity":4}]}`,
+			mockError:    models.ErrNotFound,
+			answerStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockCellService)
+			handler := NewCellHandler(mockService)
+
+			if pvzId, err := uuid.Parse(tt.pvzId); err == nil && json.Valid([]byte(tt.requestBody)) {
+				mockService.On("CreateCells", mock.Anything, pvzId, mock.MatchedBy(func(input *models.StorageCellsInputAPI) bool {
+					return len(input.Cells) == 1 && input.Cells[0].Rack == "A"
+				})).Return([]models.StorageCellAPI{{Id: uuid.New(), Code: "A-1-1"}}, tt.mockError)
+			}
+
+			httpRequest := httptest.NewRequest("POST", "/pvz/"+tt.pvzId+"/cells", bytes.NewBufferString(tt.requestBody))
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"pvzId": tt.pvzId})
+			rec := httptest.NewRecorder()
+
+			handler.CreateCells(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestMoveProduct(t *testing.T) {
+	tests := []struct {
+		name         string
+		mockError    error
+		answerStatus int
+	}{
+		{name: "moved", answerStatus: http.StatusOK},
+		{name: "cell full", mockError: models.ErrCellFull, answerStatus: http.StatusConflict},
+		{name: "product does not fit", mockError: models.ErrCellMismatch, answerStatus: http.StatusConflict},
+		{name: "unknown cell", mockError: models.ErrUnknownCell, answerStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockCellService)
+			handler := NewCellHandler(mockService)
+
+			productId := uuid.New()
+			mockService.On("MoveProduct", mock.Anything, productId, "A-1-2").Return(tt.mockError)
+
+			httpRequest := httptest.NewRequest("POST", "/products/"+productId.String()+"/move", bytes.NewBufferString(`{"cell":"A-1-2"}`))
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"productId": productId.String()})
+			rec := httptest.NewRecorder()
+
+			handler.MoveProduct(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFindPlacements(t *testing.T) {
+	pvzId := uuid.New()
+	orderId := uuid.New()
+	barcode := "4006381333931"
+
+	tests := []struct {
+		name         string
+		query        string
+		wantFilter   *models.PlacementFilter
+		answerStatus int
+	}{
+		{
+			name:         "by barcode",
+			query:        "?barcode=%204006381333931%20",
+			wantFilter:   &models.PlacementFilter{PvzId: pvzId, Barcode: &barcode},
+			answerStatus: http.StatusOK,
+		},
+		{
+			name:         "by order id",
+			query:        "?orderId=" + orderId.String(),
+			wantFilter:   &models.PlacementFilter{PvzId: pvzId, OrderId: &orderId},
+			answerStatus: http.StatusOK,
+		},
+		{
+			name:         "invalid order id",
+			query:        "?orderId=42",
+			answerStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockCellService)
+			handler := NewCellHandler(mockService)
+
+			if tt.wantFilter != nil {
+				mockService.On("FindPlacements", mock.Anything, tt.wantFilter).Return([]models.PlacementAPI{}, nil)
+			}
+
+			httpRequest := httptest.NewRequest("GET", "/pvz/"+pvzId.String()+"/placements"+tt.query, nil)
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"pvzId": pvzId.String()})
+			rec := httptest.NewRecorder()
+
+			handler.FindPlacements(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
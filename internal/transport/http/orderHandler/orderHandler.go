@@ -0,0 +1,181 @@
+package orderHandler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/service"
+	"orderPickupPoint/internal/utils/errorsHandl"
+	"orderPickupPoint/internal/utils/queryParams"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+type OrderHandler struct {
+	orderService service.Order
+}
+
+func NewOrderHandler(orderService service.Order) *OrderHandler {
+	return &OrderHandler{
+		orderService: orderService,
+	}
+}
+
+func (h *OrderHandler) CreateOrder(w http.ResponseWriter, r *http.Request) {
+	var input models.OrderInputAPI
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orderService.CreateOrder(r.Context(), &input)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	orderId, err := uuid.Parse(mux.Vars(r)["orderId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orderService.GetOrder(r.Context(), orderId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
+
+func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	pvzId, err := uuid.Parse(mux.Vars(r)["pvzId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	pagination, err := queryParams.ParsePagination(query)
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	filter := &models.OrderFilter{
+		PvzId:     pvzId,
+		Statuses:  queryParams.List(query, "status"),
+		Cursor:    pagination.Cursor,
+		Page:      pagination.Page,
+		PageLimit: pagination.PageLimit,
+	}
+
+	orders, err := h.orderService.ListOrders(r.Context(), filter)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(orders)
+}
+
+func (h *OrderHandler) IssueOrder(w http.ResponseWriter, r *http.Request) {
+	orderId, err := uuid.Parse(mux.Vars(r)["orderId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	// without a body all the remaining items are issued
+	var request models.OrderIssueAPI
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil && !errors.Is(err, io.EOF) {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.orderService.IssueOrder(r.Context(), orderId, &request)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *OrderHandler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	orderId, err := uuid.Parse(mux.Vars(r)["orderId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	// the reason is optional, so is the body
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	err = h.orderService.CancelOrder(r.Context(), orderId, strings.TrimSpace(body.Reason))
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+}
+
+func (h *OrderHandler) GetOrderHistory(w http.ResponseWriter, r *http.Request) {
+	orderId, err := uuid.Parse(mux.Vars(r)["orderId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.orderService.GetOrderHistory(r.Context(), orderId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
+
+// the new code is sent to the customer, the response only tells when it was generated
+func (h *OrderHandler) RegeneratePickupCode(w http.ResponseWriter, r *http.Request) {
+	orderId, err := uuid.Parse(mux.Vars(r)["orderId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.orderService.RegeneratePickupCode(r.Context(), orderId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(order)
+}
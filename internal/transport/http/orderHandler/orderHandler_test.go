@@ -0,0 +1,239 @@
+package orderHandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/service"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockOrderService struct {
+	mock.Mock
+	service.Order
+}
+
+func (m *mockOrderService) CreateOrder(ctx context.Context, input *models.OrderInputAPI) (*models.OrderAPI, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*models.OrderAPI), args.Error(1)
+}
+
+func (m *mockOrderService) IssueOrder(ctx context.Context, orderId uuid.UUID, request *models.OrderIssueAPI) (*models.OrderIssueResultAPI, error) {
+	args := m.Called(ctx, orderId, request)
+	return args.Get(0).(*models.OrderIssueResultAPI), args.Error(1)
+}
+
+func (m *mockOrderService) CancelOrder(ctx context.Context, orderId uuid.UUID, reason string) error {
+	args := m.Called(ctx, orderId, reason)
+	return args.Error(0)
+}
+
+func TestCreateOrder(t *testing.T) {
+	tests := []struct {
+		name         string
+		requestBody  string
+		mockError    error
+		answerStatus int
+	}{
+		{
+			name:         "valid request",
+			requestBody:  `{"externalId":"WB-1","pvzId":"` + uuid.NewString() + `","expectedItems":1,"customerPhone":"+79991234567"}`,
+			answerStatus: http.StatusCreated,
+		},
+		{
+			name:         "invalid json",
+			requestBody:  `{"externalId":`,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid order",
+			requestBody:  `{"externalId":"WB-1"}`,
+			mockError:    fmt.Errorf("%w: customerPhone is required", models.ErrInvalidOrder),
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "order exists",
+			requestBody:  `{"externalId":"WB-1"}`,
+			mockError:    models.ErrOrderExists,
+			answerStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockOrderService)
+			handler := NewOrderHandler(mockService)
+
+			if json.Valid([]byte(tt.requestBody)) {
+				mockService.On("CreateOrder", mock.Anything, mock.Anything).Return(&models.OrderAPI{Id: uuid.New(), ExternalId: "WB-1"}, tt.mockError)
+			}
+
+			httpRequest := httptest.NewRequest("POST", "/orders", bytes.NewBufferString(tt.requestBody))
+			rec := httptest.NewRecorder()
+
+			handler.CreateOrder(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestIssueOrder(t *testing.T) {
+	tests := []struct {
+		name         string
+		orderId      string
+		requestBody  string
+		wantRequest  *models.OrderIssueAPI
+		mockError    error
+		answerStatus int
+	}{
+		{
+			name:         "items and code",
+			orderId:      uuid.NewString(),
+			requestBody:  `{"pickupCode":"123456","barcodes":["4006381333931"]}`,
+			wantRequest:  &models.OrderIssueAPI{PickupCode: "123456", Barcodes: []string{"4006381333931"}},
+			answerStatus: http.StatusOK,
+		},
+		{
+			// all the remaining items, the service asks for the code
+			name:         "empty body",
+			orderId:      uuid.NewString(),
+			wantRequest:  &models.OrderIssueAPI{},
+			mockError:    models.ErrPickupCodeRequired,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid order id",
+			orderId:      "42",
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid json",
+			orderId:      uuid.NewString(),
+			requestBody:  `{"barcodes":`,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "wrong pickup code",
+			orderId:      uuid.NewString(),
+			requestBody:  `{"pickupCode":"000000"}`,
+			wantRequest:  &models.OrderIssueAPI{PickupCode: "000000"},
+			mockError:    fmt.Errorf("%w: 4 attempts left", models.ErrWrongPickupCode),
+			answerStatus: http.StatusForbidden,
+		},
+		{
+			name:         "item not issuable",
+			orderId:      uuid.NewString(),
+			requestBody:  `{"pickupCode":"123456","barcodes":["4006381333931"]}`,
+			wantRequest:  &models.OrderIssueAPI{PickupCode: "123456", Barcodes: []string{"4006381333931"}},
+			mockError:    models.ErrItemNotIssuable,
+			answerStatus: http.StatusConflict,
+		},
+		{
+			name:         "order not ready",
+			orderId:      uuid.NewString(),
+			requestBody:  `{"pickupCode":"123456"}`,
+			wantRequest:  &models.OrderIssueAPI{PickupCode: "123456"},
+			mockError:    models.ErrOrderNotReady,
+			answerStatus: http.StatusConflict,
+		},
+		{
+			name:         "order closed",
+			orderId:      uuid.NewString(),
+			requestBody:  `{"pickupCode":"123456"}`,
+			wantRequest:  &models.OrderIssueAPI{PickupCode: "123456"},
+			mockError:    models.ErrOrderClosed,
+			answerStatus: http.StatusConflict,
+		},
+		{
+			name:         "order not found",
+			orderId:      uuid.NewString(),
+			requestBody:  `{"pickupCode":"123456"}`,
+			wantRequest:  &models.OrderIssueAPI{PickupCode: "123456"},
+			mockError:    models.ErrNotFound,
+			answerStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockOrderService)
+			handler := NewOrderHandler(mockService)
+
+			if tt.wantRequest != nil {
+				orderId := uuid.MustParse(tt.orderId)
+				mockService.On("IssueOrder", mock.Anything, orderId, tt.wantRequest).
+					Return(&models.OrderIssueResultAPI{}, tt.mockError)
+			}
+
+			httpRequest := httptest.NewRequest("POST", "/orders/"+tt.orderId+"/issue", bytes.NewBufferString(tt.requestBody))
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"orderId": tt.orderId})
+			rec := httptest.NewRecorder()
+
+			handler.IssueOrder(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			if tt.mockError != nil {
+				var response map[string]string
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+				require.Equal(t, tt.mockError.Error(), response["message"])
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCancelOrder(t *testing.T) {
+	tests := []struct {
+		name         string
+		requestBody  string
+		wantReason   string
+		mockError    error
+		answerStatus int
+	}{
+		{
+			name:         "with reason",
+			requestBody:  `{"reason":"  клиент отказался "}`,
+			wantReason:   "клиент отказался",
+			answerStatus: http.StatusOK,
+		},
+		{
+			name:         "empty body",
+			answerStatus: http.StatusOK,
+		},
+		{
+			name:         "already issued",
+			mockError:    models.ErrOrderTransition,
+			answerStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockOrderService)
+			handler := NewOrderHandler(mockService)
+
+			orderId := uuid.New()
+			mockService.On("CancelOrder", mock.Anything, orderId, tt.wantReason).Return(tt.mockError)
+
+			httpRequest := httptest.NewRequest("POST", "/orders/"+orderId.String()+"/cancel", bytes.NewBufferString(tt.requestBody))
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"orderId": orderId.String()})
+			rec := httptest.NewRecorder()
+
+			handler.CancelOrder(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
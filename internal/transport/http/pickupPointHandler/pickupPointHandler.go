@@ -6,8 +6,11 @@ import (
 	"orderPickupPoint/internal/models"
 	"orderPickupPoint/internal/service"
 	"orderPickupPoint/internal/utils/errorsHandl"
+	"orderPickupPoint/internal/utils/queryParams"
 	"strconv"
-	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 )
 
 type PickupPointHandler struct {
@@ -46,57 +49,211 @@ func (h *PickupPointHandler) Create(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *PickupPointHandler) GetReceptionsInfo(w http.ResponseWriter, r *http.Request) {
-	startDate := r.URL.Query().Get("startDate")
-	endDate := r.URL.Query().Get("endDate")
-	page := r.URL.Query().Get("page")
-	pageLimit := r.URL.Query().Get("limit")
+	query := r.URL.Query()
 
-	filter := &models.PvzFilter{}
+	filter := &models.PvzFilter{
+		Cities:       queryParams.List(query, "city"),
+		Statuses:     queryParams.List(query, "status"),
+		ProductTypes: queryParams.List(query, "type"),
+		Conditions:   queryParams.List(query, "condition"),
+		SortBy:       query.Get("sortBy"),
+	}
 
-	if startDate != "" && endDate != "" {
-		if sd, err := time.Parse(time.RFC3339Nano, startDate); err == nil {
-			filter.StartDate = &sd
-		} else {
-			errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
-			return
-		}
-		if ed, err := time.Parse(time.RFC3339Nano, endDate); err == nil {
-			filter.EndDate = &ed
-		} else {
-			errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
-			return
-		}
+	var err error
+	if filter.StartDate, err = queryParams.Time(query, "startDate"); err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if filter.EndDate, err = queryParams.Time(query, "endDate"); err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if filter.IncludeCancelled, err = queryParams.Bool(query, "includeCancelled"); err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
 	}
-	if page != "" {
-		val, err := strconv.Atoi(page)
-		if err != nil || val < 1 {
+	if filter.WithProblems, err = queryParams.Bool(query, "hasProblems"); err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	for _, item := range queryParams.List(query, "pvzId") {
+		pvzId, err := uuid.Parse(item)
+		if err != nil {
 			errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
 			return
 		}
-		filter.Page = val
-	} else {
-		filter.Page = 1
+		filter.PvzIds = append(filter.PvzIds, pvzId)
+	}
+
+	switch query.Get("sortOrder") {
+	case "", "asc":
+	case "desc":
+		filter.SortDesc = true
+	default:
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	pagination, err := queryParams.ParsePagination(query)
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	filter.Page = pagination.Page
+	filter.PageLimit = pagination.PageLimit
+	filter.Cursor = pagination.Cursor
+
+	info, err := h.pickupPointService.GetInfo(r.Context(), filter)
+
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+func (h *PickupPointHandler) GetDetails(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	pvzId, err := uuid.Parse(vars["pvzId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
 	}
 
-	if pageLimit != "" {
-		val, err := strconv.Atoi(pageLimit)
-		if err != nil || val < 1 || val > 30 {
+	details, err := h.pickupPointService.GetDetails(r.Context(), pvzId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(details)
+}
+
+func (h *PickupPointHandler) SetCapacity(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	pvzId, err := uuid.Parse(vars["pvzId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	var capacity *models.PvzCapacity
+	if err := json.NewDecoder(r.Body).Decode(&capacity); err != nil || capacity == nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	err = h.pickupPointService.SetCapacity(r.Context(), pvzId, capacity)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+}
+
+func (h *PickupPointHandler) SetStoragePeriods(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	pvzId, err := uuid.Parse(vars["pvzId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	var periods *models.PvzStoragePeriods
+	if err := json.NewDecoder(r.Body).Decode(&periods); err != nil || periods == nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	err = h.pickupPointService.SetStoragePeriods(r.Context(), pvzId, periods)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+}
+
+// products of the pvz whose storage deadline comes within the days
+func (h *PickupPointHandler) ListExpiring(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter, err := parseStorageDeadlineFilter(r)
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	days := 0
+	if value := query.Get("days"); value != "" {
+		days, err = strconv.Atoi(value)
+		if err != nil || days <= 0 {
 			errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
 			return
 		}
-		filter.PageLimit = val
-	} else {
-		filter.PageLimit = 10
 	}
 
-	info, err := h.pickupPointService.GetInfo(r.Context(), filter)
+	placements, err := h.pickupPointService.ListExpiring(r.Context(), filter, days)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(placements)
+}
 
+func (h *PickupPointHandler) ListOverdue(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseStorageDeadlineFilter(r)
 	if err != nil {
 		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
+	placements, err := h.pickupPointService.ListOverdue(r.Context(), filter)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(info)
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(placements)
+}
+
+// the pvz and pagination of the storage deadline lists, the service sets the deadline window
+func parseStorageDeadlineFilter(r *http.Request) (*models.StorageDeadlineFilter, error) {
+	pvzId, err := uuid.Parse(mux.Vars(r)["pvzId"])
+	if err != nil {
+		return nil, err
+	}
+
+	pagination, err := queryParams.ParsePagination(r.URL.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.StorageDeadlineFilter{
+		PvzId:     pvzId,
+		Cursor:    pagination.Cursor,
+		Page:      pagination.Page,
+		PageLimit: pagination.PageLimit,
+	}, nil
 }
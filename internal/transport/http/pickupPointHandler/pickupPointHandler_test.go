@@ -11,6 +11,8 @@ import (
 	"orderPickupPoint/internal/service"
 	"testing"
 
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
@@ -25,6 +27,16 @@ func (m *mockPickupPoint) Create(ctx context.Context, pickupPoint *models.Pickup
 	return args.Get(0).(*models.PickupPointAPI), args.Error(1)
 }
 
+func (m *mockPickupPoint) ListExpiring(ctx context.Context, filter *models.StorageDeadlineFilter, days int) (*models.Page[models.PlacementAPI], error) {
+	args := m.Called(ctx, filter, days)
+	return args.Get(0).(*models.Page[models.PlacementAPI]), args.Error(1)
+}
+
+func (m *mockPickupPoint) ListOverdue(ctx context.Context, filter *models.StorageDeadlineFilter) (*models.Page[models.PlacementAPI], error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(*models.Page[models.PlacementAPI]), args.Error(1)
+}
+
 func TestCreate(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -95,3 +107,84 @@ func TestCreate(t *testing.T) {
 		})
 	}
 }
+
+func TestListExpiring(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantDays     int
+		wantPage     int
+		wantLimit    int
+		answerStatus int
+	}{
+		{name: "default days", query: "", wantDays: 0, wantPage: 1, wantLimit: 10, answerStatus: http.StatusOK},
+		{name: "days", query: "?days=3", wantDays: 3, wantPage: 1, wantLimit: 10, answerStatus: http.StatusOK},
+		{name: "second page", query: "?days=3&page=2&limit=5", wantDays: 3, wantPage: 2, wantLimit: 5, answerStatus: http.StatusOK},
+		{name: "zero days", query: "?days=0", answerStatus: http.StatusBadRequest},
+		{name: "not a number", query: "?days=week", answerStatus: http.StatusBadRequest},
+		{name: "invalid cursor", query: "?cursor=!!!", answerStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockPickupPoint)
+			handler := NewPickupPointHandler(mockService)
+
+			pvzId := uuid.New()
+			if tt.answerStatus == http.StatusOK {
+				mockService.On("ListExpiring", mock.Anything, &models.StorageDeadlineFilter{PvzId: pvzId, Page: tt.wantPage, PageLimit: tt.wantLimit}, tt.wantDays).
+					Return(&models.Page[models.PlacementAPI]{Items: []models.PlacementAPI{}, Page: tt.wantPage, Limit: tt.wantLimit}, nil)
+			}
+
+			httpRequest := httptest.NewRequest("GET", "/pvz/"+pvzId.String()+"/expiring"+tt.query, nil)
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"pvzId": pvzId.String()})
+			rec := httptest.NewRecorder()
+
+			handler.ListExpiring(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListOverdue(t *testing.T) {
+	tests := []struct {
+		name         string
+		pvzId        string
+		query        string
+		mockError    error
+		answerStatus int
+	}{
+		{name: "first page", pvzId: uuid.NewString(), answerStatus: http.StatusOK},
+		{name: "cursor of another list", pvzId: uuid.NewString(), mockError: models.ErrInvalidFilter, answerStatus: http.StatusBadRequest},
+		{name: "invalid pvz id", pvzId: "pvz", answerStatus: http.StatusBadRequest},
+		{name: "invalid limit", pvzId: uuid.NewString(), query: "?limit=0", answerStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockPickupPoint)
+			handler := NewPickupPointHandler(mockService)
+
+			if pvzId, err := uuid.Parse(tt.pvzId); err == nil && tt.query == "" {
+				mockService.On("ListOverdue", mock.Anything, &models.StorageDeadlineFilter{PvzId: pvzId, Page: 1, PageLimit: 10}).
+					Return(&models.Page[models.PlacementAPI]{Items: []models.PlacementAPI{}, Page: 1, Limit: 10}, tt.mockError)
+			}
+
+			httpRequest := httptest.NewRequest("GET", "/pvz/"+tt.pvzId+"/overdue"+tt.query, nil)
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"pvzId": tt.pvzId})
+			rec := httptest.NewRecorder()
+
+			handler.ListOverdue(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			if rec.Code == http.StatusOK {
+				var response models.Page[models.PlacementAPI]
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+				require.Equal(t, 1, response.Page)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
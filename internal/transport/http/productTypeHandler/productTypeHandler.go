@@ -0,0 +1,88 @@
+package productTypeHandler
+
+import (
+	"encoding/json"
+	"net/http"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/service"
+	"orderPickupPoint/internal/utils/errorsHandl"
+	"orderPickupPoint/internal/utils/queryParams"
+
+	"github.com/gorilla/mux"
+)
+
+type ProductTypeHandler struct {
+	productTypeService service.ProductType
+}
+
+func NewProductTypeHandler(productTypeService service.ProductType) *ProductTypeHandler {
+	return &ProductTypeHandler{
+		productTypeService: productTypeService,
+	}
+}
+
+func (h *ProductTypeHandler) List(w http.ResponseWriter, r *http.Request) {
+	includeInactive, err := queryParams.Bool(r.URL.Query(), "includeInactive")
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	productTypes, err := h.productTypeService.List(r.Context(), includeInactive)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(productTypes)
+}
+
+func (h *ProductTypeHandler) Create(w http.ResponseWriter, r *http.Request) {
+	input, ok := decodeInput(w, r)
+	if !ok {
+		return
+	}
+
+	productType, err := h.productTypeService.Create(r.Context(), input)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(productType)
+}
+
+func (h *ProductTypeHandler) Update(w http.ResponseWriter, r *http.Request) {
+	input, ok := decodeInput(w, r)
+	if !ok {
+		return
+	}
+
+	productType, err := h.productTypeService.Update(r.Context(), mux.Vars(r)["code"], input)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(productType)
+}
+
+func decodeInput(w http.ResponseWriter, r *http.Request) (*models.ProductTypeInputAPI, bool) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return nil, false
+	}
+
+	var input *models.ProductTypeInputAPI
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input == nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return nil, false
+	}
+	return input, true
+}
@@ -0,0 +1,171 @@
+package productTypeHandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/service"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockProductTypeService struct {
+	mock.Mock
+	service.ProductType
+}
+
+func (m *mockProductTypeService) List(ctx context.Context, includeInactive bool) ([]models.ProductTypeAPI, error) {
+	args := m.Called(ctx, includeInactive)
+	return args.Get(0).([]models.ProductTypeAPI), args.Error(1)
+}
+
+func (m *mockProductTypeService) Create(ctx context.Context, input *models.ProductTypeInputAPI) (*models.ProductTypeAPI, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*models.ProductTypeAPI), args.Error(1)
+}
+
+func (m *mockProductTypeService) Update(ctx context.Context, code string, input *models.ProductTypeInputAPI) (*models.ProductTypeAPI, error) {
+	args := m.Called(ctx, code, input)
+	return args.Get(0).(*models.ProductTypeAPI), args.Error(1)
+}
+
+func TestList(t *testing.T) {
+	tests := []struct {
+		name            string
+		query           string
+		includeInactive bool
+		answerStatus    int
+	}{
+		{name: "active only", query: "", answerStatus: http.StatusOK},
+		{name: "include inactive", query: "?includeInactive=true", includeInactive: true, answerStatus: http.StatusOK},
+		{name: "invalid flag", query: "?includeInactive=maybe", answerStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockProductTypeService)
+			handler := NewProductTypeHandler(mockService)
+
+			if tt.answerStatus == http.StatusOK {
+				mockService.On("List", mock.Anything, tt.includeInactive).
+					Return([]models.ProductTypeAPI{{Code: "shoes", Name: "обувь", Active: true}}, nil)
+			}
+
+			httpRequest := httptest.NewRequest("GET", "/product-types"+tt.query, nil)
+			rec := httptest.NewRecorder()
+
+			handler.List(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name         string
+		contentType  string
+		requestBody  string
+		mockError    error
+		answerStatus int
+	}{
+		{
+			name:         "valid request",
+			contentType:  "application/json",
+			requestBody:  `{"code":"furniture","name":"мебель"}`,
+			answerStatus: http.StatusCreated,
+		},
+		{
+			name:         "invalid content type",
+			contentType:  "text/plain",
+			requestBody:  `{"code":"furniture","name":"мебель"}`,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "null body",
+			contentType:  "application/json",
+			requestBody:  `null`,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid type",
+			contentType:  "application/json",
+			requestBody:  `{"code":"furniture","name":"мебель"}`,
+			mockError:    models.ErrInvalidProductType,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "type exists",
+			contentType:  "application/json",
+			requestBody:  `{"code":"furniture","name":"мебель"}`,
+			mockError:    models.ErrProductTypeExists,
+			answerStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockProductTypeService)
+			handler := NewProductTypeHandler(mockService)
+
+			if tt.contentType == "application/json" && tt.requestBody != "null" {
+				mockService.On("Create", mock.Anything, mock.MatchedBy(func(input *models.ProductTypeInputAPI) bool {
+					return input.Code == "furniture" && *input.Name == "мебель"
+				})).Return(&models.ProductTypeAPI{Code: "furniture", Name: "мебель", Active: true}, tt.mockError)
+			}
+
+			httpRequest := httptest.NewRequest("POST", "/product-types", bytes.NewBufferString(tt.requestBody))
+			httpRequest.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+
+			handler.Create(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			if rec.Code == http.StatusCreated {
+				var response models.ProductTypeAPI
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+				require.Equal(t, "furniture", response.Code)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	tests := []struct {
+		name         string
+		mockError    error
+		answerStatus int
+	}{
+		{name: "deactivated", answerStatus: http.StatusOK},
+		{name: "unknown type", mockError: models.ErrNotFound, answerStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockProductTypeService)
+			handler := NewProductTypeHandler(mockService)
+
+			mockService.On("Update", mock.Anything, "shoes", mock.MatchedBy(func(input *models.ProductTypeInputAPI) bool {
+				return input.Active != nil && !*input.Active && input.Name == nil
+			})).Return(&models.ProductTypeAPI{Code: "shoes"}, tt.mockError)
+
+			httpRequest := httptest.NewRequest("PATCH", "/product-types/shoes", bytes.NewBufferString(`{"active":false}`))
+			httpRequest.Header.Set("Content-Type", "application/json")
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"code": "shoes"})
+			rec := httptest.NewRecorder()
+
+			handler.Update(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
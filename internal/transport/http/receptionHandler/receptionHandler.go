@@ -2,11 +2,15 @@ package receptionHandler
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"orderPickupPoint/internal/models"
 	"orderPickupPoint/internal/service"
 	"orderPickupPoint/internal/utils/errorsHandl"
+	"orderPickupPoint/internal/utils/queryParams"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -40,7 +44,7 @@ func (h *ReceptionHandler) CreateReception(w http.ResponseWriter, r *http.Reques
 
 	reception, err := h.receptionService.CreateReception(r.Context(), reception.PickupPointId)
 	if err != nil {
-		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		errorsHandl.SendServiceError(w, err)
 		return
 	}
 
@@ -66,7 +70,7 @@ func (h *ReceptionHandler) AddProduct(w http.ResponseWriter, r *http.Request) {
 
 	product, err := h.receptionService.AddProduct(r.Context(), productAPI)
 	if err != nil {
-		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		errorsHandl.SendServiceError(w, err)
 		return
 	}
 	w.Header().Set("Content-Type", "application/json")
@@ -76,6 +80,60 @@ func (h *ReceptionHandler) AddProduct(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (h *ReceptionHandler) AddProductsBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	receptionId, err := uuid.Parse(vars["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	var batch models.ProductBatchAPI
+	if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.receptionService.AddProductsBatch(r.Context(), receptionId, batch.Products)
+	status := http.StatusCreated
+	if err != nil {
+		// rejected items are reported one by one
+		if result == nil {
+			errorsHandl.SendServiceError(w, err)
+			return
+		}
+		status = http.StatusBadRequest
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+func (h *ReceptionHandler) FindProducts(w http.ResponseWriter, r *http.Request) {
+	code := r.URL.Query().Get("barcode")
+	if code == "" {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	products, err := h.receptionService.FindProductsByBarcode(r.Context(), code)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(products)
+}
+
 func (h *ReceptionHandler) DeleteLastProduct(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
@@ -92,6 +150,27 @@ func (h *ReceptionHandler) DeleteLastProduct(w http.ResponseWriter, r *http.Requ
 	}
 }
 
+func (h *ReceptionHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	receptionId, err := uuid.Parse(vars["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	productId, err := uuid.Parse(vars["productId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	err = h.receptionService.DeleteProductInReception(r.Context(), receptionId, productId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+}
+
 func (h *ReceptionHandler) CloseReception(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
@@ -101,9 +180,348 @@ func (h *ReceptionHandler) CloseReception(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	err = h.receptionService.CloseReception(r.Context(), pvzId)
+	report, err := h.receptionService.CloseReception(r.Context(), pvzId)
+	sendCloseResult(w, report, err)
+}
+
+func (h *ReceptionHandler) CloseReceptionById(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	receptionId, err := uuid.Parse(vars["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.receptionService.CloseReceptionById(r.Context(), receptionId)
+	sendCloseResult(w, report, err)
+}
+
+// the reconciliation report is sent for receptions with a manifest, also when it blocks closing
+func sendCloseResult(w http.ResponseWriter, report *models.ReconciliationAPI, err error) {
+	status := http.StatusOK
+	if err != nil {
+		if report == nil || !errors.Is(err, models.ErrDiscrepancyExceeded) {
+			errorsHandl.SendServiceError(w, err)
+			return
+		}
+		status = http.StatusConflict
+	}
+	if report == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *ReceptionHandler) SetManifest(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/json" {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	vars := mux.Vars(r)
+
+	receptionId, err := uuid.Parse(vars["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	var manifest *models.ManifestAPI
+	if err := json.NewDecoder(r.Body).Decode(&manifest); err != nil || manifest == nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	manifest, err = h.receptionService.SetManifest(r.Context(), receptionId, manifest)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(manifest)
+}
+
+func (h *ReceptionHandler) GetManifest(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	receptionId, err := uuid.Parse(vars["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	manifest, err := h.receptionService.GetManifest(r.Context(), receptionId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(manifest)
+}
+
+func (h *ReceptionHandler) GetReconciliation(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	receptionId, err := uuid.Parse(vars["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.receptionService.GetReconciliation(r.Context(), receptionId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *ReceptionHandler) GetDiscrepancyReport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	receptionId, err := uuid.Parse(vars["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.receptionService.GetDiscrepancyReport(r.Context(), receptionId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
+
+func (h *ReceptionHandler) VerifyReception(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	receptionId, err := uuid.Parse(vars["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	err = h.receptionService.VerifyReception(r.Context(), receptionId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+}
+
+func (h *ReceptionHandler) CancelReception(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	receptionId, err := uuid.Parse(vars["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	// the reason is optional, so is the body
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	err = h.receptionService.CancelReception(r.Context(), receptionId, strings.TrimSpace(body.Reason))
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+}
+
+func (h *ReceptionHandler) GetReceptionHistory(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	receptionId, err := uuid.Parse(vars["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.receptionService.GetReceptionHistory(r.Context(), receptionId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
+
+func (h *ReceptionHandler) GetReception(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	receptionId, err := uuid.Parse(vars["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	reception, err := h.receptionService.GetReception(r.Context(), receptionId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reception)
+}
+
+func (h *ReceptionHandler) ListReceptions(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	query := r.URL.Query()
+
+	pvzId, err := uuid.Parse(vars["pvzId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	filter := &models.ReceptionFilter{
+		PvzId:    pvzId,
+		Statuses: queryParams.List(query, "status"),
+	}
+
+	if filter.StartDate, err = queryParams.Time(query, "startDate"); err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	if filter.EndDate, err = queryParams.Time(query, "endDate"); err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	pagination, err := queryParams.ParsePagination(query)
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+	filter.Page = pagination.Page
+	filter.PageLimit = pagination.PageLimit
+	filter.Cursor = pagination.Cursor
+
+	receptions, err := h.receptionService.ListReceptions(r.Context(), filter)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(receptions)
+}
+
+func (h *ReceptionHandler) RequestReopen(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	receptionId, err := uuid.Parse(vars["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	var body models.ReopenRequestAPI
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || strings.TrimSpace(body.Reason) == "" {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	request, err := h.receptionService.RequestReopen(r.Context(), receptionId, strings.TrimSpace(body.Reason))
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(request)
+}
+
+func (h *ReceptionHandler) ListReopenRequests(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	receptionId, err := uuid.Parse(vars["receptionId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	requests, err := h.receptionService.ListReopenRequests(r.Context(), &receptionId, false)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(requests)
+}
+
+// moderator's queue of requests waiting for a decision
+func (h *ReceptionHandler) ListPendingReopenRequests(w http.ResponseWriter, r *http.Request) {
+	requests, err := h.receptionService.ListReopenRequests(r.Context(), nil, true)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(requests)
+}
+
+func (h *ReceptionHandler) ApproveReopen(w http.ResponseWriter, r *http.Request) {
+	h.resolveReopen(w, r, true)
+}
+
+func (h *ReceptionHandler) RejectReopen(w http.ResponseWriter, r *http.Request) {
+	h.resolveReopen(w, r, false)
+}
+
+func (h *ReceptionHandler) resolveReopen(w http.ResponseWriter, r *http.Request, approve bool) {
+	vars := mux.Vars(r)
+
+	requestId, err := uuid.Parse(vars["requestId"])
 	if err != nil {
 		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
 		return
 	}
+
+	// the comment is optional, so is the body
+	var body models.ReopenRequestAPI
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	request, err := h.receptionService.ResolveReopen(r.Context(), requestId, approve, strings.TrimSpace(body.Comment))
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(request)
 }
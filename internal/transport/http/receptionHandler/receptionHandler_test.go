@@ -37,9 +37,9 @@ func (m *mockReceptionService) DeleteLastProductInReception(ctx context.Context,
 	return args.Error(0)
 }
 
-func (m *mockReceptionService) CloseReception(ctx context.Context, pvzId uuid.UUID) error {
+func (m *mockReceptionService) CloseReception(ctx context.Context, pvzId uuid.UUID) (*models.ReconciliationAPI, error) {
 	args := m.Called(ctx, pvzId)
-	return args.Error(0)
+	return args.Get(0).(*models.ReconciliationAPI), args.Error(1)
 }
 
 func TestCreateReception(t *testing.T) {
@@ -70,6 +70,14 @@ func TestCreateReception(t *testing.T) {
 			mockReturn:   &models.ReceptionAPI{Status: "in_progress"},
 			answerStatus: http.StatusOK,
 		},
+		{
+			name:         "reception in progress",
+			contentType:  "application/json",
+			requestBody:  models.ReceptionAPI{PickupPointId: uuid.New()},
+			mockReturn:   &models.ReceptionAPI{},
+			mockError:    models.ErrReceptionOpen,
+			answerStatus: http.StatusConflict,
+		},
 	}
 
 	for _, tt := range tests {
@@ -186,6 +194,103 @@ func TestAddProduct(t *testing.T) {
 	}
 }
 
+func (m *mockReceptionService) AddProductsBatch(ctx context.Context, receptionId uuid.UUID, items []models.ProductAPI) (*models.ProductBatchResultAPI, error) {
+	args := m.Called(ctx, receptionId, items)
+	return args.Get(0).(*models.ProductBatchResultAPI), args.Error(1)
+}
+
+func TestAddProductsBatch(t *testing.T) {
+	validBody := `{"products":[{"type":"обувь"},{"type":"одежда"}]}`
+	tests := []struct {
+		name         string
+		contentType  string
+		receptionId  string
+		requestBody  string
+		mockReturn   *models.ProductBatchResultAPI
+		mockError    error
+		answerStatus int
+	}{
+		{
+			name:         "valid request",
+			contentType:  "application/json",
+			receptionId:  uuid.NewString(),
+			requestBody:  validBody,
+			mockReturn:   &models.ProductBatchResultAPI{Accepted: 2},
+			answerStatus: http.StatusCreated,
+		},
+		{
+			name:         "invalid content type",
+			contentType:  "text/plain",
+			receptionId:  uuid.NewString(),
+			requestBody:  validBody,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid reception id",
+			contentType:  "application/json",
+			receptionId:  "42",
+			requestBody:  validBody,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid json",
+			contentType:  "application/json",
+			receptionId:  uuid.NewString(),
+			requestBody:  `{"products":`,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			// the rejected items are reported in the body
+			name:        "rejected items",
+			contentType: "application/json",
+			receptionId: uuid.NewString(),
+			requestBody: validBody,
+			mockReturn: &models.ProductBatchResultAPI{Items: []models.ProductBatchItemAPI{
+				{Index: 0},
+				{Index: 1, Error: "unknown product type"},
+			}},
+			mockError:    models.ErrInvalidBatch,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "reception closed",
+			contentType:  "application/json",
+			receptionId:  uuid.NewString(),
+			requestBody:  validBody,
+			mockError:    models.ErrReceptionNotOpen,
+			answerStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockReceptionService)
+			handler := NewReceptionHandler(mockService)
+
+			if tt.mockReturn != nil || tt.mockError != nil {
+				mockService.On("AddProductsBatch", mock.Anything, uuid.MustParse(tt.receptionId), mock.MatchedBy(func(items []models.ProductAPI) bool {
+					return len(items) == 2 && items[1].Type == "одежда"
+				})).Return(tt.mockReturn, tt.mockError)
+			}
+
+			httpRequest := httptest.NewRequest("POST", "/receptions/"+tt.receptionId+"/products:batch", bytes.NewBufferString(tt.requestBody))
+			httpRequest.Header.Set("Content-Type", tt.contentType)
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"receptionId": tt.receptionId})
+			rec := httptest.NewRecorder()
+
+			handler.AddProductsBatch(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			if tt.mockReturn != nil {
+				var response models.ProductBatchResultAPI
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+				require.Equal(t, *tt.mockReturn, response)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
 func TestDeleteLastProductInReception(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -262,7 +367,7 @@ func TestCloseReception(t *testing.T) {
 			rec := httptest.NewRecorder()
 			if tt.mockError == nil {
 				parsedId, _ := uuid.Parse(tt.pvzId)
-				mockService.On("CloseReception", mock.Anything, parsedId).Return(tt.mockError)
+				mockService.On("CloseReception", mock.Anything, parsedId).Return((*models.ReconciliationAPI)(nil), tt.mockError)
 			}
 			handler.CloseReception(rec, httpRequset)
 
@@ -273,3 +378,222 @@ func TestCloseReception(t *testing.T) {
 		})
 	}
 }
+
+func (m *mockReceptionService) GetReception(ctx context.Context, receptionId uuid.UUID) (*models.ReceptionDetailsAPI, error) {
+	args := m.Called(ctx, receptionId)
+	return args.Get(0).(*models.ReceptionDetailsAPI), args.Error(1)
+}
+
+func TestGetReception(t *testing.T) {
+	tests := []struct {
+		name         string
+		receptionId  string
+		mockReturn   *models.ReceptionDetailsAPI
+		mockError    error
+		answerStatus int
+	}{
+		{
+			name:         "valid request",
+			receptionId:  uuid.New().String(),
+			mockReturn:   &models.ReceptionDetailsAPI{Status: "close", ProductsCount: 1},
+			answerStatus: http.StatusOK,
+		},
+		{
+			name:         "invalid uuid",
+			receptionId:  "invalid_uuid",
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "not found",
+			receptionId:  uuid.New().String(),
+			mockReturn:   &models.ReceptionDetailsAPI{},
+			mockError:    models.ErrNotFound,
+			answerStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockReceptionService)
+			handler := NewReceptionHandler(mockService)
+
+			httpRequset := httptest.NewRequest("GET", "/receptions/"+tt.receptionId, nil)
+			httpRequset = mux.SetURLVars(httpRequset, map[string]string{"receptionId": tt.receptionId})
+			rec := httptest.NewRecorder()
+			if tt.mockReturn != nil {
+				parsedId, _ := uuid.Parse(tt.receptionId)
+				mockService.On("GetReception", mock.Anything, parsedId).Return(tt.mockReturn, tt.mockError)
+			}
+			handler.GetReception(rec, httpRequset)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			if rec.Code == http.StatusOK {
+				var response models.ReceptionDetailsAPI
+				err := json.NewDecoder(rec.Body).Decode(&response)
+				require.NoError(t, err)
+				require.Equal(t, tt.mockReturn.Status, response.Status)
+				require.Equal(t, tt.mockReturn.ProductsCount, response.ProductsCount)
+			}
+			if tt.mockReturn != nil {
+				mockService.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func (m *mockReceptionService) VerifyReception(ctx context.Context, receptionId uuid.UUID) error {
+	args := m.Called(ctx, receptionId)
+	return args.Error(0)
+}
+
+func TestVerifyReception(t *testing.T) {
+	tests := []struct {
+		name         string
+		mockError    error
+		answerStatus int
+	}{
+		{
+			name:         "valid request",
+			answerStatus: http.StatusOK,
+		},
+		{
+			name:         "illegal transition",
+			mockError:    models.ErrIllegalTransition,
+			answerStatus: http.StatusConflict,
+		},
+		{
+			name:         "not found",
+			mockError:    models.ErrNotFound,
+			answerStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockReceptionService)
+			handler := NewReceptionHandler(mockService)
+
+			receptionId := uuid.New()
+			httpRequset := httptest.NewRequest("POST", "/receptions/"+receptionId.String()+"/verify", nil)
+			httpRequset = mux.SetURLVars(httpRequset, map[string]string{"receptionId": receptionId.String()})
+			rec := httptest.NewRecorder()
+			mockService.On("VerifyReception", mock.Anything, receptionId).Return(tt.mockError)
+
+			handler.VerifyReception(rec, httpRequset)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func (m *mockReceptionService) RequestReopen(ctx context.Context, receptionId uuid.UUID, reason string) (*models.ReopenRequestAPI, error) {
+	args := m.Called(ctx, receptionId, reason)
+	return args.Get(0).(*models.ReopenRequestAPI), args.Error(1)
+}
+
+func TestRequestReopen(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		mockError    error
+		callService  bool
+		answerStatus int
+	}{
+		{
+			name:         "valid request",
+			body:         `{"reason":"wrong type"}`,
+			callService:  true,
+			answerStatus: http.StatusCreated,
+		},
+		{
+			name:         "empty reason",
+			body:         `{"reason":"  "}`,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "reception is open",
+			body:         `{"reason":"wrong type"}`,
+			mockError:    models.ErrIllegalTransition,
+			callService:  true,
+			answerStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockReceptionService)
+			handler := NewReceptionHandler(mockService)
+
+			receptionId := uuid.New()
+			httpRequset := httptest.NewRequest("POST", "/receptions/"+receptionId.String()+"/reopen-requests", bytes.NewBufferString(tt.body))
+			httpRequset = mux.SetURLVars(httpRequset, map[string]string{"receptionId": receptionId.String()})
+			rec := httptest.NewRecorder()
+			if tt.callService {
+				mockService.On("RequestReopen", mock.Anything, receptionId, "wrong type").Return(&models.ReopenRequestAPI{Status: "pending"}, tt.mockError)
+			}
+
+			handler.RequestReopen(rec, httpRequset)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func (m *mockReceptionService) DeleteProductInReception(ctx context.Context, receptionId uuid.UUID, productId uuid.UUID) error {
+	args := m.Called(ctx, receptionId, productId)
+	return args.Error(0)
+}
+
+func TestDeleteProduct(t *testing.T) {
+	tests := []struct {
+		name         string
+		productId    string
+		mockError    error
+		answerStatus int
+	}{
+		{
+			name:         "valid request",
+			productId:    uuid.New().String(),
+			answerStatus: http.StatusOK,
+		},
+		{
+			name:         "invalid product id",
+			productId:    "invalid_uuid",
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "reception is closed",
+			productId:    uuid.New().String(),
+			mockError:    models.ErrReceptionNotOpen,
+			answerStatus: http.StatusConflict,
+		},
+		{
+			name:         "product not found",
+			productId:    uuid.New().String(),
+			mockError:    models.ErrNotFound,
+			answerStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockReceptionService)
+			handler := NewReceptionHandler(mockService)
+
+			receptionId := uuid.New()
+			httpRequset := httptest.NewRequest("DELETE", "/receptions/"+receptionId.String()+"/products/"+tt.productId, nil)
+			httpRequset = mux.SetURLVars(httpRequset, map[string]string{"receptionId": receptionId.String(), "productId": tt.productId})
+			rec := httptest.NewRecorder()
+			if productId, err := uuid.Parse(tt.productId); err == nil {
+				mockService.On("DeleteProductInReception", mock.Anything, receptionId, productId).Return(tt.mockError)
+			}
+
+			handler.DeleteProduct(rec, httpRequset)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
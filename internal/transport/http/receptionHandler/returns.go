@@ -0,0 +1,137 @@
+package receptionHandler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/utils/errorsHandl"
+	"orderPickupPoint/internal/utils/queryParams"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func (h *ReceptionHandler) RegisterReturn(w http.ResponseWriter, r *http.Request) {
+	var input models.ReturnInputAPI
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	ret, err := h.receptionService.RegisterReturn(r.Context(), &input)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(ret)
+}
+
+func (h *ReceptionHandler) GetReturn(w http.ResponseWriter, r *http.Request) {
+	returnId, err := uuid.Parse(mux.Vars(r)["returnId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	ret, err := h.receptionService.GetReturn(r.Context(), returnId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ret)
+}
+
+func (h *ReceptionHandler) ListReturns(w http.ResponseWriter, r *http.Request) {
+	pvzId, err := uuid.Parse(mux.Vars(r)["pvzId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	query := r.URL.Query()
+	pagination, err := queryParams.ParsePagination(query)
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	returns, err := h.receptionService.ListReturns(r.Context(), &models.ReturnFilter{
+		PvzId:     pvzId,
+		Statuses:  queryParams.List(query, "status"),
+		Cursor:    pagination.Cursor,
+		Page:      pagination.Page,
+		PageLimit: pagination.PageLimit,
+	})
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(returns)
+}
+
+func (h *ReceptionHandler) DispatchReturn(w http.ResponseWriter, r *http.Request) {
+	returnId, err := uuid.Parse(mux.Vars(r)["returnId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	err = h.receptionService.DispatchReturn(r.Context(), returnId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+}
+
+func (h *ReceptionHandler) CancelReturn(w http.ResponseWriter, r *http.Request) {
+	returnId, err := uuid.Parse(mux.Vars(r)["returnId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	// the reason is optional, so is the body
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	err = h.receptionService.CancelReturn(r.Context(), returnId, strings.TrimSpace(body.Reason))
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+}
+
+func (h *ReceptionHandler) GetReturnHistory(w http.ResponseWriter, r *http.Request) {
+	returnId, err := uuid.Parse(mux.Vars(r)["returnId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.receptionService.GetReturnHistory(r.Context(), returnId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
@@ -0,0 +1,215 @@
+package receptionHandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"orderPickupPoint/internal/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func (m *mockReceptionService) RegisterReturn(ctx context.Context, input *models.ReturnInputAPI) (*models.ReturnAPI, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*models.ReturnAPI), args.Error(1)
+}
+
+func (m *mockReceptionService) ListReturns(ctx context.Context, filter *models.ReturnFilter) (*models.Page[models.ReturnAPI], error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(*models.Page[models.ReturnAPI]), args.Error(1)
+}
+
+func (m *mockReceptionService) DispatchReturn(ctx context.Context, returnId uuid.UUID) error {
+	return m.Called(ctx, returnId).Error(0)
+}
+
+func (m *mockReceptionService) CancelReturn(ctx context.Context, returnId uuid.UUID, reason string) error {
+	return m.Called(ctx, returnId, reason).Error(0)
+}
+
+func TestRegisterReturn(t *testing.T) {
+	orderId := uuid.New()
+	tests := []struct {
+		name         string
+		requestBody  string
+		mockError    error
+		answerStatus int
+	}{
+		{
+			name:         "valid request",
+			requestBody:  `{"orderId":"` + orderId.String() + `","barcode":"4006381333931","reason":"defect"}`,
+			answerStatus: http.StatusCreated,
+		},
+		{
+			name:         "invalid json",
+			requestBody:  `{"orderId":`,
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown reason",
+			requestBody:  `{"orderId":"` + orderId.String() + `","reason":"bored"}`,
+			mockError:    fmt.Errorf("%w: unknown reason %q", models.ErrInvalidReturn, "bored"),
+			answerStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "product was not issued",
+			requestBody:  `{"orderId":"` + orderId.String() + `","barcode":"4006381333931","reason":"defect"}`,
+			mockError:    models.ErrNotReturnable,
+			answerStatus: http.StatusConflict,
+		},
+		{
+			name:         "already returned",
+			requestBody:  `{"orderId":"` + orderId.String() + `","barcode":"4006381333931","reason":"defect"}`,
+			mockError:    models.ErrReturnExists,
+			answerStatus: http.StatusConflict,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockReceptionService)
+			handler := NewReceptionHandler(mockService)
+
+			if json.Valid([]byte(tt.requestBody)) {
+				mockService.On("RegisterReturn", mock.Anything, mock.MatchedBy(func(input *models.ReturnInputAPI) bool {
+					return input.OrderId == orderId
+				})).Return(&models.ReturnAPI{Id: uuid.New(), OrderId: orderId}, tt.mockError)
+			}
+
+			httpRequest := httptest.NewRequest("POST", "/returns", bytes.NewBufferString(tt.requestBody))
+			rec := httptest.NewRecorder()
+
+			handler.RegisterReturn(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListReturns(t *testing.T) {
+	pvzId := uuid.New()
+
+	t.Run("status filter", func(t *testing.T) {
+		mockService := new(mockReceptionService)
+		handler := NewReceptionHandler(mockService)
+
+		mockService.On("ListReturns", mock.Anything, &models.ReturnFilter{PvzId: pvzId, Statuses: []string{"queued", "dispatched"}, Page: 2, PageLimit: 5}).
+			Return(&models.Page[models.ReturnAPI]{Items: []models.ReturnAPI{{Id: uuid.New()}}, Page: 2, Limit: 5}, nil)
+
+		httpRequest := httptest.NewRequest("GET", "/pvz/"+pvzId.String()+"/returns?status=queued,dispatched&page=2&limit=5", nil)
+		httpRequest = mux.SetURLVars(httpRequest, map[string]string{"pvzId": pvzId.String()})
+		rec := httptest.NewRecorder()
+
+		handler.ListReturns(rec, httpRequest)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		var response models.Page[models.ReturnAPI]
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+		require.Len(t, response.Items, 1)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		mockService := new(mockReceptionService)
+		handler := NewReceptionHandler(mockService)
+
+		httpRequest := httptest.NewRequest("GET", "/pvz/"+pvzId.String()+"/returns?cursor=!!!", nil)
+		httpRequest = mux.SetURLVars(httpRequest, map[string]string{"pvzId": pvzId.String()})
+		rec := httptest.NewRecorder()
+
+		handler.ListReturns(rec, httpRequest)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+		mockService.AssertNotCalled(t, "ListReturns", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown status", func(t *testing.T) {
+		mockService := new(mockReceptionService)
+		handler := NewReceptionHandler(mockService)
+
+		mockService.On("ListReturns", mock.Anything, mock.Anything).Return((*models.Page[models.ReturnAPI])(nil), models.ErrInvalidFilter)
+
+		httpRequest := httptest.NewRequest("GET", "/pvz/"+pvzId.String()+"/returns?status=lost", nil)
+		httpRequest = mux.SetURLVars(httpRequest, map[string]string{"pvzId": pvzId.String()})
+		rec := httptest.NewRecorder()
+
+		handler.ListReturns(rec, httpRequest)
+
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+}
+
+func TestDispatchReturn(t *testing.T) {
+	tests := []struct {
+		name         string
+		returnId     string
+		mockError    error
+		answerStatus int
+	}{
+		{name: "dispatched", returnId: uuid.NewString(), answerStatus: http.StatusOK},
+		{name: "invalid id", returnId: "42", answerStatus: http.StatusBadRequest},
+		{name: "not queued", returnId: uuid.NewString(), mockError: models.ErrReturnTransition, answerStatus: http.StatusConflict},
+		{name: "not found", returnId: uuid.NewString(), mockError: models.ErrNotFound, answerStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockReceptionService)
+			handler := NewReceptionHandler(mockService)
+
+			if returnId, err := uuid.Parse(tt.returnId); err == nil {
+				mockService.On("DispatchReturn", mock.Anything, returnId).Return(tt.mockError)
+			}
+
+			httpRequest := httptest.NewRequest("POST", "/returns/"+tt.returnId+"/dispatch", nil)
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"returnId": tt.returnId})
+			rec := httptest.NewRecorder()
+
+			handler.DispatchReturn(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCancelReturn(t *testing.T) {
+	tests := []struct {
+		name         string
+		requestBody  string
+		wantReason   string
+		answerStatus int
+	}{
+		{name: "with reason", requestBody: `{"reason":" оформлен по ошибке "}`, wantReason: "оформлен по ошибке", answerStatus: http.StatusOK},
+		{name: "empty body", answerStatus: http.StatusOK},
+		{name: "invalid json", requestBody: `{"reason":`, answerStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockReceptionService)
+			handler := NewReceptionHandler(mockService)
+
+			returnId := uuid.New()
+			if tt.answerStatus == http.StatusOK {
+				mockService.On("CancelReturn", mock.Anything, returnId, tt.wantReason).Return(nil)
+			}
+
+			httpRequest := httptest.NewRequest("POST", "/returns/"+returnId.String()+"/cancel", bytes.NewBufferString(tt.requestBody))
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"returnId": returnId.String()})
+			rec := httptest.NewRecorder()
+
+			handler.CancelReturn(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
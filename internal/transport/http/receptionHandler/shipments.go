@@ -0,0 +1,168 @@
+package receptionHandler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"orderPickupPoint/internal/models"
+	"orderPickupPoint/internal/utils/errorsHandl"
+	"orderPickupPoint/internal/utils/queryParams"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func (h *ReceptionHandler) CreateShipment(w http.ResponseWriter, r *http.Request) {
+	var input models.ShipmentInputAPI
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	shipment, err := h.receptionService.CreateShipment(r.Context(), &input)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(shipment)
+}
+
+func (h *ReceptionHandler) GetShipment(w http.ResponseWriter, r *http.Request) {
+	shipmentId, err := uuid.Parse(mux.Vars(r)["shipmentId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	shipment, err := h.receptionService.GetShipment(r.Context(), shipmentId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(shipment)
+}
+
+// outbound shipments of the pvz, direction=inbound lists the ones coming to it
+func (h *ReceptionHandler) ListShipments(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	pvzId, err := uuid.Parse(mux.Vars(r)["pvzId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	direction := query.Get("direction")
+	if direction != "" && direction != "outbound" && direction != "inbound" {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	pagination, err := queryParams.ParsePagination(query)
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	shipments, err := h.receptionService.ListShipments(r.Context(), &models.ShipmentFilter{
+		PvzId:     pvzId,
+		Inbound:   direction == "inbound",
+		Statuses:  queryParams.List(query, "status"),
+		Cursor:    pagination.Cursor,
+		Page:      pagination.Page,
+		PageLimit: pagination.PageLimit,
+	})
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(shipments)
+}
+
+func (h *ReceptionHandler) DispatchShipment(w http.ResponseWriter, r *http.Request) {
+	shipmentId, err := uuid.Parse(mux.Vars(r)["shipmentId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	shipment, err := h.receptionService.DispatchShipment(r.Context(), shipmentId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(shipment)
+}
+
+func (h *ReceptionHandler) ReceiveShipment(w http.ResponseWriter, r *http.Request) {
+	shipmentId, err := uuid.Parse(mux.Vars(r)["shipmentId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	shipment, err := h.receptionService.ReceiveShipment(r.Context(), shipmentId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(shipment)
+}
+
+func (h *ReceptionHandler) CancelShipment(w http.ResponseWriter, r *http.Request) {
+	shipmentId, err := uuid.Parse(mux.Vars(r)["shipmentId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	// the reason is optional, so is the body
+	var body struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil && !errors.Is(err, io.EOF) {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	err = h.receptionService.CancelShipment(r.Context(), shipmentId, strings.TrimSpace(body.Reason))
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+}
+
+func (h *ReceptionHandler) GetShipmentHistory(w http.ResponseWriter, r *http.Request) {
+	shipmentId, err := uuid.Parse(mux.Vars(r)["shipmentId"])
+	if err != nil {
+		errorsHandl.SendJsonError(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.receptionService.GetShipmentHistory(r.Context(), shipmentId)
+	if err != nil {
+		errorsHandl.SendServiceError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(history)
+}
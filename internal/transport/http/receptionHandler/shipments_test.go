@@ -0,0 +1,187 @@
+package receptionHandler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"orderPickupPoint/internal/models"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func (m *mockReceptionService) CreateShipment(ctx context.Context, input *models.ShipmentInputAPI) (*models.ShipmentAPI, error) {
+	args := m.Called(ctx, input)
+	return args.Get(0).(*models.ShipmentAPI), args.Error(1)
+}
+
+func (m *mockReceptionService) ListShipments(ctx context.Context, filter *models.ShipmentFilter) (*models.Page[models.ShipmentAPI], error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).(*models.Page[models.ShipmentAPI]), args.Error(1)
+}
+
+func (m *mockReceptionService) ReceiveShipment(ctx context.Context, shipmentId uuid.UUID) (*models.ShipmentAPI, error) {
+	args := m.Called(ctx, shipmentId)
+	return args.Get(0).(*models.ShipmentAPI), args.Error(1)
+}
+
+func (m *mockReceptionService) CancelShipment(ctx context.Context, shipmentId uuid.UUID, reason string) error {
+	return m.Called(ctx, shipmentId, reason).Error(0)
+}
+
+func TestCreateShipment(t *testing.T) {
+	fromPvzId, toPvzId := uuid.New(), uuid.New()
+	validBody := fmt.Sprintf(`{"fromPvzId":"%s","toPvzId":"%s","productIds":["%s"]}`, fromPvzId, toPvzId, uuid.New())
+	tests := []struct {
+		name         string
+		requestBody  string
+		mockError    error
+		answerStatus int
+	}{
+		{name: "valid request", requestBody: validBody, answerStatus: http.StatusCreated},
+		{name: "invalid json", requestBody: `{"fromPvzId":`, answerStatus: http.StatusBadRequest},
+		{name: "invalid shipment", requestBody: validBody, mockError: fmt.Errorf("%w: no products", models.ErrInvalidShipment), answerStatus: http.StatusBadRequest},
+		{name: "product not shippable", requestBody: validBody, mockError: models.ErrNotShippable, answerStatus: http.StatusConflict},
+		{name: "unknown pvz", requestBody: validBody, mockError: models.ErrNotFound, answerStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockReceptionService)
+			handler := NewReceptionHandler(mockService)
+
+			if json.Valid([]byte(tt.requestBody)) {
+				mockService.On("CreateShipment", mock.Anything, mock.MatchedBy(func(input *models.ShipmentInputAPI) bool {
+					return input.FromPvzId == fromPvzId && input.ToPvzId == toPvzId && len(input.ProductIds) == 1
+				})).Return(&models.ShipmentAPI{Id: uuid.New(), Status: "created"}, tt.mockError)
+			}
+
+			httpRequest := httptest.NewRequest("POST", "/shipments", bytes.NewBufferString(tt.requestBody))
+			rec := httptest.NewRecorder()
+
+			handler.CreateShipment(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestListShipments(t *testing.T) {
+	tests := []struct {
+		name         string
+		query        string
+		wantInbound  bool
+		wantPage     int
+		wantLimit    int
+		answerStatus int
+	}{
+		{name: "outbound by default", query: "", wantPage: 1, wantLimit: 10, answerStatus: http.StatusOK},
+		{name: "inbound", query: "?direction=inbound", wantInbound: true, wantPage: 1, wantLimit: 10, answerStatus: http.StatusOK},
+		{name: "second page", query: "?page=2&limit=5", wantPage: 2, wantLimit: 5, answerStatus: http.StatusOK},
+		{name: "unknown direction", query: "?direction=sideways", answerStatus: http.StatusBadRequest},
+		{name: "invalid cursor", query: "?cursor=!!!", answerStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockReceptionService)
+			handler := NewReceptionHandler(mockService)
+
+			pvzId := uuid.New()
+			if tt.answerStatus == http.StatusOK {
+				mockService.On("ListShipments", mock.Anything, &models.ShipmentFilter{PvzId: pvzId, Inbound: tt.wantInbound, Page: tt.wantPage, PageLimit: tt.wantLimit}).
+					Return(&models.Page[models.ShipmentAPI]{Items: []models.ShipmentAPI{}, Page: tt.wantPage, Limit: tt.wantLimit}, nil)
+			}
+
+			httpRequest := httptest.NewRequest("GET", "/pvz/"+pvzId.String()+"/shipments"+tt.query, nil)
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"pvzId": pvzId.String()})
+			rec := httptest.NewRecorder()
+
+			handler.ListShipments(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestReceiveShipment(t *testing.T) {
+	tests := []struct {
+		name         string
+		mockError    error
+		answerStatus int
+	}{
+		{name: "arrived", answerStatus: http.StatusOK},
+		{name: "not dispatched", mockError: models.ErrShipmentTransition, answerStatus: http.StatusConflict},
+		{name: "reception opened meanwhile", mockError: models.ErrReceptionOpen, answerStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockReceptionService)
+			handler := NewReceptionHandler(mockService)
+
+			shipmentId, receptionId := uuid.New(), uuid.New()
+			mockService.On("ReceiveShipment", mock.Anything, shipmentId).
+				Return(&models.ShipmentAPI{Id: shipmentId, Status: "arrived", ReceptionId: &receptionId}, tt.mockError)
+
+			httpRequest := httptest.NewRequest("POST", "/shipments/"+shipmentId.String()+"/arrive", nil)
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"shipmentId": shipmentId.String()})
+			rec := httptest.NewRecorder()
+
+			handler.ReceiveShipment(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			if tt.answerStatus == http.StatusOK {
+				var response models.ShipmentAPI
+				require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+				require.Equal(t, receptionId, *response.ReceptionId)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCancelShipment(t *testing.T) {
+	tests := []struct {
+		name         string
+		shipmentId   string
+		requestBody  string
+		mockError    error
+		answerStatus int
+	}{
+		{name: "with reason", shipmentId: uuid.NewString(), requestBody: `{"reason":"ошибка"}`, answerStatus: http.StatusOK},
+		{name: "empty body", shipmentId: uuid.NewString(), answerStatus: http.StatusOK},
+		{name: "invalid id", shipmentId: "42", answerStatus: http.StatusBadRequest},
+		{name: "already dispatched", shipmentId: uuid.NewString(), mockError: models.ErrShipmentTransition, answerStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(mockReceptionService)
+			handler := NewReceptionHandler(mockService)
+
+			if shipmentId, err := uuid.Parse(tt.shipmentId); err == nil {
+				var body struct{ Reason string }
+				json.Unmarshal([]byte(tt.requestBody), &body)
+				mockService.On("CancelShipment", mock.Anything, shipmentId, body.Reason).Return(tt.mockError)
+			}
+
+			httpRequest := httptest.NewRequest("POST", "/shipments/"+tt.shipmentId+"/cancel", bytes.NewBufferString(tt.requestBody))
+			httpRequest = mux.SetURLVars(httpRequest, map[string]string{"shipmentId": tt.shipmentId})
+			rec := httptest.NewRecorder()
+
+			handler.CancelShipment(rec, httpRequest)
+
+			require.Equal(t, tt.answerStatus, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -2,8 +2,12 @@ package transport
 
 import (
 	"orderPickupPoint/internal/service"
+	"orderPickupPoint/internal/transport/http/attachmentHandler"
 	"orderPickupPoint/internal/transport/http/authHandler"
+	"orderPickupPoint/internal/transport/http/cellHandler"
+	"orderPickupPoint/internal/transport/http/orderHandler"
 	"orderPickupPoint/internal/transport/http/pickupPointHandler"
+	"orderPickupPoint/internal/transport/http/productTypeHandler"
 	"orderPickupPoint/internal/transport/http/receptionHandler"
 
 	"github.com/gorilla/mux"
@@ -25,6 +29,10 @@ func (h *Handler) InitRouter() *mux.Router {
 	authHandler := authHandler.NewAuthHandler(h.Services.Auth)
 	receptionHandler := receptionHandler.NewReceptionHandler(h.Services.Reception)
 	pupHandler := pickupPointHandler.NewPickupPointHandler(h.Services.PickupPoint)
+	productTypeHandler := productTypeHandler.NewProductTypeHandler(h.Services.ProductType)
+	attachmentHandler := attachmentHandler.NewAttachmentHandler(h.Services.Attachment)
+	orderHandler := orderHandler.NewOrderHandler(h.Services.Order)
+	cellHandler := cellHandler.NewCellHandler(h.Services.Cell)
 
 	modOnly := []string{"moderator"}
 	modAndEmpOnly := []string{"moderator", "employee"}
@@ -36,11 +44,73 @@ func (h *Handler) InitRouter() *mux.Router {
 
 	router.HandleFunc("/pvz", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(pupHandler.Create), modOnly)).Methods("POST")
 	router.HandleFunc("/pvz", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(pupHandler.GetReceptionsInfo), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/pvz/{pvzId}", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(pupHandler.GetDetails), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/pvz/{pvzId}/capacity", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(pupHandler.SetCapacity), modOnly)).Methods("PUT")
+	router.HandleFunc("/pvz/{pvzId}/storage-periods", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(pupHandler.SetStoragePeriods), modOnly)).Methods("PUT")
+	router.HandleFunc("/pvz/{pvzId}/expiring", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(pupHandler.ListExpiring), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/pvz/{pvzId}/overdue", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(pupHandler.ListOverdue), modAndEmpOnly)).Methods("GET")
+
+	router.HandleFunc("/product-types", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(productTypeHandler.List), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/product-types", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(productTypeHandler.Create), modOnly)).Methods("POST")
+	router.HandleFunc("/product-types/{code}", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(productTypeHandler.Update), modOnly)).Methods("PATCH")
 
 	router.Handle("/receptions", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.CreateReception), empOnly)).Methods("POST")
 	router.Handle("/products", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.AddProduct), empOnly)).Methods("POST")
+	router.HandleFunc("/products", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.FindProducts), modAndEmpOnly)).Methods("GET")
 	router.HandleFunc("/pvz/{pvzId}/delete_last_product", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.DeleteLastProduct), empOnly)).Methods("POST")
 	router.HandleFunc("/pvz/{pvzId}/close_last_reception", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.CloseReception), empOnly)).Methods("POST")
+	router.HandleFunc("/pvz/{pvzId}/receptions", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.ListReceptions), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/receptions/{receptionId}", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.GetReception), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/receptions/{receptionId}/history", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.GetReceptionHistory), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/receptions/{receptionId}/close", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.CloseReceptionById), empOnly)).Methods("POST")
+	router.HandleFunc("/receptions/{receptionId}/verify", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.VerifyReception), modOnly)).Methods("POST")
+	router.HandleFunc("/receptions/{receptionId}/cancel", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.CancelReception), modAndEmpOnly)).Methods("POST")
+	router.HandleFunc("/receptions/{receptionId}/manifest", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.SetManifest), modAndEmpOnly)).Methods("PUT")
+	router.HandleFunc("/receptions/{receptionId}/manifest", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.GetManifest), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/receptions/{receptionId}/reconciliation", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.GetReconciliation), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/receptions/{receptionId}/discrepancies", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.GetDiscrepancyReport), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/receptions/{receptionId}/products:batch", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.AddProductsBatch), empOnly)).Methods("POST")
+	router.HandleFunc("/receptions/{receptionId}/products/{productId}", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.DeleteProduct), empOnly)).Methods("DELETE")
+	router.HandleFunc("/receptions/{receptionId}/reopen-requests", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.RequestReopen), empOnly)).Methods("POST")
+	router.HandleFunc("/receptions/{receptionId}/reopen-requests", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.ListReopenRequests), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/reopen-requests", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.ListPendingReopenRequests), modOnly)).Methods("GET")
+	router.HandleFunc("/reopen-requests/{requestId}/approve", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.ApproveReopen), modOnly)).Methods("POST")
+	router.HandleFunc("/reopen-requests/{requestId}/reject", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.RejectReopen), modOnly)).Methods("POST")
+
+	router.HandleFunc("/returns", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.RegisterReturn), empOnly)).Methods("POST")
+	router.HandleFunc("/returns/{returnId}", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.GetReturn), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/returns/{returnId}/history", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.GetReturnHistory), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/returns/{returnId}/dispatch", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.DispatchReturn), empOnly)).Methods("POST")
+	router.HandleFunc("/returns/{returnId}/cancel", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.CancelReturn), modAndEmpOnly)).Methods("POST")
+	router.HandleFunc("/pvz/{pvzId}/returns", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.ListReturns), modAndEmpOnly)).Methods("GET")
+
+	router.HandleFunc("/shipments", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.CreateShipment), empOnly)).Methods("POST")
+	router.HandleFunc("/shipments/{shipmentId}", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.GetShipment), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/shipments/{shipmentId}/history", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.GetShipmentHistory), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/shipments/{shipmentId}/dispatch", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.DispatchShipment), empOnly)).Methods("POST")
+	router.HandleFunc("/shipments/{shipmentId}/arrive", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.ReceiveShipment), empOnly)).Methods("POST")
+	router.HandleFunc("/shipments/{shipmentId}/cancel", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.CancelShipment), modAndEmpOnly)).Methods("POST")
+	router.HandleFunc("/pvz/{pvzId}/shipments", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(receptionHandler.ListShipments), modAndEmpOnly)).Methods("GET")
+
+	router.HandleFunc("/receptions/{receptionId}/attachments", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(attachmentHandler.Upload), modAndEmpOnly)).Methods("POST")
+	router.HandleFunc("/receptions/{receptionId}/attachments", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(attachmentHandler.List), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/receptions/{receptionId}/products/{productId}/attachments", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(attachmentHandler.Upload), modAndEmpOnly)).Methods("POST")
+	router.HandleFunc("/attachments/{attachmentId}/content", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(attachmentHandler.Download), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/attachments/{attachmentId}/thumbnail", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(attachmentHandler.DownloadThumbnail), modAndEmpOnly)).Methods("GET")
+
+	router.HandleFunc("/orders", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(orderHandler.CreateOrder), modAndEmpOnly)).Methods("POST")
+	router.HandleFunc("/orders/{orderId}", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(orderHandler.GetOrder), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/orders/{orderId}/history", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(orderHandler.GetOrderHistory), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/orders/{orderId}/issue", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(orderHandler.IssueOrder), empOnly)).Methods("POST")
+	router.HandleFunc("/orders/{orderId}/cancel", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(orderHandler.CancelOrder), modAndEmpOnly)).Methods("POST")
+	router.HandleFunc("/orders/{orderId}/pickup-code", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(orderHandler.RegeneratePickupCode), modAndEmpOnly)).Methods("POST")
+	router.HandleFunc("/pvz/{pvzId}/orders", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(orderHandler.ListOrders), modAndEmpOnly)).Methods("GET")
+
+	router.HandleFunc("/pvz/{pvzId}/cells", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(cellHandler.CreateCells), modOnly)).Methods("POST")
+	router.HandleFunc("/pvz/{pvzId}/cells", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(cellHandler.ListCells), modAndEmpOnly)).Methods("GET")
+	router.HandleFunc("/cells/{cellId}", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(cellHandler.UpdateCell), modOnly)).Methods("PATCH")
+	router.HandleFunc("/products/{productId}/move", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(cellHandler.MoveProduct), modAndEmpOnly)).Methods("POST")
+	router.HandleFunc("/pvz/{pvzId}/placements", authHandler.IsAvaliableRoleMiddleware(authHandler.IsSignedInMiddleware(cellHandler.FindPlacements), modAndEmpOnly)).Methods("GET")
 
 	return router
 }
@@ -0,0 +1,64 @@
+package barcode
+
+import (
+	"fmt"
+	"orderPickupPoint/internal/models"
+)
+
+const (
+	FormatEAN13 = "ean13"
+	// any other scanned payload, e.g. of Code128 or Code39 labels. The scanner decodes the symbology,
+	// only the decoded text reaches the server, so just the characters and the length are checked
+	FormatPrintable = "printable"
+
+	maxPrintableLength = 80
+)
+
+// returns the format of a scanned barcode. 13 digits are treated as EAN-13 and must
+// have a valid check digit, anything else must be printable ASCII of up to 80 characters.
+// The error wraps models.ErrInvalidBarcode
+func Validate(code string) (string, error) {
+	if len(code) == 13 && isDigits(code) {
+		if !validEAN13(code) {
+			return "", invalid(code, "wrong EAN-13 check digit")
+		}
+		return FormatEAN13, nil
+	}
+
+	if len(code) == 0 || len(code) > maxPrintableLength {
+		return "", invalid(code, fmt.Sprintf("1 to %d characters expected", maxPrintableLength))
+	}
+	for i := 0; i < len(code); i++ {
+		if code[i] < 32 || code[i] > 126 {
+			return "", invalid(code, "only printable ASCII characters are allowed")
+		}
+	}
+	return FormatPrintable, nil
+}
+
+func invalid(code, reason string) error {
+	return fmt.Errorf("%w %q: %s", models.ErrInvalidBarcode, code, reason)
+}
+
+func isDigits(code string) bool {
+	for i := 0; i < len(code); i++ {
+		if code[i] < '0' || code[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// digits in odd positions have weight 1, in even positions 3; the sum with the check digit is a multiple of 10
+func validEAN13(code string) bool {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		digit := int(code[i] - '0')
+		if i%2 == 1 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	check := (10 - sum%10) % 10
+	return check == int(code[12]-'0')
+}
@@ -0,0 +1,36 @@
+package barcode
+
+import (
+	"orderPickupPoint/internal/models"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		code       string
+		wantFormat string
+		wantError  error
+	}{
+		{"ean13", "4006381333931", FormatEAN13, nil},
+		{"ean13 zero check digit", "5901234123457", FormatEAN13, nil},
+		{"ean13 bad check digit", "4006381333932", "", models.ErrInvalidBarcode},
+		{"printable", "WB-12345/A", FormatPrintable, nil},
+		{"printable digits", "123456789012", FormatPrintable, nil},
+		{"empty", "", "", models.ErrInvalidBarcode},
+		{"control characters", "ABC\t123", "", models.ErrInvalidBarcode},
+		{"non ascii", "обувь-1", "", models.ErrInvalidBarcode},
+		{"too long", strings.Repeat("A", maxPrintableLength+1), "", models.ErrInvalidBarcode},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, err := Validate(tt.code)
+			require.ErrorIs(t, err, tt.wantError)
+			require.Equal(t, tt.wantFormat, format)
+		})
+	}
+}
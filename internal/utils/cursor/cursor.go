@@ -0,0 +1,85 @@
+package cursor
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"slices"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// position in a list sorted by some field, the id breaks ties between equal sort keys.
+// Clients get it as an opaque string and send it back to get the next or previous page
+type Cursor struct {
+	SortBy  string `json:"s"`
+	Desc    bool   `json:"d,omitempty"`
+	SortKey string `json:"k"`
+	Id      string `json:"i"`
+
+	// the page before the cursor position is requested
+	Backward bool `json:"b,omitempty"`
+}
+
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func Decode(encoded string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &Cursor{}
+	if err := json.Unmarshal(data, c); err != nil || c.Id == "" {
+		return nil, ErrInvalidCursor
+	}
+	return c, nil
+}
+
+// checks that the cursor was created for the same list ordering
+func (c *Cursor) Matches(sortBy string, desc bool) bool {
+	return c.SortBy == sortBy && c.Desc == desc
+}
+
+// encoded cursor or nil, convenient for optional json fields
+func EncodeOrNil(c *Cursor) *string {
+	if c == nil {
+		return nil
+	}
+	encoded := c.Encode()
+	return &encoded
+}
+
+// takes rows fetched with limit+1 in the direction of current cursor (or from offset
+// when there is no cursor) and returns the page in list order with cursors of the
+// neighbouring pages. key returns a forward cursor pointing at the row
+func Slice[T any](rows []T, limit int, current *Cursor, offset int, key func(row T) Cursor) ([]T, *Cursor, *Cursor) {
+	backward := current != nil && current.Backward
+
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+	if backward {
+		slices.Reverse(rows)
+	}
+	if len(rows) == 0 {
+		return rows, nil, nil
+	}
+
+	var next, prev *Cursor
+	cameFromPage := current != nil || offset > 0
+	if (!backward && hasMore) || (backward && cameFromPage) {
+		c := key(rows[len(rows)-1])
+		next = &c
+	}
+	if (backward && hasMore) || (!backward && cameFromPage) {
+		c := key(rows[0])
+		c.Backward = true
+		prev = &c
+	}
+	return rows, next, prev
+}
@@ -0,0 +1,127 @@
+package cursor
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor *Cursor
+	}{
+		{
+			name:   "forward cursor",
+			cursor: &Cursor{SortBy: "city", SortKey: "Москва", Id: "2099bc4c-0dba-44c5-87ab-7fb0811cf83e"},
+		},
+		{
+			name:   "backward desc cursor",
+			cursor: &Cursor{SortBy: "regDate", Desc: true, SortKey: "2025-04-21", Id: "1", Backward: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decoded, err := Decode(tt.cursor.Encode())
+			require.NoError(t, err)
+			require.Equal(t, tt.cursor, decoded)
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{name: "not base64", encoded: "!!!"},
+		{name: "not json", encoded: "bm90IGpzb24"},
+		{name: "without id", encoded: (&Cursor{SortBy: "city"}).Encode()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.encoded)
+			require.ErrorIs(t, err, ErrInvalidCursor)
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	c := &Cursor{SortBy: "city", Desc: true, Id: "1"}
+	require.True(t, c.Matches("city", true))
+	require.False(t, c.Matches("city", false))
+	require.False(t, c.Matches("regDate", true))
+}
+
+func TestSlice(t *testing.T) {
+	key := func(row int) Cursor {
+		return Cursor{SortBy: "n", Id: strconv.Itoa(row)}
+	}
+
+	tests := []struct {
+		name     string
+		rows     []int
+		current  *Cursor
+		offset   int
+		wantRows []int
+		wantNext string
+		wantPrev string
+	}{
+		{
+			name:     "first page with more rows",
+			rows:     []int{1, 2, 3},
+			wantRows: []int{1, 2},
+			wantNext: "2",
+		},
+		{
+			name:     "last page by offset",
+			rows:     []int{5, 6},
+			offset:   4,
+			wantRows: []int{5, 6},
+			wantPrev: "5",
+		},
+		{
+			name:     "forward from cursor",
+			rows:     []int{3, 4, 5},
+			current:  &Cursor{SortBy: "n", Id: "2"},
+			wantRows: []int{3, 4},
+			wantNext: "4",
+			wantPrev: "3",
+		},
+		{
+			name:     "backward to the first page",
+			rows:     []int{2, 1},
+			current:  &Cursor{SortBy: "n", Id: "3", Backward: true},
+			wantRows: []int{1, 2},
+			wantNext: "2",
+		},
+		{
+			name:     "empty page",
+			rows:     []int{},
+			current:  &Cursor{SortBy: "n", Id: "3"},
+			wantRows: []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, next, prev := Slice(tt.rows, 2, tt.current, tt.offset, key)
+			require.Equal(t, tt.wantRows, rows)
+			if tt.wantNext == "" {
+				require.Nil(t, next)
+			} else {
+				require.Equal(t, tt.wantNext, next.Id)
+				require.False(t, next.Backward)
+			}
+			if tt.wantPrev == "" {
+				require.Nil(t, prev)
+			} else {
+				require.Equal(t, tt.wantPrev, prev.Id)
+				require.True(t, prev.Backward)
+			}
+		})
+	}
+}
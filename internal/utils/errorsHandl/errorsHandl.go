@@ -2,7 +2,9 @@ package errorsHandl
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"orderPickupPoint/internal/models"
 )
 
 type jsonError struct {
@@ -16,3 +18,82 @@ func SendJsonError(w http.ResponseWriter, message string, statusCode int) {
 		Message: message,
 	})
 }
+
+// domain errors sent with 409 and 400 and their own message
+var (
+	conflictErrors = []error{
+		models.ErrCapacityExceeded,
+		models.ErrIllegalTransition,
+		models.ErrReceptionOpen,
+		models.ErrReceptionNotOpen,
+		models.ErrReopenPending,
+		models.ErrReopenResolved,
+		models.ErrDuplicateBarcode,
+		models.ErrDiscrepancyExceeded,
+		models.ErrProductTypeExists,
+		models.ErrOrderExists,
+		models.ErrOrderNotReady,
+		models.ErrOrderClosed,
+		models.ErrOrderTransition,
+		models.ErrItemNotIssuable,
+		models.ErrNoPickupCode,
+		models.ErrNoCustomerPhone,
+		models.ErrPickupCodeLocked,
+		models.ErrNotReturnable,
+		models.ErrReturnExists,
+		models.ErrReturnTransition,
+		models.ErrCellExists,
+		models.ErrCellFull,
+		models.ErrCellMismatch,
+		models.ErrNotShippable,
+		models.ErrShipmentTransition,
+	}
+	badRequestErrors = []error{
+		models.ErrUnknownProductType,
+		models.ErrInactiveProductType,
+		models.ErrInvalidProductType,
+		models.ErrInvalidAttributes,
+		models.ErrInvalidCondition,
+		models.ErrInvalidFilter,
+		models.ErrInvalidBatch,
+		models.ErrInvalidBarcode,
+		models.ErrInvalidManifest,
+		models.ErrInvalidOrder,
+		models.ErrPickupCodeRequired,
+		models.ErrInvalidReturn,
+		models.ErrInvalidCell,
+		models.ErrUnknownCell,
+		models.ErrInvalidShipment,
+		models.ErrInvalidStorageDays,
+		models.ErrInvalidCapacity,
+	}
+)
+
+// known domain errors are sent with their own status and message, everything else is a bad request
+func SendServiceError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, models.ErrNotFound):
+		SendJsonError(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, models.ErrAttachmentTooLarge):
+		SendJsonError(w, err.Error(), http.StatusRequestEntityTooLarge)
+	case errors.Is(err, models.ErrWrongPickupCode):
+		SendJsonError(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, models.ErrUnsupportedMedia):
+		SendJsonError(w, err.Error(), http.StatusUnsupportedMediaType)
+	case isOneOf(err, conflictErrors):
+		SendJsonError(w, err.Error(), http.StatusConflict)
+	case isOneOf(err, badRequestErrors):
+		SendJsonError(w, err.Error(), http.StatusBadRequest)
+	default:
+		SendJsonError(w, "Bad request", http.StatusBadRequest)
+	}
+}
+
+func isOneOf(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package queryParams
+
+import (
+	"errors"
+	"net/url"
+	"orderPickupPoint/internal/utils/cursor"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 30
+)
+
+var ErrBadParam = errors.New("bad query param")
+
+// supports both repeated params (?city=a&city=b) and comma separated values (?city=a,b)
+func List(query url.Values, key string) []string {
+	var out []string
+	for _, value := range query[key] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
+
+// optional RFC3339 time, nil if the param is not set
+func Time(query url.Values, key string) (*time.Time, error) {
+	value := query.Get(key)
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return nil, ErrBadParam
+	}
+	return &t, nil
+}
+
+// optional boolean, false if the param is not set
+func Bool(query url.Values, key string) (bool, error) {
+	value := query.Get(key)
+	if value == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, ErrBadParam
+	}
+	return b, nil
+}
+
+type Pagination struct {
+	Page      int
+	PageLimit int
+	Cursor    *cursor.Cursor
+}
+
+// reads page, limit and cursor params. Page defaults to 1 and limit to 10
+func ParsePagination(query url.Values) (*Pagination, error) {
+	out := &Pagination{
+		Page:      1,
+		PageLimit: defaultPageLimit,
+	}
+
+	if page := query.Get("page"); page != "" {
+		val, err := strconv.Atoi(page)
+		if err != nil || val < 1 {
+			return nil, ErrBadParam
+		}
+		out.Page = val
+	}
+
+	if pageLimit := query.Get("limit"); pageLimit != "" {
+		val, err := strconv.Atoi(pageLimit)
+		if err != nil || val < 1 || val > maxPageLimit {
+			return nil, ErrBadParam
+		}
+		out.PageLimit = val
+	}
+
+	if pageCursor := query.Get("cursor"); pageCursor != "" {
+		c, err := cursor.Decode(pageCursor)
+		if err != nil {
+			return nil, ErrBadParam
+		}
+		out.Cursor = c
+	}
+
+	return out, nil
+}
@@ -0,0 +1,44 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+)
+
+// longest side of a thumbnail in pixels
+const MaxSide = 256
+
+const ContentType = "image/jpeg"
+
+// scales the image down to fit MaxSide keeping the aspect ratio (nearest neighbour)
+// and encodes it as jpeg. Smaller images keep their size
+func Make(src image.Image) ([]byte, image.Point, error) {
+	bounds := src.Bounds()
+	size := fit(bounds.Dx(), bounds.Dy())
+
+	dst := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	for y := 0; y < size.Y; y++ {
+		srcY := bounds.Min.Y + y*bounds.Dy()/size.Y
+		for x := 0; x < size.X; x++ {
+			srcX := bounds.Min.X + x*bounds.Dx()/size.X
+			dst.Set(x, y, src.At(srcX, srcY))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, image.Point{}, err
+	}
+	return buf.Bytes(), size, nil
+}
+
+func fit(width, height int) image.Point {
+	if width <= MaxSide && height <= MaxSide {
+		return image.Pt(width, height)
+	}
+	if width >= height {
+		return image.Pt(MaxSide, max(1, height*MaxSide/width))
+	}
+	return image.Pt(max(1, width*MaxSide/height), MaxSide)
+}
@@ -0,0 +1,40 @@
+package thumbnail
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		name   string
+		width  int
+		height int
+		want   image.Point
+	}{
+		{name: "landscape", width: 1024, height: 512, want: image.Pt(256, 128)},
+		{name: "portrait", width: 300, height: 1200, want: image.Pt(64, 256)},
+		{name: "thin strip", width: 4000, height: 2, want: image.Pt(256, 1)},
+		{name: "small image keeps size", width: 100, height: 50, want: image.Pt(100, 50)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+			src.Set(0, 0, color.White)
+
+			data, size, err := Make(src)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, size)
+
+			cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+			require.NoError(t, err)
+			require.Equal(t, tt.want, image.Pt(cfg.Width, cfg.Height))
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package userCtx
+
+import (
+	"context"
+	"orderPickupPoint/internal/models"
+)
+
+type ctxKey struct{}
+
+func WithUser(ctx context.Context, user *models.User) context.Context {
+	return context.WithValue(ctx, ctxKey{}, user)
+}
+
+func User(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(ctxKey{}).(*models.User)
+	return user, ok && user != nil
+}
+
+// id of the user who performs the request, nil if the request is anonymous
+func UserId(ctx context.Context) *int {
+	user, ok := User(ctx)
+	if !ok {
+		return nil
+	}
+	id := user.Id
+	return &id
+}
@@ -0,0 +1,23 @@
+package userCtx
+
+import (
+	"context"
+	"orderPickupPoint/internal/models"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserId(t *testing.T) {
+	ctx := context.Background()
+	require.Nil(t, UserId(ctx))
+
+	ctx = WithUser(ctx, &models.User{Id: 7, Role: "employee"})
+	id := UserId(ctx)
+	require.NotNil(t, id)
+	require.Equal(t, 7, *id)
+
+	user, ok := User(ctx)
+	require.True(t, ok)
+	require.Equal(t, "employee", user.Role)
+}
@@ -93,7 +93,7 @@ func TestPvzLogic(t *testing.T) {
 	}
 
 	for num, item := range products {
-		t.Logf("new item %d: %s", num, item)
+		t.Logf("new item %d: %v", num, item)
 	}
 
 	closeReceptionUrl := fmt.Sprintf("/pvz/%s/close_last_reception", createPvz_jsonResp.Id)